@@ -0,0 +1,60 @@
+package engagespot_test
+
+import (
+	"context"
+	"testing"
+
+	engagespot "github.com/ssiyad/engagespot-go"
+	"github.com/stretchr/testify/assert"
+)
+
+// the exported types can be named by users of the package, and the documented entry points build
+// as they did when the types were unexported
+
+type service struct {
+	client *engagespot.Client
+}
+
+var digest = func() *engagespot.Notification {
+	n, _ := engagespot.NewEngagespotClient("A", "B").NewNotification("Weekly digest")
+	return n
+}()
+
+func build(c *engagespot.Client, title string) (*engagespot.Notification, error) {
+	n, err := c.NewNotification(title)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := n.SetMessage("hello"); err != nil {
+		return nil, err
+	}
+	if _, err := n.AddRecipient("a"); err != nil {
+		return nil, err
+	}
+	return n, nil
+}
+
+func TestExportedTypes(t *testing.T) {
+	s := service{client: engagespot.NewEngagespotClient("A", "B", engagespot.WithReadOnly())}
+	var _ engagespot.EngagespotClient = s.client
+
+	n, err := build(s.client, "Hello")
+	assert.NoError(t, err)
+	var content *engagespot.Content = n.Notification
+	assert.Equal(t, "hello", content.Message)
+
+	var override engagespot.Override
+	override.AddChannel(engagespot.ChannelEmail)
+	n.Override = &override
+	assert.Equal(t, []string{engagespot.ChannelEmail}, n.Snapshot().Channels)
+
+	// sending still goes through the client the notification was built with
+	_, err = n.SendCtx(context.Background())
+	assert.ErrorIs(t, err, engagespot.ErrReadOnlyClient)
+
+	assert.Equal(t, "Weekly digest", digest.Notification.Title)
+
+	c, err := engagespot.NewClient("A", "B")
+	assert.NoError(t, err)
+	assert.IsType(t, &engagespot.Client{}, c)
+}
@@ -0,0 +1,89 @@
+package engagespot
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// AppInfo describes the Engagespot app the client's credentials belong to
+type AppInfo struct {
+	Response
+	Id              string   `json:"id"`
+	Name            string   `json:"name"`
+	EnabledChannels []string `json:"enabledChannels"`
+}
+
+// GetAppInfo can be used to fetch information about the app, like its enabled channels
+func (c *Client) GetAppInfo() (*AppInfo, error) {
+	req, err := c.newRequest(endpointGetAppInfo, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	info := &AppInfo{}
+	if err := c.callJSON(endpointGetAppInfo, req, info); err != nil {
+		return nil, err
+	}
+	return info, nil
+}
+
+// enabled channels of the app, fetched lazily and refreshed every interval
+type channelCache struct {
+	interval time.Duration
+
+	mu        sync.Mutex
+	channels  []string
+	fetchedAt time.Time
+}
+
+// WithChannelValidation makes Send reject notifications overriding channels which are not enabled
+// for the app, since those silently deliver nothing. enabled channels are fetched using GetAppInfo
+// on first use and refreshed every refresh. if they can't be fetched, validation is skipped with
+// a warning
+func WithChannelValidation(refresh time.Duration) Option {
+	return func(c *Client) {
+		c.channelValidation = &channelCache{interval: refresh}
+	}
+}
+
+// enabled channels of the app, from cache when it's recent enough
+func (c *Client) enabledChannels() ([]string, error) {
+	cache := c.channelValidation
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+
+	if cache.channels != nil && c.now().Sub(cache.fetchedAt) < cache.interval {
+		return cache.channels, nil
+	}
+
+	info, err := c.GetAppInfo()
+	if err != nil {
+		return nil, err
+	}
+	cache.channels = append([]string{}, info.EnabledChannels...)
+	sort.Strings(cache.channels)
+	cache.fetchedAt = c.now()
+	return cache.channels, nil
+}
+
+// reject channels not enabled for the app
+func (c *Client) validateChannels(channels []string) ([]Warning, error) {
+	if len(channels) == 0 {
+		return nil, nil
+	}
+
+	enabled, err := c.enabledChannels()
+	if err != nil {
+		return []Warning{{Field: "channels", Message: "channel validation skipped: " + err.Error()}}, nil
+	}
+
+	for _, channel := range channels {
+		if !containsString(enabled, channel) {
+			return nil, fmt.Errorf("channel %q is not enabled for this app, enabled channels are: %s", channel, strings.Join(enabled, ", "))
+		}
+	}
+	return nil, nil
+}
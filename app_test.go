@@ -0,0 +1,115 @@
+package engagespot
+
+import (
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// fake API serving app info with the given channels, or failing when channels is nil
+type appServer struct {
+	mu       sync.Mutex
+	channels string
+	fetches  int
+}
+
+func (s *appServer) setChannels(channels string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.channels = channels
+}
+
+func (s *appServer) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Method == http.MethodPost {
+		return respondWith(200, `{}`).RoundTrip(req)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.fetches++
+	if s.channels == "" {
+		return respondWith(503, `{}`).RoundTrip(req)
+	}
+	return respondWith(200, `{"id":"app","name":"App","enabledChannels":`+s.channels+`}`).RoundTrip(req)
+}
+
+func newChannelClient(s *appServer, clock *fakeClock) *Client {
+	c := NewEngagespotClient("A", "B", WithChannelValidation(time.Minute))
+	c.httpClient.Transport = s
+	c.now = clock.Now
+	return c
+}
+
+func sendThrough(c *Client, channels ...string) (*Notification, error) {
+	n, _ := c.NewNotification("Hello")
+	n.SetIcon("https://example.com/icon.svg")
+	n.AddRecipient("a")
+	for _, channel := range channels {
+		n.Override.AddChannel(channel)
+	}
+	_, err := n.Send()
+	return n, err
+}
+
+func TestChannelValidationEnabled(t *testing.T) {
+	s := &appServer{channels: `["inApp","email"]`}
+	c := newChannelClient(s, newFakeClock())
+
+	n, err := sendThrough(c, "email", "inApp")
+	assert.NoError(t, err)
+	assert.Empty(t, n.Warnings())
+}
+
+func TestChannelValidationDisabled(t *testing.T) {
+	s := &appServer{channels: `["inApp","email"]`}
+	c := newChannelClient(s, newFakeClock())
+	c.httpClient.Transport = roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		if req.Method == http.MethodPost {
+			t.Fatal("notification with a disabled channel was sent")
+		}
+		return s.RoundTrip(req)
+	})
+
+	_, err := sendThrough(c, "email", "sms")
+	assert.EqualError(t, err, `channel "sms" is not enabled for this app, enabled channels are: email, inApp`)
+}
+
+func TestChannelValidationCacheRefresh(t *testing.T) {
+	s := &appServer{channels: `["inApp"]`}
+	clock := newFakeClock()
+	c := newChannelClient(s, clock)
+
+	_, err := sendThrough(c, "inApp")
+	assert.NoError(t, err)
+	_, err = sendThrough(c, "sms")
+	assert.Error(t, err)
+	assert.Equal(t, 1, s.fetches)
+
+	s.setChannels(`["inApp","sms"]`)
+	clock.Advance(time.Minute)
+	_, err = sendThrough(c, "sms")
+	assert.NoError(t, err)
+	assert.Equal(t, 2, s.fetches)
+}
+
+func TestChannelValidationEndpointUnavailable(t *testing.T) {
+	s := &appServer{}
+	c := newChannelClient(s, newFakeClock())
+
+	n, err := sendThrough(c, "sms")
+	assert.NoError(t, err)
+	assert.Len(t, n.Warnings(), 1)
+	assert.Equal(t, "channels", n.Warnings()[0].Field)
+}
+
+func TestChannelValidationWithoutOverride(t *testing.T) {
+	s := &appServer{}
+	c := newChannelClient(s, newFakeClock())
+
+	_, err := sendThrough(c)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, s.fetches)
+}
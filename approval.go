@@ -0,0 +1,97 @@
+package engagespot
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+)
+
+// ErrSendNotApproved is returned by Send when the approval gate denied the send
+var ErrSendNotApproved = errors.New("send not approved")
+
+// ErrInvalidApprovalToken is returned by Send when the token given to PreApproved doesn't match
+// the notification
+var ErrInvalidApprovalToken = errors.New("invalid approval token")
+
+// SendSummary describes a send waiting for approval
+type SendSummary struct {
+	Title    string
+	Category string
+	// 0 for notifications with a recipient source, see SetRecipientSource
+	Recipients int
+	// topics or segments of a broadcast, see AddTopic
+	Topics   []string
+	Channels []string
+}
+
+// WithApprovalGate makes every send to more than threshold recipients, and every broadcast to
+// topics, wait for approve to allow it. a denial fails the send with ErrSendNotApproved, an error from approve fails it as is
+func WithApprovalGate(threshold int, approve func(ctx context.Context, summary SendSummary) (bool, error)) Option {
+	return func(c *Client) {
+		c.declare(optApprovalGate)
+		c.approvalThreshold = threshold
+		c.approve = approve
+	}
+}
+
+// PreApproved skips the approval gate for a send which has been approved beforehand. token must
+// be produced by ApprovalToken for the very same notification
+func PreApproved(token string) CallOption {
+	return func(o *callOptions) {
+		o.approvalToken = &token
+	}
+}
+
+type approvalTokenKey struct{}
+
+// ApprovalToken can be used to approve a notification ahead of sending it, see PreApproved. the
+// token is an HMAC of the notification payload, so any change to the notification invalidates it
+func (c *Client) ApprovalToken(n *Notification) (string, error) {
+	payload, err := json.Marshal(n)
+	if err != nil {
+		return "", err
+	}
+	hash := sha256.Sum256(payload)
+
+	h := hmac.New(sha256.New, []byte(c.apiSecret))
+	h.Write(hash[:])
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// run the approval gate for n, if the send requires it
+func (c *Client) checkApproval(ctx context.Context, n *Notification) error {
+	// the number of recipients of a source or a topic isn't known before sending, so it always
+	// needs approval
+	if c.approve == nil || (n.recipientSource == nil && len(n.Topics) == 0 && len(n.Recipients) <= c.approvalThreshold) {
+		return nil
+	}
+
+	if token, ok := ctx.Value(approvalTokenKey{}).(string); ok {
+		expected, err := c.ApprovalToken(n)
+		if err != nil {
+			return err
+		}
+		if !hmac.Equal([]byte(token), []byte(expected)) {
+			return ErrInvalidApprovalToken
+		}
+		return nil
+	}
+
+	approved, err := c.approve(ctx, SendSummary{
+		Title:      n.Notification.Title,
+		Category:   n.Category,
+		Recipients: len(n.Recipients),
+		Topics:     n.Topics,
+		Channels:   n.Override.Channels,
+	})
+	if err != nil {
+		return err
+	}
+	if !approved {
+		return ErrSendNotApproved
+	}
+	return nil
+}
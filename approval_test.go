@@ -0,0 +1,98 @@
+package engagespot
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func gatedClient(t *testing.T, approved bool, err error) (*Client, *[]SendSummary) {
+	var summaries []SendSummary
+	c := NewEngagespotClient("A", "B", WithApprovalGate(2, func(ctx context.Context, s SendSummary) (bool, error) {
+		summaries = append(summaries, s)
+		return approved, err
+	}))
+	c.httpClient.Transport = respondWith(200, `{}`)
+	return c, &summaries
+}
+
+func notificationTo(c *Client, count int) *Notification {
+	n := newMessage(c, recipientIds(count)...)
+	n.SetCategory("promo")
+	n.Override.AddChannel("email")
+	return n
+}
+
+func TestApprovalGateThreshold(t *testing.T) {
+	c, summaries := gatedClient(t, false, nil)
+
+	_, err := notificationTo(c, 2).Send()
+	assert.NoError(t, err)
+	assert.Empty(t, *summaries)
+
+	_, err = notificationTo(c, 3).Send()
+	assert.ErrorIs(t, err, ErrSendNotApproved)
+	assert.Equal(t, []SendSummary{{Title: "Hello", Category: "promo", Recipients: 3, Channels: []string{"email"}}}, *summaries)
+}
+
+func TestApprovalGateApproved(t *testing.T) {
+	c, summaries := gatedClient(t, true, nil)
+
+	res, err := notificationTo(c, 3).Send()
+	assert.NoError(t, err)
+	assert.Equal(t, 200, res.StatusCode)
+	assert.Len(t, *summaries, 1)
+}
+
+func TestApprovalGateError(t *testing.T) {
+	c, _ := gatedClient(t, true, errors.New("approver unreachable"))
+	c.httpClient.Transport = noNetwork(t)
+
+	_, err := notificationTo(c, 3).Send()
+	assert.EqualError(t, err, "approver unreachable")
+}
+
+func TestApprovalGatePreApproved(t *testing.T) {
+	c, summaries := gatedClient(t, false, nil)
+
+	n := notificationTo(c, 3)
+	token, err := c.ApprovalToken(n)
+	assert.NoError(t, err)
+
+	_, err = n.Send(PreApproved(token))
+	assert.NoError(t, err)
+	assert.Empty(t, *summaries)
+
+	// the token is bound to the notification it was produced for
+	n.AddRecipient("someone-else")
+	_, err = n.Send(PreApproved(token))
+	assert.ErrorIs(t, err, ErrInvalidApprovalToken)
+
+	_, err = notificationTo(c, 3).Send(PreApproved("forged"))
+	assert.ErrorIs(t, err, ErrInvalidApprovalToken)
+
+	other := NewEngagespotClient("A", "other secret")
+	otherToken, _ := other.ApprovalToken(notificationTo(c, 3))
+	_, err = notificationTo(c, 3).Send(PreApproved(otherToken))
+	assert.ErrorIs(t, err, ErrInvalidApprovalToken)
+}
+
+func TestApprovalGateBroadcast(t *testing.T) {
+	c, summaries := gatedClient(t, false, nil)
+
+	// a broadcast reaches an unknown number of recipients, under the threshold or not
+	n, _ := c.NewNotification("Hello")
+	n.SetCategory("promo")
+	n.Override.AddChannel("email")
+	n.AddTopic("all-beta-users")
+	_, err := n.Send()
+	assert.ErrorIs(t, err, ErrSendNotApproved)
+	assert.Equal(t, []SendSummary{{Title: "Hello", Category: "promo", Topics: []string{"all-beta-users"}, Channels: []string{"email"}}}, *summaries)
+
+	token, err := c.ApprovalToken(n)
+	assert.NoError(t, err)
+	_, err = n.Send(PreApproved(token))
+	assert.NoError(t, err)
+}
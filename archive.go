@@ -0,0 +1,260 @@
+package engagespot
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ArchiveMode selects how notifications are handed to an Archiver
+type ArchiveMode int
+
+const (
+	// ArchiveSync archives every notification before Send returns. an archive failure fails the
+	// send, even though the notification has already been accepted by the API
+	ArchiveSync ArchiveMode = iota
+	// ArchiveAsync archives notifications in the background on a best-effort basis. records which
+	// don't fit in the buffer are dropped and counted, see ArchiveDropped
+	ArchiveAsync
+)
+
+// size of the buffer of records waiting to be archived in ArchiveAsync mode
+const defaultArchiveBufferSize = 256
+
+// ArchiveOutcome is what happened to an archived notification
+type ArchiveOutcome string
+
+const (
+	// the API answered the request, whatever the status
+	OutcomeSent ArchiveOutcome = "sent"
+	// the request failed before getting an answer
+	OutcomeFailed ArchiveOutcome = "failed"
+	// the notification was never sent, because a Dispatcher shut down before getting to it
+	OutcomeAbandoned ArchiveOutcome = "abandoned"
+)
+
+// ArchiveAttempt is a single request made for an archived notification
+type ArchiveAttempt struct {
+	// wait before the attempt, see WithRandSource
+	Backoff    time.Duration
+	StartedAt  time.Time
+	Duration   time.Duration
+	StatusCode int
+	Err        string
+}
+
+// ArchiveRecord is a copy of an outbound notification along with its outcome
+type ArchiveRecord struct {
+	// payload exactly as sent, minus the fields removed by WithArchiveFieldFilter and with the
+	// fields encrypted by WithArchiveEncryption
+	Payload    []byte
+	Outcome    ArchiveOutcome
+	StatusCode int
+	Err        string
+	StartedAt  time.Time
+	Duration   time.Duration
+	Attempts   []ArchiveAttempt
+	// warnings of the send, see Warnings
+	Warnings []Warning
+	// state read from the response, before any wait set with WaitForProcessing
+	State SendState
+}
+
+// Archiver receives a record of every notification sent by the client, for example to copy them
+// into a data warehouse. implementations must be safe for concurrent use
+type Archiver interface {
+	Archive(ctx context.Context, record ArchiveRecord) error
+}
+
+// WithArchiver can be used to archive every outbound notification with the Archiver a, inline or in
+// the background depending on mode. a record is made for every request, so a send split into
+// chunks or channel groups is archived once per request
+func WithArchiver(a Archiver, mode ArchiveMode) Option {
+	return func(c *Client) {
+		c.declare(optArchiver)
+		c.archiver = a
+		c.archiveMode = mode
+	}
+}
+
+// WithArchiveFieldFilter removes the given fields from archived payloads, to keep PII out of the
+// archive. nested fields are separated by dots, like "notification.message" or "recipients"
+func WithArchiveFieldFilter(fields ...string) Option {
+	return func(c *Client) {
+		c.declare(optArchiveFieldFilter)
+		c.archiveFilter = append(c.archiveFilter, fields...)
+	}
+}
+
+// WithArchiveBuffer changes how many records can wait to be archived in ArchiveAsync mode
+func WithArchiveBuffer(size int) Option {
+	return func(c *Client) {
+		c.declare(optArchiveBuffer)
+		c.archiveBufferSize = size
+	}
+}
+
+// ArchiveDropped returns the number of records dropped because the async archive buffer was full,
+// or because they were made after Close
+func (c *Client) ArchiveDropped() uint64 {
+	return atomic.LoadUint64(&c.archiveDropped)
+}
+
+// state of the background archiver used in ArchiveAsync mode, started on first use and stopped by
+// Close
+type archiveQueue struct {
+	mu      sync.Mutex
+	records chan queuedRecord
+	// closed once the worker archived every record queued before Close
+	done   chan struct{}
+	closed bool
+}
+
+// record waiting to be archived, with the context of its send
+type queuedRecord struct {
+	ctx    context.Context
+	record ArchiveRecord
+}
+
+// must be called with archiveQueue.mu held
+func (c *Client) startArchiveWorker() {
+	size := c.archiveBufferSize
+	if size <= 0 {
+		size = defaultArchiveBufferSize
+	}
+	records, done := make(chan queuedRecord, size), make(chan struct{})
+	c.archiveQueue.records, c.archiveQueue.done = records, done
+
+	go func() {
+		defer close(done)
+		for queued := range records {
+			c.archiver.Archive(queued.ctx, queued.record)
+		}
+	}()
+}
+
+// stop the background archiver once the records already queued are archived. records submitted
+// afterwards are dropped, see ArchiveDropped
+func (c *Client) stopArchiveWorker() {
+	c.archiveQueue.mu.Lock()
+	if c.archiveQueue.closed {
+		c.archiveQueue.mu.Unlock()
+		return
+	}
+	c.archiveQueue.closed = true
+	records, done := c.archiveQueue.records, c.archiveQueue.done
+	c.archiveQueue.mu.Unlock()
+
+	if records != nil {
+		close(records)
+		<-done
+	}
+}
+
+// build the record of a request and hand it to the archiver
+func (c *Client) archive(ctx context.Context, payload []byte, started time.Time, res *http.Response, err error, attempts []ArchiveAttempt, warnings []Warning) error {
+	record := ArchiveRecord{
+		Outcome:   OutcomeSent,
+		StartedAt: started,
+		Duration:  time.Since(started),
+		Attempts:  attempts,
+		Warnings:  warnings,
+	}
+	if res != nil {
+		record.StatusCode = res.StatusCode
+		record.State = readStatus(res).state(res.StatusCode)
+	}
+	if err != nil {
+		record.Outcome = OutcomeFailed
+		record.Err = err.Error()
+	}
+
+	archived, encryptErr := c.archivedPayload(payload)
+	if encryptErr != nil {
+		return encryptErr
+	}
+	record.Payload = archived
+	return c.submitArchive(ctx, record)
+}
+
+// archive a notification which has never been sent
+func (c *Client) archiveAbandoned(ctx context.Context, payload []byte) error {
+	archived, err := c.archivedPayload(payload)
+	if err != nil {
+		return err
+	}
+	return c.submitArchive(ctx, ArchiveRecord{
+		Payload: archived,
+		Outcome: OutcomeAbandoned,
+	})
+}
+
+// payload as archived, filtered and encrypted if configured
+func (c *Client) archivedPayload(payload []byte) ([]byte, error) {
+	payload = filterFields(payload, c.archiveFilter)
+	if c.archiveEncryptor == nil {
+		return payload, nil
+	}
+	return encryptFields(payload, c.archiveEncryptor)
+}
+
+// hand record to the archiver according to the archive mode. the archiver gets the values of ctx,
+// like tracing ones, but not its cancellation: the request is done whatever happens to ctx
+func (c *Client) submitArchive(ctx context.Context, record ArchiveRecord) error {
+	ctx = context.WithoutCancel(ctx)
+	if c.archiveMode == ArchiveSync {
+		return c.archiver.Archive(ctx, record)
+	}
+
+	// held while queueing, so Close can't close the queue in between
+	c.archiveQueue.mu.Lock()
+	defer c.archiveQueue.mu.Unlock()
+	if c.archiveQueue.closed {
+		atomic.AddUint64(&c.archiveDropped, 1)
+		return nil
+	}
+	if c.archiveQueue.records == nil {
+		c.startArchiveWorker()
+	}
+	select {
+	case c.archiveQueue.records <- queuedRecord{ctx: ctx, record: record}:
+	default:
+		atomic.AddUint64(&c.archiveDropped, 1)
+	}
+	return nil
+}
+
+// remove dotted field paths from a json object. payloads which are not objects are left as is
+func filterFields(payload []byte, fields []string) []byte {
+	if len(fields) == 0 {
+		return payload
+	}
+
+	var m map[string]interface{}
+	if err := json.Unmarshal(payload, &m); err != nil {
+		return payload
+	}
+	for _, field := range fields {
+		deletePath(m, strings.Split(field, "."))
+	}
+
+	filtered, err := json.Marshal(m)
+	if err != nil {
+		return payload
+	}
+	return filtered
+}
+
+func deletePath(m map[string]interface{}, path []string) {
+	if len(path) == 1 {
+		delete(m, path[0])
+		return
+	}
+	if child, ok := m[path[0]].(map[string]interface{}); ok {
+		deletePath(child, path[1:])
+	}
+}
@@ -0,0 +1,183 @@
+package engagespot
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type recordingArchiver struct {
+	mu      sync.Mutex
+	records []ArchiveRecord
+	// contexts of the Archive calls
+	contexts []context.Context
+	err      error
+	// if set, every Archive call signals started and then waits for release
+	started chan struct{}
+	release chan struct{}
+}
+
+func (a *recordingArchiver) Archive(ctx context.Context, record ArchiveRecord) error {
+	if a.started != nil {
+		a.started <- struct{}{}
+		<-a.release
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.records = append(a.records, record)
+	a.contexts = append(a.contexts, ctx)
+	return a.err
+}
+
+func (a *recordingArchiver) recorded() []ArchiveRecord {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return append([]ArchiveRecord{}, a.records...)
+}
+
+func TestArchiveSync(t *testing.T) {
+	a := &recordingArchiver{}
+	c := NewEngagespotClient("A", "B", WithArchiver(a, ArchiveSync))
+	c.httpClient.Transport = respondWith(201, `{}`)
+
+	assert.NoError(t, sendTo(c, "hello@example.com"))
+
+	records := a.recorded()
+	assert.Len(t, records, 1)
+	assert.Equal(t, 201, records[0].StatusCode)
+	assert.Empty(t, records[0].Err)
+	assert.Len(t, records[0].Attempts, 1)
+	assert.False(t, records[0].StartedAt.IsZero())
+	assert.Equal(t, StateDelivered, records[0].State)
+	assert.Contains(t, records[0].Warnings, Warning{Field: "icon", Message: "no icon set"})
+
+	var payload map[string]interface{}
+	assert.NoError(t, json.Unmarshal(records[0].Payload, &payload))
+	assert.Equal(t, []interface{}{"hello@example.com"}, payload["recipients"])
+}
+
+func TestArchiveQueuedState(t *testing.T) {
+	a := &recordingArchiver{}
+	c := NewEngagespotClient("A", "B", WithArchiver(a, ArchiveSync))
+	c.httpClient.Transport = respondWith(202, `{"id":"n-1","status":"queued"}`)
+
+	assert.NoError(t, sendTo(c, "hello@example.com"))
+	assert.Equal(t, StateAccepted, a.recorded()[0].State)
+}
+
+func TestArchiveSyncFailureFailsSend(t *testing.T) {
+	a := &recordingArchiver{err: errors.New("warehouse down")}
+	c := NewEngagespotClient("A", "B", WithArchiver(a, ArchiveSync))
+	c.httpClient.Transport = respondWith(201, `{}`)
+
+	assert.EqualError(t, sendTo(c, "hello@example.com"), "warehouse down")
+}
+
+func TestArchiveFieldFilter(t *testing.T) {
+	a := &recordingArchiver{}
+	c := NewEngagespotClient("A", "B",
+		WithArchiver(a, ArchiveSync),
+		WithArchiveFieldFilter("recipients", "notification.message"),
+	)
+	c.httpClient.Transport = respondWith(201, `{}`)
+
+	n := newMessage(c, "hello@example.com")
+	n.SetMessage("secret message")
+	_, err := n.SendCtx(context.Background())
+	assert.NoError(t, err)
+
+	var payload map[string]interface{}
+	assert.NoError(t, json.Unmarshal(a.recorded()[0].Payload, &payload))
+	assert.NotContains(t, payload, "recipients")
+	assert.Equal(t, map[string]interface{}{"title": "Hello"}, payload["notification"])
+}
+
+func TestArchiveAsync(t *testing.T) {
+	a := &recordingArchiver{err: errors.New("warehouse down")}
+	c := NewEngagespotClient("A", "B", WithArchiver(a, ArchiveAsync))
+	c.httpClient.Transport = respondWith(201, `{}`)
+	defer c.Close()
+
+	// failures of the archiver never reach the caller
+	assert.NoError(t, sendTo(c, "hello@example.com"))
+	assert.Eventually(t, func() bool { return len(a.recorded()) == 1 }, time.Second, time.Millisecond)
+	assert.Equal(t, uint64(0), c.ArchiveDropped())
+}
+
+func TestArchiveAsyncOverflow(t *testing.T) {
+	a := &recordingArchiver{started: make(chan struct{}), release: make(chan struct{})}
+	c := NewEngagespotClient("A", "B", WithArchiver(a, ArchiveAsync), WithArchiveBuffer(1))
+	c.httpClient.Transport = respondWith(201, `{}`)
+	defer c.Close()
+
+	// the first record keeps the worker busy, the second waits in the buffer
+	assert.NoError(t, sendTo(c, "hello@example.com"))
+	<-a.started
+	for i := 0; i < 4; i++ {
+		assert.NoError(t, sendTo(c, "hello@example.com"))
+	}
+	assert.Equal(t, uint64(3), c.ArchiveDropped())
+
+	close(a.release)
+	<-a.started
+	assert.Eventually(t, func() bool { return len(a.recorded()) == 2 }, time.Second, time.Millisecond)
+}
+
+type archiveTraceKey struct{}
+
+func TestArchiveKeepsSendContext(t *testing.T) {
+	for _, mode := range []ArchiveMode{ArchiveSync, ArchiveAsync} {
+		a := &recordingArchiver{}
+		c := NewEngagespotClient("A", "B", WithArchiver(a, mode))
+		c.httpClient.Transport = respondWith(201, `{}`)
+
+		ctx, cancel := context.WithCancel(context.WithValue(context.Background(), archiveTraceKey{}, "trace-1"))
+		_, err := newMessage(c).SendCtx(ctx)
+		assert.NoError(t, err)
+		cancel()
+		assert.NoError(t, c.Close())
+
+		a.mu.Lock()
+		archived := a.contexts[0]
+		a.mu.Unlock()
+		assert.Equal(t, "trace-1", archived.Value(archiveTraceKey{}))
+		assert.NoError(t, archived.Err(), "the archiver isn't cancelled along with the send")
+	}
+}
+
+func TestCloseStopsArchiveWorker(t *testing.T) {
+	a := &recordingArchiver{started: make(chan struct{}), release: make(chan struct{})}
+	c := NewEngagespotClient("A", "B", WithArchiver(a, ArchiveAsync))
+	c.httpClient.Transport = respondWith(201, `{}`)
+
+	assert.NoError(t, sendTo(c, "hello@example.com"))
+	<-a.started
+	assert.NoError(t, sendTo(c, "hello@example.com"))
+
+	// Close waits for the queued records to be archived
+	closed := make(chan struct{})
+	go func() {
+		c.Close()
+		close(closed)
+	}()
+	close(a.release)
+	<-a.started
+	<-closed
+	assert.Len(t, a.recorded(), 2)
+	select {
+	case <-c.archiveQueue.done:
+	default:
+		t.Fatal("archive worker still running after Close")
+	}
+
+	// records made after Close are dropped
+	assert.NoError(t, sendTo(c, "hello@example.com"))
+	assert.Len(t, a.recorded(), 2)
+	assert.Equal(t, uint64(1), c.ArchiveDropped())
+	assert.NoError(t, c.Close())
+}
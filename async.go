@@ -0,0 +1,104 @@
+package engagespot
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+)
+
+// workers and queue size of the pool of SendAsync
+const (
+	defaultAsyncWorkers   = 4
+	defaultAsyncQueueSize = 256
+)
+
+// AsyncResult is the outcome of a send made with SendAsync. Err is what Send would have returned
+type AsyncResult struct {
+	Result *SendResult
+	Err    error
+}
+
+// dispatcher of SendAsync, created on first use and shut down by Close
+type asyncPool struct {
+	mu         sync.Mutex
+	dispatcher *Dispatcher
+	closed     bool
+}
+
+// WithWorkers sets the number of notifications SendAsync and SendAll send at the same time, 4 by
+// default
+func WithWorkers(n int) Option {
+	return func(c *Client) {
+		c.declare(optWorkers)
+		if n < 1 {
+			c.rejectOption(optWorkers, fmt.Errorf("needs at least 1 worker, got %d", n))
+			return
+		}
+		c.asyncWorkers = n
+	}
+}
+
+// SendAsync queues n to be sent in the background by the worker pool of the client, see
+// WithWorkers. the returned channel receives the outcome of the send, then is closed. SendAsync
+// blocks while the queue is full. once the client is closed, the channel receives
+// ErrDispatcherClosed
+func (c *Client) SendAsync(n *Notification) <-chan AsyncResult {
+	results := make(chan AsyncResult, 1)
+	d, err := c.asyncDispatcher()
+	if err == nil {
+		_, err = d.enqueue(n, results)
+	}
+	if err != nil {
+		results <- AsyncResult{Err: err}
+		close(results)
+	}
+	return results
+}
+
+// SendAsync is SendAsync of the client of n
+func (n *Notification) SendAsync() <-chan AsyncResult {
+	return n.Client.SendAsync(n)
+}
+
+// Close stops SendAsync from accepting notifications and waits for those already queued to be
+// sent, then stops the background archiver of ArchiveAsync mode once it archived every record. it
+// can be called more than once
+func (c *Client) Close() error {
+	c.async.mu.Lock()
+	c.async.closed = true
+	d := c.async.dispatcher
+	c.async.mu.Unlock()
+
+	var err error
+	if d != nil {
+		err = d.Shutdown(context.Background())
+	}
+	c.stopArchiveWorker()
+	return err
+}
+
+func (c *Client) asyncDispatcher() (*Dispatcher, error) {
+	c.async.mu.Lock()
+	defer c.async.mu.Unlock()
+	if c.async.closed {
+		return nil, ErrDispatcherClosed
+	}
+	if c.async.dispatcher == nil {
+		workers := c.asyncWorkers
+		if workers == 0 {
+			workers = defaultAsyncWorkers
+		}
+		c.async.dispatcher = c.NewDispatcher(workers, defaultAsyncQueueSize, nil)
+	}
+	return c.async.dispatcher, nil
+}
+
+// outcome of a send, decoded like Send does
+func asyncResult(out *sendOutcome, res *http.Response, err error) AsyncResult {
+	if err != nil {
+		return AsyncResult{Err: err}
+	}
+	result, err := sendResult(out, res)
+	return AsyncResult{Result: result, Err: err}
+}
@@ -0,0 +1,87 @@
+package engagespot
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSendAsync(t *testing.T) {
+	var mu sync.Mutex
+	delivered := map[string]bool{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			Recipients []string `json:"recipients"`
+		}
+		json.NewDecoder(r.Body).Decode(&body)
+		mu.Lock()
+		defer mu.Unlock()
+		for _, recipient := range body.Recipients {
+			delivered[recipient] = true
+		}
+		w.Write([]byte(`{"id":"` + body.Recipients[0] + `"}`))
+	}))
+	defer server.Close()
+
+	c := clientFor(server.URL+"/v3/", time.Second, WithWorkers(8))
+	ids := recipientIds(100)
+	var results []<-chan AsyncResult
+	for _, id := range ids {
+		results = append(results, newMessage(c, id).SendAsync())
+	}
+	assert.NoError(t, c.Close())
+
+	for i, ch := range results {
+		r, ok := <-ch
+		assert.True(t, ok)
+		assert.NoError(t, r.Err)
+		assert.Equal(t, ids[i], r.Result.Id)
+		_, open := <-ch
+		assert.False(t, open)
+	}
+	assert.Len(t, delivered, 100)
+}
+
+func TestSendAsyncErrorsPerNotification(t *testing.T) {
+	c := NewEngagespotClient("A", "B")
+	c.httpClient.Transport = roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		var body struct {
+			Recipients []string `json:"recipients"`
+		}
+		json.NewDecoder(req.Body).Decode(&body)
+		if body.Recipients[0] == "bad" {
+			return respondWith(500, `{"message":"boom"}`).RoundTrip(req)
+		}
+		return respondWith(200, `{}`).RoundTrip(req)
+	})
+
+	good := newMessage(c, "good").SendAsync()
+	bad := newMessage(c, "bad").SendAsync()
+	assert.NoError(t, (<-good).Err)
+
+	var apiErr *APIError
+	assert.True(t, errors.As((<-bad).Err, &apiErr))
+	assert.Equal(t, 500, apiErr.StatusCode)
+	assert.NoError(t, c.Close())
+}
+
+func TestSendAsyncAfterClose(t *testing.T) {
+	c := NewEngagespotClient("A", "B")
+	c.httpClient.Transport = noNetwork(t)
+	assert.NoError(t, c.Close())
+	assert.NoError(t, c.Close())
+
+	r := <-newMessage(c).SendAsync()
+	assert.ErrorIs(t, r.Err, ErrDispatcherClosed)
+}
+
+func TestWithWorkersInvalid(t *testing.T) {
+	_, err := NewClient("A", "B", WithWorkers(0))
+	assert.EqualError(t, err, "engagespot: conflicting options: WithWorkers: needs at least 1 worker, got 0")
+}
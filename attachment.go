@@ -0,0 +1,97 @@
+package engagespot
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+)
+
+// MaxEmailAttachmentBytes is the total size of inline attachment content a single notification
+// may carry, counted before base64 encoding. it is enforced before sending to avoid a round trip
+// which the email provider would reject anyway. url attachments don't count, they are fetched by
+// the provider
+const MaxEmailAttachmentBytes = 10 << 20
+
+// EmailAttachment is a file attached to the email channel of a notification. either Url or
+// Content must be set, not both. Content is base64 encoded when serialized
+type EmailAttachment struct {
+	Filename    string
+	ContentType string
+	Url         string
+	Content     []byte
+}
+
+func (a EmailAttachment) validate() error {
+	if a.Filename == "" {
+		return errors.New("empty attachment filename")
+	}
+	if a.Url == "" && len(a.Content) == 0 {
+		return errors.New("attachment requires either url or content")
+	}
+	if a.Url != "" && len(a.Content) > 0 {
+		return errors.New("attachment url and content are mutually exclusive")
+	}
+	return nil
+}
+
+// attachment object as defined by Sendgrid's mail send API. Sendgrid only supports inline content
+func (a EmailAttachment) sendgrid() map[string]interface{} {
+	m := map[string]interface{}{
+		"content":     base64.StdEncoding.EncodeToString(a.Content),
+		"filename":    a.Filename,
+		"disposition": "attachment",
+	}
+	if a.ContentType != "" {
+		m["type"] = a.ContentType
+	}
+	return m
+}
+
+// attachment object as accepted by the SMTP provider, either fetched from path or inline content
+func (a EmailAttachment) smtp() map[string]interface{} {
+	m := map[string]interface{}{
+		"filename": a.Filename,
+	}
+	if a.Url != "" {
+		m["path"] = a.Url
+	} else {
+		m["content"] = base64.StdEncoding.EncodeToString(a.Content)
+		m["encoding"] = "base64"
+	}
+	if a.ContentType != "" {
+		m["contentType"] = a.ContentType
+	}
+	return m
+}
+
+// append an attachment to the `attachments` list of an email provider override
+func appendAttachment(provider map[string]interface{}, attachment map[string]interface{}) map[string]interface{} {
+	if provider == nil {
+		provider = map[string]interface{}{}
+	}
+	list, _ := provider["attachments"].([]map[string]interface{})
+	provider["attachments"] = append(list, attachment)
+	return provider
+}
+
+// AddEmailAttachment can be used to attach a file to the email sent for this notification. the
+// attachment is added to both Sendgrid and SMTP overrides, in the order of insertion. as Sendgrid
+// can't fetch files by url, url based attachments are only sent to SMTP
+func (n *Notification) AddEmailAttachment(a EmailAttachment) (*Notification, error) {
+	if err := a.validate(); err != nil {
+		return nil, invalidField("attachments", a.Filename, err)
+	}
+
+	size := n.attachmentBytes + len(a.Content)
+	if size > MaxEmailAttachmentBytes {
+		return nil, invalidField("attachments", a.Filename, fmt.Errorf("attachments exceed %d bytes", MaxEmailAttachmentBytes))
+	}
+	n.attachmentBytes = size
+
+	if len(a.Content) > 0 {
+		n.Override.SendgridEmail = appendAttachment(n.Override.SendgridEmail, a.sendgrid())
+	}
+	n.Override.SmtpEmail = appendAttachment(n.Override.SmtpEmail, a.smtp())
+
+	return n, nil
+}
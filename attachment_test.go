@@ -0,0 +1,112 @@
+package engagespot
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// encode override of n and decode it back to a generic map for assertions
+func encodedOverride(t *testing.T, n *Notification) map[string]interface{} {
+	b, err := json.Marshal(n)
+	assert.NoError(t, err)
+
+	var payload struct {
+		Override map[string]interface{} `json:"override"`
+	}
+	assert.NoError(t, json.Unmarshal(b, &payload))
+	return payload.Override
+}
+
+func TestAddEmailAttachmentUrl(t *testing.T) {
+	n := newTestNotification(t)
+	_, err := n.AddEmailAttachment(EmailAttachment{
+		Filename:    "invoice.pdf",
+		ContentType: "application/pdf",
+		Url:         "https://example.com/invoice.pdf",
+	})
+	assert.NoError(t, err)
+
+	o := encodedOverride(t, n)
+	assert.NotContains(t, o, "sendgrid_email")
+	assert.Equal(t, []interface{}{
+		map[string]interface{}{
+			"filename":    "invoice.pdf",
+			"contentType": "application/pdf",
+			"path":        "https://example.com/invoice.pdf",
+		},
+	}, o["smtp_email"].(map[string]interface{})["attachments"])
+}
+
+func TestAddEmailAttachmentContent(t *testing.T) {
+	n := newTestNotification(t)
+	_, err := n.AddEmailAttachment(EmailAttachment{
+		Filename:    "hello.txt",
+		ContentType: "text/plain",
+		Content:     []byte("hello"),
+	})
+	assert.NoError(t, err)
+
+	o := encodedOverride(t, n)
+	assert.Equal(t, []interface{}{
+		map[string]interface{}{
+			"content":     "aGVsbG8=",
+			"filename":    "hello.txt",
+			"type":        "text/plain",
+			"disposition": "attachment",
+		},
+	}, o["sendgrid_email"].(map[string]interface{})["attachments"])
+	assert.Equal(t, []interface{}{
+		map[string]interface{}{
+			"content":     "aGVsbG8=",
+			"encoding":    "base64",
+			"filename":    "hello.txt",
+			"contentType": "text/plain",
+		},
+	}, o["smtp_email"].(map[string]interface{})["attachments"])
+}
+
+func TestAddEmailAttachmentOrder(t *testing.T) {
+	n := newTestNotification(t)
+	for _, name := range []string{"a.txt", "b.txt", "c.txt"} {
+		_, err := n.AddEmailAttachment(EmailAttachment{Filename: name, Content: []byte(name)})
+		assert.NoError(t, err)
+	}
+
+	attachments := n.Override.SmtpEmail["attachments"].([]map[string]interface{})
+	assert.Len(t, attachments, 3)
+	for i, name := range []string{"a.txt", "b.txt", "c.txt"} {
+		assert.Equal(t, name, attachments[i]["filename"])
+	}
+}
+
+func TestAddEmailAttachmentMutuallyExclusive(t *testing.T) {
+	n := newTestNotification(t)
+	_, err := n.AddEmailAttachment(EmailAttachment{
+		Filename: "invoice.pdf",
+		Url:      "https://example.com/invoice.pdf",
+		Content:  []byte("%PDF"),
+	})
+	assert.EqualError(t, err, "attachment url and content are mutually exclusive")
+
+	_, err = n.AddEmailAttachment(EmailAttachment{Filename: "invoice.pdf"})
+	assert.Error(t, err)
+	assert.Nil(t, n.Override.SmtpEmail)
+}
+
+func TestAddEmailAttachmentSizeCap(t *testing.T) {
+	n := newTestNotification(t)
+	_, err := n.AddEmailAttachment(EmailAttachment{
+		Filename: "big.bin",
+		Content:  make([]byte, MaxEmailAttachmentBytes-1),
+	})
+	assert.NoError(t, err)
+
+	_, err = n.AddEmailAttachment(EmailAttachment{Filename: "two.bin", Content: []byte("12")})
+	assert.Error(t, err)
+	assert.Len(t, n.Override.SmtpEmail["attachments"], 1)
+
+	_, err = n.AddEmailAttachment(EmailAttachment{Filename: "one.bin", Content: []byte("1")})
+	assert.NoError(t, err)
+}
@@ -0,0 +1,184 @@
+package engagespot
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+)
+
+// ErrSendCancelled is the reason of the PartialSendError of a bulk send stopped by Cancel
+var ErrSendCancelled = errors.New("send cancelled")
+
+// ChunkResult is the outcome of a single chunk of a bulk send
+type ChunkResult struct {
+	Recipients []string
+	StatusCode int
+	Err        error
+}
+
+// SendReport tells what a bulk send did. Sent and Failed list chunks in the order they were sent.
+// a chunk rejected by the API or failing on the way doesn't stop the others, it is listed in
+// Failed and Err holds the first such failure. Unsent lists the recipients left out by a cancellation, for notifications with added
+// recipients. for notifications with a recipient source, those weren't pulled and aren't known
+type SendReport struct {
+	Sent      []ChunkResult
+	Failed    []ChunkResult
+	Unsent    []string
+	Submitted int
+	Cancelled bool
+	// error the send ended with, nil if it went through entirely
+	Err error
+}
+
+// BulkSendHandle controls a bulk send running in the background, see SendBulk
+type BulkSendHandle struct {
+	control *bulkControl
+	abort   context.CancelFunc
+	done    chan struct{}
+}
+
+// state of a bulk send shared with sendStreamed through the context
+type bulkControl struct {
+	once    sync.Once
+	cancel  chan struct{}
+	abortIn bool
+
+	mu     sync.Mutex
+	report SendReport
+}
+
+type bulkControlKey struct{}
+
+func bulkControlFrom(ctx context.Context) *bulkControl {
+	b, _ := ctx.Value(bulkControlKey{}).(*bulkControl)
+	return b
+}
+
+// AbortInFlightOnCancel makes Cancel of a bulk send also abort the requests in flight, instead of
+// letting them finish. aborted chunks are reported as failed
+func AbortInFlightOnCancel() CallOption {
+	return func(o *callOptions) {
+		o.abortInFlight = true
+	}
+}
+
+type abortInFlightKey struct{}
+
+// BatchSize sets the number of recipients per chunk of a bulk send, or of a send with a recipient
+// source, instead of Defaults.ChunkSize. it must be between 1 and MaxRecipients
+func BatchSize(n int) CallOption {
+	return func(o *callOptions) {
+		o.batchSize = &n
+	}
+}
+
+type batchSizeKey struct{}
+
+// tells if err is the failure of a single chunk, which a bulk send goes on after
+func chunkFailed(err error) bool {
+	var apiErr *APIError
+	var opErr *OperationError
+	return errors.As(err, &apiErr) || errors.As(err, &opErr)
+}
+
+// SendBulk can be used to send the notification in the background, in chunks of
+// Defaults.ChunkSize or as set with BatchSize, with a handle to follow the send and cancel it midway. recipients can be
+// added or come from a recipient source. the notification goes through the same steps as Send
+func (n *Notification) SendBulk(ctx context.Context, opts ...CallOption) *BulkSendHandle {
+	ctx = applyCallOptions(ctx, opts)
+	abortIn, _ := ctx.Value(abortInFlightKey{}).(bool)
+
+	control := &bulkControl{cancel: make(chan struct{}), abortIn: abortIn}
+	ctx, abort := context.WithCancel(context.WithValue(ctx, bulkControlKey{}, control))
+	h := &BulkSendHandle{control: control, abort: abort, done: make(chan struct{})}
+
+	go func() {
+		defer close(h.done)
+		defer abort()
+		res, _, err := n.Client.sendContext(ctx, n)
+		if res != nil {
+			discard(res)
+		}
+
+		control.mu.Lock()
+		defer control.mu.Unlock()
+		control.report.Err = err
+	}()
+	return h
+}
+
+// Cancel stops the send from going on with the next chunks. it can be called any number of times,
+// from any goroutine
+func (h *BulkSendHandle) Cancel() {
+	h.control.once.Do(func() {
+		h.control.mu.Lock()
+		h.control.report.Cancelled = true
+		h.control.mu.Unlock()
+		close(h.control.cancel)
+		if h.control.abortIn {
+			h.abort()
+		}
+	})
+}
+
+// Done is closed once the send is over, whether it completed, failed or was cancelled
+func (h *BulkSendHandle) Done() <-chan struct{} {
+	return h.done
+}
+
+// Report returns a snapshot of the report of the send, final once Done is closed
+func (h *BulkSendHandle) Report() *SendReport {
+	h.control.mu.Lock()
+	defer h.control.mu.Unlock()
+	r := h.control.report
+	r.Sent = append([]ChunkResult{}, r.Sent...)
+	r.Failed = append([]ChunkResult{}, r.Failed...)
+	r.Unsent = append([]string{}, r.Unsent...)
+	return &r
+}
+
+// tells if the bulk send was cancelled. false for sends which aren't bulk sends
+func (b *bulkControl) cancelled() bool {
+	if b == nil {
+		return false
+	}
+	select {
+	case <-b.cancel:
+		return true
+	default:
+		return false
+	}
+}
+
+// record the outcome of a chunk, submitted being the number of its recipients left after opt-out
+// filtering
+func (b *bulkControl) record(recipients []string, submitted int, res *http.Response, err error) {
+	if b == nil {
+		return
+	}
+	result := ChunkResult{Recipients: recipients, Err: err}
+	if res != nil {
+		result.StatusCode = res.StatusCode
+	}
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		result.StatusCode = apiErr.StatusCode
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if err != nil {
+		b.report.Failed = append(b.report.Failed, result)
+		return
+	}
+	b.report.Sent = append(b.report.Sent, result)
+	b.report.Submitted += submitted
+}
+
+// record the recipients left out by a cancellation
+func (b *bulkControl) unsent(recipients []string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.report.Unsent = append([]string{}, recipients...)
+}
@@ -0,0 +1,195 @@
+package engagespot
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSendBulk(t *testing.T) {
+	var bodies []map[string]interface{}
+	c := chunkedClient(2)
+	c.httpClient.Transport = recordBodies(&bodies)
+
+	h := newMessage(c, recipientIds(10)...).SendBulk(context.Background())
+	<-h.Done()
+
+	r := h.Report()
+	assert.NoError(t, r.Err)
+	assert.Len(t, r.Sent, 5)
+	assert.Equal(t, []string{"user-8", "user-9"}, r.Sent[4].Recipients)
+	assert.Equal(t, 10, r.Submitted)
+	assert.False(t, r.Cancelled)
+	assert.Len(t, bodies, 5)
+}
+
+func TestSendBulkCancel(t *testing.T) {
+	second, proceed := make(chan struct{}), make(chan struct{})
+	calls := 0
+	c := chunkedClient(2)
+	c.httpClient.Transport = roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		calls++
+		time.Sleep(10 * time.Millisecond)
+		if calls == 2 {
+			close(second)
+			<-proceed
+		}
+		return respondWith(200, `{}`).RoundTrip(req)
+	})
+
+	h := newMessage(c, recipientIds(10)...).SendBulk(context.Background())
+	<-second
+	h.Cancel()
+	close(proceed)
+	<-h.Done()
+
+	r := h.Report()
+	assert.True(t, r.Cancelled)
+	assert.ErrorIs(t, r.Err, ErrSendCancelled)
+	assert.Len(t, r.Sent, 2)
+	assert.Empty(t, r.Failed)
+	assert.Equal(t, 4, r.Submitted)
+	assert.Equal(t, []string{"user-4", "user-5", "user-6", "user-7", "user-8", "user-9"}, r.Unsent)
+	assert.Equal(t, 2, calls)
+}
+
+func TestSendBulkAbortInFlight(t *testing.T) {
+	third := make(chan struct{})
+	calls := 0
+	c := chunkedClient(2)
+	c.httpClient.Transport = roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		calls++
+		if calls == 3 {
+			close(third)
+			<-req.Context().Done()
+			return nil, req.Context().Err()
+		}
+		return respondWith(200, `{}`).RoundTrip(req)
+	})
+
+	h := newMessage(c, recipientIds(10)...).SendBulk(context.Background(), AbortInFlightOnCancel())
+	<-third
+	h.Cancel()
+	<-h.Done()
+
+	r := h.Report()
+	assert.True(t, r.Cancelled)
+	assert.Len(t, r.Sent, 2)
+	assert.Len(t, r.Failed, 1)
+	assert.Equal(t, []string{"user-4", "user-5"}, r.Failed[0].Recipients)
+	assert.True(t, errors.Is(r.Failed[0].Err, context.Canceled))
+	assert.Equal(t, 4, r.Submitted)
+}
+
+func TestSendBulkCancelConcurrent(t *testing.T) {
+	c := chunkedClient(2)
+	c.httpClient.Transport = respondWith(200, `{}`)
+
+	h := newMessage(c, recipientIds(10)...).SendBulk(context.Background())
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			h.Cancel()
+			h.Report()
+		}()
+	}
+	wg.Wait()
+	<-h.Done()
+	assert.True(t, h.Report().Cancelled)
+}
+
+func TestSendBulkSource(t *testing.T) {
+	var bodies []map[string]interface{}
+	c := chunkedClient(2)
+	c.httpClient.Transport = recordBodies(&bodies)
+
+	n, _ := c.NewNotification("Campaign")
+	n.SetRecipientSource(sliceSource([]string{"a", "b", "c"}, -1, nil))
+	h := n.SendBulk(context.Background())
+	<-h.Done()
+	assert.NoError(t, h.Report().Err)
+	assert.Len(t, h.Report().Sent, 2)
+}
+
+func TestSendBulkBatchSize(t *testing.T) {
+	var bodies []map[string]interface{}
+	c := NewEngagespotClient("A", "B")
+	c.httpClient.Transport = recordBodies(&bodies)
+
+	h := newMessage(c, recipientIds(7)...).SendBulk(context.Background(), BatchSize(3))
+	<-h.Done()
+
+	r := h.Report()
+	assert.NoError(t, r.Err)
+	assert.Len(t, r.Sent, 3)
+	// the last batch takes what is left
+	assert.Equal(t, []string{"user-6"}, r.Sent[2].Recipients)
+	assert.Equal(t, 7, r.Submitted)
+	assert.Len(t, bodies, 3)
+}
+
+func TestSendBulkBatchSizeInvalid(t *testing.T) {
+	c := NewEngagespotClient("A", "B")
+	c.httpClient.Transport = noNetwork(t)
+	for _, size := range []int{0, MaxRecipients + 1} {
+		h := newMessage(c).SendBulk(context.Background(), BatchSize(size))
+		<-h.Done()
+		assert.ErrorIs(t, h.Report().Err, ErrRecipientLimit)
+	}
+}
+
+func TestSendBulkGoesOnAfterFailedChunk(t *testing.T) {
+	calls := 0
+	c := chunkedClient(2)
+	c.httpClient.Transport = roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		calls++
+		if calls == 2 {
+			return respondWith(500, `{"message":"boom"}`).RoundTrip(req)
+		}
+		return respondWith(200, `{}`).RoundTrip(req)
+	})
+
+	h := newMessage(c, recipientIds(7)...).SendBulk(context.Background())
+	<-h.Done()
+
+	r := h.Report()
+	assert.Equal(t, 4, calls)
+	assert.Len(t, r.Sent, 3)
+	assert.Len(t, r.Failed, 1)
+	assert.Equal(t, []string{"user-2", "user-3"}, r.Failed[0].Recipients)
+	assert.Equal(t, 500, r.Failed[0].StatusCode)
+	assert.Equal(t, 5, r.Submitted)
+
+	var apiErr *APIError
+	assert.True(t, errors.As(r.Err, &apiErr))
+	var partial *PartialSendError
+	assert.True(t, errors.As(r.Err, &partial))
+	assert.Equal(t, 5, partial.Submitted)
+}
+
+func TestSendBulkStopsOnContextCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	calls := 0
+	c := chunkedClient(2)
+	c.httpClient.Transport = roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		calls++
+		if calls == 2 {
+			cancel()
+			return nil, req.Context().Err()
+		}
+		return respondWith(200, `{}`).RoundTrip(req)
+	})
+
+	h := newMessage(c, recipientIds(7)...).SendBulk(ctx)
+	<-h.Done()
+
+	assert.Equal(t, 2, calls)
+	assert.ErrorIs(t, h.Report().Err, context.Canceled)
+}
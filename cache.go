@@ -0,0 +1,108 @@
+package engagespot
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// number of background refreshes running at once when CachePolicy.MaxRefreshes is not set
+const defaultMaxRefreshes = 4
+
+// CachePolicy controls how entries of a cache used by pre-send filters expire
+type CachePolicy struct {
+	// entries younger than TTL are served without any lookup
+	TTL time.Duration
+	// entries older than TTL, but by no more than StaleWhileRevalidate, are still served while
+	// being refreshed in the background, so expiring entries don't add latency to sends. zero
+	// disables it, expired entries are then looked up before sending
+	StaleWhileRevalidate time.Duration
+	// maximum number of background refreshes at once. when all are busy, stale entries are served
+	// without scheduling a refresh
+	MaxRefreshes int
+}
+
+// freshness of a cached entry according to a policy
+type freshness int
+
+const (
+	fresh freshness = iota
+	stale
+	expired
+)
+
+func (p CachePolicy) freshness(age time.Duration) freshness {
+	switch {
+	case age < p.TTL:
+		return fresh
+	case age < p.TTL+p.StaleWhileRevalidate:
+		return stale
+	default:
+		return expired
+	}
+}
+
+// CacheStats counts how caches of pre-send filters behaved
+type CacheStats struct {
+	StaleServes     uint64
+	RefreshFailures uint64
+}
+
+// bounded pool of background refreshes, deduplicated by key
+type refresher struct {
+	once     sync.Once
+	slots    chan struct{}
+	inFlight sync.Map
+
+	staleServes     uint64
+	refreshFailures uint64
+}
+
+// run refresh in the background unless a refresh of key is already running or the pool is full
+func (r *refresher) refresh(policy CachePolicy, key string, refresh func() error) {
+	r.once.Do(func() {
+		size := policy.MaxRefreshes
+		if size <= 0 {
+			size = defaultMaxRefreshes
+		}
+		r.slots = make(chan struct{}, size)
+	})
+
+	if _, running := r.inFlight.LoadOrStore(key, struct{}{}); running {
+		return
+	}
+	select {
+	case r.slots <- struct{}{}:
+	default:
+		r.inFlight.Delete(key)
+		return
+	}
+
+	go func() {
+		defer func() {
+			<-r.slots
+			r.inFlight.Delete(key)
+		}()
+		if err := refresh(); err != nil {
+			atomic.AddUint64(&r.refreshFailures, 1)
+		}
+	}()
+}
+
+// WithPreferenceCachePolicy replaces the expiry policy of the preference cache used by
+// WithCategoryOptOutFiltering, for example to enable stale-while-revalidate
+func WithPreferenceCachePolicy(policy CachePolicy) Option {
+	return func(c *Client) {
+		c.declare(optPreferenceCache)
+		c.optOutPolicy = policy
+	}
+}
+
+// CacheStats returns counters of the caches used by pre-send filters, summed over the preference
+// and device caches
+func (c *Client) CacheStats() CacheStats {
+	return CacheStats{
+		StaleServes:     atomic.LoadUint64(&c.optOutRefresher.staleServes) + atomic.LoadUint64(&c.deviceRefresher.staleServes),
+		RefreshFailures: atomic.LoadUint64(&c.optOutRefresher.refreshFailures) + atomic.LoadUint64(&c.deviceRefresher.refreshFailures),
+	}
+}
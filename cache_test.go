@@ -0,0 +1,264 @@
+package engagespot
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// preference server whose lookups can be held back, and whose answer can be changed
+type gatedPreferenceServer struct {
+	mu       sync.Mutex
+	optedOut bool
+	failing  bool
+	gate     chan struct{}
+}
+
+func (s *gatedPreferenceServer) set(optedOut, failing bool, gate chan struct{}) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.optedOut, s.failing, s.gate = optedOut, failing, gate
+}
+
+func (s *gatedPreferenceServer) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Method == http.MethodPost {
+		return respondWith(200, `{}`).RoundTrip(req)
+	}
+
+	s.mu.Lock()
+	optedOut, failing, gate := s.optedOut, s.failing, s.gate
+	s.mu.Unlock()
+	if gate != nil {
+		<-gate
+	}
+	if failing {
+		return respondWith(500, `{}`).RoundTrip(req)
+	}
+	return respondWith(200, `{"preferences":[{"category":"promo","enabled":`+map[bool]string{true: "false", false: "true"}[optedOut]+`}]}`).RoundTrip(req)
+}
+
+func newStaleClient(s *gatedPreferenceServer, cache PreferenceCache, clock *fakeClock) *Client {
+	c := NewEngagespotClient("A", "B",
+		WithCategoryOptOutFiltering(cache, 0),
+		WithPreferenceCachePolicy(CachePolicy{TTL: time.Minute, StaleWhileRevalidate: time.Hour, MaxRefreshes: 1}),
+	)
+	c.httpClient.Transport = s
+	c.now = clock.Now
+	return c
+}
+
+func TestStaleWhileRevalidate(t *testing.T) {
+	s := &gatedPreferenceServer{}
+	cache := NewMemoryPreferenceCache()
+	clock := newFakeClock()
+	c := newStaleClient(s, cache, clock)
+
+	_, err := sendPromo(c, "a")
+	assert.NoError(t, err)
+
+	// past the TTL, lookups hang but the stale value is served right away
+	gate := make(chan struct{})
+	s.set(true, false, gate)
+	clock.Advance(2 * time.Minute)
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		n, err := sendPromo(c, "a")
+		assert.NoError(t, err)
+		assert.Equal(t, SuppressionReport{CacheHits: 1}, n.Suppression())
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("send blocked on a stale entry")
+	}
+	assert.Equal(t, uint64(1), c.CacheStats().StaleServes)
+
+	// the background refresh updates the entry
+	close(gate)
+	assert.Eventually(t, func() bool {
+		optedOut, storedAt, _ := cache.Get("a", "promo")
+		return optedOut && storedAt.Equal(clock.Now())
+	}, time.Second, time.Millisecond)
+
+	n, err := sendPromo(c, "a")
+	assert.ErrorIs(t, err, ErrAllRecipientsSuppressed)
+	assert.Equal(t, []string{"a"}, n.Suppression().Suppressed)
+}
+
+func TestStaleWhileRevalidateRefreshFailure(t *testing.T) {
+	s := &gatedPreferenceServer{}
+	clock := newFakeClock()
+	c := newStaleClient(s, NewMemoryPreferenceCache(), clock)
+
+	_, err := sendPromo(c, "a")
+	assert.NoError(t, err)
+
+	s.set(false, true, nil)
+	clock.Advance(2 * time.Minute)
+	_, err = sendPromo(c, "a")
+	assert.NoError(t, err)
+	assert.Eventually(t, func() bool { return c.CacheStats().RefreshFailures == 1 }, time.Second, time.Millisecond)
+}
+
+func TestStaleBudgetExceeded(t *testing.T) {
+	s := &gatedPreferenceServer{}
+	clock := newFakeClock()
+	c := newStaleClient(s, NewMemoryPreferenceCache(), clock)
+
+	_, err := sendPromo(c, "a")
+	assert.NoError(t, err)
+
+	// too old to be served, looked up before sending
+	s.set(true, false, nil)
+	clock.Advance(2 * time.Hour)
+	n, err := sendPromo(c, "a")
+	assert.ErrorIs(t, err, ErrAllRecipientsSuppressed)
+	assert.Equal(t, 1, n.Suppression().Lookups)
+	assert.Equal(t, uint64(0), c.CacheStats().StaleServes)
+}
+
+func TestCachePolicyFreshness(t *testing.T) {
+	p := CachePolicy{TTL: time.Minute}
+	assert.Equal(t, fresh, p.freshness(time.Second))
+	assert.Equal(t, expired, p.freshness(time.Minute))
+
+	p.StaleWhileRevalidate = time.Minute
+	assert.Equal(t, stale, p.freshness(time.Minute))
+	assert.Equal(t, expired, p.freshness(2*time.Minute))
+}
+
+// device lookup whose answers can be held back, and changed
+type gatedDeviceLookup struct {
+	mu        sync.Mutex
+	hasDevice bool
+	err       error
+	gate      chan struct{}
+	lookups   int
+}
+
+func (l *gatedDeviceLookup) set(hasDevice bool, err error, gate chan struct{}) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.hasDevice, l.err, l.gate = hasDevice, err, gate
+}
+
+func (l *gatedDeviceLookup) lookup(ctx context.Context, userId string) (bool, error) {
+	l.mu.Lock()
+	hasDevice, err, gate := l.hasDevice, l.err, l.gate
+	l.lookups++
+	l.mu.Unlock()
+	if gate != nil {
+		<-gate
+	}
+	return hasDevice, err
+}
+
+func newStaleDeviceClient(l *gatedDeviceLookup, cache DeviceCache, clock *fakeClock) *Client {
+	c := NewEngagespotClient("A", "B",
+		WithDeviceLookup(l.lookup, cache),
+		WithDeviceCachePolicy(CachePolicy{TTL: time.Minute, StaleWhileRevalidate: time.Hour, MaxRefreshes: 1}),
+	)
+	c.now = clock.Now
+	return c
+}
+
+func TestDeviceStaleWhileRevalidate(t *testing.T) {
+	l := &gatedDeviceLookup{}
+	cache := NewMemoryDeviceCache()
+	clock := newFakeClock()
+	c := newStaleDeviceClient(l, cache, clock)
+
+	hasDevice, err := c.hasDevice(context.Background(), "a")
+	assert.NoError(t, err)
+	assert.False(t, hasDevice)
+
+	// within the TTL, no lookup
+	clock.Advance(30 * time.Second)
+	_, err = c.hasDevice(context.Background(), "a")
+	assert.NoError(t, err)
+	assert.Equal(t, 1, l.lookups)
+
+	// past the TTL, lookups hang but the stale value is served right away
+	gate := make(chan struct{})
+	l.set(true, nil, gate)
+	clock.Advance(time.Minute)
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		hasDevice, err := c.hasDevice(context.Background(), "a")
+		assert.NoError(t, err)
+		assert.False(t, hasDevice)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("lookup blocked on a stale entry")
+	}
+	assert.Equal(t, uint64(1), c.CacheStats().StaleServes)
+
+	// the background refresh updates the entry
+	close(gate)
+	assert.Eventually(t, func() bool {
+		hasDevice, storedAt, _ := cache.Get("a")
+		return hasDevice && storedAt.Equal(clock.Now())
+	}, time.Second, time.Millisecond)
+
+	hasDevice, err = c.hasDevice(context.Background(), "a")
+	assert.NoError(t, err)
+	assert.True(t, hasDevice)
+}
+
+func TestDeviceStaleRefreshFailure(t *testing.T) {
+	l := &gatedDeviceLookup{}
+	clock := newFakeClock()
+	c := newStaleDeviceClient(l, NewMemoryDeviceCache(), clock)
+
+	_, err := c.hasDevice(context.Background(), "a")
+	assert.NoError(t, err)
+
+	l.set(false, errors.New("lookup failed"), nil)
+	clock.Advance(2 * time.Minute)
+	_, err = c.hasDevice(context.Background(), "a")
+	assert.NoError(t, err)
+	assert.Eventually(t, func() bool { return c.CacheStats().RefreshFailures == 1 }, time.Second, time.Millisecond)
+}
+
+func TestDeviceStaleBudgetExceeded(t *testing.T) {
+	l := &gatedDeviceLookup{}
+	clock := newFakeClock()
+	c := newStaleDeviceClient(l, NewMemoryDeviceCache(), clock)
+
+	_, err := c.hasDevice(context.Background(), "a")
+	assert.NoError(t, err)
+
+	// too old to be served, looked up before sending
+	l.set(true, nil, nil)
+	clock.Advance(2 * time.Hour)
+	hasDevice, err := c.hasDevice(context.Background(), "a")
+	assert.NoError(t, err)
+	assert.True(t, hasDevice)
+	assert.Equal(t, 2, l.lookups)
+	assert.Equal(t, uint64(0), c.CacheStats().StaleServes)
+}
+
+func TestDeviceCacheWithoutPolicy(t *testing.T) {
+	l := &gatedDeviceLookup{}
+	clock := newFakeClock()
+	c := NewEngagespotClient("A", "B", WithDeviceLookup(l.lookup, NewMemoryDeviceCache()))
+	c.now = clock.Now
+
+	_, err := c.hasDevice(context.Background(), "a")
+	assert.NoError(t, err)
+	clock.Advance(24 * time.Hour)
+	_, err = c.hasDevice(context.Background(), "a")
+	assert.NoError(t, err)
+	assert.Equal(t, 1, l.lookups)
+}
@@ -0,0 +1,50 @@
+package engagespot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+// ErrAlreadyDelivered is returned when cancelling a notification or a workflow run which was
+// already delivered, the API answering with a 409. it tells a cancel which lost the race with the
+// delivery apart from a failed one. the *APIError is still reachable with errors.As
+var ErrAlreadyDelivered = errors.New("already delivered")
+
+// tell a 409 of a cancel apart from other errors
+func alreadyDelivered(err error) error {
+	var apiErr *APIError
+	if errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusConflict {
+		return fmt.Errorf("%w: %w", ErrAlreadyDelivered, err)
+	}
+	return err
+}
+
+// CancelNotification can be used to cancel a notification which isn't delivered yet, like one
+// scheduled with SetSendAt, by the id in its SendResult. ErrAlreadyDelivered is returned if it was
+// delivered already, and ErrNotFound if there is no such notification
+func (c *Client) CancelNotification(ctx context.Context, notificationId string, opts ...CallOption) error {
+	if notificationId == "" {
+		return errors.New("empty notification id string")
+	}
+	req, err := c.newRequestContext(applyCallOptions(ctx, opts), endpointCancelNotification, nil, notificationId)
+	if err != nil {
+		return err
+	}
+	return alreadyDelivered(c.callJSON(endpointCancelNotification, req, nil))
+}
+
+// CancelWorkflowRun can be used to stop a workflow run, by the id in its WorkflowRun, before its
+// remaining steps are delivered. ErrAlreadyDelivered is returned if the run is already over, and
+// ErrNotFound if there is no such run
+func (c *Client) CancelWorkflowRun(ctx context.Context, runId string, opts ...CallOption) error {
+	if runId == "" {
+		return errors.New("empty workflow run id string")
+	}
+	req, err := c.newRequestContext(applyCallOptions(ctx, opts), endpointCancelWorkflowRun, nil, runId)
+	if err != nil {
+		return err
+	}
+	return alreadyDelivered(c.callJSON(endpointCancelWorkflowRun, req, nil))
+}
@@ -0,0 +1,76 @@
+package engagespot
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// server answering cancels with the status set for each path, 404 for other paths
+func cancelServer(statuses map[string]int, requests *[]*http.Request) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		*requests = append(*requests, r)
+		status, ok := statuses[r.URL.EscapedPath()]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"message":"not found"}`))
+			return
+		}
+		w.WriteHeader(status)
+		if status == http.StatusConflict {
+			w.Write([]byte(`{"message":"notification already sent"}`))
+		}
+	}))
+}
+
+func TestCancelNotification(t *testing.T) {
+	var requests []*http.Request
+	server := cancelServer(map[string]int{
+		"/v3/notifications/n%2F1": http.StatusNoContent,
+		"/v3/notifications/sent":  http.StatusConflict,
+	}, &requests)
+	defer server.Close()
+	c := clientFor(server.URL+"/v3/", time.Second)
+	ctx := context.Background()
+
+	assert.NoError(t, c.CancelNotification(ctx, "n/1"))
+	assert.Equal(t, http.MethodDelete, requests[0].Method)
+
+	err := c.CancelNotification(ctx, "sent")
+	assert.ErrorIs(t, err, ErrAlreadyDelivered)
+	var apiErr *APIError
+	assert.True(t, errors.As(err, &apiErr))
+	assert.Equal(t, "notification already sent", apiErr.Message)
+
+	err = c.CancelNotification(ctx, "missing")
+	assert.ErrorIs(t, err, ErrNotFound)
+	assert.NotErrorIs(t, err, ErrAlreadyDelivered)
+
+	assert.EqualError(t, c.CancelNotification(ctx, ""), "empty notification id string")
+}
+
+func TestCancelWorkflowRun(t *testing.T) {
+	var requests []*http.Request
+	server := cancelServer(map[string]int{
+		"/v3/workflows/runs/run%201/cancel": http.StatusOK,
+		"/v3/workflows/runs/done/cancel":    http.StatusConflict,
+	}, &requests)
+	defer server.Close()
+	c := clientFor(server.URL+"/v3/", time.Second)
+	ctx := context.Background()
+
+	assert.NoError(t, c.CancelWorkflowRun(ctx, "run 1"))
+	assert.Equal(t, http.MethodPost, requests[0].Method)
+
+	assert.ErrorIs(t, c.CancelWorkflowRun(ctx, "done"), ErrAlreadyDelivered)
+	err := c.CancelWorkflowRun(ctx, "missing")
+	assert.ErrorIs(t, err, ErrNotFound)
+	assert.NotErrorIs(t, err, ErrAlreadyDelivered)
+
+	assert.EqualError(t, c.CancelWorkflowRun(ctx, ""), "empty workflow run id string")
+}
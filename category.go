@@ -0,0 +1,113 @@
+package engagespot
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// categories fetched per request by ListCategories
+const categoryPageSize = 100
+
+// Category is a notification category of the app, see SetCategory
+type Category struct {
+	Response
+	Id        string    `json:"id"`
+	Name      string    `json:"name"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// page of the response of `GET categories`
+type categoryPage struct {
+	Data       []Category `json:"data"`
+	TotalCount int        `json:"totalCount"`
+}
+
+// ListCategories can be used to list every category of the app. categories are fetched page by
+// page, an error on any page fails the whole list. listed categories no longer get the warning
+// for categories not seen before
+func (c *Client) ListCategories(ctx context.Context, opts ...CallOption) ([]Category, error) {
+	ctx = applyCallOptions(ctx, opts)
+	var categories []Category
+	for page := 1; ; page++ {
+		req, err := c.newRequestContext(ctx, endpointListCategories, nil)
+		if err != nil {
+			return nil, err
+		}
+		req.URL.RawQuery = url.Values{
+			"page":  {strconv.Itoa(page)},
+			"limit": {strconv.Itoa(categoryPageSize)},
+		}.Encode()
+
+		p := &categoryPage{}
+		if err := c.callJSON(endpointListCategories, req, p); err != nil {
+			return nil, err
+		}
+		categories = append(categories, p.Data...)
+		for _, category := range p.Data {
+			c.knowCategory(category)
+		}
+		if len(p.Data) == 0 || len(categories) >= p.TotalCount {
+			return categories, nil
+		}
+	}
+}
+
+// CreateCategory can be used to create a category, instead of letting the first notification sent
+// with it create it
+func (c *Client) CreateCategory(ctx context.Context, name string, opts ...CallOption) (*Category, error) {
+	if name == "" {
+		return nil, errors.New("empty category string")
+	}
+	body, err := json.Marshal(map[string]string{"name": name})
+	if err != nil {
+		return nil, err
+	}
+	req, err := c.newRequestContext(applyCallOptions(ctx, opts), endpointCreateCategory, body)
+	if err != nil {
+		return nil, err
+	}
+
+	category := &Category{}
+	if err := c.callJSON(endpointCreateCategory, req, category); err != nil {
+		return nil, err
+	}
+	c.categories.Store(name, struct{}{})
+	c.categoryNames.Store(category.Id, name)
+	return category, nil
+}
+
+// record that category exists, so it doesn't get the warning for categories not seen before
+func (c *Client) knowCategory(category Category) {
+	c.categories.Store(category.Name, struct{}{})
+	c.categoryNames.Store(category.Id, category.Name)
+}
+
+// DeleteCategory can be used to delete a category by id. the next send to the deleted category gets
+// the warning for categories not seen before again. the name of a category which wasn't listed or
+// created by the client isn't known, so every category is taken as not seen then
+func (c *Client) DeleteCategory(ctx context.Context, categoryId string, opts ...CallOption) error {
+	if categoryId == "" {
+		return errors.New("empty category id string")
+	}
+	req, err := c.newRequestContext(applyCallOptions(ctx, opts), endpointDeleteCategory, nil, categoryId)
+	if err != nil {
+		return err
+	}
+	if err := c.callJSON(endpointDeleteCategory, req, nil); err != nil {
+		return err
+	}
+
+	if name, ok := c.categoryNames.LoadAndDelete(categoryId); ok {
+		c.categories.Delete(name)
+		return nil
+	}
+	c.categories.Range(func(name, _ interface{}) bool {
+		c.categories.Delete(name)
+		return true
+	})
+	return nil
+}
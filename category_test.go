@@ -0,0 +1,182 @@
+package engagespot
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// fake category store of an app, paginated like the API
+type categoryServer struct {
+	mu         sync.Mutex
+	categories []Category
+	requests   []string
+}
+
+func (s *categoryServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.requests = append(s.requests, r.Method+" "+r.URL.RequestURI())
+
+	switch r.Method {
+	case http.MethodGet:
+		page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+		limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+		from, to := (page-1)*limit, page*limit
+		if from > len(s.categories) {
+			from = len(s.categories)
+		}
+		if to > len(s.categories) {
+			to = len(s.categories)
+		}
+		json.NewEncoder(w).Encode(categoryPage{Data: s.categories[from:to], TotalCount: len(s.categories)})
+	case http.MethodPost:
+		var body struct {
+			Name string `json:"name"`
+		}
+		json.NewDecoder(r.Body).Decode(&body)
+		if body.Name == "taken" {
+			w.WriteHeader(409)
+			w.Write([]byte(`{"message":"category already exists"}`))
+			return
+		}
+		category := Category{Id: fmt.Sprintf("cat-%d", len(s.categories)), Name: body.Name, CreatedAt: time.Date(2022, 1, 2, 3, 4, 5, 0, time.UTC)}
+		s.categories = append(s.categories, category)
+		json.NewEncoder(w).Encode(category)
+	case http.MethodDelete:
+		id := r.URL.Path[strings.LastIndex(r.URL.Path, "/")+1:]
+		for i, category := range s.categories {
+			if category.Id == id {
+				s.categories = append(s.categories[:i], s.categories[i+1:]...)
+				w.WriteHeader(204)
+				return
+			}
+		}
+		w.WriteHeader(404)
+		w.Write([]byte(`{"message":"no such category"}`))
+	}
+}
+
+func newCategoryClient(t *testing.T, s *categoryServer) *Client {
+	server := httptest.NewServer(s)
+	t.Cleanup(server.Close)
+	return clientFor(server.URL+"/v3/", time.Second)
+}
+
+func TestCreateCategory(t *testing.T) {
+	s := &categoryServer{}
+	c := newCategoryClient(t, s)
+
+	category, err := c.CreateCategory(context.Background(), "billing")
+	assert.NoError(t, err)
+	assert.Equal(t, &Category{Id: "cat-0", Name: "billing", CreatedAt: time.Date(2022, 1, 2, 3, 4, 5, 0, time.UTC)}, category)
+	assert.Equal(t, []string{"POST /v3/categories"}, s.requests)
+
+	_, err = c.CreateCategory(context.Background(), "taken")
+	var apiErr *APIError
+	assert.True(t, errors.As(err, &apiErr))
+	assert.Equal(t, 409, apiErr.StatusCode)
+	assert.Equal(t, "category already exists", apiErr.Message)
+
+	_, err = c.CreateCategory(context.Background(), "")
+	assert.EqualError(t, err, "empty category string")
+}
+
+func TestListCategoriesPaginated(t *testing.T) {
+	s := &categoryServer{}
+	for i := 0; i < categoryPageSize*2+1; i++ {
+		s.categories = append(s.categories, Category{Id: fmt.Sprintf("cat-%d", i)})
+	}
+	c := newCategoryClient(t, s)
+
+	categories, err := c.ListCategories(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, s.categories, categories)
+	assert.Equal(t, []string{
+		"GET /v3/categories?limit=100&page=1",
+		"GET /v3/categories?limit=100&page=2",
+		"GET /v3/categories?limit=100&page=3",
+	}, s.requests)
+}
+
+func TestListCategoriesEmpty(t *testing.T) {
+	s := &categoryServer{}
+	c := newCategoryClient(t, s)
+
+	categories, err := c.ListCategories(context.Background())
+	assert.NoError(t, err)
+	assert.Empty(t, categories)
+	assert.Len(t, s.requests, 1)
+}
+
+func TestDeleteCategory(t *testing.T) {
+	s := &categoryServer{categories: []Category{{Id: "cat-0"}, {Id: "cat-1"}}}
+	c := newCategoryClient(t, s)
+
+	assert.NoError(t, c.DeleteCategory(context.Background(), "cat-0"))
+	assert.Equal(t, []Category{{Id: "cat-1"}}, s.categories)
+	assert.Equal(t, []string{"DELETE /v3/categories/cat-0"}, s.requests)
+
+	err := c.DeleteCategory(context.Background(), "cat-0")
+	assert.ErrorIs(t, err, ErrNotFound)
+	assert.EqualError(t, c.DeleteCategory(context.Background(), ""), "empty category id string")
+}
+
+func TestDeletedCategoryWarnsAgain(t *testing.T) {
+	s := &categoryServer{categories: []Category{{Id: "cat-0", Name: "billing"}}}
+	c := newCategoryClient(t, s)
+	_, err := c.ListCategories(context.Background())
+	assert.NoError(t, err)
+	promo, err := c.CreateCategory(context.Background(), "promo")
+	assert.NoError(t, err)
+	categoryWarned := func(category string) bool {
+		n := newMessage(c)
+		n.SetCategory(category)
+		warnings, err := c.check(n, false)
+		assert.NoError(t, err)
+		for _, w := range warnings {
+			if w.Field == "category" {
+				return true
+			}
+		}
+		return false
+	}
+
+	assert.NoError(t, c.DeleteCategory(context.Background(), promo.Id))
+	assert.True(t, categoryWarned("promo"))
+	assert.False(t, categoryWarned("billing"))
+
+	// the name of a category the client didn't list or create isn't known, every one warns again
+	s.categories = append(s.categories, Category{Id: "cat-9", Name: "orders"})
+	assert.NoError(t, c.DeleteCategory(context.Background(), "cat-9"))
+	assert.True(t, categoryWarned("billing"))
+}
+
+func TestKnownCategoriesDontWarn(t *testing.T) {
+	s := &categoryServer{categories: []Category{{Id: "cat-0", Name: "billing"}}}
+	c := newCategoryClient(t, s)
+	_, err := c.ListCategories(context.Background())
+	assert.NoError(t, err)
+	_, err = c.CreateCategory(context.Background(), "promo")
+	assert.NoError(t, err)
+
+	for _, category := range []string{"billing", "promo"} {
+		n := newMessage(c)
+		n.SetCategory(category)
+		warnings, err := c.validate(n)
+		assert.NoError(t, err)
+		for _, w := range warnings {
+			assert.NotEqual(t, "category", w.Field)
+		}
+	}
+}
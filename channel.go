@@ -0,0 +1,103 @@
+package engagespot
+
+import (
+	"errors"
+	"fmt"
+)
+
+// channels a notification can be delivered through, see AddChannelStrict
+const (
+	ChannelInApp      = "inApp"
+	ChannelEmail      = "email"
+	ChannelWebPush    = "webPush"
+	ChannelMobilePush = "mobilePush"
+	ChannelSMS        = "sms"
+	ChannelWhatsApp   = "whatsapp"
+)
+
+var knownChannels = map[string]bool{
+	ChannelInApp:      true,
+	ChannelEmail:      true,
+	ChannelWebPush:    true,
+	ChannelMobilePush: true,
+	ChannelSMS:        true,
+	ChannelWhatsApp:   true,
+}
+
+func validChannel(channel string) error {
+	if channel == "" {
+		return errors.New("empty channel string")
+	}
+	if !knownChannels[channel] {
+		return fmt.Errorf("unknown channel %q, use AddChannelRaw for channels without a constant", channel)
+	}
+	return nil
+}
+
+// AddChannel is a method to override notification channels. a channel already added is skipped.
+// channels without a constant are accepted but warned about when sending, use AddChannelStrict to
+// reject them instead
+func (o *Override) AddChannel(channel string) {
+	o.addChannel(channel)
+}
+
+// AddChannelStrict is AddChannel rejecting channels which aren't one of the Channel constants,
+// so a typo like "emial" doesn't silently disable delivery
+func (o *Override) AddChannelStrict(channel string) error {
+	if err := validChannel(channel); err != nil {
+		return invalidField("channels", channel, err)
+	}
+	o.addChannel(channel)
+	return nil
+}
+
+// AddChannelRaw is AddChannel for channels this version of the SDK has no constant for. they are
+// sent as is, without a warning
+func (o *Override) AddChannelRaw(channel string) {
+	if o.rawChannels == nil {
+		o.rawChannels = map[string]bool{}
+	}
+	o.rawChannels[channel] = true
+	o.addChannel(channel)
+}
+
+func (o *Override) addChannel(channel string) {
+	for _, c := range o.Channels {
+		if c == channel {
+			return
+		}
+	}
+	o.Channels = append(o.Channels, channel)
+}
+
+// SetChannels can be used to set the channels of the notification, replacing any channel added
+// before. every channel must be one of the Channel constants, see AddChannelRaw for others
+func (n *Notification) SetChannels(channels ...string) (*Notification, error) {
+	if len(channels) == 0 {
+		return nil, invalidField("channels", channels, errors.New("no channels"))
+	}
+	for _, channel := range channels {
+		if err := validChannel(channel); err != nil {
+			return nil, invalidField("channels", channel, err)
+		}
+	}
+	n.Override.Channels = nil
+	for _, channel := range channels {
+		n.Override.addChannel(channel)
+	}
+	return n, nil
+}
+
+// warning for channels added with AddChannel which aren't known
+func (o *Override) channelWarnings() []Warning {
+	if o == nil {
+		return nil
+	}
+	var warnings []Warning
+	for _, channel := range o.Channels {
+		if !knownChannels[channel] && !o.rawChannels[channel] {
+			warnings = append(warnings, Warning{Field: "channels", Message: fmt.Sprintf("unknown channel %q, it may deliver nothing", channel)})
+		}
+	}
+	return warnings
+}
@@ -0,0 +1,256 @@
+package engagespot
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// RecipientInfo is what a ChannelRule gets to know about a recipient
+type RecipientInfo struct {
+	Recipient string
+	HasDevice bool
+}
+
+// ChannelRule conditionally adds Channel to the channels a recipient is notified through
+type ChannelRule struct {
+	Channel string
+	When    func(RecipientInfo) bool
+}
+
+// OnlyIfNoDevice creates a rule including channel only for recipients without a registered push
+// device, like sending an email only to users who won't see the push notification
+func OnlyIfNoDevice(channel string) ChannelRule {
+	return ChannelRule{
+		Channel: channel,
+		When: func(r RecipientInfo) bool {
+			return !r.HasDevice
+		},
+	}
+}
+
+// DeviceLookup reports if a user has at least one registered push device. ctx is the one of the
+// send evaluating the channel rules
+type DeviceLookup func(ctx context.Context, userId string) (bool, error)
+
+// DeviceCache stores results of DeviceLookup. implementations must be safe for concurrent use
+type DeviceCache interface {
+	Get(userId string) (hasDevice bool, storedAt time.Time, ok bool)
+	Set(userId string, hasDevice bool, storedAt time.Time)
+}
+
+type deviceEntry struct {
+	hasDevice bool
+	storedAt  time.Time
+}
+
+// in-memory DeviceCache
+type memoryDeviceCache struct {
+	m sync.Map
+}
+
+// NewMemoryDeviceCache creates a DeviceCache keeping every lookup in memory for the lifetime of
+// the process. entries are never evicted, staleness is decided by WithDeviceCachePolicy
+func NewMemoryDeviceCache() DeviceCache {
+	return &memoryDeviceCache{}
+}
+
+func (c *memoryDeviceCache) Get(userId string) (bool, time.Time, bool) {
+	v, ok := c.m.Load(userId)
+	if !ok {
+		return false, time.Time{}, false
+	}
+	e := v.(deviceEntry)
+	return e.hasDevice, e.storedAt, true
+}
+
+func (c *memoryDeviceCache) Set(userId string, hasDevice bool, storedAt time.Time) {
+	c.m.Store(userId, deviceEntry{hasDevice: hasDevice, storedAt: storedAt})
+}
+
+// WithDeviceLookup configures how channel rules find out if a recipient has a push device, instead
+// of listing the devices of every recipient with ListDevices. a nil lookup keeps ListDevices.
+// cache is optional, its entries never expire unless WithDeviceCachePolicy is used
+func WithDeviceLookup(lookup DeviceLookup, cache DeviceCache) Option {
+	return func(c *Client) {
+		c.declare(optDeviceLookup)
+		c.deviceLookup = lookup
+		c.deviceCache = cache
+	}
+}
+
+// WithDeviceCachePolicy sets the expiry policy of the cache given to WithDeviceLookup, for example
+// to enable stale-while-revalidate. it has no effect without a cache
+func WithDeviceCachePolicy(policy CachePolicy) Option {
+	return func(c *Client) {
+		c.declare(optDeviceCachePolicy)
+		c.devicePolicy = &policy
+	}
+}
+
+// default DeviceLookup, through the device registration API
+func (c *Client) listDevicesLookup(ctx context.Context, userId string) (bool, error) {
+	devices, err := c.ListDevices(ctx, userId)
+	if err != nil {
+		return false, err
+	}
+	return len(devices) > 0, nil
+}
+
+// AddChannelRule can be used to add a channel to the override only for recipients matching the
+// rule. rules are evaluated client-side before sending and recipients ending up with the same
+// channels are grouped into a single request. base channels must be set using AddChannel
+func (n *Notification) AddChannelRule(rule ChannelRule) (*Notification, error) {
+	if rule.Channel == "" {
+		return nil, invalidField("channelRules", rule.Channel, errors.New("empty channel rule channel"))
+	}
+	if rule.When == nil {
+		return nil, invalidField("channelRules", rule.Channel, errors.New("channel rule without condition"))
+	}
+	n.channelRules = append(n.channelRules, rule)
+	return n, nil
+}
+
+// SkipChannelRules makes the next sends of the notification ignore its channel rules and go out
+// with the base channels only, avoiding the device lookups on latency-critical paths
+func (n *Notification) SkipChannelRules() *Notification {
+	n.skipChannelRules = true
+	return n
+}
+
+func (n *Notification) hasActiveChannelRules() bool {
+	return len(n.channelRules) > 0 && !n.skipChannelRules
+}
+
+func (c *Client) hasDevice(ctx context.Context, userId string) (bool, error) {
+	if c.deviceCache != nil {
+		if hasDevice, storedAt, ok := c.deviceCache.Get(userId); ok {
+			if c.devicePolicy == nil {
+				return hasDevice, nil
+			}
+			switch c.devicePolicy.freshness(c.now().Sub(storedAt)) {
+			case fresh:
+				return hasDevice, nil
+			case stale:
+				atomic.AddUint64(&c.deviceRefresher.staleServes, 1)
+				// the refresh outlives the send, it only keeps the values of its context
+				refreshCtx := context.WithoutCancel(ctx)
+				c.deviceRefresher.refresh(*c.devicePolicy, userId, func() error {
+					_, err := c.lookupDevice(refreshCtx, userId)
+					return err
+				})
+				return hasDevice, nil
+			}
+		}
+	}
+	return c.lookupDevice(ctx, userId)
+}
+
+// look up whether a user has a device and cache it
+func (c *Client) lookupDevice(ctx context.Context, userId string) (bool, error) {
+	lookup := c.deviceLookup
+	if lookup == nil {
+		lookup = c.listDevicesLookup
+	}
+	hasDevice, err := lookup(ctx, userId)
+	if err != nil {
+		return false, err
+	}
+
+	if c.deviceCache != nil {
+		c.deviceCache.Set(userId, hasDevice, c.now())
+	}
+	return hasDevice, nil
+}
+
+// a set of recipients which share the same channels after evaluating the rules
+type channelGroup struct {
+	channels   []string
+	recipients []string
+}
+
+// evaluate channel rules for every recipient and group them by the resulting channels. groups are
+// ordered by the first recipient appearing in each
+func (c *Client) groupByChannels(ctx context.Context, n *Notification) ([]channelGroup, error) {
+	if len(n.Override.Channels) == 0 {
+		return nil, errors.New("channel rules require base channels")
+	}
+
+	var groups []channelGroup
+	index := map[string]int{}
+
+	for _, recipient := range n.Recipients {
+		hasDevice, err := c.hasDevice(ctx, recipient)
+		if err != nil {
+			return nil, err
+		}
+		info := RecipientInfo{Recipient: recipient, HasDevice: hasDevice}
+
+		channels := append([]string{}, n.Override.Channels...)
+		for _, rule := range n.channelRules {
+			if rule.When(info) && !containsString(channels, rule.Channel) {
+				channels = append(channels, rule.Channel)
+			}
+		}
+		sort.Strings(channels)
+
+		key := strings.Join(channels, ",")
+		i, ok := index[key]
+		if !ok {
+			i = len(groups)
+			index[key] = i
+			groups = append(groups, channelGroup{channels: channels})
+		}
+		groups[i].recipients = append(groups[i].recipients, recipient)
+	}
+
+	return groups, nil
+}
+
+// send one request per channel group. the response of the last group is returned, the others
+// are drained and closed. the first group failing, with an error or a non-2xx status, stops the
+// remaining groups and is returned as the error, so a failed group is never hidden behind the
+// success of a later one. groups sent before the failure stay sent
+func (c *Client) sendGrouped(ctx context.Context, n *Notification) (*http.Response, error) {
+	groups, err := c.groupByChannels(ctx, n)
+	if err != nil {
+		return nil, err
+	}
+
+	var res *http.Response
+	for i, g := range groups {
+		o := *n.Override
+		o.Channels = g.channels
+		group := *n
+		group.Override = &o
+		group.Recipients = g.recipients
+		group.channelGroup = i
+
+		res, err = c.send(ctx, &group)
+		if err != nil {
+			return nil, err
+		}
+		if res.StatusCode < 200 || res.StatusCode > 299 {
+			return nil, decodeInto(c.sendPath(n).endpoint(), res, nil)
+		}
+		if i < len(groups)-1 {
+			discard(res)
+		}
+	}
+
+	return res, nil
+}
+
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
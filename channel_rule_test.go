@@ -0,0 +1,205 @@
+package engagespot
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// transport recording the decoded body of every request before answering with 200
+func recordBodies(bodies *[]map[string]interface{}) http.RoundTripper {
+	return roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		var body map[string]interface{}
+		json.NewDecoder(req.Body).Decode(&body)
+		*bodies = append(*bodies, body)
+		return &http.Response{
+			StatusCode: 200,
+			Body:       io.NopCloser(strings.NewReader(`{}`)),
+			Request:    req,
+		}, nil
+	})
+}
+
+func devicesOf(owners ...string) DeviceLookup {
+	return func(ctx context.Context, userId string) (bool, error) {
+		return containsString(owners, userId), nil
+	}
+}
+
+func newRuledNotification(t *testing.T, c *Client, recipients ...string) *Notification {
+	n, err := c.NewNotification("Hello")
+	assert.NoError(t, err)
+	n.Override.AddChannel("inApp")
+	_, err = n.AddChannelRule(OnlyIfNoDevice("email"))
+	assert.NoError(t, err)
+	for _, r := range recipients {
+		n.AddRecipient(r)
+	}
+	return n
+}
+
+func TestChannelRuleGrouping(t *testing.T) {
+	c := NewEngagespotClient("A", "B", WithDeviceLookup(devicesOf("b", "d"), nil))
+	n := newRuledNotification(t, c, "a", "b", "c", "d", "e")
+
+	groups, err := c.groupByChannels(context.Background(), n)
+	assert.NoError(t, err)
+	assert.Equal(t, []channelGroup{
+		{channels: []string{"email", "inApp"}, recipients: []string{"a", "c", "e"}},
+		{channels: []string{"inApp"}, recipients: []string{"b", "d"}},
+	}, groups)
+}
+
+func TestChannelRuleSendsOneRequestPerGroup(t *testing.T) {
+	var bodies []map[string]interface{}
+	c := NewEngagespotClient("A", "B", WithDeviceLookup(devicesOf("b"), nil))
+	c.httpClient.Transport = recordBodies(&bodies)
+	n := newRuledNotification(t, c, "a", "b", "c")
+
+	res, err := n.Send()
+	assert.NoError(t, err)
+	assert.Equal(t, 200, res.StatusCode)
+
+	assert.Len(t, bodies, 2)
+	assert.Equal(t, []interface{}{"a", "c"}, bodies[0]["recipients"])
+	assert.Equal(t, []interface{}{"email", "inApp"}, bodies[0]["override"].(map[string]interface{})["channels"])
+	assert.Equal(t, []interface{}{"b"}, bodies[1]["recipients"])
+	assert.Equal(t, []interface{}{"inApp"}, bodies[1]["override"].(map[string]interface{})["channels"])
+
+	// the notification itself is left untouched
+	assert.Equal(t, []string{"a", "b", "c"}, n.Recipients)
+	assert.Equal(t, []string{"inApp"}, n.Override.Channels)
+}
+
+func TestChannelRuleGroupFailureStops(t *testing.T) {
+	calls := 0
+	c := NewEngagespotClient("A", "B", WithDeviceLookup(devicesOf("b", "d"), nil))
+	c.httpClient.Transport = roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		calls++
+		if calls == 1 {
+			return respondWith(400, `{"message":"invalid channel"}`).RoundTrip(req)
+		}
+		return respondWith(200, `{}`).RoundTrip(req)
+	})
+	n := newRuledNotification(t, c, "a", "b", "c", "d")
+
+	// the failure of the first group isn't hidden behind the success of the second
+	_, err := n.Send()
+	var apiErr *APIError
+	assert.True(t, errors.As(err, &apiErr))
+	assert.Equal(t, 400, apiErr.StatusCode)
+	assert.Equal(t, "invalid channel", apiErr.Message)
+	assert.Equal(t, 1, calls)
+
+	// SendRaw can't hand back the response of a single group either
+	calls = 0
+	_, err = n.SendRaw()
+	assert.True(t, errors.As(err, &apiErr))
+}
+
+func TestChannelRuleCacheHit(t *testing.T) {
+	lookups := 0
+	lookup := func(ctx context.Context, userId string) (bool, error) {
+		lookups++
+		return false, nil
+	}
+	cache := NewMemoryDeviceCache()
+	cache.Set("a", true, time.Time{})
+
+	c := NewEngagespotClient("A", "B", WithDeviceLookup(lookup, cache))
+	n := newRuledNotification(t, c, "a", "b")
+
+	_, err := c.groupByChannels(context.Background(), n)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, lookups)
+
+	_, err = c.groupByChannels(context.Background(), n)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, lookups)
+
+	hasDevice, _, ok := cache.Get("b")
+	assert.True(t, ok)
+	assert.False(t, hasDevice)
+}
+
+func TestChannelRuleSkipped(t *testing.T) {
+	var bodies []map[string]interface{}
+	lookup := func(context.Context, string) (bool, error) {
+		t.Fatal("unexpected device lookup")
+		return false, nil
+	}
+	c := NewEngagespotClient("A", "B", WithDeviceLookup(lookup, nil))
+	c.httpClient.Transport = recordBodies(&bodies)
+	n := newRuledNotification(t, c, "a", "b").SkipChannelRules()
+
+	_, err := n.Send()
+	assert.NoError(t, err)
+	assert.Len(t, bodies, 1)
+	assert.Equal(t, []interface{}{"inApp"}, bodies[0]["override"].(map[string]interface{})["channels"])
+}
+
+func TestChannelRuleErrors(t *testing.T) {
+	c := NewEngagespotClient("A", "B", WithDeviceLookup(func(context.Context, string) (bool, error) {
+		return false, errors.New("lookup failed")
+	}, nil))
+	c.httpClient.Transport = noNetwork(t)
+	n := newRuledNotification(t, c, "a")
+	_, err := n.Send()
+	assert.EqualError(t, err, "lookup failed")
+
+	n = newRuledNotification(t, c, "a")
+	n.Override.Channels = nil
+	_, err = n.Send()
+	assert.EqualError(t, err, "channel rules require base channels")
+
+	_, err = n.AddChannelRule(ChannelRule{Channel: "email"})
+	assert.Error(t, err)
+}
+
+func TestChannelRuleDefaultDeviceLookup(t *testing.T) {
+	var bodies []map[string]interface{}
+	var listed []string
+	record := recordBodies(&bodies)
+	c := NewEngagespotClient("A", "B")
+	c.httpClient.Transport = roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		if req.Method != http.MethodGet {
+			return record.RoundTrip(req)
+		}
+		listed = append(listed, req.URL.Path)
+		if strings.Contains(req.URL.Path, "/users/b/") {
+			return respondWith(200, `{"devices":[{"id":"d1","platform":"ios"}]}`).RoundTrip(req)
+		}
+		return respondWith(200, `{"devices":[]}`).RoundTrip(req)
+	})
+
+	_, err := newRuledNotification(t, c, "a", "b").Send()
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"/v3/users/a/devices", "/v3/users/b/devices"}, listed)
+	assert.Len(t, bodies, 2)
+	assert.Equal(t, []interface{}{"a"}, bodies[0]["recipients"])
+	assert.Equal(t, []interface{}{"email", "inApp"}, bodies[0]["override"].(map[string]interface{})["channels"])
+	assert.Equal(t, []interface{}{"b"}, bodies[1]["recipients"])
+}
+
+func TestChannelRuleLookupGetsSendContext(t *testing.T) {
+	type key struct{}
+	var got []interface{}
+	c := NewEngagespotClient("A", "B", WithDeviceLookup(func(ctx context.Context, userId string) (bool, error) {
+		got = append(got, ctx.Value(key{}))
+		return false, ctx.Err()
+	}, nil))
+	c.httpClient.Transport = noNetwork(t)
+
+	ctx, cancel := context.WithCancel(context.WithValue(context.Background(), key{}, "send"))
+	cancel()
+	_, err := newRuledNotification(t, c, "a", "b").SendCtx(ctx)
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Equal(t, []interface{}{"send"}, got)
+}
@@ -0,0 +1,59 @@
+package engagespot
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAddChannelStrict(t *testing.T) {
+	n := newTestNotification(t)
+	for _, channel := range []string{ChannelInApp, ChannelEmail, ChannelWebPush, ChannelMobilePush, ChannelSMS, ChannelWhatsApp, ChannelEmail} {
+		assert.NoError(t, n.Override.AddChannelStrict(channel))
+	}
+	assert.Equal(t, []string{"inApp", "email", "webPush", "mobilePush", "sms", "whatsapp"}, n.Override.Channels)
+
+	assert.EqualError(t, n.Override.AddChannelStrict("emial"), `unknown channel "emial", use AddChannelRaw for channels without a constant`)
+	assert.EqualError(t, n.Override.AddChannelStrict(""), "empty channel string")
+	assert.Len(t, n.Override.Channels, 6)
+}
+
+func TestAddChannelDeduplicates(t *testing.T) {
+	n := newTestNotification(t)
+	n.Override.AddChannel(ChannelEmail)
+	n.Override.AddChannel(ChannelEmail)
+	n.Override.AddChannelRaw("pigeon")
+	n.Override.AddChannelRaw("pigeon")
+	assert.Equal(t, []interface{}{"email", "pigeon"}, encodedPayload(t, n)["override"].(map[string]interface{})["channels"])
+}
+
+func TestUnknownChannelWarning(t *testing.T) {
+	c := NewEngagespotClient("A", "B")
+	c.httpClient.Transport = respondWith(200, `{}`)
+
+	n := newMessage(c)
+	n.Override.AddChannel("emial")
+	n.Override.AddChannelRaw("pigeon")
+	result, err := n.SendCtx(context.Background())
+	assert.NoError(t, err)
+	assert.Contains(t, result.Warnings, Warning{Field: "channels", Message: `unknown channel "emial", it may deliver nothing`})
+	for _, w := range result.Warnings {
+		assert.NotContains(t, w.Message, "pigeon")
+	}
+}
+
+func TestSetChannels(t *testing.T) {
+	n := newTestNotification(t)
+	n.Override.AddChannel(ChannelSMS)
+
+	_, err := n.SetChannels(ChannelEmail, ChannelInApp, ChannelEmail)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"email", "inApp"}, n.Override.Channels)
+
+	_, err = n.SetChannels(ChannelEmail, "emial")
+	assert.EqualError(t, err, `unknown channel "emial", use AddChannelRaw for channels without a constant`)
+	_, err = n.SetChannels()
+	assert.EqualError(t, err, "no channels")
+	assert.Equal(t, []string{"email", "inApp"}, n.Override.Channels)
+}
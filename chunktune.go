@@ -0,0 +1,155 @@
+package engagespot
+
+import (
+	"sync"
+	"time"
+)
+
+// ChunkTuning configures WithAdaptiveChunkSize. zero values get the defaults documented on each
+// field
+type ChunkTuning struct {
+	// bounds of the chunk size. Min defaults to 1, Max to Defaults.ChunkSize
+	Min int
+	Max int
+	// factor the size is multiplied by on a 413 or a slow chunk, 0.5 by default
+	Shrink float64
+	// recipients added to the size after Successes chunks in a row were sent fast enough. Step
+	// defaults to a tenth of Defaults.ChunkSize, Successes to 3
+	Step      int
+	Successes int
+	// chunks taking longer than this to be sent shrink the size. 0 ignores latency
+	LatencyTarget time.Duration
+	// learn a size per category instead of one for the whole client
+	PerCategory bool
+}
+
+// WithAdaptiveChunkSize makes chunked sends, like recipient sources and bulk sends, tune their
+// chunk size from the responses of the API. starting from Defaults.ChunkSize, the size shrinks
+// multiplicatively when a chunk is rejected with 413 Payload Too Large or with ErrPayloadTooLarge
+// before it's sent, or when a chunk is slower than the latency target. a rejected chunk is sent
+// again in smaller chunks. the size grows additively back after sustained success. the learned
+// size is kept by the client across sends, see Stats
+func WithAdaptiveChunkSize(tuning ChunkTuning) Option {
+	return func(c *Client) {
+		c.declare(optAdaptiveChunkSize)
+		c.chunkTuner = &chunkTuner{tuning: tuning}
+	}
+}
+
+// learned chunk sizes, by category or under "" for the whole client
+type chunkTuner struct {
+	tuning ChunkTuning
+
+	mu        sync.Mutex
+	sizes     map[string]int
+	successes map[string]int
+}
+
+// key sizes are learned under for category
+func (t *chunkTuner) key(category string) string {
+	if t.tuning.PerCategory {
+		return category
+	}
+	return ""
+}
+
+// bounds and steps of the tuning, with defaults relative to base, the configured chunk size
+func (t *chunkTuner) limits(base int) (min, max, step, successes int, shrink float64) {
+	min, max, step, successes, shrink = t.tuning.Min, t.tuning.Max, t.tuning.Step, t.tuning.Successes, t.tuning.Shrink
+	if min <= 0 {
+		min = 1
+	}
+	if max <= 0 {
+		max = base
+	}
+	if step <= 0 {
+		step = base / 10
+		if step < 1 {
+			step = 1
+		}
+	}
+	if successes <= 0 {
+		successes = 3
+	}
+	if shrink <= 0 || shrink >= 1 {
+		shrink = 0.5
+	}
+	return
+}
+
+// current size for key, base if nothing has been learned yet
+func (t *chunkTuner) size(key string, base int) int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if size, ok := t.sizes[key]; ok {
+		return size
+	}
+	min, max, _, _, _ := t.limits(base)
+	return clampInt(base, min, max)
+}
+
+// shrink the size for key after a chunk of sent recipients was rejected or slow. false if the
+// size is already at its minimum
+func (t *chunkTuner) shrink(key string, base, sent int) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	min, max, _, _, factor := t.limits(base)
+	if sent <= min {
+		return false
+	}
+	t.set(key, clampInt(int(float64(sent)*factor), min, max))
+	return true
+}
+
+// record a chunk sent successfully in latency
+func (t *chunkTuner) success(key string, base int, latency time.Duration) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	min, max, step, successes, factor := t.limits(base)
+	size, ok := t.sizes[key]
+	if !ok {
+		size = clampInt(base, min, max)
+	}
+
+	if t.tuning.LatencyTarget > 0 && latency > t.tuning.LatencyTarget {
+		t.set(key, clampInt(int(float64(size)*factor), min, max))
+		return
+	}
+	if t.successes == nil {
+		t.successes = map[string]int{}
+	}
+	t.successes[key]++
+	if t.successes[key] >= successes {
+		t.set(key, clampInt(size+step, min, max))
+	}
+}
+
+// set the size for key, restarting the count of successes. must be called with mu held
+func (t *chunkTuner) set(key string, size int) {
+	if t.sizes == nil {
+		t.sizes = map[string]int{}
+	}
+	t.sizes[key] = size
+	delete(t.successes, key)
+}
+
+// learned sizes, for Stats
+func (t *chunkTuner) snapshot() map[string]int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	sizes := make(map[string]int, len(t.sizes))
+	for key, size := range t.sizes {
+		sizes[key] = size
+	}
+	return sizes
+}
+
+func clampInt(v, min, max int) int {
+	if v < min {
+		return min
+	}
+	if v > max {
+		return max
+	}
+	return v
+}
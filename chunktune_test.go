@@ -0,0 +1,129 @@
+package engagespot
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// fake API rejecting chunks over limit recipients with 413, and taking perRecipient to answer
+type limitServer struct {
+	limit        int
+	perRecipient time.Duration
+	clock        *fakeClock
+	attempts     []int
+	delivered    []string
+}
+
+func (s *limitServer) RoundTrip(req *http.Request) (*http.Response, error) {
+	var body struct {
+		Recipients []string `json:"recipients"`
+	}
+	json.NewDecoder(req.Body).Decode(&body)
+	s.attempts = append(s.attempts, len(body.Recipients))
+	if s.clock != nil {
+		s.clock.Advance(time.Duration(len(body.Recipients)) * s.perRecipient)
+	}
+	if s.limit > 0 && len(body.Recipients) > s.limit {
+		return respondWith(413, `{}`).RoundTrip(req)
+	}
+	s.delivered = append(s.delivered, body.Recipients...)
+	return respondWith(200, `{}`).RoundTrip(req)
+}
+
+func sendFromSource(c *Client, category string, ids []string) error {
+	n, _ := c.NewNotification("Campaign")
+	if category != "" {
+		n.SetCategory(category)
+	}
+	n.SetRecipientSource(sliceSource(ids, -1, nil))
+	_, err := n.Send()
+	return err
+}
+
+func TestAdaptiveChunkSizeConverges(t *testing.T) {
+	s := &limitServer{limit: 30}
+	c := chunkedClient(100, WithAdaptiveChunkSize(ChunkTuning{Step: 5, Successes: 2}))
+	c.httpClient.Transport = s
+
+	ids := recipientIds(200)
+	assert.NoError(t, sendFromSource(c, "", ids))
+	// halved until under the limit, then grown back by 5 every 2 chunks, shrinking again when
+	// growing over the limit
+	assert.Equal(t, []int{100, 50, 25, 25, 30, 30, 35, 17, 17, 22, 22, 12}, s.attempts)
+	assert.Equal(t, ids, s.delivered)
+	assert.Equal(t, map[string]int{"": 27}, c.Stats().ChunkSizes)
+
+	// the learned size is kept for the next send
+	s.attempts = nil
+	assert.NoError(t, sendFromSource(c, "", recipientIds(60)))
+	// one success at 27 was already counted, so the size grows over the limit again
+	assert.Equal(t, []int{27, 32, 16, 16, 1}, s.attempts)
+}
+
+func TestAdaptiveChunkSizeRecovers(t *testing.T) {
+	s := &limitServer{limit: 10}
+	c := chunkedClient(40, WithAdaptiveChunkSize(ChunkTuning{Step: 10}))
+	c.httpClient.Transport = s
+	assert.NoError(t, sendFromSource(c, "", recipientIds(60)))
+	assert.Equal(t, []int{40, 20, 10, 10, 10, 20, 10, 10, 10}, s.attempts)
+
+	s.limit, s.attempts = 0, nil
+	assert.NoError(t, sendFromSource(c, "", recipientIds(200)))
+	// back to the configured size, which is the default maximum
+	assert.Equal(t, []int{20, 20, 20, 30, 30, 30, 40, 10}, s.attempts)
+	assert.Equal(t, map[string]int{"": 40}, c.Stats().ChunkSizes)
+}
+
+func TestAdaptiveChunkSizeLatency(t *testing.T) {
+	clock := newFakeClock()
+	s := &limitServer{clock: clock, perRecipient: 10 * time.Millisecond}
+	c := chunkedClient(100, WithAdaptiveChunkSize(ChunkTuning{Min: 20, LatencyTarget: 300 * time.Millisecond}))
+	c.httpClient.Transport = s
+	c.now = clock.Now
+
+	assert.NoError(t, sendFromSource(c, "", recipientIds(200)))
+	// slow chunks are still delivered, the next ones are smaller
+	assert.Equal(t, []int{100, 50, 25, 25}, s.attempts)
+	assert.Len(t, s.delivered, 200)
+}
+
+func TestAdaptiveChunkSizePerCategory(t *testing.T) {
+	s := &limitServer{limit: 10}
+	c := chunkedClient(20, WithAdaptiveChunkSize(ChunkTuning{Min: 5, PerCategory: true}))
+	c.httpClient.Transport = s
+
+	assert.NoError(t, sendFromSource(c, "reports", recipientIds(20)))
+	assert.Equal(t, map[string]int{"reports": 10}, c.Stats().ChunkSizes)
+
+	s.limit, s.attempts = 0, nil
+	assert.NoError(t, sendFromSource(c, "alerts", recipientIds(20)))
+	assert.Equal(t, []int{20}, s.attempts)
+}
+
+func TestAdaptiveChunkSizeAtMinimum(t *testing.T) {
+	s := &limitServer{limit: 3}
+	c := chunkedClient(8, WithAdaptiveChunkSize(ChunkTuning{Min: 4}))
+	c.httpClient.Transport = s
+
+	err := sendFromSource(c, "", recipientIds(8))
+	var partial *PartialSendError
+	assert.True(t, errors.As(err, &partial))
+	assert.Equal(t, 0, partial.Submitted)
+	assert.Equal(t, []int{8, 4}, s.attempts)
+}
+
+func TestAdaptiveChunkSizeDisabled(t *testing.T) {
+	s := &limitServer{limit: 30}
+	c := chunkedClient(100)
+	c.httpClient.Transport = s
+
+	err := sendFromSource(c, "", recipientIds(200))
+	assert.EqualError(t, err, "engagespot: send stopped after 0 recipients: engagespot: Send notifications: unexpected status 413: Request Entity Too Large")
+	assert.Equal(t, []int{100}, s.attempts)
+	assert.Nil(t, c.Stats().ChunkSizes)
+}
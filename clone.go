@@ -0,0 +1,115 @@
+package engagespot
+
+import "encoding/json"
+
+// Clone returns a deep copy of n, which can be changed and sent without changing n: recipients,
+// topics, channels, data and email overrides are all copied. the copy has its own send outcome and
+// no idempotency key, so it's never taken for a retry of n. a recipient source is consumed by the
+// send using it, and isn't copied
+func (n *Notification) Clone() *Notification {
+	c := *n
+	c.last = &lastSend{}
+	c.idempotencyKey = ""
+	c.recipientSource = nil
+	c.chunked = false
+
+	if n.Notification != nil {
+		content := *n.Notification
+		content.Data = copyMap(n.Notification.Data)
+		content.Actions = copySlice(n.Notification.Actions)
+		c.Notification = &content
+	}
+	if n.Override != nil {
+		o := *n.Override
+		o.Channels = copySlice(n.Override.Channels)
+		o.SendgridEmail = copyMap(n.Override.SendgridEmail)
+		o.SmtpEmail = copyMap(n.Override.SmtpEmail)
+		if n.Override.rawChannels != nil {
+			o.rawChannels = make(map[string]bool, len(n.Override.rawChannels))
+			for channel := range n.Override.rawChannels {
+				o.rawChannels[channel] = true
+			}
+		}
+		c.Override = &o
+	}
+	if n.SendAt != nil {
+		at := *n.SendAt
+		c.SendAt = &at
+	}
+	if n.ExpiresAt != nil {
+		at := *n.ExpiresAt
+		c.ExpiresAt = &at
+	}
+	c.Recipients = copySlice(n.Recipients)
+	c.Topics = copySlice(n.Topics)
+	c.channelRules = copySlice(n.channelRules)
+	c.sanitization = copySlice(n.sanitization)
+	c.buildErrors = copySlice(n.buildErrors)
+	if n.recipientDetails != nil {
+		c.recipientDetails = make(map[string]Recipient, len(n.recipientDetails))
+		for id, r := range n.recipientDetails {
+			r.Data = copyMap(r.Data)
+			c.recipientDetails[id] = r
+		}
+	}
+	if n.localized != nil {
+		c.localized = make(map[string]LocalizedContent, len(n.localized))
+		for locale, content := range n.localized {
+			c.localized[locale] = content
+		}
+	}
+	return &c
+}
+
+// NewNotificationFromTemplate returns a copy of the notification t, see Clone, sent by c whatever
+// the client t was built with. a template can be built once, even with a client of its own, and
+// customized for every send
+func (c *Client) NewNotificationFromTemplate(t *Notification) *Notification {
+	n := t.Clone()
+	n.Client = c
+	return n
+}
+
+// copy of s, nil if s is. appending to either never changes the other
+func copySlice[T any](s []T) []T {
+	if s == nil {
+		return nil
+	}
+	return append(make([]T, 0, len(s)), s...)
+}
+
+func copyMap(m map[string]interface{}) map[string]interface{} {
+	if m == nil {
+		return nil
+	}
+	c := make(map[string]interface{}, len(m))
+	for k, v := range m {
+		c[k] = copyValue(v)
+	}
+	return c
+}
+
+// deep copy of the values found in data and overrides: nested maps and slices, and raw json
+func copyValue(v interface{}) interface{} {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		return copyMap(v)
+	case []interface{}:
+		c := make([]interface{}, len(v))
+		for i, child := range v {
+			c[i] = copyValue(child)
+		}
+		return c
+	case []map[string]interface{}:
+		c := make([]map[string]interface{}, len(v))
+		for i, child := range v {
+			c[i] = copyMap(child)
+		}
+		return c
+	case []string:
+		return copySlice(v)
+	case json.RawMessage:
+		return copySlice(v)
+	}
+	return v
+}
@@ -0,0 +1,109 @@
+package engagespot
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func digestTemplate(t *testing.T, c *Client) *Notification {
+	n, _ := c.NewNotification("Weekly digest")
+	n.Message("your week").InCategory("digest")
+	// spare capacity, appending to a shared slice would write to the same array
+	n.Recipients = make([]string, 0, 10)
+	n.AddRecipient("a")
+	n.SetChannels(ChannelInApp, ChannelEmail)
+	n.Override.Channels = append(make([]string, 0, 10), n.Override.Channels...)
+	n.AddData("stats", map[string]interface{}{"reads": 3, "tags": []interface{}{"go"}})
+	_, err := n.AddEmailAttachment(EmailAttachment{Filename: "digest.pdf", Content: []byte("pdf")})
+	assert.NoError(t, err)
+	n.SetSendgridField("_config", map[string]interface{}{"templateId": "t-1"})
+	n.AddRecipientWithData("personal", map[string]interface{}{"firstName": "Ada"})
+	return n
+}
+
+func TestCloneIsIndependent(t *testing.T) {
+	c := NewEngagespotClient("A", "B")
+	original := digestTemplate(t, c)
+	before := encodedPayload(t, original)
+
+	clone := original.Clone()
+	assert.Equal(t, before, encodedPayload(t, clone))
+
+	clone.SetTitle("Monthly digest")
+	clone.Url("https://example.com/b")
+	clone.AddRecipient("b")
+	clone.Override.AddChannel(ChannelSMS)
+	clone.AddData("extra", true)
+	clone.Notification.Data["stats"].(map[string]interface{})["reads"] = 10
+	clone.Notification.Data["stats"].(map[string]interface{})["tags"].([]interface{})[0] = "rust"
+	clone.SetSendgridField("_config", nil)
+	clone.AddEmailAttachment(EmailAttachment{Filename: "other.pdf", Content: []byte("pdf")})
+	clone.AddTopic("weekly")
+	clone.recipientDetails["personal"].Data["firstName"] = "Grace"
+
+	assert.Equal(t, before, encodedPayload(t, original))
+	assert.Equal(t, []string{"a", "personal"}, original.Recipients)
+	assert.Equal(t, []string{ChannelInApp, ChannelEmail}, original.Override.Channels)
+	assert.Empty(t, original.Topics)
+}
+
+func TestCloneAppendsDontAlias(t *testing.T) {
+	c := NewEngagespotClient("A", "B")
+	original := digestTemplate(t, c)
+
+	first, second := original.Clone(), original.Clone()
+	first.AddRecipient("b")
+	second.AddRecipient("c")
+	first.Override.AddChannel(ChannelSMS)
+	second.Override.AddChannel(ChannelWhatsApp)
+
+	assert.Equal(t, []string{"a", "personal", "b"}, first.Recipients)
+	assert.Equal(t, []string{"a", "personal", "c"}, second.Recipients)
+	assert.Equal(t, []string{ChannelInApp, ChannelEmail, ChannelSMS}, first.Override.Channels)
+	assert.Equal(t, []string{ChannelInApp, ChannelEmail, ChannelWhatsApp}, second.Override.Channels)
+	assert.Equal(t, []string{"a", "personal"}, original.Recipients)
+}
+
+func TestCloneSendState(t *testing.T) {
+	c := NewEngagespotClient("A", "B")
+	c.httpClient.Transport = respondWith(200, `{}`)
+	original := newMessage(c)
+	original.SetIdempotencyKey("digest-1")
+	at := time.Now().Add(time.Hour)
+	original.SetSendAt(at)
+
+	clone := original.Clone()
+	assert.Empty(t, clone.idempotencyKey)
+	*clone.SendAt = at.Add(time.Hour)
+	assert.Equal(t, at, *original.SendAt)
+
+	_, err := original.SendCtx(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, StateDelivered, original.State())
+	assert.Equal(t, StateUnknown, clone.State())
+}
+
+func TestNewNotificationFromTemplate(t *testing.T) {
+	template := digestTemplate(t, NewEngagespotClient("template", "B"))
+
+	var keys []string
+	c := NewEngagespotClient("A", "B")
+	c.httpClient.Transport = roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		keys = append(keys, req.Header.Get("X-ENGAGESPOT-API-KEY"))
+		return respondWith(200, `{}`).RoundTrip(req)
+	})
+
+	for _, url := range []string{"https://example.com/a", "https://example.com/b"} {
+		n := c.NewNotificationFromTemplate(template)
+		n.Url(url)
+		_, err := n.SendCtx(context.Background())
+		assert.NoError(t, err)
+	}
+	assert.Equal(t, []string{"A", "A"}, keys)
+	assert.Empty(t, template.Notification.Url)
+	assert.NotSame(t, c, template.Client)
+}
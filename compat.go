@@ -0,0 +1,73 @@
+package engagespot
+
+import (
+	"fmt"
+	"time"
+)
+
+// scenarios of CompatibilityRecords, by name. each builds the request of a common call pattern
+// with fixed values, so its record only changes when the bytes sent for it do
+var compatibilityScenarios = map[string]func(c *Client) (ContractRecord, error){
+	"minimal-send": func(c *Client) (ContractRecord, error) {
+		n, err := c.NewNotification("Welcome")
+		if err != nil {
+			return ContractRecord{}, err
+		}
+		if _, err := n.AddRecipient("user@example.com"); err != nil {
+			return ContractRecord{}, err
+		}
+		return n.ContractRecord()
+	},
+	"send-with-overrides": func(c *Client) (ContractRecord, error) {
+		n, err := c.NewNotification("Order shipped")
+		if err != nil {
+			return ContractRecord{}, err
+		}
+		n.SetMessage("Your order is on its way")
+		n.SetUrl("https://example.com/orders/42")
+		n.SetIcon("https://example.com/icon.png")
+		n.SetCategory("orders")
+		n.Override.AddChannel(ChannelEmail)
+		n.Override.AddChannel(ChannelInApp)
+		n.Override.SendgridEmail = map[string]interface{}{"_config": map[string]interface{}{"templateId": "t-1"}}
+		if _, err := n.AddRecipient("user@example.com"); err != nil {
+			return ContractRecord{}, err
+		}
+		return n.ContractRecord()
+	},
+	"connect": func(c *Client) (ContractRecord, error) {
+		return c.ConnectContractRecord("user@example.com")
+	},
+	"personalized-send": func(c *Client) (ContractRecord, error) {
+		n, err := c.NewNotification("Hi Ada")
+		if err != nil {
+			return ContractRecord{}, err
+		}
+		n.SetMessage("Your report is ready")
+		n.SetAvatar("https://example.com/ada.png")
+		n.setDataKey("firstName", "Ada")
+		n.setDataKey("reportId", 42)
+		n.SetSendAt(time.Date(2030, 1, 1, 9, 0, 0, 0, time.UTC))
+		for _, recipient := range []string{"ada@example.com", "grace@example.com"} {
+			if _, err := n.AddRecipient(recipient); err != nil {
+				return ContractRecord{}, err
+			}
+		}
+		return n.ContractRecord()
+	},
+}
+
+// CompatibilityRecords returns the contract records of a fixed set of common call patterns, as
+// built by c with its options, by scenario name. see the engagespottest package to compare them
+// with the records of previous versions of the SDK
+func (c *Client) CompatibilityRecords() (map[string]ContractRecord, error) {
+	records := make(map[string]ContractRecord, len(compatibilityScenarios))
+	for name, scenario := range compatibilityScenarios {
+		record, err := scenario(c)
+		if err != nil {
+			return nil, fmt.Errorf("scenario %s: %w", name, err)
+		}
+		records[name] = record
+	}
+	return records, nil
+}
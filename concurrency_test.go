@@ -0,0 +1,169 @@
+package engagespot
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// these tests are meant to be run with -race, they share one client between many goroutines
+
+// server answering every send with the first recipient as its id, failing one attempt in five so
+// that retries happen too
+func hammeredServer(t *testing.T) *httptest.Server {
+	var requests int64
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt64(&requests, 1)
+		if r.URL.Path == "/v3/sdk/connect" {
+			w.Write([]byte(`{"identifier":"` + r.Header.Get("X-ENGAGESPOT-USER-ID") + `"}`))
+			return
+		}
+		var body struct {
+			Recipients []string `json:"recipients"`
+		}
+		json.NewDecoder(r.Body).Decode(&body)
+		if n%5 == 0 {
+			w.WriteHeader(503)
+			return
+		}
+		w.Write([]byte(`{"id":"` + body.Recipients[0] + `"}`))
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func hammeredClient(url string, metrics Metrics, counter *RequestCounter) *Client {
+	return clientFor(url+"/v3/", 5*time.Second,
+		WithHmac(),
+		WithAutoIdempotency(),
+		WithRetry(5, time.Millisecond),
+		WithRateLimit(10000, 50),
+		WithMetrics(metrics),
+		WithWorkers(8),
+		WithCategoryStats("hammer"),
+		WithRequestHook(func(*http.Request) {}),
+		WithResponseHook(func(*http.Response, time.Duration) {}),
+		WithSendObserver(func(NotificationSnapshot) {}),
+	).Use(counter.Wrap)
+}
+
+func TestConcurrentSends(t *testing.T) {
+	server := hammeredServer(t)
+	metrics := &MemoryMetrics{}
+	counter := &RequestCounter{}
+	c := hammeredClient(server.URL, metrics, counter)
+	ctx := context.Background()
+
+	var wg sync.WaitGroup
+	ids := recipientIds(200)
+	for _, id := range ids {
+		wg.Add(1)
+		go func(id string) {
+			defer wg.Done()
+			n := newMessage(c, id)
+			n.SetCategory("hammer")
+			n.AddData("id", id)
+			result, err := n.SendCtx(ctx)
+			if assert.NoError(t, err) {
+				assert.Equal(t, id, result.Id)
+			}
+		}(id)
+	}
+	wg.Wait()
+
+	assert.Len(t, metrics.Sends(), int(counter.Requests()))
+	assert.Equal(t, uint64(200), c.Stats().Categories["hammer"].Sends)
+}
+
+func TestConcurrentMixedCalls(t *testing.T) {
+	server := hammeredServer(t)
+	c := hammeredClient(server.URL, &MemoryMetrics{}, &RequestCounter{})
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var wg sync.WaitGroup
+	run := func(f func(i int)) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 20; i++ {
+				f(i)
+			}
+		}()
+	}
+
+	run(func(i int) {
+		_, err := newMessage(c, fmt.Sprint("send-", i)).SendCtx(ctx)
+		assert.NoError(t, err)
+	})
+	run(func(i int) {
+		r := <-newMessage(c, fmt.Sprint("async-", i)).SendAsync()
+		assert.NoError(t, r.Err)
+	})
+	run(func(i int) {
+		_, err := c.SendAll(ctx, []*Notification{newMessage(c, fmt.Sprint("all-", i)), newMessage(c, fmt.Sprint("all-b-", i))})
+		assert.NoError(t, err)
+	})
+	run(func(i int) {
+		result, err := c.ConnectUser(ctx, fmt.Sprint("user-", i), ConnectOptions{DeviceType: DeviceWeb})
+		if assert.NoError(t, err) {
+			assert.Equal(t, fmt.Sprint("user-", i), result.UserId)
+		}
+	})
+	run(func(i int) {
+		// cloned from a template shared by the goroutine only
+		template := newMessage(c, "template")
+		_, err := c.NewNotificationFromTemplate(template).SendCtx(ctx)
+		assert.NoError(t, err)
+	})
+	run(func(i int) {
+		assert.NoError(t, c.ApplyDynamicConfig(DynamicConfig{
+			RateLimit: &RateLimit{RPS: float64(5000 + i*100), Burst: 50},
+			Retry:     &RetryPolicy{MaxAttempts: 5, BaseDelay: time.Millisecond},
+		}))
+		c.Stats()
+		c.RecentErrors()
+		c.ClockSkew()
+	})
+	wg.Wait()
+	assert.NoError(t, c.Close())
+}
+
+func TestConcurrentRuntimeSetters(t *testing.T) {
+	server := hammeredServer(t)
+	c := clientFor(server.URL+"/v3/", 5*time.Second, WithRetry(5, time.Millisecond))
+	ctx := context.Background()
+
+	var wg sync.WaitGroup
+	var seen int64
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			_, err := newMessage(c, fmt.Sprint("user-", i)).SendCtx(ctx)
+			assert.NoError(t, err)
+		}(i)
+	}
+	// the deprecated setters may be called while sends are in flight, each send sees either the
+	// old or the new setting
+	c.EnableHmac()
+	c.Use(func(next Doer) Doer {
+		return DoerFunc(func(req *http.Request) (*http.Response, error) {
+			atomic.AddInt64(&seen, 1)
+			return next.Do(req)
+		})
+	})
+	wg.Wait()
+
+	_, err := newMessage(c).SendCtx(ctx)
+	assert.NoError(t, err)
+	assert.NotZero(t, atomic.LoadInt64(&seen))
+}
@@ -0,0 +1,212 @@
+package engagespot
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// ContractRecord is a language agnostic description of the request sent for a notification, for
+// contract tests checking that SDKs in other languages send the same request. records are
+// normalized so that equivalent requests give equal records:
+//
+//   - Path is relative to the base url of the API, without leading slash, like "notifications"
+//   - header names are lowercased, credential headers (api key and secret, user id and
+//     signature) are left out, as are headers set by the transport like content-length
+//   - the body is canonical JSON: object keys sorted, no insignificant whitespace, numbers as
+//     written by the SDK
+//   - null values, empty strings, empty arrays and empty objects are omitted from the body,
+//     recursively, so an SDK sending `"override": {}` matches one leaving it out
+//
+// these rules are part of the contract and must not change
+type ContractRecord struct {
+	Method  string            `json:"method"`
+	Path    string            `json:"path"`
+	Headers map[string]string `json:"headers"`
+	Body    json.RawMessage   `json:"body"`
+}
+
+// Difference is a field which differs between two contract records. body fields are prefixed
+// with "body." and nested with dots, headers are prefixed with "headers."
+type Difference struct {
+	Field string
+	A     interface{}
+	B     interface{}
+}
+
+func (d Difference) String() string {
+	a, _ := json.Marshal(d.A)
+	b, _ := json.Marshal(d.B)
+	return fmt.Sprintf("%s: %s != %s", d.Field, a, b)
+}
+
+// ContractRecord returns the contract record of the request Send would make for n. nothing is
+// sent, and the record doesn't depend on the credentials or base url of the client
+func (n *Notification) ContractRecord() (ContractRecord, error) {
+	payload, e, err := n.Client.sendPayload(n)
+	if err != nil {
+		return ContractRecord{}, err
+	}
+	path, err := n.Client.apiVersion.endpoint(e).resolve()
+	if err != nil {
+		return ContractRecord{}, err
+	}
+	body, err := canonicalBody(payload)
+	if err != nil {
+		return ContractRecord{}, err
+	}
+	return ContractRecord{
+		Method:  e.method,
+		Path:    path,
+		Headers: map[string]string{"content-type": "application/json"},
+		Body:    body,
+	}, nil
+}
+
+// device id of the record of Connect
+const contractDeviceId = "<device-id>"
+
+// ConnectContractRecord returns the contract record of the request Connect would make for userId,
+// see ContractRecord. nothing is sent
+func (c *Client) ConnectContractRecord(userId string) (ContractRecord, error) {
+	path, err := c.apiVersion.endpoint(endpointConnect).resolve()
+	if err != nil {
+		return ContractRecord{}, err
+	}
+	// a fixed device id, the generated one would change the record on every run
+	opts, err := c.connectOptions(ConnectOptions{DeviceId: contractDeviceId})
+	if err != nil {
+		return ContractRecord{}, err
+	}
+	headers := map[string]string{"content-type": "application/json"}
+	for name, values := range c.connectHeaders(c.NormalizeUserID(userId), opts) {
+		if !isAuthHeader(name) {
+			headers[strings.ToLower(name)] = values[0]
+		}
+	}
+	return ContractRecord{
+		Method:  endpointConnect.method,
+		Path:    path,
+		Headers: headers,
+		Body:    json.RawMessage("{}"),
+	}, nil
+}
+
+// body in canonical form, see ContractRecord
+func canonicalBody(payload []byte) (json.RawMessage, error) {
+	d := json.NewDecoder(bytes.NewReader(payload))
+	d.UseNumber()
+	var v interface{}
+	if err := d.Decode(&v); err != nil {
+		return nil, err
+	}
+	v, _ = omitEmpty(v)
+	if v == nil {
+		v = map[string]interface{}{}
+	}
+	return json.Marshal(v)
+}
+
+// drop empty values from v recursively. false if v itself is empty
+func omitEmpty(v interface{}) (interface{}, bool) {
+	switch v := v.(type) {
+	case nil:
+		return nil, false
+	case string:
+		return v, v != ""
+	case map[string]interface{}:
+		for key, child := range v {
+			if kept, ok := omitEmpty(child); ok {
+				v[key] = kept
+			} else {
+				delete(v, key)
+			}
+		}
+		return v, len(v) > 0
+	case []interface{}:
+		kept := v[:0]
+		for _, child := range v {
+			if c, ok := omitEmpty(child); ok {
+				kept = append(kept, c)
+			}
+		}
+		return kept, len(kept) > 0
+	}
+	return v, true
+}
+
+// CompareContractRecords lists the differences between a and b, sorted by field. records are
+// compared after normalization, so records built by hand or by other SDKs can be passed as is
+func CompareContractRecords(a, b ContractRecord) []Difference {
+	fa, errA := a.fields()
+	fb, errB := b.fields()
+	if errA != nil || errB != nil {
+		return []Difference{{Field: "body", A: errString(errA), B: errString(errB)}}
+	}
+
+	keys := map[string]struct{}{}
+	for k := range fa {
+		keys[k] = struct{}{}
+	}
+	for k := range fb {
+		keys[k] = struct{}{}
+	}
+
+	var diffs []Difference
+	for k := range keys {
+		if !reflect.DeepEqual(fa[k], fb[k]) {
+			diffs = append(diffs, Difference{Field: k, A: fa[k], B: fb[k]})
+		}
+	}
+	sort.Slice(diffs, func(i, j int) bool { return diffs[i].Field < diffs[j].Field })
+	return diffs
+}
+
+func errString(err error) interface{} {
+	if err == nil {
+		return nil
+	}
+	return err.Error()
+}
+
+// normalized fields of the record, flattened to dotted paths
+func (r ContractRecord) fields() (map[string]interface{}, error) {
+	f := map[string]interface{}{
+		"method": strings.ToUpper(r.Method),
+		"path":   strings.TrimPrefix(r.Path, "/"),
+	}
+	for name, value := range r.Headers {
+		name = strings.ToLower(name)
+		if isAuthHeader(name) {
+			continue
+		}
+		f["headers."+name] = value
+	}
+
+	if len(r.Body) > 0 {
+		body, err := canonicalBody(r.Body)
+		if err != nil {
+			return nil, err
+		}
+		var m map[string]interface{}
+		d := json.NewDecoder(bytes.NewReader(body))
+		d.UseNumber()
+		if err := d.Decode(&m); err != nil {
+			return nil, err
+		}
+		flatten("body", m, f)
+	}
+	return f, nil
+}
+
+func isAuthHeader(name string) bool {
+	for _, h := range authHeaders {
+		if strings.EqualFold(h, name) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,80 @@
+package engagespot
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// record of digest(c, "3 new posts", "a") as produced by every SDK
+const goldenDigest = `{
+	"method": "POST",
+	"path": "notifications",
+	"headers": {"content-type": "application/json"},
+	"body": {"notification": {"message": "3 new posts", "title": "Daily digest"}, "override": {"channels": ["email"]}, "recipients": ["a"]}
+}`
+
+func goldenRecord(t *testing.T, s string) ContractRecord {
+	var r ContractRecord
+	assert.NoError(t, json.Unmarshal([]byte(s), &r))
+	return r
+}
+
+func TestContractRecord(t *testing.T) {
+	c := NewEngagespotClient("A", "B")
+
+	r, err := digest(c, "3 new posts", "a").ContractRecord()
+	assert.NoError(t, err)
+	assert.Equal(t, "POST", r.Method)
+	assert.Equal(t, "notifications", r.Path)
+	assert.Equal(t, map[string]string{"content-type": "application/json"}, r.Headers)
+	assert.JSONEq(t, `{"notification": {"message": "3 new posts", "title": "Daily digest"}, "override": {"channels": ["email"]}, "recipients": ["a"]}`, string(r.Body))
+	assert.Empty(t, CompareContractRecords(goldenRecord(t, goldenDigest), r))
+}
+
+func TestContractRecordWorkflow(t *testing.T) {
+	c := NewEngagespotClient("A", "B", WithWorkflowMapping(map[string]string{"orders": "order-shipped"}))
+	n := digest(c, "shipped", "a")
+	n.SetCategory("orders")
+
+	r, err := n.ContractRecord()
+	assert.NoError(t, err)
+	assert.Empty(t, CompareContractRecords(goldenRecord(t, `{
+		"method": "post",
+		"path": "/workflows/trigger",
+		"headers": {"Content-Type": "application/json", "X-ENGAGESPOT-API-KEY": "other"},
+		"body": {
+			"workflow": {"identifier": "order-shipped"},
+			"sendTo": {"recipients": [{"identifier": "a"}]},
+			"data": {"title": "Daily digest", "message": "shipped", "url": ""}
+		}
+	}`), r))
+}
+
+func TestCompareContractRecordsMismatch(t *testing.T) {
+	c := NewEngagespotClient("A", "B")
+	n := digest(c, "4 new posts", "a", "b")
+	n.SetUrl("https://example.com/digest")
+
+	r, err := n.ContractRecord()
+	assert.NoError(t, err)
+	diffs := CompareContractRecords(goldenRecord(t, goldenDigest), r)
+	assert.Equal(t, []Difference{
+		{Field: "body.notification.message", A: "3 new posts", B: "4 new posts"},
+		{Field: "body.notification.url", A: nil, B: "https://example.com/digest"},
+		{Field: "body.recipients", A: []interface{}{"a"}, B: []interface{}{"a", "b"}},
+	}, diffs)
+	assert.Equal(t, `body.notification.message: "3 new posts" != "4 new posts"`, diffs[0].String())
+}
+
+func TestConnectContractRecord(t *testing.T) {
+	r, err := NewEngagespotClient("A", "B", WithHmac()).ConnectContractRecord("user@example.com")
+	assert.NoError(t, err)
+	assert.Equal(t, ContractRecord{
+		Method:  "POST",
+		Path:    "sdk/connect",
+		Headers: map[string]string{"content-type": "application/json", "x-engagespot-device-id": "<device-id>", "x-engagespot-device-type": "ios"},
+		Body:    json.RawMessage("{}"),
+	}, r)
+}
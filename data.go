@@ -0,0 +1,153 @@
+package engagespot
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+)
+
+// keys of the data payload interpreted by Engagespot templates and the web SDK. they are set by
+// the helpers below, setting them by hand is flagged by a warning if the value has the wrong type
+const (
+	// url of the image shown next to the notification
+	DataKeyAvatar = "avatar"
+	// action routed by the web SDK when the notification is clicked
+	DataKeyClickAction = "clickAction"
+)
+
+// reserved data keys and a check of their value, used to warn about values of the wrong type
+var reservedDataKeys = []struct {
+	key   string
+	valid func(interface{}) bool
+}{
+	{DataKeyAvatar, isString},
+	{DataKeyClickAction, isString},
+}
+
+func isString(v interface{}) bool {
+	_, ok := dataValue(v).(string)
+	return ok
+}
+
+// value of a data key, with raw JSON set by SetDataRaw or AddDataRaw decoded
+func dataValue(v interface{}) interface{} {
+	raw, ok := v.(json.RawMessage)
+	if !ok {
+		return v
+	}
+	var decoded interface{}
+	d := json.NewDecoder(bytes.NewReader(raw))
+	d.UseNumber()
+	if err := d.Decode(&decoded); err != nil {
+		return v
+	}
+	return decoded
+}
+
+// set a single key of the data payload, keeping every other key
+func (n *Notification) setDataKey(key string, value interface{}) {
+	if n.Notification.Data == nil {
+		n.Notification.Data = map[string]interface{}{}
+	}
+	n.Notification.Data[key] = value
+}
+
+// SetData can be used to set custom key/value data passed to in-app and push channels. like
+// SetRecipients, it replaces the data already set, keys set by helpers like SetAvatar included.
+// values are sent as given, add keys one by one with AddData to keep the existing ones
+func (n *Notification) SetData(data map[string]interface{}) (*Notification, error) {
+	if data == nil {
+		return nil, invalidField("data", data, errors.New("nil data map"))
+	}
+	replaced := make(map[string]interface{}, len(data))
+	for key, value := range data {
+		if key == "" {
+			return nil, invalidField("data", key, errors.New("empty data key string"))
+		}
+		replaced[key] = value
+	}
+	n.Notification.Data = replaced
+	return n, nil
+}
+
+// AddData can be used to set a single key of the data payload, see SetData
+func (n *Notification) AddData(key string, value interface{}) (*Notification, error) {
+	if key == "" {
+		return nil, invalidField("data", key, errors.New("empty data key string"))
+	}
+	n.setDataKey(key, value)
+	return n, nil
+}
+
+// SetDataRaw can be used to set the data payload from a JSON object which is already marshaled,
+// like a large report. only the top level keys are split out of raw, their values are sent as is,
+// without being decoded, so they don't cost any allocation beyond a copy of the bytes. the keys are
+// merged into the data already set, like SetDataTyped. the payload is compacted when sent, and
+// canonicalized by ContentHash and ContractRecord like any other payload
+func (n *Notification) SetDataRaw(raw json.RawMessage) error {
+	var data map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &data); err != nil {
+		return invalidField("data", raw, fmt.Errorf("invalid raw data: %w", err))
+	}
+	if data == nil {
+		return invalidField("data", raw, errors.New("invalid raw data: must be a JSON object, got null"))
+	}
+	for key, value := range data {
+		n.setDataKey(key, value)
+	}
+	return nil
+}
+
+// AddDataRaw can be used to set a single key of the data payload to JSON which is already
+// marshaled, see SetDataRaw
+func (n *Notification) AddDataRaw(key string, raw json.RawMessage) error {
+	if key == "" {
+		return invalidField("data", key, errors.New("empty data key string"))
+	}
+	if !json.Valid(raw) {
+		return invalidField("data", raw, fmt.Errorf("invalid raw data for key %q", key))
+	}
+	n.setDataKey(key, append(json.RawMessage(nil), raw...))
+	return nil
+}
+
+// SetAvatar can be used to set the image shown next to the notification. the url must be absolute
+func (n *Notification) SetAvatar(avatarUrl string) (*Notification, error) {
+	if avatarUrl == "" {
+		return nil, invalidField("avatar", avatarUrl, errors.New("empty avatar url string"))
+	}
+	u, err := url.Parse(avatarUrl)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, invalidField("avatar", avatarUrl, fmt.Errorf("invalid avatar url %q", avatarUrl))
+	}
+	n.setDataKey(DataKeyAvatar, avatarUrl)
+	return n, nil
+}
+
+// SetClickAction can be used to set the action the web SDK routes to when the notification is
+// clicked
+func (n *Notification) SetClickAction(action string) (*Notification, error) {
+	if action == "" {
+		return nil, invalidField("clickAction", action, errors.New("empty click action string"))
+	}
+	n.setDataKey(DataKeyClickAction, action)
+	return n, nil
+}
+
+// warnings for reserved data keys set by hand to a value of the wrong type
+func (n *Notification) reservedDataWarnings() []Warning {
+	var warnings []Warning
+	for _, reserved := range reservedDataKeys {
+		key := reserved.key
+		value, ok := n.Notification.Data[key]
+		if ok && !reserved.valid(value) {
+			warnings = append(warnings, Warning{
+				Field:   "data." + key,
+				Message: fmt.Sprintf("reserved key %q is set to a %T, Engagespot expects a string", key, dataValue(value)),
+			})
+		}
+	}
+	return warnings
+}
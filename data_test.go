@@ -0,0 +1,226 @@
+package engagespot
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func encodedData(t *testing.T, n *Notification) map[string]interface{} {
+	b, err := json.Marshal(n)
+	assert.NoError(t, err)
+
+	var payload struct {
+		Notification struct {
+			Data map[string]interface{} `json:"data"`
+		} `json:"notification"`
+	}
+	assert.NoError(t, json.Unmarshal(b, &payload))
+	return payload.Notification.Data
+}
+
+// data object of the body sent for n, with numbers kept as sent
+func sentData(t *testing.T, n *Notification) map[string]interface{} {
+	var requests []capturedRequest
+	n.Client.httpClient.Transport = captureRequests(&requests)
+	_, err := n.Send()
+	assert.NoError(t, err)
+	assert.Len(t, requests, 1)
+	return decodeNumbers(t, requests[0].body)["notification"].(map[string]interface{})["data"].(map[string]interface{})
+}
+
+func decodeNumbers(t *testing.T, b []byte) map[string]interface{} {
+	var v map[string]interface{}
+	d := json.NewDecoder(bytes.NewReader(b))
+	d.UseNumber()
+	assert.NoError(t, d.Decode(&v))
+	return v
+}
+
+func TestSetData(t *testing.T) {
+	n := newTestNotification(t)
+	n.AddRecipient("a")
+	_, err := n.SetData(map[string]interface{}{
+		"orderId": int64(9007199254740993),
+		"total":   12.5,
+		"paid":    true,
+		"note":    nil,
+		"items":   []interface{}{"book", 2},
+		"shipping": map[string]interface{}{
+			"address": map[string]interface{}{"city": "Kochi", "zip": 682001},
+		},
+	})
+	assert.NoError(t, err)
+	_, err = n.AddData("coupon", "SAVE10")
+	assert.NoError(t, err)
+
+	expected := decodeNumbers(t, []byte(`{
+		"orderId": 9007199254740993,
+		"total": 12.5,
+		"paid": true,
+		"note": null,
+		"items": ["book", 2],
+		"shipping": {"address": {"city": "Kochi", "zip": 682001}},
+		"coupon": "SAVE10"
+	}`))
+	assert.Equal(t, expected, sentData(t, n))
+}
+
+func TestSetDataReplaces(t *testing.T) {
+	n := newTestNotification(t)
+	n.SetAvatar("https://example.com/a.png")
+	n.SetData(map[string]interface{}{"a": 1})
+	n.SetData(map[string]interface{}{"b": 2})
+	n.AddData("a", 3)
+	assert.Equal(t, map[string]interface{}{"a": 3.0, "b": 2.0}, encodedData(t, n))
+
+	// the map given is copied
+	data := map[string]interface{}{"c": 1}
+	n.SetData(data)
+	data["d"] = 2
+	assert.Equal(t, map[string]interface{}{"c": 1.0}, encodedData(t, n))
+}
+
+func TestSetDataInvalid(t *testing.T) {
+	n := newTestNotification(t)
+	_, err := n.SetData(nil)
+	assert.EqualError(t, err, "nil data map")
+	_, err = n.SetData(map[string]interface{}{"a": 1, "": 2})
+	assert.EqualError(t, err, "empty data key string")
+	_, err = n.AddData("", 1)
+	assert.EqualError(t, err, "empty data key string")
+	assert.Nil(t, n.Notification.Data)
+}
+
+func TestReservedDataKeys(t *testing.T) {
+	n := newTestNotification(t)
+	_, err := n.SetAvatar("https://example.com/a.png")
+	assert.NoError(t, err)
+	_, err = n.SetClickAction("open_order")
+	assert.NoError(t, err)
+
+	assert.Equal(t, map[string]interface{}{
+		"avatar":      "https://example.com/a.png",
+		"clickAction": "open_order",
+	}, encodedData(t, n))
+}
+
+func TestReservedDataKeysKeepOtherKeys(t *testing.T) {
+	n := newTestNotification(t)
+	n.Notification.Data = map[string]interface{}{"orderId": 42.0, "avatar": "https://example.com/old.png"}
+	n.SetAvatar("https://example.com/new.png")
+
+	assert.Equal(t, map[string]interface{}{
+		"orderId": 42.0,
+		"avatar":  "https://example.com/new.png",
+	}, encodedData(t, n))
+}
+
+func TestReservedDataKeysValidation(t *testing.T) {
+	n := newTestNotification(t)
+	for _, u := range []string{"", "/a.png", "ftp://example.com/a.png", "https://"} {
+		_, err := n.SetAvatar(u)
+		assert.Error(t, err, u)
+	}
+	_, err := n.SetClickAction("")
+	assert.Error(t, err)
+	assert.Nil(t, n.Notification.Data)
+}
+
+func TestReservedDataKeysLint(t *testing.T) {
+	n := newTestNotification(t)
+	n.AddRecipient("a")
+	n.SetIcon("https://example.com/icon.png")
+	n.Notification.Data = map[string]interface{}{"avatar": 1, "clickAction": "open", "other": 2}
+
+	warnings, err := n.Client.validate(n)
+	assert.NoError(t, err)
+	assert.Equal(t, []Warning{{Field: "data.avatar", Message: `reserved key "avatar" is set to a int, Engagespot expects a string`}}, warnings)
+}
+
+func TestSetDataRaw(t *testing.T) {
+	n := newTestNotification(t)
+	n.SetAvatar("https://example.com/a.png")
+	assert.NoError(t, SetDataTyped(n, struct {
+		OrderId int `json:"orderId"`
+	}{42}))
+	assert.NoError(t, n.SetDataRaw(json.RawMessage(`{"report": {"rows": [1, 2, 3]}, "orderId": 43}`)))
+	assert.NoError(t, n.AddDataRaw("summary", json.RawMessage(` { "total" : 6 } `)))
+
+	assert.Equal(t, map[string]interface{}{
+		"avatar":  "https://example.com/a.png",
+		"orderId": 43.0,
+		"report":  map[string]interface{}{"rows": []interface{}{1.0, 2.0, 3.0}},
+		"summary": map[string]interface{}{"total": 6.0},
+	}, encodedData(t, n))
+
+	b, err := json.Marshal(n.Notification)
+	assert.NoError(t, err)
+	assert.Contains(t, string(b), `"summary":{"total":6}`)
+}
+
+func TestSetDataRawInvalid(t *testing.T) {
+	n := newTestNotification(t)
+	assert.Contains(t, n.SetDataRaw(json.RawMessage(`{"report": `)).Error(), "invalid raw data")
+	assert.Contains(t, n.SetDataRaw(json.RawMessage(`[1, 2]`)).Error(), "invalid raw data")
+	assert.EqualError(t, n.SetDataRaw(json.RawMessage(`null`)), "invalid raw data: must be a JSON object, got null")
+	assert.EqualError(t, n.AddDataRaw("report", json.RawMessage(`{"rows": [1, 2`)), `invalid raw data for key "report"`)
+	assert.EqualError(t, n.AddDataRaw("", json.RawMessage(`1`)), "empty data key string")
+	assert.Nil(t, n.Notification.Data)
+}
+
+func TestRawDataReservedKeyWarning(t *testing.T) {
+	n := newTestNotification(t)
+	n.SetDataRaw(json.RawMessage(`{"avatar": "https://example.com/a.png", "clickAction": 1}`))
+
+	assert.Equal(t, []Warning{{
+		Field:   "data.clickAction",
+		Message: `reserved key "clickAction" is set to a json.Number, Engagespot expects a string`,
+	}}, n.reservedDataWarnings())
+}
+
+func TestRawDataFingerprint(t *testing.T) {
+	n := newTestNotification(t)
+	n.AddDataRaw("report", json.RawMessage(`{"rows": [{"id": 1}]}`))
+
+	assert.Equal(t, Fingerprint{
+		"report":           "object",
+		"report.rows":      "array",
+		"report.rows[]":    "object",
+		"report.rows[].id": "number",
+	}, FingerprintData(n.Notification.Data))
+}
+
+// a report of a few hundred KB
+var benchmarkReport = func() json.RawMessage {
+	rows := make([]map[string]interface{}, 5000)
+	for i := range rows {
+		rows[i] = map[string]interface{}{"id": i, "name": "item", "price": 9.99, "tags": []string{"a", "b"}}
+	}
+	b, _ := json.Marshal(map[string]interface{}{"report": map[string]interface{}{"rows": rows}})
+	return b
+}()
+
+func BenchmarkDataMap(b *testing.B) {
+	c := NewEngagespotClient("A", "B")
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		n, _ := c.NewNotification("Report")
+		var data map[string]interface{}
+		json.Unmarshal(benchmarkReport, &data)
+		n.Notification.Data = data
+		json.Marshal(n)
+	}
+}
+
+func BenchmarkDataRaw(b *testing.B) {
+	c := NewEngagespotClient("A", "B")
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		n, _ := c.NewNotification("Report")
+		n.SetDataRaw(benchmarkReport)
+		json.Marshal(n)
+	}
+}
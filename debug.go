@@ -0,0 +1,132 @@
+package engagespot
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"hash/fnv"
+	"io"
+	"math"
+	"net/http"
+)
+
+// headers which never leave the client in debug output
+var redactedHeaders = []string{
+	"X-ENGAGESPOT-API-SECRET",
+	"X-ENGAGESPOT-USER-SIGNATURE",
+	BodySignatureHeader,
+}
+
+const redacted = "REDACTED"
+
+// Sample is a redacted copy of a request made by the client and the response it got
+type Sample struct {
+	CorrelationId   string
+	Method          string
+	Url             string
+	RequestHeaders  http.Header
+	RequestBody     []byte
+	StatusCode      int
+	ResponseHeaders http.Header
+	ResponseBody    []byte
+}
+
+// WithDebugSampling captures a Sample of the given fraction, between 0 and 1, of calls made by
+// the client and hands it to the sink set using WithDebugSampleSink. whether a call is sampled is
+// decided by its correlation id, so every attempt of the same logical call is sampled alike
+func WithDebugSampling(rate float64) Option {
+	return func(c *Client) {
+		c.declare(optDebugSampling)
+		c.initialSettings().sampleRate = math.Max(0, math.Min(1, rate))
+	}
+}
+
+// WithDebugSampleSink sets the function receiving samples captured by WithDebugSampling
+func WithDebugSampleSink(sink func(Sample)) Option {
+	return func(c *Client) {
+		c.declare(optDebugSampleSink)
+		c.sampleSink = sink
+	}
+}
+
+type correlationKey struct{}
+
+// attach a correlation id to ctx. every attempt of a logical call must share it
+func withCorrelationId(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, correlationKey{}, id)
+}
+
+func correlationId(ctx context.Context) string {
+	id, _ := ctx.Value(correlationKey{}).(string)
+	return id
+}
+
+func newCorrelationId() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+func (c *Client) samplingEnabled(s *liveSettings) bool {
+	return s.sampleRate > 0 && c.sampleSink != nil
+}
+
+// deterministic sampling decision for a correlation id
+func (c *Client) sampled(s *liveSettings, id string) bool {
+	if s.sampleRate >= 1 {
+		return true
+	}
+	h := fnv.New64a()
+	h.Write([]byte(id))
+	return float64(h.Sum64()) < s.sampleRate*math.MaxUint64
+}
+
+func redactHeaders(h http.Header) http.Header {
+	h = h.Clone()
+	for _, name := range redactedHeaders {
+		if h.Get(name) != "" {
+			h.Set(name, redacted)
+		}
+	}
+	return h
+}
+
+// capture the sample of a finished call. the response body is read and replaced by a copy so the
+// caller can still consume it
+func (c *Client) captureSample(id string, req *http.Request, res *http.Response) {
+	sample := Sample{
+		CorrelationId:  id,
+		Method:         req.Method,
+		Url:            req.URL.String(),
+		RequestHeaders: redactHeaders(req.Header),
+	}
+
+	sample.RequestBody = requestBody(req)
+
+	if res != nil {
+		sample.StatusCode = res.StatusCode
+		sample.ResponseHeaders = res.Header.Clone()
+		if res.Body != nil {
+			sample.ResponseBody, _ = io.ReadAll(res.Body)
+			res.Body.Close()
+			res.Body = io.NopCloser(bytes.NewReader(sample.ResponseBody))
+		}
+	}
+
+	c.sampleSink(sample)
+}
+
+// copy of the body of req, nil if it can't be read again
+func requestBody(req *http.Request) []byte {
+	if req.GetBody == nil {
+		return nil
+	}
+	body, err := req.GetBody()
+	if err != nil {
+		return nil
+	}
+	defer body.Close()
+	b, _ := io.ReadAll(body)
+	return b
+}
@@ -0,0 +1,111 @@
+package engagespot
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDebugSamplingProportion(t *testing.T) {
+	c := NewEngagespotClient("A", "B", WithDebugSampling(0.1), WithDebugSampleSink(func(Sample) {}))
+
+	const calls = 20000
+	sampled := 0
+	for i := 0; i < calls; i++ {
+		if c.sampled(c.settings(), newCorrelationId()) {
+			sampled++
+		}
+	}
+	assert.InDelta(t, 0.1, float64(sampled)/calls, 0.02)
+}
+
+func TestDebugSamplingConsistentAcrossRetries(t *testing.T) {
+	c := NewEngagespotClient("A", "B", WithDebugSampling(0.5), WithDebugSampleSink(func(Sample) {}))
+	for i := 0; i < 100; i++ {
+		id := fmt.Sprintf("send-%d", i)
+		first := c.sampled(c.settings(), id)
+		for attempt := 0; attempt < 5; attempt++ {
+			assert.Equal(t, first, c.sampled(c.settings(), id))
+		}
+	}
+}
+
+func TestDebugSampleCapturedAndRedacted(t *testing.T) {
+	var samples []Sample
+	c := NewEngagespotClient("A", "B",
+		WithDebugSampling(1),
+		WithDebugSampleSink(func(s Sample) { samples = append(samples, s) }),
+		WithHmac(),
+	)
+	c.httpClient.Transport = respondWith(200, `{"ok":true}`)
+
+	n, _ := c.NewNotification("Hello")
+	n.AddRecipient("hello@example.com")
+	res, err := n.SendRaw()
+	assert.NoError(t, err)
+
+	// the caller still gets the whole body
+	body, _ := io.ReadAll(res.Body)
+	assert.Equal(t, `{"ok":true}`, string(body))
+
+	_, err = c.Connect("hello@example.com")
+	assert.NoError(t, err)
+
+	assert.Len(t, samples, 2)
+	assert.Equal(t, http.MethodPost, samples[0].Method)
+	assert.Contains(t, string(samples[0].RequestBody), `"recipients":["hello@example.com"]`)
+	assert.Equal(t, `{"ok":true}`, string(samples[0].ResponseBody))
+	assert.Equal(t, 200, samples[0].StatusCode)
+	for _, s := range samples {
+		assert.NotEmpty(t, s.CorrelationId)
+		assert.Equal(t, "A", s.RequestHeaders.Get("X-ENGAGESPOT-API-KEY"))
+		assert.Equal(t, redacted, s.RequestHeaders.Get("X-ENGAGESPOT-API-SECRET"))
+	}
+	assert.Equal(t, redacted, samples[1].RequestHeaders.Get("X-ENGAGESPOT-USER-SIGNATURE"))
+}
+
+func TestDebugSamplingUsesCorrelationId(t *testing.T) {
+	var samples []Sample
+	c := NewEngagespotClient("A", "B",
+		WithDebugSampling(0.5),
+		WithDebugSampleSink(func(s Sample) { samples = append(samples, s) }),
+	)
+	c.httpClient.Transport = respondWith(200, `{}`)
+
+	for i := 0; i < 50; i++ {
+		id := fmt.Sprintf("send-%d", i)
+		req, _ := http.NewRequestWithContext(withCorrelationId(context.Background(), id), http.MethodGet, "http://example.com", nil)
+		before := len(samples)
+		c.call(endpointSendNotification, req)
+		assert.Equal(t, c.sampled(c.settings(), id), len(samples) > before)
+	}
+}
+
+func benchmarkCall(b *testing.B, opts ...Option) {
+	c := NewEngagespotClient("A", "B", opts...)
+	c.httpClient.Transport = respondWith(200, `{}`)
+	req, _ := http.NewRequest(http.MethodGet, "http://example.com", nil)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		req.Header = http.Header{}
+		c.call(endpointSendNotification, req)
+	}
+}
+
+func BenchmarkCallWithoutSampling(b *testing.B) {
+	benchmarkCall(b)
+}
+
+func BenchmarkCallNotSampled(b *testing.B) {
+	benchmarkCall(b, WithDebugSampling(0.0000001), WithDebugSampleSink(func(Sample) {}))
+}
+
+func BenchmarkCallSampled(b *testing.B) {
+	benchmarkCall(b, WithDebugSampling(1), WithDebugSampleSink(func(Sample) {}))
+}
@@ -0,0 +1,93 @@
+package engagespot
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// MaxResponseBytes is the size, in bytes, above which DecodeInto, and every typed method of the
+// client, stops reading a response body
+const MaxResponseBytes = 10 << 20
+
+// how much of the body a DecodeError keeps
+const decodeErrorBodyBytes = 512
+
+// ErrResponseTooLarge is the error of a DecodeError for a body over MaxResponseBytes
+var ErrResponseTooLarge = errors.New("response body too large")
+
+// DecodeError is returned for a successful response whose body couldn't be read or decoded, like an
+// html page served by a proxy or a body cut short. Body holds the first bytes of the body, to see
+// what was received instead of the expected JSON
+type DecodeError struct {
+	Op       string
+	Endpoint string
+	// status of the response
+	StatusCode int
+	// at most the first 512 bytes of the body
+	Body []byte
+	Err  error
+}
+
+func (e *DecodeError) Error() string {
+	return fmt.Sprintf("engagespot: %s %s: decoding response: %s (status %d, body %q)", e.Op, e.Endpoint, e.Err, e.StatusCode, e.Body)
+}
+
+func (e *DecodeError) Unwrap() error {
+	return e.Err
+}
+
+// DecodeInto decodes the JSON body of res into v, for the methods returning an *http.Response, and
+// closes the body whatever happens. a non-2xx response is returned as an *APIError, and a body
+// over MaxResponseBytes, or which isn't valid JSON, as a *DecodeError. v may embed Response to
+// keep the fields it doesn't declare, see Response.Extra. for a nil v the body is only checked for
+// the status and discarded
+func DecodeInto(res *http.Response, v interface{}) error {
+	e := endpoint{op: "DecodeInto"}
+	if res.Request != nil {
+		e = endpoint{op: res.Request.Method, method: res.Request.Method, path: res.Request.URL.Path}
+	}
+	return decodeInto(e, res, v)
+}
+
+// DecodeInto for a response of e
+func decodeInto(e endpoint, res *http.Response, v interface{}) error {
+	if res.StatusCode >= 200 && res.StatusCode <= 299 && v == nil {
+		discard(res)
+		return nil
+	}
+
+	defer res.Body.Close()
+	body, err := readBody(res)
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return newAPIError(e, res.StatusCode, body)
+	}
+	if err == nil {
+		err = decodeResponse(body, v)
+	}
+	if err != nil {
+		return newDecodeError(e, res.StatusCode, body, err)
+	}
+	return nil
+}
+
+// the body of res, up to MaxResponseBytes. the bytes read so far are returned along with the error
+// if the body is bigger or its read failed
+func readBody(res *http.Response) ([]byte, error) {
+	body, err := io.ReadAll(io.LimitReader(res.Body, MaxResponseBytes+1))
+	if err != nil {
+		return body, err
+	}
+	if len(body) > MaxResponseBytes {
+		return body[:MaxResponseBytes], ErrResponseTooLarge
+	}
+	return body, nil
+}
+
+func newDecodeError(e endpoint, status int, body []byte, err error) *DecodeError {
+	if len(body) > decodeErrorBodyBytes {
+		body = body[:decodeErrorBodyBytes]
+	}
+	return &DecodeError{Op: e.op, Endpoint: e.path, StatusCode: status, Body: append([]byte(nil), body...), Err: err}
+}
@@ -0,0 +1,120 @@
+package engagespot
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func responseOf(status int, body io.Reader) (*http.Response, *trackedBody) {
+	tracked := &trackedBody{Reader: body}
+	req, _ := http.NewRequest(http.MethodGet, "https://api.engagespot.co/v3/app", nil)
+	return &http.Response{StatusCode: status, Body: tracked, Request: req}, tracked
+}
+
+type appName struct {
+	Response
+	Name string `json:"name"`
+}
+
+func TestDecodeInto(t *testing.T) {
+	res, body := responseOf(200, strings.NewReader(`{"name":"demo","plan":"pro"}`))
+	var v appName
+	assert.NoError(t, DecodeInto(res, &v))
+	assert.Equal(t, "demo", v.Name)
+	var plan string
+	assert.NoError(t, v.Extra("plan", &plan))
+	assert.Equal(t, "pro", plan)
+	assert.True(t, body.closed)
+
+	// drained, so the connection can be reused
+	res, body = responseOf(200, strings.NewReader(`{"ignored":true}`))
+	assert.NoError(t, DecodeInto(res, nil))
+	assert.True(t, body.drained)
+	assert.True(t, body.closed)
+}
+
+func TestDecodeIntoErrorStatus(t *testing.T) {
+	res, body := responseOf(404, strings.NewReader(`{"message":"no such app"}`))
+	err := DecodeInto(res, &appName{})
+	assert.ErrorIs(t, err, ErrNotFound)
+	assert.EqualError(t, err, "engagespot: GET /v3/app: unexpected status 404: no such app")
+	assert.True(t, body.closed)
+
+	// error pages of a proxy fall back to the status text
+	res, _ = responseOf(502, strings.NewReader(`<html><body><h1>502 Bad Gateway</h1></body></html>`))
+	var apiErr *APIError
+	assert.True(t, errors.As(DecodeInto(res, nil), &apiErr))
+	assert.Equal(t, "Bad Gateway", apiErr.Message)
+}
+
+func TestDecodeIntoHTMLPage(t *testing.T) {
+	page := `<html><head><title>Sign in</title></head><body>` + strings.Repeat("<p>captive portal</p>", 100) + `</body></html>`
+	res, body := responseOf(200, strings.NewReader(page))
+	err := DecodeInto(res, &appName{})
+
+	var decodeErr *DecodeError
+	assert.True(t, errors.As(err, &decodeErr))
+	assert.Equal(t, 200, decodeErr.StatusCode)
+	assert.Equal(t, page[:512], string(decodeErr.Body))
+	var syntaxErr *json.SyntaxError
+	assert.True(t, errors.As(err, &syntaxErr))
+	assert.Contains(t, err.Error(), `engagespot: GET /v3/app: decoding response: invalid character '<'`)
+	assert.Contains(t, err.Error(), `body "<html><head><title>Sign in</title>`)
+	assert.True(t, body.closed)
+}
+
+func TestDecodeIntoTruncatedBody(t *testing.T) {
+	// cut short by the server
+	res, body := responseOf(200, strings.NewReader(`{"name":"de`))
+	err := DecodeInto(res, &appName{})
+	var decodeErr *DecodeError
+	assert.True(t, errors.As(err, &decodeErr))
+	assert.Equal(t, `{"name":"de`, string(decodeErr.Body))
+	assert.Contains(t, err.Error(), "unexpected end of JSON input")
+	assert.True(t, body.closed)
+
+	// cut short before the announced length
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Length", "100")
+		w.Write([]byte(`{"name":`))
+	}))
+	defer server.Close()
+	live, err := http.Get(server.URL)
+	assert.NoError(t, err)
+	err = DecodeInto(live, &appName{})
+	assert.ErrorIs(t, err, io.ErrUnexpectedEOF)
+	assert.True(t, errors.As(err, &decodeErr))
+	assert.Equal(t, `{"name":`, string(decodeErr.Body))
+}
+
+func TestDecodeIntoTooLarge(t *testing.T) {
+	res, body := responseOf(200, io.MultiReader(strings.NewReader(`{"name":"`), strings.NewReader(strings.Repeat("x", MaxResponseBytes)), strings.NewReader(`"}`)))
+	err := DecodeInto(res, &appName{})
+	assert.ErrorIs(t, err, ErrResponseTooLarge)
+	assert.True(t, body.closed)
+}
+
+func TestTypedMethodsDecodeErrors(t *testing.T) {
+	c := NewEngagespotClient("A", "B")
+	c.httpClient.Transport = roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		return &http.Response{StatusCode: 200, Body: io.NopCloser(strings.NewReader("<html>maintenance</html>")), Request: req}, nil
+	})
+
+	_, err := c.GetAppInfo()
+	var decodeErr *DecodeError
+	assert.True(t, errors.As(err, &decodeErr))
+	assert.Equal(t, "GetAppInfo", decodeErr.Op)
+	assert.Equal(t, "<html>maintenance</html>", string(decodeErr.Body))
+
+	_, err = newMessage(c).SendCtx(context.Background())
+	assert.True(t, errors.As(err, &decodeErr))
+	assert.Equal(t, "Send", decodeErr.Op)
+}
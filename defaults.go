@@ -0,0 +1,90 @@
+package engagespot
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const defaultEndpoint = "https://api.engagespot.co/v3/"
+const defaultDeviceType = "ios"
+
+// RetryPolicy controls how failed calls are retried. MaxAttempts includes the first attempt, so 1
+// means no retries
+type RetryPolicy struct {
+	MaxAttempts int
+	BaseDelay   time.Duration
+}
+
+// Defaults holds the values every new client is seeded with. they can be changed per client using
+// WithDefaults
+type Defaults struct {
+	// base url of the API, every endpoint path is relative to it
+	Endpoint string
+	// device type sent by Connect, see ConnectWithDevice for other devices
+	DeviceType string
+	// timeout of the http client built by NewEngagespotClient
+	Timeout time.Duration
+	// retry policy of calls to the API
+	Retry RetryPolicy
+	// number of recipients sent per request when a send is split into chunks
+	ChunkSize int
+}
+
+// DefaultConfig returns the defaults used by NewEngagespotClient. any change to these values
+// changes behaviour of every client created without options, so they are pinned by tests
+func DefaultConfig() Defaults {
+	return Defaults{
+		Endpoint:   defaultEndpoint,
+		DeviceType: defaultDeviceType,
+		Timeout:    30 * time.Second,
+		Retry: RetryPolicy{
+			MaxAttempts: 1,
+			BaseDelay:   500 * time.Millisecond,
+		},
+		ChunkSize: MaxRecipients,
+	}
+}
+
+// WithDefaults can be used to replace the defaults of a single client. start from DefaultConfig
+// and change what is needed, zero values are not filled in
+func WithDefaults(d Defaults) Option {
+	return func(c *Client) {
+		c.declare(optDefaults)
+		c.defaults = d
+	}
+}
+
+// WithBaseURL can be used to send calls to another API endpoint than the default v3 one, like a
+// staging environment, a regional endpoint or a test server. the url must be absolute http(s),
+// without query or fragment. the path is kept, with a single trailing slash, so paths of the
+// endpoints are joined to it as is. an invalid url is logged, or rejected by NewClient, and the
+// endpoint is left unchanged. takes precedence over Defaults.Endpoint
+func WithBaseURL(baseURL string) Option {
+	return func(c *Client) {
+		c.declare(optBaseURL)
+		normalized, err := normalizeBaseURL(baseURL)
+		if err != nil {
+			c.rejectOption(optBaseURL, err)
+			return
+		}
+		c.baseURL = normalized
+	}
+}
+
+func normalizeBaseURL(baseURL string) (string, error) {
+	u, err := url.Parse(baseURL)
+	if err != nil {
+		return "", err
+	}
+	if (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return "", fmt.Errorf("%q is not an absolute http(s) url", baseURL)
+	}
+	if u.RawQuery != "" || u.Fragment != "" || u.User != nil {
+		return "", fmt.Errorf("%q can't have a query, fragment or credentials", baseURL)
+	}
+	u.Path = strings.TrimRight(u.Path, "/") + "/"
+	u.RawPath = ""
+	return u.String(), nil
+}
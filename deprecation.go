@@ -0,0 +1,25 @@
+package engagespot
+
+import "sync"
+
+// deprecated methods already warned about in this process
+var deprecationWarned sync.Map
+
+// WithDeprecationWarnings controls whether calling a deprecated method logs a warning naming its
+// replacement. enabled by default, each method warns at most once per process
+func WithDeprecationWarnings(enabled bool) Option {
+	return func(c *Client) {
+		c.config.silenceDeprecations = !enabled
+	}
+}
+
+// warn, once per process, that method is deprecated in favour of replacement
+func (c *Client) deprecated(method, replacement string) {
+	if c.config.silenceDeprecations {
+		return
+	}
+	if _, warned := deprecationWarned.LoadOrStore(method, struct{}{}); warned {
+		return
+	}
+	c.log().Warn("engagespot: deprecated method called", "method", method, "replacement", replacement)
+}
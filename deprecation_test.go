@@ -0,0 +1,77 @@
+package engagespot
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func resetDeprecationWarnings() {
+	deprecationWarned = sync.Map{}
+}
+
+func TestDeprecationWarnedOncePerMethod(t *testing.T) {
+	resetDeprecationWarnings()
+	defer resetDeprecationWarnings()
+
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewTextHandler(&buf, nil))
+	for i := 0; i < 10; i++ {
+		NewEngagespotClient("A", "B", WithLogger(logger)).EnableHmac()
+	}
+
+	assert.Equal(t, 1, strings.Count(buf.String(), "deprecated method called"))
+	assert.Contains(t, buf.String(), "method=EnableHmac replacement=WithHmac")
+}
+
+func TestDeprecatedSendMethods(t *testing.T) {
+	resetDeprecationWarnings()
+	defer resetDeprecationWarnings()
+
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewTextHandler(&buf, nil))
+	c := NewEngagespotClient("A", "B", WithLogger(logger))
+	c.httpClient.Transport = respondWith(200, `{}`)
+
+	for i := 0; i < 5; i++ {
+		n := newMessage(c)
+		n.Send()
+		c.Send(n)
+		n.SendRaw()
+		c.SendRaw(n)
+		n.SendRawCtx(context.Background())
+		c.SendRawCtx(context.Background(), n)
+		n.SendCtx(context.Background())
+	}
+
+	logged := buf.String()
+	assert.Equal(t, 3, strings.Count(logged, "deprecated method called"))
+	for _, method := range []string{"Send", "SendRaw", "SendRawCtx"} {
+		assert.Equal(t, 1, strings.Count(logged, "method="+method+" replacement=SendCtx"), method)
+	}
+}
+
+func TestDeprecationWarningsDisabled(t *testing.T) {
+	resetDeprecationWarnings()
+	defer resetDeprecationWarnings()
+
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewTextHandler(&buf, nil))
+	c := NewEngagespotClient("A", "B", WithLogger(logger), WithDeprecationWarnings(false)).EnableHmac()
+	c.httpClient.Transport = respondWith(200, `{}`)
+	newMessage(c).Send()
+	newMessage(c).SendRaw()
+
+	assert.True(t, c.settings().hmac)
+	assert.Empty(t, buf.String())
+}
+
+func TestWithHmac(t *testing.T) {
+	assert.True(t, NewEngagespotClient("A", "B", WithHmac()).settings().hmac)
+	assert.False(t, NewEngagespotClient("A", "B").settings().hmac)
+}
@@ -0,0 +1,144 @@
+package engagespot
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// device types accepted by ConnectWithDevice and RegisterDeviceToken
+const (
+	DeviceIOS     = "ios"
+	DeviceAndroid = "android"
+	DeviceWeb     = "web"
+	DeviceBrowser = "browser"
+)
+
+func validDeviceType(deviceType string) error {
+	switch deviceType {
+	case DeviceIOS, DeviceAndroid, DeviceWeb, DeviceBrowser:
+		return nil
+	}
+	return fmt.Errorf("unsupported device type %q, expected one of ios, android, web, browser", deviceType)
+}
+
+// Device is a push device registered for a user
+type Device struct {
+	Response
+	Id         string    `json:"id"`
+	Platform   string    `json:"platform"`
+	LastSeenAt time.Time `json:"lastSeenAt"`
+	CreatedAt  time.Time `json:"createdAt"`
+}
+
+// response of `GET users/{userId}/devices`
+type deviceList struct {
+	Devices []Device `json:"devices"`
+}
+
+// RegisterDeviceToken can be used to register the push token of a device of a user, like an FCM
+// or APNs token, so push notifications are delivered to it. deviceType is one of DeviceIOS,
+// DeviceAndroid, DeviceWeb or DeviceBrowser
+func (c *Client) RegisterDeviceToken(ctx context.Context, userId, deviceType, token string, opts ...CallOption) (*Device, error) {
+	if err := validDeviceType(deviceType); err != nil {
+		return nil, err
+	}
+	if token == "" {
+		return nil, errors.New("empty device token string")
+	}
+	body, err := json.Marshal(map[string]string{"platform": deviceType, "token": token})
+	if err != nil {
+		return nil, err
+	}
+	req, err := c.newRequestContext(applyCallOptions(ctx, opts), endpointRegisterDevice, body, c.NormalizeUserID(userId))
+	if err != nil {
+		return nil, err
+	}
+
+	device := &Device{}
+	if err := c.callJSON(endpointRegisterDevice, req, device); err != nil {
+		return nil, err
+	}
+	return device, nil
+}
+
+// WithIdempotentRevoke makes RevokeDevice succeed for devices which are already absent
+func WithIdempotentRevoke() Option {
+	return func(c *Client) {
+		c.idempotentRevoke = true
+	}
+}
+
+// ListDevices can be used to list the push devices registered for a user
+func (c *Client) ListDevices(ctx context.Context, userId string, opts ...CallOption) ([]Device, error) {
+	req, err := c.newRequestContext(applyCallOptions(ctx, opts), endpointListDevices, nil, c.NormalizeUserID(userId))
+	if err != nil {
+		return nil, err
+	}
+
+	list := &deviceList{}
+	if err := c.callJSON(endpointListDevices, req, list); err != nil {
+		return nil, err
+	}
+	return list.Devices, nil
+}
+
+// RevokeDevice can be used to stop push delivery to a device of a user, like a stolen phone
+func (c *Client) RevokeDevice(ctx context.Context, userId, deviceId string, opts ...CallOption) error {
+	if deviceId == "" {
+		return errors.New("empty device id string")
+	}
+
+	req, err := c.newRequestContext(applyCallOptions(ctx, opts), endpointRevokeDevice, nil, c.NormalizeUserID(userId), deviceId)
+	if err != nil {
+		return err
+	}
+
+	err = c.callJSON(endpointRevokeDevice, req, nil)
+	if c.idempotentRevoke && errors.Is(err, ErrNotFound) {
+		return nil
+	}
+	return err
+}
+
+// RevokeReport lists the outcome of RevokeAllDevices per device
+type RevokeReport struct {
+	Revoked []string
+	Failed  map[string]error
+}
+
+// RevokeAllDevices can be used to revoke every device of a user. devices are revoked one by one
+// and a failure doesn't stop the others, an error is returned if any of them failed
+func (c *Client) RevokeAllDevices(ctx context.Context, userId string, opts ...CallOption) (*RevokeReport, error) {
+	// fail before listing the devices, which is a read and would go through
+	if c.config.readOnly {
+		return nil, ErrReadOnlyClient
+	}
+	devices, err := c.ListDevices(ctx, userId, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &RevokeReport{Failed: map[string]error{}}
+	for _, d := range devices {
+		if err := c.RevokeDevice(ctx, userId, d.Id, opts...); err != nil {
+			report.Failed[d.Id] = err
+			continue
+		}
+		report.Revoked = append(report.Revoked, d.Id)
+	}
+
+	if len(report.Failed) > 0 {
+		ids := make([]string, 0, len(report.Failed))
+		for _, d := range devices {
+			if _, ok := report.Failed[d.Id]; ok {
+				ids = append(ids, d.Id)
+			}
+		}
+		return report, fmt.Errorf("failed to revoke devices %s", strings.Join(ids, ", "))
+	}
+	return report, nil
+}
@@ -0,0 +1,200 @@
+package engagespot
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// fake device registry of a single user
+type deviceServer struct {
+	mu       sync.Mutex
+	devices  []string
+	failing  []string
+	requests []string
+}
+
+func (s *deviceServer) RoundTrip(req *http.Request) (*http.Response, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.requests = append(s.requests, req.Method+" "+req.URL.EscapedPath())
+
+	if req.Method == http.MethodGet {
+		var items []string
+		for _, d := range s.devices {
+			items = append(items, `{"id":"`+d+`","platform":"ios","lastSeenAt":"2022-01-02T03:04:05Z","createdAt":"2021-01-02T03:04:05Z"}`)
+		}
+		return respondWith(200, `{"devices":[`+strings.Join(items, ",")+`]}`).RoundTrip(req)
+	}
+
+	id := req.URL.Path[strings.LastIndex(req.URL.Path, "/")+1:]
+	if containsString(s.failing, id) {
+		return respondWith(500, `{}`).RoundTrip(req)
+	}
+	for i, d := range s.devices {
+		if d == id {
+			s.devices = append(s.devices[:i], s.devices[i+1:]...)
+			return respondWith(204, ``).RoundTrip(req)
+		}
+	}
+	return respondWith(404, `{}`).RoundTrip(req)
+}
+
+func newDeviceClient(s *deviceServer, opts ...Option) *Client {
+	c := NewEngagespotClient("A", "B", opts...)
+	c.httpClient.Transport = s
+	return c
+}
+
+func TestListDevices(t *testing.T) {
+	s := &deviceServer{devices: []string{"d1", "d2"}}
+	c := newDeviceClient(s)
+
+	devices, err := c.ListDevices(context.Background(), "hello@example.com")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"GET /v3/users/hello@example.com/devices"}, s.requests)
+	assert.Len(t, devices, 2)
+	assert.Equal(t, Device{
+		Id:         "d1",
+		Platform:   "ios",
+		LastSeenAt: time.Date(2022, 1, 2, 3, 4, 5, 0, time.UTC),
+		CreatedAt:  time.Date(2021, 1, 2, 3, 4, 5, 0, time.UTC),
+	}, devices[0])
+}
+
+func TestRevokeDevice(t *testing.T) {
+	s := &deviceServer{devices: []string{"d1"}}
+	c := newDeviceClient(s)
+
+	assert.NoError(t, c.RevokeDevice(context.Background(), "hello@example.com", "d1"))
+	assert.Equal(t, []string{"DELETE /v3/users/hello@example.com/devices/d1"}, s.requests)
+
+	// absent devices are an error by default
+	assert.Error(t, c.RevokeDevice(context.Background(), "hello@example.com", "d1"))
+}
+
+func TestRevokeDeviceIdempotent(t *testing.T) {
+	s := &deviceServer{}
+	c := newDeviceClient(s, WithIdempotentRevoke())
+
+	assert.NoError(t, c.RevokeDevice(context.Background(), "hello@example.com", "d1"))
+
+	s.failing = []string{"d1"}
+	assert.Error(t, c.RevokeDevice(context.Background(), "hello@example.com", "d1"))
+}
+
+func TestRevokeAllDevicesPartialFailure(t *testing.T) {
+	s := &deviceServer{devices: []string{"d1", "d2", "d3"}, failing: []string{"d2"}}
+	c := newDeviceClient(s)
+
+	report, err := c.RevokeAllDevices(context.Background(), "hello@example.com")
+	assert.EqualError(t, err, "failed to revoke devices d2")
+	assert.Equal(t, []string{"d1", "d3"}, report.Revoked)
+	assert.Len(t, report.Failed, 1)
+	assert.Contains(t, report.Failed, "d2")
+	assert.Equal(t, []string{"d2"}, s.devices)
+}
+
+func TestRegisterDeviceToken(t *testing.T) {
+	var requests []*http.Request
+	var bodies []string
+	c := NewEngagespotClient("A", "B")
+	c.httpClient.Transport = roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		body, _ := io.ReadAll(req.Body)
+		requests, bodies = append(requests, req), append(bodies, string(body))
+		return respondWith(201, `{"id":"d1","platform":"android","createdAt":"2022-01-02T03:04:05Z"}`).RoundTrip(req)
+	})
+
+	device, err := c.RegisterDeviceToken(context.Background(), "hello@example.com", DeviceAndroid, "fcm-token")
+	assert.NoError(t, err)
+	assert.Equal(t, "d1", device.Id)
+	assert.Equal(t, DeviceAndroid, device.Platform)
+	assert.Equal(t, "POST /v3/users/hello@example.com/devices", requests[0].Method+" "+requests[0].URL.Path)
+	assert.JSONEq(t, `{"platform":"android","token":"fcm-token"}`, bodies[0])
+
+	_, err = c.RegisterDeviceToken(context.Background(), "hello@example.com", "toaster", "fcm-token")
+	assert.EqualError(t, err, `unsupported device type "toaster", expected one of ios, android, web, browser`)
+	_, err = c.RegisterDeviceToken(context.Background(), "hello@example.com", DeviceWeb, "")
+	assert.EqualError(t, err, "empty device token string")
+	assert.Len(t, requests, 1)
+}
+
+func TestConnectWithDevice(t *testing.T) {
+	var requests []*http.Request
+	c := NewEngagespotClient("A", "B")
+	c.httpClient.Transport = roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		requests = append(requests, req)
+		return respondWith(200, `{}`).RoundTrip(req)
+	})
+	ctx := context.Background()
+
+	for _, deviceType := range []string{DeviceIOS, DeviceAndroid, DeviceWeb, DeviceBrowser} {
+		res, err := c.ConnectWithDevice(ctx, "hello@example.com", ConnectOptions{DeviceType: deviceType})
+		assert.NoError(t, err)
+		res.Body.Close()
+		assert.Equal(t, deviceType, requests[len(requests)-1].Header.Get("X-ENGAGESPOT-DEVICE-TYPE"))
+	}
+
+	// Connect keeps the default device type
+	res, err := c.Connect("hello@example.com")
+	assert.NoError(t, err)
+	res.Body.Close()
+	assert.Equal(t, DeviceIOS, requests[len(requests)-1].Header.Get("X-ENGAGESPOT-DEVICE-TYPE"))
+
+	_, err = c.ConnectWithDevice(ctx, "hello@example.com", ConnectOptions{DeviceType: "tv"})
+	assert.EqualError(t, err, `unsupported device type "tv", expected one of ios, android, web, browser`)
+	assert.Len(t, requests, 5)
+}
+
+func TestConnectUser(t *testing.T) {
+	var requests []*http.Request
+	c := NewEngagespotClient("A", "B")
+	c.httpClient.Transport = roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		requests = append(requests, req)
+		return respondWith(200, `{"identifier":"hello@example.com","token":"sdk-token","keys":{"vapid":"k"}}`).RoundTrip(req)
+	})
+	ctx := context.Background()
+
+	result, err := c.ConnectUser(ctx, "hello@example.com", ConnectOptions{DeviceType: DeviceAndroid, DeviceId: "pixel-7"})
+	assert.NoError(t, err)
+	assert.Equal(t, "hello@example.com", result.UserId)
+	assert.Equal(t, "sdk-token", result.Token)
+	assert.Equal(t, "pixel-7", result.DeviceId)
+	var keys map[string]string
+	assert.NoError(t, result.Extra("keys", &keys))
+	assert.Equal(t, map[string]string{"vapid": "k"}, keys)
+	assert.Equal(t, "pixel-7", requests[0].Header.Get("X-ENGAGESPOT-DEVICE-ID"))
+	assert.Equal(t, DeviceAndroid, requests[0].Header.Get("X-ENGAGESPOT-DEVICE-TYPE"))
+	assert.Equal(t, "hello@example.com", requests[0].Header.Get("X-ENGAGESPOT-USER-ID"))
+
+	// without a device id every connect gets a new random one, which is returned to be reused
+	first, err := c.ConnectUser(ctx, "hello@example.com", ConnectOptions{})
+	assert.NoError(t, err)
+	second, err := c.ConnectUser(ctx, "hello@example.com", ConnectOptions{})
+	assert.NoError(t, err)
+	assert.Regexp(t, `^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`, first.DeviceId)
+	assert.NotEqual(t, first.DeviceId, second.DeviceId)
+	assert.Equal(t, first.DeviceId, requests[1].Header.Get("X-ENGAGESPOT-DEVICE-ID"))
+	assert.Equal(t, DeviceIOS, requests[1].Header.Get("X-ENGAGESPOT-DEVICE-TYPE"))
+}
+
+func TestConnectUserResult(t *testing.T) {
+	c := NewEngagespotClient("A", "B", WithUserIDNormalizer(strings.ToLower))
+	c.httpClient.Transport = respondWith(200, `{}`)
+
+	// the sent user id if the endpoint doesn't return one
+	result, err := c.ConnectUser(context.Background(), "Hello@Example.com", ConnectOptions{})
+	assert.NoError(t, err)
+	assert.Equal(t, "hello@example.com", result.UserId)
+	assert.Empty(t, result.Token)
+
+	c.httpClient.Transport = respondWith(404, `{"message":"no such user"}`)
+	_, err = c.ConnectUser(context.Background(), "hello@example.com", ConnectOptions{})
+	assert.ErrorIs(t, err, ErrNotFound)
+}
@@ -0,0 +1,151 @@
+package engagespot
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// fields left out of comparisons by default. recipients change from one send to the other
+// without the content being any different
+var defaultDiffIgnore = []string{"recipients"}
+
+// DiffOptions controls which fields are compared by Diff and ContentHash. fields are dotted paths
+// in the payload, like "notification.url"
+type DiffOptions struct {
+	// compare recipients too, which are ignored by default
+	IncludeRecipients bool
+	// additional fields to ignore
+	Ignore []string
+}
+
+// FieldChange is a field which differs between two notifications. Before or After is nil when the
+// field is only set on one side
+type FieldChange struct {
+	Field  string
+	Before interface{}
+	After  interface{}
+}
+
+// Diff lists the fields which differ between two notifications, ordered by field
+type Diff []FieldChange
+
+func (d Diff) String() string {
+	lines := make([]string, len(d))
+	for i, c := range d {
+		before, _ := json.Marshal(c.Before)
+		after, _ := json.Marshal(c.After)
+		lines[i] = fmt.Sprintf("%s: %s -> %s", c.Field, before, after)
+	}
+	return strings.Join(lines, "\n")
+}
+
+func (o DiffOptions) ignored() []string {
+	ignore := append([]string{}, o.Ignore...)
+	if !o.IncludeRecipients {
+		ignore = append(ignore, defaultDiffIgnore...)
+	}
+	return ignore
+}
+
+// canonical form of the payload of n, without the ignored fields
+func (o DiffOptions) canonical(n *Notification) (map[string]interface{}, error) {
+	payload, err := json.Marshal(n)
+	if err != nil {
+		return nil, err
+	}
+	var m map[string]interface{}
+	if err := json.Unmarshal(payload, &m); err != nil {
+		return nil, err
+	}
+	for _, field := range o.ignored() {
+		deletePath(m, strings.Split(field, "."))
+	}
+	return m, nil
+}
+
+// flatten nested objects into dotted paths. arrays are kept whole since their order matters
+func flatten(prefix string, m map[string]interface{}, into map[string]interface{}) {
+	for key, value := range m {
+		path := key
+		if prefix != "" {
+			path = prefix + "." + key
+		}
+		if child, ok := value.(map[string]interface{}); ok {
+			flatten(path, child, into)
+			continue
+		}
+		into[path] = value
+	}
+}
+
+// Diff compares the payloads of a and b field by field. the bool is true if they differ
+func (o DiffOptions) Diff(a, b *Notification) (Diff, bool) {
+	ca, errA := o.canonical(a)
+	cb, errB := o.canonical(b)
+	if errA != nil || errB != nil {
+		return Diff{{Field: "", Before: errA, After: errB}}, true
+	}
+
+	fa, fb := map[string]interface{}{}, map[string]interface{}{}
+	flatten("", ca, fa)
+	flatten("", cb, fb)
+
+	fields := map[string]struct{}{}
+	for f := range fa {
+		fields[f] = struct{}{}
+	}
+	for f := range fb {
+		fields[f] = struct{}{}
+	}
+
+	var diff Diff
+	for f := range fields {
+		if !reflect.DeepEqual(fa[f], fb[f]) {
+			diff = append(diff, FieldChange{Field: f, Before: fa[f], After: fb[f]})
+		}
+	}
+	sort.Slice(diff, func(i, j int) bool { return diff[i].Field < diff[j].Field })
+	return diff, len(diff) > 0
+}
+
+// ContentHash returns a hash of the canonical payload of n, without the ignored fields. two
+// notifications with the same content have the same hash, whatever the order maps were built in
+func (o DiffOptions) ContentHash(n *Notification) (string, error) {
+	c, err := o.canonical(n)
+	if err != nil {
+		return "", err
+	}
+	b, err := json.Marshal(c)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// NotificationDiff compares a and b using the default DiffOptions
+func NotificationDiff(a, b *Notification) (Diff, bool) {
+	return DiffOptions{}.Diff(a, b)
+}
+
+// SendIfChanged sends the notification only if its content hash, see DiffOptions.ContentHash,
+// differs from previousHash. the new hash is returned for the caller to persist, along with a nil
+// result if the send was skipped
+func (n *Notification) SendIfChanged(ctx context.Context, previousHash string, opts ...CallOption) (*SendResult, string, error) {
+	hash, err := DiffOptions{}.ContentHash(n)
+	if err != nil {
+		return nil, "", err
+	}
+	if hash == previousHash {
+		return nil, hash, nil
+	}
+
+	result, err := n.SendCtx(ctx, opts...)
+	return result, hash, err
+}
@@ -0,0 +1,88 @@
+package engagespot
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func digest(c *Client, message string, recipients ...string) *Notification {
+	n, _ := c.NewNotification("Daily digest")
+	n.SetMessage(message)
+	n.Override.AddChannel("email")
+	for _, r := range recipients {
+		n.AddRecipient(r)
+	}
+	return n
+}
+
+func TestNotificationDiff(t *testing.T) {
+	c := NewEngagespotClient("A", "B")
+
+	_, changed := NotificationDiff(digest(c, "3 new posts", "a"), digest(c, "3 new posts", "b"))
+	assert.False(t, changed)
+
+	a, b := digest(c, "3 new posts", "a"), digest(c, "4 new posts", "a")
+	b.SetUrl("https://example.com/digest")
+	diff, changed := NotificationDiff(a, b)
+	assert.True(t, changed)
+	assert.Equal(t, Diff{
+		{Field: "notification.message", Before: "3 new posts", After: "4 new posts"},
+		{Field: "notification.url", Before: nil, After: "https://example.com/digest"},
+	}, diff)
+	assert.Equal(t, "notification.message: \"3 new posts\" -> \"4 new posts\"\nnotification.url: null -> \"https://example.com/digest\"", diff.String())
+}
+
+func TestNotificationDiffOptions(t *testing.T) {
+	c := NewEngagespotClient("A", "B")
+	a, b := digest(c, "3 new posts", "a"), digest(c, "4 new posts", "b")
+
+	diff, changed := DiffOptions{IncludeRecipients: true, Ignore: []string{"notification.message"}}.Diff(a, b)
+	assert.True(t, changed)
+	assert.Equal(t, Diff{{Field: "recipients", Before: []interface{}{"a"}, After: []interface{}{"b"}}}, diff)
+
+	_, changed = DiffOptions{Ignore: []string{"notification.message"}}.Diff(a, b)
+	assert.False(t, changed)
+}
+
+func TestContentHashMapOrder(t *testing.T) {
+	c := NewEngagespotClient("A", "B")
+	a, b := digest(c, "x", "a"), digest(c, "x", "a")
+	a.Override.SendgridEmail = map[string]interface{}{"one": 1, "two": map[string]interface{}{"x": 1, "y": 2}}
+	b.Override.SendgridEmail = map[string]interface{}{"two": map[string]interface{}{"y": 2, "x": 1}, "one": 1}
+
+	ha, err := DiffOptions{}.ContentHash(a)
+	assert.NoError(t, err)
+	hb, err := DiffOptions{}.ContentHash(b)
+	assert.NoError(t, err)
+	assert.Equal(t, ha, hb)
+
+	_, changed := NotificationDiff(a, b)
+	assert.False(t, changed)
+}
+
+func TestSendIfChanged(t *testing.T) {
+	var bodies []map[string]interface{}
+	c := NewEngagespotClient("A", "B")
+	c.httpClient.Transport = recordBodies(&bodies)
+
+	res, hash, err := digest(c, "3 new posts", "a").SendIfChanged(context.Background(), "")
+	assert.NoError(t, err)
+	assert.NotNil(t, res)
+	assert.NotEmpty(t, hash)
+	assert.Len(t, bodies, 1)
+
+	// same content, even to other recipients, is skipped
+	res, next, err := digest(c, "3 new posts", "b").SendIfChanged(context.Background(), hash)
+	assert.NoError(t, err)
+	assert.Nil(t, res)
+	assert.Equal(t, hash, next)
+	assert.Len(t, bodies, 1)
+
+	res, next, err = digest(c, "4 new posts", "a").SendIfChanged(context.Background(), hash)
+	assert.NoError(t, err)
+	assert.NotNil(t, res)
+	assert.NotEqual(t, hash, next)
+	assert.Len(t, bodies, 2)
+}
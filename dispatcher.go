@@ -0,0 +1,231 @@
+package engagespot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+)
+
+// ErrDispatcherClosed is returned when enqueueing into a Dispatcher which is shutting down
+var ErrDispatcherClosed = errors.New("dispatcher closed")
+
+// AbandonedNotification summarizes a notification which a Dispatcher couldn't send before
+// shutting down. it only keeps the number of recipients, not the recipients themselves
+type AbandonedNotification struct {
+	Key        string
+	Title      string
+	Recipients int
+	EnqueuedAt time.Time
+	// whether the notification was being sent, rather than still queued, at shutdown
+	InFlight bool
+}
+
+// ShutdownError is returned by Dispatcher.Shutdown when the deadline expired before every
+// notification has been sent
+type ShutdownError struct {
+	Abandoned []AbandonedNotification
+}
+
+func (e *ShutdownError) Error() string {
+	return fmt.Sprintf("engagespot: dispatcher shut down with %d abandoned notifications", len(e.Abandoned))
+}
+
+type dispatchItem struct {
+	n       *Notification
+	summary AbandonedNotification
+	// set by the worker picking the item up, guarded by Dispatcher.mu
+	inFlight bool
+	// receives the outcome of items queued by SendAsync, instead of onResult
+	results chan AsyncResult
+}
+
+// Dispatcher sends notifications in the background using a fixed number of workers
+type Dispatcher struct {
+	client   *Client
+	queue    chan *dispatchItem
+	onResult func(key string, res *http.Response, err error)
+
+	// cancels requests of in-flight notifications once the shutdown deadline expired
+	ctx    context.Context
+	cancel context.CancelFunc
+	// workers, and calls of Enqueue not done yet
+	wg        sync.WaitGroup
+	enqueuing sync.WaitGroup
+
+	mu      sync.Mutex
+	closed  bool
+	pending map[*dispatchItem]struct{}
+}
+
+// NewDispatcher can be used to send notifications in the background using workers goroutines. up
+// to queueSize notifications can wait to be sent. onResult, if not nil, is called with the outcome
+// of every send, identified by the key returned by Enqueue. the response body is closed after
+// onResult returns
+func (c *Client) NewDispatcher(workers, queueSize int, onResult func(key string, res *http.Response, err error)) *Dispatcher {
+	if workers < 1 {
+		workers = 1
+	}
+	if queueSize < 0 {
+		queueSize = 0
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	d := &Dispatcher{
+		client:   c,
+		queue:    make(chan *dispatchItem, queueSize),
+		onResult: onResult,
+		ctx:      ctx,
+		cancel:   cancel,
+		pending:  map[*dispatchItem]struct{}{},
+	}
+
+	d.wg.Add(workers)
+	for i := 0; i < workers; i++ {
+		go d.work()
+	}
+	return d
+}
+
+// Enqueue adds n to the queue, blocking while the queue is full. the returned key identifies the
+// notification in results and in the ShutdownError
+func (d *Dispatcher) Enqueue(n *Notification) (string, error) {
+	return d.enqueue(n, nil)
+}
+
+func (d *Dispatcher) enqueue(n *Notification, results chan AsyncResult) (string, error) {
+	item := &dispatchItem{
+		n:       n,
+		results: results,
+		summary: AbandonedNotification{
+			Key:        newCorrelationId(),
+			Title:      n.Notification.Title,
+			Recipients: len(n.Recipients),
+			EnqueuedAt: d.client.now(),
+		},
+	}
+
+	d.mu.Lock()
+	if d.closed {
+		d.mu.Unlock()
+		return "", ErrDispatcherClosed
+	}
+	d.pending[item] = struct{}{}
+	d.enqueuing.Add(1)
+	d.mu.Unlock()
+	defer d.enqueuing.Done()
+
+	select {
+	case d.queue <- item:
+		return item.summary.Key, nil
+	case <-d.ctx.Done():
+		d.mu.Lock()
+		delete(d.pending, item)
+		d.mu.Unlock()
+		return "", ErrDispatcherClosed
+	}
+}
+
+func (d *Dispatcher) work() {
+	defer d.wg.Done()
+
+	for item := range d.queue {
+		d.mu.Lock()
+		_, pending := d.pending[item]
+		if pending {
+			item.inFlight = true
+		}
+		d.mu.Unlock()
+
+		// abandoned by an expired shutdown
+		if !pending || d.ctx.Err() != nil {
+			if item.results != nil {
+				item.results <- AsyncResult{Err: ErrDispatcherClosed}
+				close(item.results)
+			}
+			continue
+		}
+
+		ctx := withCorrelationId(d.ctx, item.summary.Key)
+		res, out, err := d.client.sendContext(ctx, item.n)
+
+		d.mu.Lock()
+		_, pending = d.pending[item]
+		delete(d.pending, item)
+		d.mu.Unlock()
+
+		if item.results != nil {
+			item.results <- asyncResult(out, res, err)
+			close(item.results)
+			continue
+		}
+
+		if pending && d.onResult != nil {
+			d.onResult(item.summary.Key, res, err)
+		}
+		if res != nil {
+			discard(res)
+		}
+	}
+}
+
+// Shutdown stops accepting notifications and waits for the queued ones to be sent. if ctx expires
+// first, requests still in flight are cancelled and a *ShutdownError listing every notification
+// not sent is returned. abandoned notifications are also archived, if an Archiver is configured
+func (d *Dispatcher) Shutdown(ctx context.Context) error {
+	d.mu.Lock()
+	first := !d.closed
+	d.closed = true
+	d.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		if first {
+			d.enqueuing.Wait()
+			close(d.queue)
+		}
+		d.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		d.cancel()
+		return nil
+	case <-ctx.Done():
+	}
+
+	// inFlight is written by workers under d.mu, so the summaries are taken under it too
+	d.mu.Lock()
+	abandoned := make([]*dispatchItem, 0, len(d.pending))
+	summaries := make(map[*dispatchItem]AbandonedNotification, len(d.pending))
+	for item := range d.pending {
+		abandoned = append(abandoned, item)
+		summary := item.summary
+		summary.InFlight = item.inFlight
+		summaries[item] = summary
+	}
+	d.pending = map[*dispatchItem]struct{}{}
+	d.mu.Unlock()
+	d.cancel()
+
+	sort.Slice(abandoned, func(i, j int) bool {
+		return abandoned[i].summary.EnqueuedAt.Before(abandoned[j].summary.EnqueuedAt)
+	})
+
+	err := &ShutdownError{}
+	for _, item := range abandoned {
+		err.Abandoned = append(err.Abandoned, summaries[item])
+
+		if d.client.archiver != nil {
+			// archived as it would have been sent, like the records of sends
+			if payload, _, encodeErr := d.client.sendPayload(item.n); encodeErr == nil {
+				d.client.archiveAbandoned(ctx, payload)
+			}
+		}
+	}
+	return err
+}
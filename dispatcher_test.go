@@ -0,0 +1,107 @@
+package engagespot
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDispatcherDrains(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	var mu sync.Mutex
+	results := map[string]error{}
+	c := clientFor(server.URL+"/v3/", time.Second)
+	d := c.NewDispatcher(4, 10, func(key string, res *http.Response, err error) {
+		mu.Lock()
+		defer mu.Unlock()
+		results[key] = err
+	})
+
+	var keys []string
+	for i := 0; i < 20; i++ {
+		key, err := d.Enqueue(newMessage(c))
+		assert.NoError(t, err)
+		keys = append(keys, key)
+	}
+
+	assert.NoError(t, d.Shutdown(context.Background()))
+	assert.Len(t, results, 20)
+	for _, key := range keys {
+		assert.Contains(t, results, key)
+		assert.NoError(t, results[key])
+	}
+
+	_, err := d.Enqueue(newMessage(c))
+	assert.ErrorIs(t, err, ErrDispatcherClosed)
+}
+
+func TestDispatcherShutdownAbandoned(t *testing.T) {
+	stalled := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-stalled:
+		case <-r.Context().Done():
+		}
+	}))
+	defer server.Close()
+	defer close(stalled)
+
+	a := &recordingArchiver{}
+	c := clientFor(server.URL+"/v3/", time.Minute)
+	WithArchiver(a, ArchiveSync)(c)
+
+	var resultsAfterShutdown int
+	d := c.NewDispatcher(1, 10, func(string, *http.Response, error) { resultsAfterShutdown++ })
+
+	var keys []string
+	for i, recipients := range [][]string{{"a"}, {"a", "b"}, {"a", "b", "c"}} {
+		n := newMessage(c, recipients...)
+		n.Notification.Title = []string{"one", "two", "three"}[i]
+		n.SetTTL(time.Hour)
+		key, err := d.Enqueue(n)
+		assert.NoError(t, err)
+		keys = append(keys, key)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	err := d.Shutdown(ctx)
+
+	var shutdownErr *ShutdownError
+	assert.True(t, errors.As(err, &shutdownErr))
+	assert.Len(t, shutdownErr.Abandoned, 3)
+	for i, abandoned := range shutdownErr.Abandoned {
+		assert.Equal(t, keys[i], abandoned.Key)
+		assert.Equal(t, []string{"one", "two", "three"}[i], abandoned.Title)
+		assert.Equal(t, i+1, abandoned.Recipients)
+		assert.False(t, abandoned.EnqueuedAt.IsZero())
+	}
+	assert.True(t, shutdownErr.Abandoned[0].InFlight)
+	assert.False(t, shutdownErr.Abandoned[1].InFlight)
+	assert.False(t, shutdownErr.Abandoned[2].InFlight)
+	assert.Equal(t, "engagespot: dispatcher shut down with 3 abandoned notifications", err.Error())
+
+	abandonedRecords := 0
+	for _, r := range a.recorded() {
+		if r.Outcome == OutcomeAbandoned {
+			abandonedRecords++
+			// archived as it would have been sent, with the expiry time of its ttl
+			var payload map[string]interface{}
+			assert.NoError(t, json.Unmarshal(r.Payload, &payload))
+			assert.Contains(t, payload, "expiresAt")
+		}
+	}
+	assert.Equal(t, 3, abandonedRecords)
+	assert.Equal(t, 0, resultsAfterShutdown)
+}
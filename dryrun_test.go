@@ -0,0 +1,122 @@
+package engagespot
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func campaign(c *Client) *Notification {
+	n, _ := c.NewNotification("Spring sale")
+	n.Message("20% off everything").Url("https://example.com/sale").Icon("https://example.com/icon.png")
+	n.AddRecipientWithData("ada", map[string]interface{}{"firstName": "Ada"})
+	n.AddRecipient("grace")
+	n.AddData("campaign", "spring")
+	n.SetChannels(ChannelInApp, ChannelEmail)
+	n.AddAction("Shop", "https://example.com/sale/shop")
+	return n
+}
+
+func TestDryRunMatchesLiveBody(t *testing.T) {
+	var live []byte
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		live, _ = io.ReadAll(r.Body)
+		w.Write([]byte(`{"id":"n1"}`))
+	}))
+	defer server.Close()
+	c := clientFor(server.URL+"/v3/", time.Second)
+
+	dry, _, err := campaign(c).DryRun()
+	assert.NoError(t, err)
+	_, err = campaign(c).SendCtx(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, string(live), string(dry))
+}
+
+func TestDryRunValidates(t *testing.T) {
+	c := NewEngagespotClient("A", "B")
+	c.httpClient.Transport = noNetwork(t)
+
+	n, _ := c.NewNotification("Hello")
+	_, _, err := n.DryRun()
+	assert.EqualError(t, err, "not enough recipients")
+
+	n.Url("htps://example.com").Recipient("a")
+	_, _, err = n.DryRun()
+	assert.Contains(t, err.Error(), `invalid url "htps://example.com"`)
+
+	n, _ = c.NewNotification("Hello")
+	n.SetRecipientSource(sliceSource([]string{"a"}, -1, nil))
+	_, _, err = n.DryRun()
+	assert.EqualError(t, err, "dry run of a notification with a recipient source")
+}
+
+func TestWithDryRun(t *testing.T) {
+	c := NewEngagespotClient("A", "B", WithDryRun(), WithAutoIdempotency())
+	c.httpClient.Transport = noNetwork(t)
+
+	res, err := campaign(c).SendCtx(context.Background())
+	assert.NoError(t, err)
+	assert.True(t, res.DryRun)
+	assert.True(t, res.Success)
+	assert.Equal(t, StateUnknown, res.State)
+	assert.NotEmpty(t, res.IdempotencyKey)
+	assert.Equal(t, []string{"ada", "grace"}, res.Recipients)
+
+	// validation still applies
+	n, _ := c.NewNotification("Hello")
+	_, err = n.SendCtx(context.Background())
+	assert.EqualError(t, err, "not enough recipients")
+}
+
+func TestLiveSendNotDryRun(t *testing.T) {
+	c := NewEngagespotClient("A", "B")
+	c.httpClient.Transport = respondWith(200, `{}`)
+
+	res, err := campaign(c).SendCtx(context.Background())
+	assert.NoError(t, err)
+	assert.False(t, res.DryRun)
+}
+
+func TestDryRunWarningsWithoutSideEffects(t *testing.T) {
+	c := NewEngagespotClient("A", "B", WithSchemaFingerprinting(NewMemoryFingerprintStore()))
+	c.httpClient.Transport = respondWith(200, `{}`)
+	order := func(total interface{}) *Notification {
+		n := newMessage(c)
+		n.SetCategory("orders")
+		n.AddData("total", total)
+		return n
+	}
+
+	_, err := order(42).SendCtx(context.Background())
+	assert.NoError(t, err)
+
+	_, warnings, err := order("42").DryRun()
+	assert.NoError(t, err)
+	drift := Warning{Field: "data", Message: `data schema of category "orders" changed: total changed from number to string`}
+	assert.Contains(t, warnings, drift)
+
+	// the dry run recorded nothing, the send after it still gets the drift warning
+	n := order("42")
+	_, err = n.SendCtx(context.Background())
+	assert.NoError(t, err)
+	assert.Contains(t, n.Warnings(), drift)
+
+	// nor is the category of a dry run taken as seen
+	categoryWarning := Warning{Field: "category", Message: `category "promo" not seen before, it will be created`}
+	for i := 0; i < 2; i++ {
+		n = newMessage(c)
+		n.SetCategory("promo")
+		_, warnings, err = n.DryRun()
+		assert.NoError(t, err)
+		assert.Contains(t, warnings, categoryWarning)
+	}
+	_, err = n.SendCtx(context.Background())
+	assert.NoError(t, err)
+	assert.Contains(t, n.Warnings(), categoryWarning)
+}
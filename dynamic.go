@@ -0,0 +1,137 @@
+package engagespot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+)
+
+// settings of a client which can be changed while it's in use, see ApplyDynamicConfig. a call
+// loads them once and uses the same snapshot for every attempt, so a change never applies halfway
+// through a call
+type liveSettings struct {
+	limiter    *rateLimiter
+	retry      RetryPolicy
+	sampleRate float64
+
+	// set by the deprecated EnableHmac and by Use, which may be called on a client in use
+	hmac       bool
+	middleware []Middleware
+}
+
+// current settings of the client
+func (c *Client) settings() *liveSettings {
+	return c.live.Load()
+}
+
+// settings changed by options while the client is being built, before it is shared
+func (c *Client) initialSettings() *liveSettings {
+	s := c.live.Load()
+	if s == nil {
+		s = &liveSettings{}
+		c.live.Store(s)
+	}
+	return s
+}
+
+// RateLimit is the rate limit of a DynamicConfig, as set with WithRateLimit. an RPS of 0 removes
+// the rate limit
+type RateLimit struct {
+	RPS   float64
+	Burst int
+}
+
+// DynamicConfig holds the settings which can be changed on a live client with ApplyDynamicConfig.
+// nil fields are left as they are. the credentials and the endpoint can only be set to their
+// current value, so a config holding every setting of a client can be applied as is
+type DynamicConfig struct {
+	RateLimit *RateLimit
+	Retry     *RetryPolicy
+	// fraction of calls captured, see WithDebugSampling
+	DebugSampleRate *float64
+
+	// not hot-swappable, create a new client to change these
+	APIKey    string
+	APISecret string
+	Endpoint  string
+}
+
+// ApplyDynamicConfig changes the settings of cfg while the client is in use. calls already in
+// flight carry on with the settings they started with. nothing is changed if cfg is rejected, which
+// it is if it changes a setting which can't be hot-swapped, or holds an invalid value
+func (c *Client) ApplyDynamicConfig(cfg DynamicConfig) error {
+	var frozen []string
+	if cfg.APIKey != "" && cfg.APIKey != c.apiKey {
+		frozen = append(frozen, "api key")
+	}
+	if cfg.APISecret != "" && cfg.APISecret != c.apiSecret {
+		frozen = append(frozen, "api secret")
+	}
+	if cfg.Endpoint != "" && cfg.Endpoint != c.defaults.Endpoint {
+		frozen = append(frozen, "endpoint")
+	}
+	if len(frozen) > 0 {
+		return fmt.Errorf("engagespot: dynamic config: %s can't be changed on a live client, create a new one instead", strings.Join(frozen, ", "))
+	}
+	if r := cfg.RateLimit; r != nil && (r.RPS < 0 || math.IsNaN(r.RPS) || math.IsInf(r.RPS, 0)) {
+		return fmt.Errorf("engagespot: dynamic config: invalid rate limit %v", r.RPS)
+	}
+	if r := cfg.Retry; r != nil && (r.MaxAttempts < 1 || r.BaseDelay < 0) {
+		return errors.New("engagespot: dynamic config: retry policy needs at least 1 attempt and a non-negative delay")
+	}
+	if rate := cfg.DebugSampleRate; rate != nil && (*rate < 0 || *rate > 1 || math.IsNaN(*rate)) {
+		return fmt.Errorf("engagespot: dynamic config: debug sample rate %v is not between 0 and 1", *rate)
+	}
+
+	c.liveMu.Lock()
+	defer c.liveMu.Unlock()
+	next := *c.settings()
+	if r := cfg.RateLimit; r != nil {
+		next.limiter = next.limiter.resized(r.RPS, r.Burst)
+	}
+	if cfg.Retry != nil {
+		next.retry = *cfg.Retry
+	}
+	if cfg.DebugSampleRate != nil {
+		next.sampleRate = *cfg.DebugSampleRate
+	}
+	c.live.Store(&next)
+	return nil
+}
+
+// WithConfigErrorHandler sets the function told about configs rejected by WatchConfig. they are
+// logged if there is no handler
+func WithConfigErrorHandler(handler func(DynamicConfig, error)) Option {
+	return func(c *Client) {
+		c.configErrorHandler = handler
+	}
+}
+
+// WatchConfig applies every config received from source, until source is closed or ctx is done.
+// configs which are rejected are reported to the handler set with WithConfigErrorHandler and the
+// client keeps its previous settings
+func (c *Client) WatchConfig(ctx context.Context, source <-chan DynamicConfig) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case cfg, ok := <-source:
+			if !ok {
+				return
+			}
+			if err := c.ApplyDynamicConfig(cfg); err != nil {
+				c.configRejected(cfg, err)
+			}
+		}
+	}
+}
+
+func (c *Client) configRejected(cfg DynamicConfig, err error) {
+	if c.configErrorHandler != nil {
+		c.configErrorHandler(cfg, err)
+		return
+	}
+	c.log().Warn("engagespot: dynamic config rejected", "error", err)
+}
@@ -0,0 +1,198 @@
+package engagespot
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// transport answering 200 and recording the recipients of every request
+type recipientRecorder struct {
+	mu   sync.Mutex
+	seen []string
+}
+
+func (r *recipientRecorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	body, _ := io.ReadAll(req.Body)
+	var payload struct {
+		Recipients []string `json:"recipients"`
+	}
+	json.Unmarshal(body, &payload)
+	r.mu.Lock()
+	r.seen = append(r.seen, payload.Recipients...)
+	r.mu.Unlock()
+	return respondWith(200, `{}`).RoundTrip(req)
+}
+
+func TestDynamicRateLimitMidFlood(t *testing.T) {
+	clock := newFakeClock()
+	var waits []time.Duration
+	c := NewEngagespotClient("A", "B", WithRateLimit(10, 1))
+	c.now = clock.Now
+	c.sleep = func(ctx context.Context, d time.Duration) error {
+		waits = append(waits, d)
+		clock.Advance(d)
+		return nil
+	}
+	recorder := &recipientRecorder{}
+	c.httpClient.Transport = recorder
+
+	ids := recipientIds(10)
+	for _, id := range ids[:5] {
+		assert.NoError(t, sendTo(c, id))
+	}
+	assert.NoError(t, c.ApplyDynamicConfig(DynamicConfig{RateLimit: &RateLimit{RPS: 2, Burst: 1}}))
+	assert.Equal(t, 2.0, c.EffectiveRateLimit())
+	for _, id := range ids[5:] {
+		assert.NoError(t, sendTo(c, id))
+	}
+
+	// the debt left by the old limit is paid at the new rate, then every send waits 1/2s
+	ms := time.Millisecond
+	assert.Equal(t, []time.Duration{100 * ms, 100 * ms, 100 * ms, 100 * ms, 900 * ms, 500 * ms, 500 * ms, 500 * ms, 500 * ms}, waits)
+	assert.Equal(t, ids, recorder.seen)
+}
+
+func TestDynamicRateLimitConcurrent(t *testing.T) {
+	c := NewEngagespotClient("A", "B", WithRateLimit(5000, 20))
+	recorder := &recipientRecorder{}
+	c.httpClient.Transport = recorder
+
+	ids := recipientIds(200)
+	var wg sync.WaitGroup
+	for w := 0; w < 10; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for i := w; i < len(ids); i += 10 {
+				assert.NoError(t, sendTo(c, ids[i]))
+			}
+		}(w)
+	}
+	for _, rps := range []float64{2000, 0, 8000, 3000} {
+		assert.NoError(t, c.ApplyDynamicConfig(DynamicConfig{RateLimit: &RateLimit{RPS: rps, Burst: 5}}))
+		time.Sleep(5 * time.Millisecond)
+	}
+	wg.Wait()
+
+	// every send went out exactly once
+	assert.ElementsMatch(t, ids, recorder.seen)
+	assert.Equal(t, 3000.0, c.EffectiveRateLimit())
+}
+
+func TestDynamicRateLimitRemoved(t *testing.T) {
+	c := NewEngagespotClient("A", "B", WithRateLimit(10, 1))
+	assert.NoError(t, c.ApplyDynamicConfig(DynamicConfig{RateLimit: &RateLimit{}}))
+	assert.Zero(t, c.EffectiveRateLimit())
+
+	assert.NoError(t, c.ApplyDynamicConfig(DynamicConfig{RateLimit: &RateLimit{RPS: 4}}))
+	assert.Equal(t, 4.0, c.EffectiveRateLimit())
+}
+
+func TestDynamicRetryPolicy(t *testing.T) {
+	var waits []time.Duration
+	var bodies []string
+	c := retryingClient(newFakeClock(), &waits)
+	c.httpClient.Transport = flaky(10, 503, &bodies)
+
+	assert.NoError(t, c.ApplyDynamicConfig(DynamicConfig{Retry: &RetryPolicy{MaxAttempts: 2, BaseDelay: time.Millisecond}}))
+	newMessage(c).SendRaw()
+	assert.Len(t, bodies, 2)
+}
+
+func TestDynamicConfigSnapshotPerCall(t *testing.T) {
+	var waits []time.Duration
+	c := retryingClient(newFakeClock(), &waits)
+	attempts := 0
+	c.httpClient.Transport = roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		attempts++
+		// a change made during the call only applies to the next one
+		c.ApplyDynamicConfig(DynamicConfig{Retry: &RetryPolicy{MaxAttempts: 1}})
+		return respondWith(503, `{}`).RoundTrip(req)
+	})
+
+	newMessage(c).SendRaw()
+	assert.Equal(t, testRetryPolicy.MaxAttempts, attempts)
+
+	attempts = 0
+	newMessage(c).SendRaw()
+	assert.Equal(t, 1, attempts)
+}
+
+func TestDynamicDebugSampling(t *testing.T) {
+	var samples []Sample
+	c := NewEngagespotClient("A", "B", WithDebugSampleSink(func(s Sample) { samples = append(samples, s) }))
+	c.httpClient.Transport = respondWith(200, `{}`)
+
+	newMessage(c).Send()
+	assert.Empty(t, samples)
+
+	rate := 1.0
+	assert.NoError(t, c.ApplyDynamicConfig(DynamicConfig{DebugSampleRate: &rate}))
+	newMessage(c).Send()
+	assert.Len(t, samples, 1)
+}
+
+func TestDynamicConfigRejected(t *testing.T) {
+	c := NewEngagespotClient("A", "B", WithRateLimit(10, 1))
+	before := c.settings()
+
+	err := c.ApplyDynamicConfig(DynamicConfig{APIKey: "other", Endpoint: "https://example.com/", RateLimit: &RateLimit{RPS: 1}})
+	assert.EqualError(t, err, "engagespot: dynamic config: api key, endpoint can't be changed on a live client, create a new one instead")
+
+	negative := -0.5
+	for _, cfg := range []DynamicConfig{
+		{RateLimit: &RateLimit{RPS: -1}},
+		{Retry: &RetryPolicy{MaxAttempts: 0}},
+		{Retry: &RetryPolicy{MaxAttempts: 2, BaseDelay: -time.Second}},
+		{DebugSampleRate: &negative},
+	} {
+		assert.Error(t, c.ApplyDynamicConfig(cfg))
+	}
+	assert.Same(t, before, c.settings())
+
+	// the current credentials and endpoint are accepted
+	assert.NoError(t, c.ApplyDynamicConfig(DynamicConfig{APIKey: "A", APISecret: "B", Endpoint: defaultEndpoint}))
+}
+
+func TestWatchConfig(t *testing.T) {
+	var rejected []error
+	c := NewEngagespotClient("A", "B", WithConfigErrorHandler(func(cfg DynamicConfig, err error) {
+		rejected = append(rejected, err)
+	}))
+
+	source := make(chan DynamicConfig, 3)
+	source <- DynamicConfig{RateLimit: &RateLimit{RPS: 5}}
+	source <- DynamicConfig{APISecret: "rotated"}
+	source <- DynamicConfig{Retry: &RetryPolicy{MaxAttempts: 3}}
+	close(source)
+	c.WatchConfig(context.Background(), source)
+
+	assert.Equal(t, 5.0, c.EffectiveRateLimit())
+	assert.Equal(t, 3, c.settings().retry.MaxAttempts)
+	assert.Len(t, rejected, 1)
+	assert.Contains(t, rejected[0].Error(), "api secret")
+}
+
+func TestWatchConfigStopsOnCancel(t *testing.T) {
+	c := NewEngagespotClient("A", "B")
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		c.WatchConfig(ctx, make(chan DynamicConfig))
+		close(done)
+	}()
+	cancel()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("WatchConfig didn't return after cancel")
+	}
+}
@@ -0,0 +1,83 @@
+package engagespot
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// key of the Sendgrid override read by Engagespot itself, every other key is passed to the
+// Sendgrid mail send API
+const sendgridConfigKey = "_config"
+
+// SetSendgridConfig can be used to override the Sendgrid configuration of the dashboard, like the
+// template id, for this notification. it is sent as the _config object of the Sendgrid override
+func (n *Notification) SetSendgridConfig(config map[string]interface{}) (*Notification, error) {
+	if len(config) == 0 {
+		return nil, invalidField("sendgrid", config, errors.New("empty sendgrid config"))
+	}
+	n.setSendgridKey(sendgridConfigKey, config)
+	return n, nil
+}
+
+// SetSendgridField can be used to set a property of the Sendgrid mail send API, like
+// personalizations or dynamic template data, for this notification. see SetSendgridConfig for
+// _config
+func (n *Notification) SetSendgridField(key string, value interface{}) (*Notification, error) {
+	if key == "" {
+		return nil, invalidField("sendgrid", key, errors.New("empty sendgrid field string"))
+	}
+	if key == sendgridConfigKey {
+		return nil, invalidField("sendgrid", key, errors.New("_config must be set with SetSendgridConfig"))
+	}
+	n.setSendgridKey(key, value)
+	return n, nil
+}
+
+// set a single key of the Sendgrid override, keeping every other key, like attachments
+func (n *Notification) setSendgridKey(key string, value interface{}) {
+	if n.Override.SendgridEmail == nil {
+		n.Override.SendgridEmail = map[string]interface{}{}
+	}
+	n.Override.SendgridEmail[key] = value
+}
+
+// SmtpOverride holds what can be overridden of the SMTP provider configuration of the dashboard
+// for a single notification. empty fields keep the dashboard value
+type SmtpOverride struct {
+	// bare email address the email is sent from
+	FromEmail string `json:"from_email,omitempty"`
+	FromName  string `json:"from_name,omitempty"`
+	Subject   string `json:"subject,omitempty"`
+	// body of the email, replacing the one rendered from the template
+	Html string `json:"html,omitempty"`
+	Text string `json:"text,omitempty"`
+}
+
+// SetSmtpOverride can be used to override the SMTP provider configuration for this notification.
+// the fields set in o are merged into the SMTP override, so attachments and fields set by a
+// previous call are kept
+func (n *Notification) SetSmtpOverride(o SmtpOverride) (*Notification, error) {
+	if o == (SmtpOverride{}) {
+		return nil, invalidField("smtp", o, errors.New("empty smtp override"))
+	}
+	if o.FromEmail != "" && !isEmailAddress(o.FromEmail) {
+		return nil, invalidField("smtp", o.FromEmail, fmt.Errorf("invalid smtp from email %q", o.FromEmail))
+	}
+
+	b, err := json.Marshal(o)
+	if err != nil {
+		return nil, err
+	}
+	var fields map[string]interface{}
+	if err := json.Unmarshal(b, &fields); err != nil {
+		return nil, err
+	}
+	if n.Override.SmtpEmail == nil {
+		n.Override.SmtpEmail = map[string]interface{}{}
+	}
+	for key, value := range fields {
+		n.Override.SmtpEmail[key] = value
+	}
+	return n, nil
+}
@@ -0,0 +1,111 @@
+package engagespot
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSendgridOverride(t *testing.T) {
+	n := newTestNotification(t)
+	n.AddRecipient("a")
+	_, err := n.SetSendgridConfig(map[string]interface{}{"templateId": "d-123"})
+	assert.NoError(t, err)
+	_, err = n.SetSendgridField("personalizations", []interface{}{
+		map[string]interface{}{"dynamic_template_data": map[string]interface{}{"orderId": 42}},
+	})
+	assert.NoError(t, err)
+	_, err = n.SetSendgridField("subject", "Your invoice")
+	assert.NoError(t, err)
+
+	assert.Equal(t, map[string]interface{}{
+		"sendgrid_email": map[string]interface{}{
+			"_config":          map[string]interface{}{"templateId": "d-123"},
+			"personalizations": []interface{}{map[string]interface{}{"dynamic_template_data": map[string]interface{}{"orderId": 42.0}}},
+			"subject":          "Your invoice",
+		},
+	}, encodedOverride(t, n))
+}
+
+func TestSendgridOverrideKeepsAttachments(t *testing.T) {
+	n := newTestNotification(t)
+	n.AddEmailAttachment(EmailAttachment{Filename: "a.txt", Content: []byte("a")})
+	n.SetSendgridConfig(map[string]interface{}{"templateId": "d-123"})
+
+	sendgrid := encodedOverride(t, n)["sendgrid_email"].(map[string]interface{})
+	assert.Contains(t, sendgrid, "attachments")
+	assert.Contains(t, sendgrid, "_config")
+}
+
+func TestSendgridOverrideInvalid(t *testing.T) {
+	n := newTestNotification(t)
+	_, err := n.SetSendgridConfig(nil)
+	assert.EqualError(t, err, "empty sendgrid config")
+	_, err = n.SetSendgridField("", 1)
+	assert.EqualError(t, err, "empty sendgrid field string")
+	_, err = n.SetSendgridField("_config", map[string]interface{}{})
+	assert.EqualError(t, err, "_config must be set with SetSendgridConfig")
+	assert.Nil(t, n.Override.SendgridEmail)
+}
+
+// smtp override of the encoded body of n, decoded back into SmtpOverride
+func decodedSmtpOverride(t *testing.T, n *Notification) SmtpOverride {
+	var payload struct {
+		Override struct {
+			SmtpEmail SmtpOverride `json:"smtp_email"`
+		} `json:"override"`
+	}
+	b, err := json.Marshal(n)
+	assert.NoError(t, err)
+	assert.NoError(t, json.Unmarshal(b, &payload))
+	return payload.Override.SmtpEmail
+}
+
+func TestSmtpOverrideRoundTrip(t *testing.T) {
+	for _, o := range []SmtpOverride{
+		{FromEmail: "billing@example.com", FromName: "Billing", Subject: "Your invoice", Html: "<p>Hi</p>", Text: "Hi"},
+		{Subject: "Only the subject"},
+		{FromEmail: "billing@example.com"},
+	} {
+		n := newTestNotification(t)
+		n.AddRecipient("a")
+		_, err := n.SetSmtpOverride(o)
+		assert.NoError(t, err)
+		assert.Equal(t, o, decodedSmtpOverride(t, n))
+	}
+}
+
+func TestSmtpOverrideEncoding(t *testing.T) {
+	n := newTestNotification(t)
+	n.AddRecipient("a")
+	n.SetSmtpOverride(SmtpOverride{FromEmail: "billing@example.com", Subject: "Your invoice"})
+	assert.Equal(t, map[string]interface{}{
+		"smtp_email": map[string]interface{}{"from_email": "billing@example.com", "subject": "Your invoice"},
+	}, encodedOverride(t, n))
+}
+
+func TestSmtpOverrideMerges(t *testing.T) {
+	n := newTestNotification(t)
+	n.AddEmailAttachment(EmailAttachment{Filename: "a.txt", Url: "https://example.com/a.txt"})
+	n.SetSmtpOverride(SmtpOverride{Subject: "First"})
+	n.SetSmtpOverride(SmtpOverride{FromName: "Billing", Subject: "Second"})
+
+	smtp := encodedOverride(t, n)["smtp_email"].(map[string]interface{})
+	assert.Contains(t, smtp, "attachments")
+	assert.Equal(t, "Billing", smtp["from_name"])
+	assert.Equal(t, "Second", smtp["subject"])
+}
+
+func TestSmtpOverrideInvalid(t *testing.T) {
+	n := newTestNotification(t)
+	_, err := n.SetSmtpOverride(SmtpOverride{})
+	assert.EqualError(t, err, "empty smtp override")
+	_, err = n.SetSmtpOverride(SmtpOverride{FromEmail: "Billing <billing@example.com>"})
+	assert.EqualError(t, err, `invalid smtp from email "Billing <billing@example.com>"`)
+	assert.Nil(t, n.Override.SmtpEmail)
+
+	// the dashboard config keeps applying while nothing is overridden
+	n.AddRecipient("a")
+	assert.NotContains(t, encodedPayload(t, n), "override")
+}
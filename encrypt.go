@@ -0,0 +1,161 @@
+package engagespot
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// FieldEncryptor encrypts fields of payloads persisted by the client, see WithArchiveEncryption.
+// implementations must be safe for concurrent use
+type FieldEncryptor interface {
+	Encrypt(plaintext []byte) ([]byte, error)
+	Decrypt(ciphertext []byte) ([]byte, error)
+}
+
+// DecryptionError is returned when an encrypted field can't be decrypted, most likely because it
+// was tampered with or encrypted with another key
+type DecryptionError struct {
+	Field string
+	Err   error
+}
+
+func (e *DecryptionError) Error() string {
+	return "engagespot: decrypt " + e.Field + ": " + e.Err.Error()
+}
+
+func (e *DecryptionError) Unwrap() error {
+	return e.Err
+}
+
+// fields holding PII, encrypted in persisted payloads. both the notification and the workflow
+// trigger forms are covered
+var encryptedFields = []string{"recipients", "notification.data", "sendTo", "data"}
+
+// key of the object replacing an encrypted field in a persisted payload
+const encryptedMarker = "$encrypted"
+
+type aesGCM struct {
+	aead cipher.AEAD
+}
+
+// NewAESGCMEncryptor returns a FieldEncryptor using AES-GCM with key, which must be 16, 24 or 32
+// bytes long. a random nonce is generated for every field and prepended to its ciphertext
+func NewAESGCMEncryptor(key []byte) (FieldEncryptor, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	return &aesGCM{aead: aead}, nil
+}
+
+func (e *aesGCM) Encrypt(plaintext []byte) ([]byte, error) {
+	nonce := make([]byte, e.aead.NonceSize(), e.aead.NonceSize()+len(plaintext)+e.aead.Overhead())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	return e.aead.Seal(nonce, nonce, plaintext, nil), nil
+}
+
+func (e *aesGCM) Decrypt(ciphertext []byte) ([]byte, error) {
+	if len(ciphertext) < e.aead.NonceSize() {
+		return nil, errors.New("ciphertext too short")
+	}
+	nonce, sealed := ciphertext[:e.aead.NonceSize()], ciphertext[e.aead.NonceSize():]
+	return e.aead.Open(nil, nonce, sealed, nil)
+}
+
+// WithArchiveEncryption encrypts recipients and the data payload of archived notifications with
+// enc, so the archive holds no PII in clear. requests sent to the API are unchanged. archived
+// payloads can be restored with DecryptArchivePayload, to replay them for example
+func WithArchiveEncryption(enc FieldEncryptor) Option {
+	return func(c *Client) {
+		c.declare(optArchiveEncryption)
+		c.archiveEncryptor = enc
+	}
+}
+
+// replace every encrypted field of payload with its ciphertext. payloads which are not objects
+// are left as is
+func encryptFields(payload []byte, enc FieldEncryptor) ([]byte, error) {
+	var m map[string]interface{}
+	if err := json.Unmarshal(payload, &m); err != nil {
+		return payload, nil
+	}
+
+	for _, field := range encryptedFields {
+		parent, key := lookupPath(m, strings.Split(field, "."))
+		value, ok := parent[key]
+		if !ok {
+			continue
+		}
+		plaintext, err := json.Marshal(value)
+		if err != nil {
+			return nil, err
+		}
+		ciphertext, err := enc.Encrypt(plaintext)
+		if err != nil {
+			return nil, fmt.Errorf("encrypt %s: %w", field, err)
+		}
+		parent[key] = map[string]interface{}{encryptedMarker: base64.StdEncoding.EncodeToString(ciphertext)}
+	}
+	return json.Marshal(m)
+}
+
+// DecryptArchivePayload restores the payload of an archive record encrypted by
+// WithArchiveEncryption. a field which fails to decrypt is reported with a DecryptionError
+func DecryptArchivePayload(payload []byte, enc FieldEncryptor) ([]byte, error) {
+	var m map[string]interface{}
+	if err := json.Unmarshal(payload, &m); err != nil {
+		return nil, err
+	}
+
+	for _, field := range encryptedFields {
+		parent, key := lookupPath(m, strings.Split(field, "."))
+		sealed, ok := parent[key].(map[string]interface{})
+		if !ok {
+			continue
+		}
+		encoded, ok := sealed[encryptedMarker].(string)
+		if !ok {
+			continue
+		}
+
+		ciphertext, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			return nil, &DecryptionError{Field: field, Err: err}
+		}
+		plaintext, err := enc.Decrypt(ciphertext)
+		if err != nil {
+			return nil, &DecryptionError{Field: field, Err: err}
+		}
+		var value interface{}
+		if err := json.Unmarshal(plaintext, &value); err != nil {
+			return nil, &DecryptionError{Field: field, Err: err}
+		}
+		parent[key] = value
+	}
+	return json.Marshal(m)
+}
+
+// object holding the last segment of a dotted path, and that segment. the object is nil if a
+// parent is missing
+func lookupPath(m map[string]interface{}, path []string) (map[string]interface{}, string) {
+	for len(path) > 1 {
+		child, ok := m[path[0]].(map[string]interface{})
+		if !ok {
+			return nil, ""
+		}
+		m, path = child, path[1:]
+	}
+	return m, path[0]
+}
@@ -0,0 +1,137 @@
+package engagespot
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// archiver appending records to a file, one json document per line
+type fileArchiver struct {
+	mu   sync.Mutex
+	path string
+}
+
+func (a *fileArchiver) Archive(ctx context.Context, record ArchiveRecord) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	f, err := os.OpenFile(a.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return json.NewEncoder(f).Encode(record)
+}
+
+func (a *fileArchiver) read(t *testing.T) []ArchiveRecord {
+	f, err := os.Open(a.path)
+	assert.NoError(t, err)
+	defer f.Close()
+
+	var records []ArchiveRecord
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var r ArchiveRecord
+		assert.NoError(t, json.Unmarshal(scanner.Bytes(), &r))
+		records = append(records, r)
+	}
+	return records
+}
+
+func encryptedArchiveClient(t *testing.T, wire *[]capturedRequest) (*Client, *fileArchiver, FieldEncryptor) {
+	enc, err := NewAESGCMEncryptor(bytes.Repeat([]byte{7}, 32))
+	assert.NoError(t, err)
+	a := &fileArchiver{path: filepath.Join(t.TempDir(), "archive.jsonl")}
+	c := NewEngagespotClient("A", "B", WithArchiver(a, ArchiveSync), WithArchiveEncryption(enc))
+	c.httpClient.Transport = captureRequests(wire)
+	return c, a, enc
+}
+
+func sendSensitive(t *testing.T, c *Client) {
+	n, _ := c.NewNotification("Lab results")
+	n.AddRecipient("patient@example.com")
+	n.Notification.Data = map[string]interface{}{"diagnosis": "confidential"}
+	_, err := n.Send()
+	assert.NoError(t, err)
+}
+
+func TestArchiveEncryptionRoundTrip(t *testing.T) {
+	var wire []capturedRequest
+	c, a, enc := encryptedArchiveClient(t, &wire)
+	sendSensitive(t, c)
+
+	records := a.read(t)
+	assert.Len(t, records, 1)
+
+	restored, err := DecryptArchivePayload(records[0].Payload, enc)
+	assert.NoError(t, err)
+	assert.JSONEq(t, string(wire[0].body), string(restored))
+
+	// the wire payload stays in clear
+	assert.Contains(t, string(wire[0].body), "patient@example.com")
+	assert.Contains(t, string(wire[0].body), "confidential")
+}
+
+func TestArchiveEncryptionNoPlaintextOnDisk(t *testing.T) {
+	var wire []capturedRequest
+	c, a, _ := encryptedArchiveClient(t, &wire)
+	sendSensitive(t, c)
+
+	onDisk, err := os.ReadFile(a.path)
+	assert.NoError(t, err)
+	for _, secret := range []string{"patient@example.com", "confidential", "diagnosis"} {
+		assert.NotContains(t, string(onDisk), secret)
+	}
+
+	// the payload is base64 encoded on disk, check the decoded form too
+	payload := string(a.read(t)[0].Payload)
+	assert.Contains(t, payload, "Lab results")
+	assert.NotContains(t, payload, "patient@example.com")
+	assert.NotContains(t, payload, "confidential")
+}
+
+func TestArchiveEncryptionTamperDetected(t *testing.T) {
+	var wire []capturedRequest
+	c, a, enc := encryptedArchiveClient(t, &wire)
+	sendSensitive(t, c)
+
+	var payload map[string]interface{}
+	assert.NoError(t, json.Unmarshal(a.read(t)[0].Payload, &payload))
+	sealed := payload["recipients"].(map[string]interface{})
+	encoded := []byte(sealed[encryptedMarker].(string))
+	if i := len(encoded) / 2; encoded[i] == 'A' {
+		encoded[i] = 'B'
+	} else {
+		encoded[i] = 'A'
+	}
+	sealed[encryptedMarker] = string(encoded)
+	tampered, _ := json.Marshal(payload)
+
+	_, err := DecryptArchivePayload(tampered, enc)
+	var decryptErr *DecryptionError
+	assert.True(t, errors.As(err, &decryptErr))
+	assert.Equal(t, "recipients", decryptErr.Field)
+}
+
+func TestAESGCMEncryptorKey(t *testing.T) {
+	_, err := NewAESGCMEncryptor([]byte("short"))
+	assert.Error(t, err)
+
+	enc, _ := NewAESGCMEncryptor(bytes.Repeat([]byte{1}, 16))
+	other, _ := NewAESGCMEncryptor(bytes.Repeat([]byte{2}, 16))
+	sealed, err := enc.Encrypt([]byte("hello"))
+	assert.NoError(t, err)
+	_, err = other.Decrypt(sealed)
+	assert.Error(t, err)
+	opened, err := enc.Decrypt(sealed)
+	assert.NoError(t, err)
+	assert.Equal(t, "hello", string(opened))
+}
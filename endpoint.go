@@ -0,0 +1,122 @@
+package engagespot
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// ErrReadOnlyClient is returned by every mutating call of a client created with WithReadOnly
+var ErrReadOnlyClient = errors.New("read-only client")
+
+// an API operation, as the name of the SDK method using it, a method and a path relative to the
+// configured endpoint. userScope tells if the API accepts user level auth, see AsUser
+type endpoint struct {
+	op        string
+	method    string
+	path      string
+	userScope bool
+}
+
+// endpoint table. every call to the API should go through one of these so that behaviour
+// depending on the kind of operation (like read-only mode) is decided in a single place
+var (
+	endpointSendNotification      = endpoint{op: "Send", method: http.MethodPost, path: "notifications"}
+	endpointTriggerWorkflow       = endpoint{op: "Send", method: http.MethodPost, path: "workflows/trigger"}
+	endpointConnect               = endpoint{op: "Connect", method: http.MethodPost, path: "sdk/connect", userScope: true}
+	endpointGetNotificationStatus = endpoint{op: "GetNotificationStatus", method: http.MethodGet, path: "notifications/{notificationId}"}
+	endpointGetAppInfo            = endpoint{op: "GetAppInfo", method: http.MethodGet, path: "app"}
+	endpointGetPreferences        = endpoint{op: "GetPreferences", method: http.MethodGet, path: "users/{userId}/preferences", userScope: true}
+	endpointUpsertUser            = endpoint{op: "CreateOrUpdateUser", method: http.MethodPut, path: "users/{userId}"}
+	endpointGetUser               = endpoint{op: "GetUser", method: http.MethodGet, path: "users/{userId}"}
+	endpointDeleteUser            = endpoint{op: "DeleteUser", method: http.MethodDelete, path: "users/{userId}"}
+	endpointListDevices           = endpoint{op: "ListDevices", method: http.MethodGet, path: "users/{userId}/devices", userScope: true}
+	endpointRegisterDevice        = endpoint{op: "RegisterDeviceToken", method: http.MethodPost, path: "users/{userId}/devices", userScope: true}
+	endpointRevokeDevice          = endpoint{op: "RevokeDevice", method: http.MethodDelete, path: "users/{userId}/devices/{deviceId}", userScope: true}
+	endpointListNotifications     = endpoint{op: "ListNotifications", method: http.MethodGet, path: "users/{userId}/notifications", userScope: true}
+	endpointMarkAsRead            = endpoint{op: "MarkAsRead", method: http.MethodPost, path: "users/{userId}/notifications/{notificationId}/read", userScope: true}
+	endpointMarkAllAsRead         = endpoint{op: "MarkAllAsRead", method: http.MethodPost, path: "users/{userId}/notifications/read", userScope: true}
+	endpointDeleteNotification    = endpoint{op: "DeleteNotification", method: http.MethodDelete, path: "users/{userId}/notifications/{notificationId}", userScope: true}
+	endpointGetUnreadCount        = endpoint{op: "GetUnreadCount", method: http.MethodGet, path: "users/{userId}/notifications/unread-count", userScope: true}
+	endpointListCategories        = endpoint{op: "ListCategories", method: http.MethodGet, path: "categories"}
+	endpointCreateCategory        = endpoint{op: "CreateCategory", method: http.MethodPost, path: "categories"}
+	endpointDeleteCategory        = endpoint{op: "DeleteCategory", method: http.MethodDelete, path: "categories/{categoryId}"}
+	endpointCancelNotification    = endpoint{op: "CancelNotification", method: http.MethodDelete, path: "notifications/{notificationId}"}
+	endpointCancelWorkflowRun     = endpoint{op: "CancelWorkflowRun", method: http.MethodPost, path: "workflows/runs/{runId}/cancel"}
+)
+
+// used to check if calling the endpoint changes any state on the API
+func (e endpoint) mutating() bool {
+	return e.method != http.MethodGet && e.method != http.MethodHead
+}
+
+// fill the `{name}` segments of the path with params, in order. params are path escaped since
+// most of them are user ids, which are often email addresses
+func (e endpoint) resolve(params ...string) (string, error) {
+	segments := strings.Split(e.path, "/")
+	for i, segment := range segments {
+		if !strings.HasPrefix(segment, "{") {
+			continue
+		}
+		if len(params) == 0 {
+			return "", fmt.Errorf("missing %s for %s", strings.Trim(segment, "{}"), e.path)
+		}
+		segments[i] = url.PathEscape(params[0])
+		params = params[1:]
+	}
+	return strings.Join(segments, "/"), nil
+}
+
+// build a request for the endpoint. mutating endpoints are rejected here, before any network I/O,
+// if the client is read-only. the body is backed by the given bytes, so GetBody can always replay
+// it, whether for a redirect followed by net/http or for a retry
+func (c *Client) newRequest(e endpoint, body []byte, params ...string) (*http.Request, error) {
+	return c.newRequestContext(context.Background(), e, body, params...)
+}
+
+// same as newRequest, with a context controlling the lifetime of the request
+func (c *Client) newRequestContext(ctx context.Context, e endpoint, body []byte, params ...string) (*http.Request, error) {
+	if c.config.readOnly && e.mutating() {
+		return nil, ErrReadOnlyClient
+	}
+	if _, ok := userScope(ctx); ok && !e.userScope {
+		return nil, ErrScopeNotSupported
+	}
+	path, err := c.apiVersion.endpoint(e).resolve(params...)
+	if err != nil {
+		return nil, err
+	}
+
+	var r io.Reader
+	if body != nil {
+		r = bytes.NewReader(body)
+	}
+	req, err := http.NewRequestWithContext(ctx, e.method, c.apiVersion.base(c.defaults.Endpoint)+path, r)
+	if err != nil {
+		return nil, err
+	}
+	c.setExpectContinue(req, len(body))
+	return req, nil
+}
+
+// call the endpoint and decode a successful response into v, which must embed Response, see
+// DecodeInto. the body is discarded if v is nil. non-2xx responses are returned as an *APIError
+func (c *Client) callJSON(e endpoint, req *http.Request, v interface{}) error {
+	res, err := c.call(e, req)
+	if err != nil {
+		return err
+	}
+	return decodeInto(e, res, v)
+}
+
+// drain and close the body of a response which isn't handed to the caller, so its connection can
+// be reused by the next request
+func discard(res *http.Response) {
+	io.Copy(io.Discard, res.Body)
+	res.Body.Close()
+}
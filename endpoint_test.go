@@ -0,0 +1,150 @@
+package engagespot
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReadOnlyRejectsMutatingCalls(t *testing.T) {
+	c := NewEngagespotClient("A", "B", WithReadOnly(), WithDeprecationWarnings(false))
+	c.httpClient.Transport = noNetwork(t)
+	defer c.Close()
+	assert.True(t, c.IsReadOnly())
+
+	n := newMessage(c)
+	ctx := context.Background()
+	users := func(context.Context) (UserUpsert, bool, error) {
+		return UserUpsert{Id: "hello@example.com"}, true, nil
+	}
+
+	calls := map[string]func() error{
+		"notification.Send":    func() error { _, err := n.Send(); return err },
+		"notification.SendCtx": func() error { _, err := n.SendCtx(ctx); return err },
+		"notification.SendRaw": func() error { _, err := n.SendRaw(); return err },
+		"Send":                 func() error { _, err := c.Send(n); return err },
+		"SendCtx":              func() error { _, err := c.SendCtx(ctx, n); return err },
+		"SendRaw":              func() error { _, err := c.SendRaw(n); return err },
+		"SendRawCtx":           func() error { _, err := c.SendRawCtx(ctx, n); return err },
+		"SendIfChanged":        func() error { _, _, err := n.SendIfChanged(ctx, ""); return err },
+		"SendTestNotification": func() error { _, err := c.SendTestNotification(ctx, "hello@example.com"); return err },
+		"SendBulk": func() error {
+			h := n.SendBulk(ctx)
+			<-h.Done()
+			return h.Report().Err
+		},
+		"SendAll": func() error {
+			_, err := c.SendAll(ctx, []*Notification{n, newMessage(c)})
+			return err
+		},
+		"SendAsync":              func() error { return (<-c.SendAsync(n)).Err },
+		"notification.SendAsync": func() error { return (<-n.SendAsync()).Err },
+		"Connect":                func() error { _, err := c.Connect("hello@example.com"); return err },
+		"ConnectCtx":             func() error { _, err := c.ConnectCtx(ctx, "hello@example.com"); return err },
+		"ConnectWithDevice": func() error {
+			_, err := c.ConnectWithDevice(ctx, "hello@example.com", ConnectOptions{DeviceType: DeviceIOS})
+			return err
+		},
+		"ConnectUser": func() error {
+			_, err := c.ConnectUser(ctx, "hello@example.com", ConnectOptions{})
+			return err
+		},
+		"CreateOrUpdateUser": func() error { return c.CreateOrUpdateUser(ctx, "hello@example.com", nil) },
+		"DeleteUser":         func() error { return c.DeleteUser(ctx, "hello@example.com") },
+		"ImportUsers": func() error {
+			_, err := c.ImportUsers(ctx, users, ImportOptions{})
+			return err
+		},
+		"RegisterDeviceToken": func() error {
+			_, err := c.RegisterDeviceToken(ctx, "hello@example.com", DeviceAndroid, "token")
+			return err
+		},
+		"RevokeDevice":       func() error { return c.RevokeDevice(ctx, "hello@example.com", "d1") },
+		"MarkAsRead":         func() error { _, err := c.MarkAsRead(ctx, "hello@example.com", "n1"); return err },
+		"MarkAllAsRead":      func() error { _, err := c.MarkAllAsRead(ctx, "hello@example.com"); return err },
+		"DeleteNotification": func() error { return c.DeleteNotification(ctx, "hello@example.com", "n1") },
+		"CreateCategory":     func() error { _, err := c.CreateCategory(ctx, "billing"); return err },
+		"DeleteCategory":     func() error { return c.DeleteCategory(ctx, "cat-1") },
+		"CancelNotification": func() error { return c.CancelNotification(ctx, "n1") },
+		"CancelWorkflowRun":  func() error { return c.CancelWorkflowRun(ctx, "run-1") },
+		"TriggerWorkflow": func() error {
+			_, err := c.TriggerWorkflow(ctx, "welcome", []string{"hello@example.com"}, nil)
+			return err
+		},
+		"RevokeAllDevices": func() error {
+			_, err := c.RevokeAllDevices(ctx, "hello@example.com")
+			return err
+		},
+	}
+	for name, call := range calls {
+		assert.ErrorIs(t, call(), ErrReadOnlyClient, name)
+	}
+}
+
+func TestReadOnlyAllowsReads(t *testing.T) {
+	var requests []capturedRequest
+	c := NewEngagespotClient("A", "B", WithReadOnly())
+	c.httpClient.Transport = roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		requests = append(requests, capturedRequest{path: req.URL.Path})
+		return respondWith(200, `{"id":"app-1","name":"Shop"}`).RoundTrip(req)
+	})
+
+	info, err := c.GetAppInfo()
+	assert.NoError(t, err)
+	assert.Equal(t, "app-1", info.Id)
+	assert.Len(t, requests, 1)
+}
+
+func TestNotReadOnlyByDefault(t *testing.T) {
+	c := NewEngagespotClient("A", "B")
+	assert.False(t, c.IsReadOnly())
+
+	_, err := c.newRequest(endpointSendNotification, nil)
+	assert.NoError(t, err)
+}
+
+func TestRequestBodyReplayedOnRedirect(t *testing.T) {
+	for _, status := range []int{http.StatusTemporaryRedirect, http.StatusPermanentRedirect} {
+		var bodies [][]byte
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			body, _ := io.ReadAll(r.Body)
+			bodies = append(bodies, body)
+			if r.URL.Path == "/v3/notifications" {
+				http.Redirect(w, r, "/v3/retry/notifications", status)
+				return
+			}
+			w.WriteHeader(http.StatusOK)
+		}))
+
+		c := clientFor(server.URL+"/v3/", time.Second)
+		n, _ := c.NewNotification("Hello")
+		n.AddRecipient("hello@example.com")
+		res, err := n.Send()
+		server.Close()
+
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusOK, res.StatusCode)
+		assert.Len(t, bodies, 2)
+		assert.NotEmpty(t, bodies[1])
+		assert.Equal(t, bodies[0], bodies[1])
+	}
+}
+
+func TestRequestGetBody(t *testing.T) {
+	payload := []byte(`{"title":"Hello"}`)
+	req, err := NewEngagespotClient("A", "B").newRequest(endpointSendNotification, payload)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(len(payload)), req.ContentLength)
+
+	for i := 0; i < 2; i++ {
+		body, err := req.GetBody()
+		assert.NoError(t, err)
+		b, _ := io.ReadAll(body)
+		assert.Equal(t, payload, b)
+	}
+}
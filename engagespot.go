@@ -1,37 +1,65 @@
 package engagespot
 
 import (
-	"bytes"
+	"context"
 	"crypto/hmac"
 	"crypto/sha256"
 	"encoding/hex"
-	"encoding/json"
 	"errors"
+	"fmt"
+	"io"
+	"log/slog"
 	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+	"unicode/utf8"
 )
 
-const ENDPOINT = "https://api.engagespot.co/v3/"
-const DEVICE_TYPE = "ios"
+// ENDPOINT is the default base url of the API.
+//
+// Deprecated: use DefaultConfig().Endpoint, or WithDefaults to change it per client
+const ENDPOINT = defaultEndpoint
+
+// DEVICE_TYPE is the default device type sent by Connect.
+//
+// Deprecated: use DefaultConfig().DeviceType, or WithDefaults to change it per client
+const DEVICE_TYPE = defaultDeviceType
 
 type config struct {
-	enableHmac bool
+	readOnly            bool
+	silenceDeprecations bool
 }
 
+// Content is the content of a notification, its "notification" object in the API and the
+// Notification field of Notification. title is required
 // https://documentation.engagespot.co/docs/rest-api#tag/Notifications/paths/~1v3~1notifications/post
-// construct a notification schema
-// title is required
-type schema struct {
+//
+// optional fields of the payload structs are never sent when unset. fields whose zero value is
+// never valid, like strings rejected empty by their setter, maps and slices, are plain values
+// tagged omitempty. fields whose zero value means something, like numbers, booleans and times, are
+// pointers tagged omitempty and set through a setter, so an explicit zero is sent while unset stays
+// absent. see SetSendAt
+type Content struct {
 	Title   string `json:"title"`
 	Message string `json:"message,omitempty"`
 	Url     string `json:"url,omitempty"`
 	Icon    string `json:"icon,omitempty"`
+	// rich content of the in-app channel, see AddAction, SetImage and SetHTMLBody
+	Actions  []Action `json:"actions,omitempty"`
+	Image    string   `json:"image,omitempty"`
+	HTMLBody string   `json:"htmlBody,omitempty"`
+	// custom payload passed to in-app and push channels. see DataKeyAvatar for keys with a
+	// special meaning
+	Data map[string]interface{} `json:"data,omitempty"`
 }
 
-// override have the following fields
+// Override is the override object of a notification, it has the following fields
 // channels
 // Array of strings
-// Specify the channels through which this notification should be delivered. See Channels to get the
-// complete list of supported channels.
+// Specify the channels through which this notification should be delivered. See the Channel
+// constants, like ChannelEmail, for the complete list of supported channels.
 // sendgrid_email
 // object
 // Overrides Sendgrid configuration specified in your Engagespot dashboard. This is considered only
@@ -42,123 +70,417 @@ type schema struct {
 // object
 // Overrides SMTP Provider configurations specified in your Engagespot dashboard. This is considered
 // only if you have enabled SMTP Email Provider.
-type override struct {
-	Channels []string `json:"channels,omitempty"`
+// apns, fcm
+// object
+// Overrides the APNs and FCM fields of the mobile push, see SetApnsOverride and SetFcmOverride.
+type Override struct {
+	Channels      []string               `json:"channels,omitempty"`
+	SendgridEmail map[string]interface{} `json:"sendgrid_email,omitempty"`
+	SmtpEmail     map[string]interface{} `json:"smtp_email,omitempty"`
+
+	// channels added with AddChannelRaw
+	rawChannels map[string]bool
+	// push provider fields, see SetApnsOverride and SetFcmOverride
+	apns *ApnsOverride
+	fcm  *FcmOverride
+	// expiry of the notification for the push providers, set on the override as sent
+	expiry *pushExpiry
 }
 
-// AddChannel is a method to override notification channels and resets any set configuration
-// on first insertion
-func (o *override) AddChannel(channel string) {
-	o.Channels = append(o.Channels, channel)
+// whether nothing is overridden
+func (o *Override) empty() bool {
+	return o == nil || (len(o.Channels) == 0 && len(o.SendgridEmail) == 0 && len(o.SmtpEmail) == 0 &&
+		o.apns == nil && o.fcm == nil)
 }
 
+// Notification is a notification built by Client.NewNotification, sent with its Send methods
 // https://documentation.engagespot.co/docs/rest-api#tag/Notifications/paths/~1v3~1notifications/post
-// represents a notification schema as defined above
-// notification and recipients are required
-type notification struct {
-	*client
-	Notification *schema   `json:"notification"`
+// notification and recipients or topics are required
+//
+// a notification is not safe for concurrent use, build and send it from one goroutine. different
+// notifications of the same client can be sent concurrently, and Clone gives each goroutine its own
+// copy of a shared one
+type Notification struct {
+	*Client      `json:"-"`
+	Notification *Content  `json:"notification"`
 	Recipients   []string  `json:"recipients"`
 	Category     string    `json:"category,omitempty"`
-	Override     *override `json:"override,omitempty"`
+	Override     *Override `json:"override,omitempty"`
+	// when the notification is delivered, see SetSendAt
+	SendAt *time.Time `json:"sendAt,omitempty"`
+	// when the notification expires, see SetExpiresAt
+	ExpiresAt *time.Time `json:"expiresAt,omitempty"`
+	// topics or segments the notification is sent to, see AddTopic
+	Topics []string `json:"topics,omitempty"`
+
+	// raw size of inline attachment content added so far
+	attachmentBytes int
+	// outcome of the last send
+	last *lastSend
+
+	channelRules     []ChannelRule
+	skipChannelRules bool
+
+	// details of recipients added with AddRecipientDetailed, by identifier
+	recipientDetails map[string]Recipient
+
+	// recipients corrected by sanitization
+	sanitization []SanitizedRecipient
+	// recipients of the source corrected by sanitization in the last send, including the ones
+	// past the sample kept in sanitization
+	sourceCorrections int
+
+	skipFingerprint bool
+
+	// recipients pulled lazily at send, instead of Recipients. see SetRecipientSource
+	recipientSource RecipientSource
+	// set on the copies of a notification sent chunk by chunk, along with the range of the
+	// recipients of the chunk in the recipient stream
+	chunked    bool
+	chunkStart int
+	chunkEnd   int
+	// position of the copy of a notification sent to a channel rule group, see sendGrouped
+	channelGroup int
+
+	// how long to wait for queued sends to be processed
+	waitForProcessing time.Duration
+	// how long the notification lives from its delivery, see SetTTL
+	ttl time.Duration
+
+	idempotencyKey string
+
+	// content by locale, see SetLocalizedContent
+	localized map[string]LocalizedContent
+
+	// errors of the chainable setters, returned by the next send
+	buildErrors []error
+}
+
+// SetTitle can be used to change the title of a notification, with the same validation as
+// NewNotification
+func (n *Notification) SetTitle(title string) (*Notification, error) {
+	if err := validTitle(title); err != nil {
+		return nil, invalidField("title", title, err)
+	}
+	n.Notification.Title = title
+	return n, nil
 }
 
 // SetMessage can be used to set notification message
-func (n *notification) SetMessage(message string) (*notification, error) {
+func (n *Notification) SetMessage(message string) (*Notification, error) {
 	if message == "" {
-		return nil, errors.New("empty message string")
+		return nil, invalidField("message", message, errors.New("empty message string"))
 	}
 	n.Notification.Message = message
 	return n, nil
 }
 
-// SetUrl can be used to set callback url
-func (n *notification) SetUrl(url string) (*notification, error) {
+// SetUrl can be used to set callback url. it must be an absolute http or https url, or of any
+// scheme with WithRelaxedValidation
+func (n *Notification) SetUrl(url string) (*Notification, error) {
 	if url == "" {
-		return nil, errors.New("empty url string")
+		return nil, invalidField("url", url, errors.New("empty url string"))
+	}
+	if err := validURL("url", url, n.Client.relaxedValidation); err != nil {
+		return nil, invalidField("url", url, err)
 	}
 	n.Notification.Url = url
 	return n, nil
 }
 
-// SetIcon can be used to set notification icon
-func (n *notification) SetIcon(iconUrl string) (*notification, error) {
+// SetIcon can be used to set notification icon, validated like the url of SetUrl
+func (n *Notification) SetIcon(iconUrl string) (*Notification, error) {
 	if iconUrl == "" {
-		return nil, errors.New("empty icon url string")
+		return nil, invalidField("icon", iconUrl, errors.New("empty icon url string"))
+	}
+	if err := validURL("icon url", iconUrl, n.Client.relaxedValidation); err != nil {
+		return nil, invalidField("icon", iconUrl, err)
 	}
 	n.Notification.Icon = iconUrl
 	return n, nil
 }
 
+// SetSendAt can be used to schedule the notification for later. the time is sent as RFC 3339 and
+// interpreted by the API, see WithSkewCorrection for hosts with an unreliable clock. times which
+// aren't in the future by the local clock are rejected
+func (n *Notification) SetSendAt(at time.Time) (*Notification, error) {
+	if at.IsZero() {
+		return nil, invalidField("sendAt", at, errors.New("zero send time"))
+	}
+	if now := n.Client.now(); !at.After(now) {
+		return nil, invalidField("sendAt", at, fmt.Errorf("send time %s is not in the future", at.Format(time.RFC3339)))
+	}
+	n.SendAt = &at
+	return n, nil
+}
+
 // SetCategory can be used to set notification category. If category doesn't exist, it will be created
-func (n *notification) SetCategory(category string) (*notification, error) {
+func (n *Notification) SetCategory(category string) (*Notification, error) {
 	if category == "" {
-		return nil, errors.New("empty category string")
+		return nil, invalidField("category", category, errors.New("empty category string"))
 	}
 	n.Category = category
 	return n, nil
 }
 
 // AddRecipient can be used to add a recipient to the list. If none is present during send, an error will be thrown
-func (n *notification) AddRecipient(recipient string) (*notification, error) {
-	if recipient == "" {
-		return nil, errors.New("empty recipient string")
+func (n *Notification) AddRecipient(recipient string) (*Notification, error) {
+	if n.recipientSource != nil {
+		return nil, invalidField("recipients", recipient, errMixedRecipients)
+	}
+	recipient, err := n.cleanRecipient(recipient)
+	if err != nil {
+		return nil, invalidField("recipients", recipient, err)
 	}
 	n.Recipients = append(n.Recipients, recipient)
 	return n, nil
 }
 
+// recipient as sent, after sanitization and normalization
+func (n *Notification) cleanRecipient(recipient string) (string, error) {
+	recipient, err := n.sanitizeRecipient(recipient)
+	if err != nil {
+		return "", err
+	}
+	recipient = n.Client.NormalizeUserID(recipient)
+	if recipient == "" {
+		return "", errors.New("empty recipient string")
+	}
+	return recipient, nil
+}
+
 // used to check if enough recipients are present
-func (n *notification) hasEnoughRecipients() bool {
-	return len(n.Recipients) > 0
+func (n *Notification) hasEnoughRecipients() bool {
+	return len(n.Recipients) > 0 || len(n.Topics) > 0 || n.recipientSource != nil
 }
 
 // send a notification
-func (n *notification) Send() (*http.Response, error) {
-	if !n.hasEnoughRecipients() {
-		return nil, errors.New("not enough recipients")
-	}
-	return n.client.Send(n)
+//
+// Deprecated: use SendCtx
+func (n *Notification) Send(opts ...CallOption) (*SendResult, error) {
+	return n.Client.Send(n, opts...)
 }
 
-// base struct of client. contain an http client used to communicate with the API
-type client struct {
+// SendCtx is Send with a context controlling the lifetime of every request made for the send
+func (n *Notification) SendCtx(ctx context.Context, opts ...CallOption) (*SendResult, error) {
+	return n.Client.SendCtx(ctx, n, opts...)
+}
+
+// SendRaw is Send returning the response of the API as is, see client.SendRaw
+//
+// Deprecated: use SendCtx, SendResult carries the status and every field of the response
+func (n *Notification) SendRaw(opts ...CallOption) (*http.Response, error) {
+	return n.Client.SendRaw(n, opts...)
+}
+
+// SendRawCtx is SendRaw with a context controlling the lifetime of every request made for the send
+//
+// Deprecated: use SendCtx
+func (n *Notification) SendRawCtx(ctx context.Context, opts ...CallOption) (*http.Response, error) {
+	return n.Client.SendRawCtx(ctx, n, opts...)
+}
+
+// Client is the Engagespot client returned by NewEngagespotClient and NewClient. it contains an
+// http client used to communicate with the API
+//
+// a client is safe for concurrent use, one client is meant to be shared by every goroutine of a
+// service. its configuration is set by the options given when it's created and doesn't change
+// afterwards, except for the settings of DynamicConfig and the middlewares added by Use, which are
+// swapped atomically: a call in flight keeps the settings it started with
+type Client struct {
 	apiKey     string
 	apiSecret  string
 	config     config
+	defaults   Defaults
 	httpClient *http.Client
+	// given with WithHTTPClient, and timeout given with WithTimeout
+	customHTTPClient *http.Client
+	timeout          *time.Duration
+
+	warningHandler func([]Warning)
+	// categories this client has sent notifications to
+	categories sync.Map
+	// names of the categories listed or created by this client, by id, see DeleteCategory
+	categoryNames sync.Map
+
+	deviceLookup DeviceLookup
+	deviceCache  DeviceCache
+	// nil when entries of deviceCache never expire
+	devicePolicy    *CachePolicy
+	deviceRefresher refresher
+
+	normalizer         func(string) string
+	sanitizeRecipients bool
+	relaxedValidation  bool
+	dryRun             bool
+
+	archiver          Archiver
+	archiveMode       ArchiveMode
+	archiveFilter     []string
+	archiveBufferSize int
+	archiveQueue      archiveQueue
+	archiveDropped    uint64
+	archiveEncryptor  FieldEncryptor
+
+	sampleSink func(Sample)
+
+	optOutCache      PreferenceCache
+	optOutPolicy     CachePolicy
+	optOutFailClosed bool
+	optOutRefresher  refresher
+
+	idempotentRevoke bool
+	autoIdempotency  bool
+
+	// interval between status checks of WaitForProcessing
+	pollInterval time.Duration
+
+	// source of the current time, and of waits between retries, replaced in tests
+	now   func() time.Time
+	sleep func(context.Context, time.Duration) error
+
+	jitter *lockedRand
+
+	logger *slog.Logger
+	debug  bool
+
+	requestHook  func(*http.Request)
+	responseHook func(*http.Response, time.Duration)
+	metrics      Metrics
+	sendObserver func(NotificationSnapshot)
+
+	approvalThreshold int
+	approve           func(context.Context, SendSummary) (bool, error)
+
+	channelValidation *channelCache
+
+	maxRecipients   int
+	maxPayloadBytes int
+
+	chunkTuner *chunkTuner
+
+	skew           skewTracker
+	skewThreshold  time.Duration
+	skewHandler    func(ClockSkewWarning)
+	skewCorrection bool
+
+	redirectAllowlist []string
+
+	fingerprints FingerprintStore
+
+	rateLimitHandler func(RateLimitAdjustment)
+
+	expectContinue        bool
+	expectContinueTimeout time.Duration
+
+	statsCategories map[string]bool
+	stats           sendStats
+
+	// workflows notifications are sent through, by category
+	workflows map[string]string
+
+	recentErrors *errorRing
+
+	// rate limit, retry policy and debug sampling, swapped by ApplyDynamicConfig
+	live               atomic.Pointer[liveSettings]
+	liveMu             sync.Mutex
+	configErrorHandler func(DynamicConfig, error)
+
+	// options applied by the constructor, see declare, and the ones rejected, see rejectOption
+	declaredOptions []optionID
+	rejectedOptions []string
+	// endpoint given with WithBaseURL, and retry policy given with WithRetry
+	baseURL     string
+	apiVersion  apiVersion
+	retryPolicy *RetryPolicy
+
+	// worker pool of SendAsync, started on first use
+	asyncWorkers int
+	async        asyncPool
 }
 
 // NewEngagespotClient can be used to create a client which can then be used to create
-// and send notifications
-func NewEngagespotClient(apiKey, apiSecret string) *client {
-	httpClient := &http.Client{}
+// and send notifications. conflicting options are logged, see NewClient to reject them instead
+func NewEngagespotClient(apiKey, apiSecret string, opts ...Option) *Client {
+	client := newClient(apiKey, apiSecret, opts)
+	if err := client.checkOptions(); err != nil {
+		client.log().Warn(err.Error())
+	}
+	return client
+}
+
+// NewClient is NewEngagespotClient returning an *OptionConflictError listing every pair of options
+// which conflict with each other, and every option which has no effect, instead of logging them
+func NewClient(apiKey, apiSecret string, opts ...Option) (*Client, error) {
+	client := newClient(apiKey, apiSecret, opts)
+	if err := client.checkOptions(); err != nil {
+		return nil, err
+	}
+	return client, nil
+}
 
-	client := &client{
-		apiKey:     apiKey,
-		apiSecret:  apiSecret,
-		config:     config{},
-		httpClient: httpClient,
+func newClient(apiKey, apiSecret string, opts []Option) *Client {
+	client := &Client{
+		apiKey:    apiKey,
+		apiSecret: apiSecret,
+		config:    config{},
+		defaults:  DefaultConfig(),
+		now:       time.Now,
+		sleep:     sleepContext,
+		jitter:    newLockedRand(),
+
+		recentErrors: newErrorRing(defaultRecentErrors),
+	}
+
+	for _, opt := range opts {
+		opt(client)
+	}
+	if client.baseURL != "" {
+		client.defaults.Endpoint = client.baseURL
+	}
+	if client.apiVersion.name == "" {
+		client.apiVersion = apiVersions[defaultAPIVersion]
+	}
+	client.initialSettings().retry = client.defaults.Retry
+	if client.retryPolicy != nil {
+		client.initialSettings().retry = *client.retryPolicy
 	}
+	client.httpClient = client.newHTTPClient()
 
 	return client
 }
 
-// NewNotification can be used to create a notification item which can later be sent by using .Send()
-func (c *client) NewNotification(title string) (*notification, error) {
-	if title == "" {
-		return nil, errors.New("empty title string")
+// MaxTitleLength is the title length, in characters, above which the API rejects a notification
+const MaxTitleLength = 255
+
+// check a title before it is set, the same way for every builder
+func validTitle(title string) error {
+	if strings.TrimSpace(title) == "" {
+		return errors.New("empty title string")
+	}
+	if length := utf8.RuneCountInString(title); length > MaxTitleLength {
+		return fmt.Errorf("title is %d characters long, at most %d are accepted", length, MaxTitleLength)
+	}
+	return nil
+}
+
+// NewNotification can be used to create a notification item which can later be sent by using .SendCtx().
+// the title can't be blank or longer than MaxTitleLength
+func (c *Client) NewNotification(title string) (*Notification, error) {
+	if err := validTitle(title); err != nil {
+		return nil, invalidField("title", title, err)
 	}
 
-	n := &schema{
+	n := &Content{
 		Title: title,
 	}
-	o := &override{}
+	o := &Override{}
 
-	notification := &notification{
+	notification := &Notification{
 		Notification: n,
 		Override:     o,
-		client:       c,
+		Client:       c,
+		last:         &lastSend{},
 	}
 
 	return notification, nil
@@ -166,59 +488,420 @@ func (c *client) NewNotification(title string) (*notification, error) {
 
 // EnableHmac can be used to enable an extra layer of security.
 // Read more: https://documentation.engagespot.co/docs/HMAC-authentication/enabling-HMAC-authentication
-func (c *client) EnableHmac() *client {
-	c.config.enableHmac = true
+//
+// Deprecated: pass WithHmac to NewEngagespotClient instead. it is safe to call on a client in use,
+// requests already in flight aren't signed
+func (c *Client) EnableHmac() *Client {
+	c.deprecated("EnableHmac", "WithHmac")
+	c.liveMu.Lock()
+	defer c.liveMu.Unlock()
+	next := *c.settings()
+	next.hmac = true
+	c.live.Store(&next)
 	return c
 }
 
-// basic method to call the API using already defined http client. credentials are set here
-func (c *client) call(req *http.Request) (*http.Response, error) {
+// IsReadOnly can be used to check if the client was created using WithReadOnly
+func (c *Client) IsReadOnly() bool {
+	return c.config.readOnly
+}
+
+// basic method to call the API using already defined http client. credentials are set here and
+// transport errors are wrapped with the operation which caused them
+func (c *Client) call(e endpoint, req *http.Request) (*http.Response, error) {
 	req.Header.Set("Content-Type", "application/json")
 
 	req.Header.Add("X-ENGAGESPOT-API-KEY", c.apiKey)
-	req.Header.Add("X-ENGAGESPOT-API-SECRET", c.apiSecret)
+	if userId, ok := userScope(req.Context()); ok {
+		userId = c.NormalizeUserID(userId)
+		req.Header.Set("X-ENGAGESPOT-USER-ID", userId)
+		req.Header.Set("X-ENGAGESPOT-USER-SIGNATURE", c.GenHmac(userId))
+	} else {
+		req.Header.Add("X-ENGAGESPOT-API-SECRET", c.apiSecret)
+	}
+	s := c.settings()
+	if s.hmac && req.GetBody != nil {
+		body, err := req.GetBody()
+		if err != nil {
+			return nil, err
+		}
+		b, err := io.ReadAll(body)
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set(BodySignatureHeader, c.GenBodyHmac(b))
+	}
+
+	var sampleId string
+	if c.samplingEnabled(s) {
+		sampleId = correlationId(req.Context())
+		if sampleId == "" {
+			sampleId = newCorrelationId()
+		}
+		if !c.sampled(s, sampleId) {
+			sampleId = ""
+		}
+	}
 
-	return c.httpClient.Do(req)
+	return c.doWithRetry(e, req, s, sampleId)
+}
+
+// make a single attempt of a request
+func (c *Client) do(e endpoint, req *http.Request, s *liveSettings, sampleId string) (*http.Response, error) {
+	req, trace := traceContinue(req)
+	if c.requestHook != nil {
+		c.requestHook(req)
+	}
+	start := time.Now()
+	res, err := c.doer(s).Do(req)
+	latency := time.Since(start)
+	if c.debug {
+		c.logAttempt(req, res, latency, err)
+	}
+	if c.responseHook != nil && res != nil {
+		c.responseHook(res, latency)
+	}
+	if sampleId != "" {
+		c.captureSample(sampleId, req, res)
+	}
+	if err != nil {
+		return nil, &OperationError{Op: e.op, Endpoint: e.path, Key: requestKey(req), Phase: trace.phase(), Err: err}
+	}
+	return res, nil
 }
 
 // Send can be used to send a notification, using `POST notification` under the hood
 // https://documentation.engagespot.co/docs/rest-api#tag/Notifications/paths/~1v3~1notifications/post
-func (c *client) Send(n *notification) (*http.Response, error) {
-	b := new(bytes.Buffer)
-	json.NewEncoder(b).Encode(n)
+// non-2xx responses are returned as an *APIError
+//
+// Deprecated: use SendCtx
+func (c *Client) Send(n *Notification, opts ...CallOption) (*SendResult, error) {
+	c.deprecated("Send", "SendCtx")
+	return c.SendCtx(context.Background(), n, opts...)
+}
 
-	req, err := http.NewRequest("POST", ENDPOINT+"notifications", b)
+// SendCtx is Send with a context controlling the lifetime of every request made for the send.
+// cancelling ctx aborts the request in flight and any retry or chunk left
+func (c *Client) SendCtx(ctx context.Context, n *Notification, opts ...CallOption) (*SendResult, error) {
+	res, out, err := c.sendContext(applyCallOptions(ctx, opts), n)
 	if err != nil {
 		return nil, err
 	}
+	return sendResult(out, res)
+}
 
-	return c.call(req)
+// SendRaw is Send returning the response of the API as is, whatever its status, for callers
+// which need more than SendResult. the caller must close the body of the response
+//
+// Deprecated: use SendCtx, SendResult carries the status and every field of the response
+func (c *Client) SendRaw(n *Notification, opts ...CallOption) (*http.Response, error) {
+	c.deprecated("SendRaw", "SendCtx")
+	return c.sendRaw(context.Background(), n, opts)
+}
+
+// SendRawCtx is SendRaw with a context controlling the lifetime of every request made for the send
+//
+// Deprecated: use SendCtx
+func (c *Client) SendRawCtx(ctx context.Context, n *Notification, opts ...CallOption) (*http.Response, error) {
+	c.deprecated("SendRawCtx", "SendCtx")
+	return c.sendRaw(ctx, n, opts)
+}
+
+func (c *Client) sendRaw(ctx context.Context, n *Notification, opts []CallOption) (*http.Response, error) {
+	res, _, err := c.sendContext(applyCallOptions(ctx, opts), n)
+	return res, err
+}
+
+// validate, filter and send n, with a context controlling the lifetime of every request made.
+// the outcome of the send is returned rather than stored on n, which only keeps a copy of it for
+// its accessors
+func (c *Client) sendContext(ctx context.Context, n *Notification) (*http.Response, *sendOutcome, error) {
+	warnings, err := c.validate(n)
+	if err != nil {
+		return nil, nil, err
+	}
+	out := &sendOutcome{warnings: warnings, state: StateUnknown, path: c.sendPath(n), idempotencyKey: c.idempotencyKey(n)}
+	defer func() { n.last.set(*out) }()
+	ctx = withSendOutcome(ctx, out)
+
+	if err := c.checkApproval(ctx, n); err != nil {
+		return nil, out, err
+	}
+
+	streamed := n.recipientSource != nil || bulkControlFrom(ctx) != nil
+	if err := c.preflight(n, streamed); err != nil {
+		return nil, out, err
+	}
+	if c.sendObserver != nil {
+		c.sendObserver(n.Snapshot())
+	}
+
+	var res *http.Response
+	started := c.now()
+	if streamed {
+		res, err = c.sendStreamed(ctx, n, out)
+		if n.recipientSource != nil && n.sourceCorrections > 0 {
+			out.warnings = append(out.warnings, n.sourceSanitizationWarning())
+		}
+	} else {
+		res, out.suppression, err = c.deliver(ctx, n, 0)
+		out.recipients = unsuppressed(n.Recipients, out.suppression)
+	}
+	c.recordSend(n.Category, c.now().Sub(started), err != nil || res.StatusCode < 200 || res.StatusCode > 299)
+	if err != nil {
+		return nil, out, err
+	}
+
+	status := readStatus(res)
+	out.state = status.state(res.StatusCode)
+	if out.dryRun {
+		out.state = StateUnknown
+	}
+	if out.state == StateAccepted && n.waitForProcessing > 0 && status.Id != "" {
+		out.state, err = c.pollState(ctx, status.Id, n.waitForProcessing)
+		if err != nil {
+			discard(res)
+			return nil, out, err
+		}
+	}
+
+	if len(out.warnings) > 0 && c.warningHandler != nil {
+		c.warningHandler(out.warnings)
+	}
+	return res, out, nil
+}
+
+// filter opt-outs of n and send it, split by channel rules if any. sent is the number of
+// recipients already sent to by previous chunks of a recipient source. the report of opt-out
+// filtering is returned whatever the error
+func (c *Client) deliver(ctx context.Context, n *Notification, sent int) (*http.Response, SuppressionReport, error) {
+	target := n
+	var report SuppressionReport
+	if c.optOutCache != nil && n.Category != "" {
+		var err error
+		target, report, err = c.filterOptOuts(ctx, n)
+		if err != nil {
+			return nil, report, err
+		}
+	}
+	if err := c.checkRecipientGuard(ctx, sent+len(target.Recipients), n.chunked, len(target.Topics) > 0); err != nil {
+		return nil, report, err
+	}
+
+	var res *http.Response
+	var err error
+	if target.hasActiveChannelRules() {
+		res, err = c.sendGrouped(ctx, target)
+	} else {
+		res, err = c.send(ctx, target)
+	}
+	return res, report, err
+}
+
+// checks of n which depend on how it's sent, after validate
+func (c *Client) preflight(n *Notification, streamed bool) error {
+	if err := c.validateTopics(n, streamed); err != nil {
+		return invalidField("topics", n.Topics, err)
+	}
+	if !streamed && len(n.Recipients) > MaxRecipients {
+		return invalidField("recipients", len(n.Recipients), fmt.Errorf("%w: %d recipients, at most %d can be sent without SendBulk", ErrRecipientLimit, len(n.Recipients), MaxRecipients))
+	}
+	return nil
+}
+
+// encode and post a single notification
+func (c *Client) send(ctx context.Context, n *Notification) (*http.Response, error) {
+	payload, e, err := c.sendPayload(n)
+	if err != nil {
+		return nil, err
+	}
+	if err := c.checkPayloadSize(payload); err != nil {
+		return nil, err
+	}
+
+	attempts := &attemptLog{}
+	req, err := c.newRequestContext(withAttemptLog(ctx, attempts), e, payload)
+	if err != nil {
+		return nil, err
+	}
+	sendOutcomeFrom(ctx).setIdempotencyKey(req, n)
+	if c.dryRun {
+		if out := sendOutcomeFrom(ctx); out != nil {
+			out.dryRun = true
+		}
+		return dryRunResponse(req), nil
+	}
+
+	started := time.Now()
+	res, err := c.call(e, req)
+
+	if c.archiver != nil {
+		var warnings []Warning
+		if out := sendOutcomeFrom(ctx); out != nil {
+			warnings = out.warnings
+		}
+		if archiveErr := c.archive(ctx, payload, started, res, err, attempts.recorded(), warnings); archiveErr != nil && err == nil {
+			discard(res)
+			return nil, archiveErr
+		}
+	}
+
+	return res, err
 }
 
 // Connect can be used to activate a user account without the need to manually login using application.
 // This is helpful for sending notifications before user's first login. Beware that this will mark the
-// user as active. uses sdk/notifications behind the scenes
+// user as active. uses sdk/notifications behind the scenes. a non-2xx response is returned as an
+// *APIError, with its body already closed
 // https://documentation.engagespot.co/docs/rest-api#tag/Notifications/paths/~1v3~1notifications/post
-func (c *client) Connect(userId string) (*http.Response, error) {
-	req, err := http.NewRequest("POST", ENDPOINT+"sdk/connect", nil)
+//
+// Deprecated: use ConnectUser, which decodes the response into a ConnectResult
+func (c *Client) Connect(userId string) (*http.Response, error) {
+	c.deprecated("Connect", "ConnectUser")
+	return c.connect(context.Background(), userId, ConnectOptions{})
+}
+
+// ConnectCtx is Connect with a context controlling the lifetime of the request
+//
+// Deprecated: use ConnectUser, which decodes the response into a ConnectResult
+func (c *Client) ConnectCtx(ctx context.Context, userId string) (*http.Response, error) {
+	c.deprecated("ConnectCtx", "ConnectUser")
+	return c.connect(ctx, userId, ConnectOptions{})
+}
+
+// ConnectOptions sets the device a user is connected from by ConnectUser
+type ConnectOptions struct {
+	// one of DeviceIOS, DeviceAndroid, DeviceWeb or DeviceBrowser. Defaults.DeviceType if not set
+	DeviceType string
+	// unique id of the device, a random UUID if not set. reuse the DeviceId of the ConnectResult
+	// to connect the same device again
+	DeviceId string
+}
+
+// ConnectResult is the response of the sdk/connect endpoint to ConnectUser. whatever else the
+// endpoint returns is kept in the embedded Response, see Response.Extra
+type ConnectResult struct {
+	Response
+	// id of the connected user, as sent if the endpoint doesn't return one
+	UserId string `json:"identifier"`
+	// token of the user for the SDK, if the endpoint returns one
+	Token string `json:"token"`
+	// id of the device the user was connected from, the generated one if ConnectOptions had none
+	DeviceId string `json:"-"`
+}
+
+// ConnectWithDevice is ConnectCtx for a user on a given device, instead of the default device
+// type of the client
+//
+// Deprecated: use ConnectUser, which decodes the response into a ConnectResult
+func (c *Client) ConnectWithDevice(ctx context.Context, userId string, opts ConnectOptions) (*http.Response, error) {
+	c.deprecated("ConnectWithDevice", "ConnectUser")
+	return c.connect(ctx, userId, opts)
+}
+
+// ConnectUser activates a user the way Connect does, from the device set by opts, and returns
+// what the endpoint answered. a non-2xx response is returned as an *APIError
+func (c *Client) ConnectUser(ctx context.Context, userId string, opts ConnectOptions) (*ConnectResult, error) {
+	opts, err := c.connectOptions(opts)
+	if err != nil {
+		return nil, err
+	}
+	userId = c.NormalizeUserID(userId)
+
+	req, err := c.connectRequest(ctx, userId, opts)
+	if err != nil {
+		return nil, err
+	}
+	result := &ConnectResult{}
+	if err := c.callJSON(endpointConnect, req, result); err != nil {
+		return nil, err
+	}
+	if result.UserId == "" {
+		result.UserId = userId
+	}
+	result.DeviceId = opts.DeviceId
+	return result, nil
+}
+
+// connect is ConnectUser handing back the raw response, for the deprecated methods
+func (c *Client) connect(ctx context.Context, userId string, opts ConnectOptions) (*http.Response, error) {
+	opts, err := c.connectOptions(opts)
+	if err != nil {
+		return nil, err
+	}
+	req, err := c.connectRequest(ctx, c.NormalizeUserID(userId), opts)
 	if err != nil {
 		return nil, err
 	}
+	res, err := c.call(endpointConnect, req)
+	if err != nil || (res.StatusCode >= 200 && res.StatusCode <= 299) {
+		return res, err
+	}
+	return nil, decodeInto(endpointConnect, res, nil)
+}
+
+// request of sdk/connect for userId, from the device of opts
+func (c *Client) connectRequest(ctx context.Context, userId string, opts ConnectOptions) (*http.Request, error) {
+	req, err := c.newRequestContext(ctx, endpointConnect, nil)
+	if err != nil {
+		return nil, err
+	}
+	for name, values := range c.connectHeaders(userId, opts) {
+		req.Header[name] = values
+	}
+	return req, nil
+}
 
-	req.Header.Add("X-ENGAGESPOT-USER-ID", userId)
-	req.Header.Add("X-ENGAGESPOT-DEVICE-ID", DEVICE_TYPE)
+// opts with defaults filled in
+func (c *Client) connectOptions(opts ConnectOptions) (ConnectOptions, error) {
+	if opts.DeviceType == "" {
+		opts.DeviceType = c.defaults.DeviceType
+	}
+	if err := validDeviceType(opts.DeviceType); err != nil {
+		return ConnectOptions{}, err
+	}
+	if opts.DeviceId == "" {
+		opts.DeviceId = newUUID()
+	}
+	return opts, nil
+}
 
-	if c.config.enableHmac {
-		req.Header.Add("X-ENGAGESPOT-USER-SIGNATURE", c.GenHmac(userId))
+// headers identifying the user connected by Connect and their device
+func (c *Client) connectHeaders(userId string, opts ConnectOptions) http.Header {
+	h := c.userHeaders(userId)
+	h.Add("X-ENGAGESPOT-DEVICE-ID", opts.DeviceId)
+	h.Add("X-ENGAGESPOT-DEVICE-TYPE", opts.DeviceType)
+	return h
+}
+
+// headers identifying the user a call is made on behalf of, with their signature if HMAC is
+// enabled
+func (c *Client) userHeaders(userId string) http.Header {
+	h := http.Header{}
+	h.Add("X-ENGAGESPOT-USER-ID", userId)
+	if c.settings().hmac {
+		h.Add("X-ENGAGESPOT-USER-SIGNATURE", c.GenHmac(userId))
 	}
+	return h
+}
+
+// BodySignatureHeader is the header carrying the signature of the body of requests made by clients
+// with HMAC enabled, see GenBodyHmac
+const BodySignatureHeader = "X-ENGAGESPOT-SIGNATURE"
 
-	return c.call(req)
+// GenBodyHmac can be used to generate the signature of a request body, the same way as for the
+// requests of clients with HMAC enabled: the hex encoded HMAC-SHA256 of body keyed with the API
+// secret. bodies are signed exactly as sent, so the receiving side has to verify the raw bytes
+func (c *Client) GenBodyHmac(body []byte) string {
+	h := hmac.New(sha256.New, []byte(c.apiSecret))
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil))
 }
 
 // GenHmac can be used to generate sha256 required if Hmac is enabled.
 // Read more: https://documentation.engagespot.co/docs/HMAC-authentication/enabling-HMAC-authentication
-func (c *client) GenHmac(userId string) string {
+func (c *Client) GenHmac(userId string) string {
 	h := hmac.New(sha256.New, []byte(c.apiSecret))
-	h.Write([]byte(userId))
+	h.Write([]byte(c.NormalizeUserID(userId)))
 	return hex.EncodeToString(h.Sum(nil))
 }
@@ -1,7 +1,18 @@
 package engagespot
 
 import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -10,3 +21,376 @@ func TestHmac(t *testing.T) {
 	client := NewEngagespotClient("A", "B")
 	assert.Equal(t, client.GenHmac("hello@example.com"), "8c10fc039230663b3b1c074f16db7c7dbb3dd9da64b68965aba85d89acd3a8da")
 }
+
+func TestBodyHmac(t *testing.T) {
+	client := NewEngagespotClient("A", "B")
+	assert.Equal(t, "045234ac3e8cf52c8604246be2ccff7c832bfc42d0c1def52ea6abcdb7b761de", client.GenBodyHmac([]byte(`{"title":"Hello"}`)))
+	assert.Equal(t, "21af20556288bfacaa002b7e1f02f22e9a0e7c1d6bb4f87cb06788930c3704b9", client.GenBodyHmac(nil))
+}
+
+func TestRequestBodySigned(t *testing.T) {
+	var signatures, bodies []string
+	signing := func(req *http.Request) (*http.Response, error) {
+		if req.Body != nil {
+			body, _ := io.ReadAll(req.Body)
+			bodies = append(bodies, string(body))
+		}
+		signatures = append(signatures, req.Header.Get(BodySignatureHeader))
+		return respondWith(200, `{"count":1}`).RoundTrip(req)
+	}
+
+	c := NewEngagespotClient("A", "B", WithHmac())
+	c.httpClient.Transport = roundTripFunc(signing)
+	assert.NoError(t, sendTo(c))
+	_, err := c.GetUnreadCount(context.Background(), "a")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{c.GenBodyHmac([]byte(bodies[0])), ""}, signatures)
+
+	// nothing is signed without HMAC
+	c = NewEngagespotClient("A", "B")
+	c.httpClient.Transport = roundTripFunc(signing)
+	assert.NoError(t, sendTo(c))
+	assert.Empty(t, signatures[2])
+}
+
+// used to stub the transport of a client in tests
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+// transport which fails the test if any request reaches the network
+func noNetwork(t *testing.T) http.RoundTripper {
+	return roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		t.Fatalf("unexpected request %s %s", req.Method, req.URL)
+		return nil, nil
+	})
+}
+
+// transport which answers every request with the given status and body
+func respondWith(status int, body string) http.RoundTripper {
+	return roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		return &http.Response{
+			StatusCode: status,
+			Header:     http.Header{"Content-Type": {"application/json"}},
+			Body:       io.NopCloser(strings.NewReader(body)),
+			Request:    req,
+		}, nil
+	})
+}
+
+// clock which only moves when told to, safe for concurrent use
+type fakeClock struct {
+	mu sync.Mutex
+	t  time.Time
+}
+
+func newFakeClock() *fakeClock {
+	return &fakeClock{t: time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)}
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.t
+}
+
+func (c *fakeClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.t = c.t.Add(d)
+}
+
+// server blocking every request until the test ends, reporting requests on started
+func blockingServer(t *testing.T) (*httptest.Server, chan struct{}) {
+	started := make(chan struct{}, 1)
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		started <- struct{}{}
+		select {
+		case <-release:
+		case <-r.Context().Done():
+		}
+	}))
+	t.Cleanup(func() {
+		close(release)
+		server.Close()
+	})
+	return server, started
+}
+
+func TestSendCtxCancel(t *testing.T) {
+	server, started := blockingServer(t)
+	c := clientFor(server.URL+"/v3/", time.Minute)
+	n, _ := c.NewNotification("Hello")
+	n.AddRecipient("a")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		<-started
+		cancel()
+	}()
+	_, err := n.SendCtx(ctx)
+	assert.ErrorIs(t, err, context.Canceled)
+}
+
+func TestSendCtxDeadline(t *testing.T) {
+	server, _ := blockingServer(t)
+	c := clientFor(server.URL+"/v3/", time.Minute)
+	n, _ := c.NewNotification("Hello")
+	n.AddRecipient("a")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	_, err := c.SendCtx(ctx, n)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+}
+
+func TestConnectCtxCancel(t *testing.T) {
+	server, started := blockingServer(t)
+	c := clientFor(server.URL+"/v3/", time.Minute)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		<-started
+		cancel()
+	}()
+	_, err := c.ConnectCtx(ctx, "a")
+	assert.ErrorIs(t, err, context.Canceled)
+
+	var opErr *OperationError
+	assert.ErrorAs(t, err, &opErr)
+	assert.Equal(t, "Connect", opErr.Op)
+}
+
+// payload structs and their required fields, which are always sent
+var payloadStructs = map[reflect.Type][]string{
+	reflect.TypeOf(Content{}):         {"Title"},
+	reflect.TypeOf(Override{}):        nil,
+	reflect.TypeOf(Notification{}):    {"Notification", "Recipients"},
+	reflect.TypeOf(Recipient{}):       {"Identifier"},
+	reflect.TypeOf(workflowTrigger{}): {"Workflow", "SendTo"},
+	reflect.TypeOf(SmtpOverride{}):    nil,
+	reflect.TypeOf(Action{}):          {"Label", "Url"},
+}
+
+// every optional field must be omitted when unset, and be a pointer if its zero value is valid
+func TestOptionalFieldsOmitted(t *testing.T) {
+	for typ, required := range payloadStructs {
+		for i := 0; i < typ.NumField(); i++ {
+			f := typ.Field(i)
+			tag := f.Tag.Get("json")
+			if !f.IsExported() || f.Anonymous || tag == "-" || containsString(required, f.Name) {
+				continue
+			}
+			assert.Contains(t, tag, ",omitempty", "%s.%s", typ.Name(), f.Name)
+			switch f.Type.Kind() {
+			case reflect.Bool, reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+				reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Float32, reflect.Float64, reflect.Struct:
+				t.Errorf("%s.%s: optional %s must be a pointer to tell zero from unset", typ.Name(), f.Name, f.Type)
+			}
+		}
+	}
+}
+
+// notification titled "Invoice" without recipients, for tests of the payload
+func newTestNotification(t *testing.T) *Notification {
+	n, err := NewEngagespotClient("A", "B").NewNotification("Invoice")
+	assert.NoError(t, err)
+	return n
+}
+
+// notification titled "Hello" to recipients, or to "a" if none are given
+func newMessage(c *Client, recipients ...string) *Notification {
+	n, _ := c.NewNotification("Hello")
+	if len(recipients) == 0 {
+		recipients = []string{"a"}
+	}
+	for _, r := range recipients {
+		n.AddRecipient(r)
+	}
+	return n
+}
+
+// ids "user-0" to "user-<count-1>"
+func recipientIds(count int) []string {
+	ids := make([]string, count)
+	for i := range ids {
+		ids[i] = fmt.Sprintf("user-%d", i)
+	}
+	return ids
+}
+
+// send newMessage to recipients
+func sendTo(c *Client, recipients ...string) error {
+	_, err := newMessage(c, recipients...).SendCtx(context.Background())
+	return err
+}
+
+func encodedPayload(t *testing.T, n *Notification) map[string]interface{} {
+	b, err := json.Marshal(n)
+	assert.NoError(t, err)
+	var payload map[string]interface{}
+	assert.NoError(t, json.Unmarshal(b, &payload))
+	return payload
+}
+
+func TestUnsetFieldsNotSent(t *testing.T) {
+	n := newTestNotification(t)
+	n.AddRecipient("a")
+	assert.Equal(t, map[string]interface{}{
+		"notification": map[string]interface{}{"title": "Invoice"},
+		"recipients":   []interface{}{"a"},
+	}, encodedPayload(t, n))
+}
+
+func TestZeroFieldsNotSent(t *testing.T) {
+	n := newTestNotification(t)
+	n.AddRecipient("a")
+	n.Notification.Message = ""
+	n.Notification.Url = ""
+	n.Notification.Icon = ""
+	n.Notification.Data = map[string]interface{}{}
+	n.Category = ""
+	n.Override.Channels = []string{}
+	n.Override.SendgridEmail = map[string]interface{}{}
+	n.Override.SmtpEmail = map[string]interface{}{}
+
+	// values which are never valid when empty are left out like unset ones
+	assert.Equal(t, map[string]interface{}{
+		"notification": map[string]interface{}{"title": "Invoice"},
+		"recipients":   []interface{}{"a"},
+	}, encodedPayload(t, n))
+}
+
+func TestPointerFieldsSentWhenZero(t *testing.T) {
+	n := newTestNotification(t)
+	n.AddRecipient("a")
+	assert.NotContains(t, encodedPayload(t, n), "sendAt")
+
+	zero := time.Unix(0, 0).UTC()
+	n.SendAt = &zero
+	assert.Equal(t, "1970-01-01T00:00:00Z", encodedPayload(t, n)["sendAt"])
+}
+
+func TestRecipientOptionalFields(t *testing.T) {
+	b, err := json.Marshal(Recipient{Identifier: "a@example.com"})
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"identifier":"a@example.com"}`, string(b))
+
+	b, err = json.Marshal(Recipient{Identifier: "a@example.com", Email: "a@example.com", Name: "A", Locale: "en"})
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"identifier":"a@example.com","email":"a@example.com","name":"A","locale":"en"}`, string(b))
+}
+
+func TestWorkflowOptionalFields(t *testing.T) {
+	n := newTestNotification(t)
+	n.AddRecipient("a")
+	b, err := json.Marshal(n.workflowTrigger("invoice"))
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"workflow":{"identifier":"invoice"},"sendTo":{"recipients":[{"identifier":"a"}]},"data":{"title":"Invoice"}}`, string(b))
+}
+
+func TestSendUnencodablePayload(t *testing.T) {
+	c := NewEngagespotClient("A", "B")
+	c.httpClient.Transport = noNetwork(t)
+
+	n := newMessage(c)
+	n.AddData("ratio", math.NaN())
+	_, err := n.SendCtx(context.Background())
+	var valueErr *json.UnsupportedValueError
+	assert.ErrorAs(t, err, &valueErr)
+
+	n = newMessage(c)
+	n.AddData("updates", make(chan int))
+	_, err = n.SendCtx(context.Background())
+	var typeErr *json.UnsupportedTypeError
+	assert.ErrorAs(t, err, &typeErr)
+	assert.Contains(t, err.Error(), "encoding notification")
+}
+
+// response body recording whether it was read to the end and closed
+type trackedBody struct {
+	io.Reader
+	drained, closed bool
+}
+
+func (b *trackedBody) Read(p []byte) (int, error) {
+	n, err := b.Reader.Read(p)
+	if err == io.EOF {
+		b.drained = true
+	}
+	return n, err
+}
+
+func (b *trackedBody) Close() error {
+	b.closed = true
+	return nil
+}
+
+// transport answering with the given statuses in turn, and the bodies of its responses
+func trackedResponses(statuses ...int) (http.RoundTripper, *[]*trackedBody) {
+	var bodies []*trackedBody
+	return roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		body := &trackedBody{Reader: strings.NewReader(`{"id":"1","message":"` + strings.Repeat("x", 64) + `"}`)}
+		bodies = append(bodies, body)
+		return &http.Response{StatusCode: statuses[len(bodies)-1], Header: http.Header{}, Body: body, Request: req}, nil
+	}), &bodies
+}
+
+func TestInternalResponsesDrained(t *testing.T) {
+	c := chunkedClient(2)
+	transport, bodies := trackedResponses(200, 200, 200)
+	c.httpClient.Transport = transport
+	n, _ := c.NewNotification("Hello")
+	n.SetRecipientSource(sliceSource(recipientIds(6), -1, nil))
+	_, err := n.SendCtx(context.Background())
+	assert.NoError(t, err)
+
+	var waits []time.Duration
+	c = retryingClient(newFakeClock(), &waits)
+	transport, retried := trackedResponses(503, 503, 200)
+	c.httpClient.Transport = transport
+	err = sendTo(c)
+	assert.NoError(t, err)
+
+	for _, body := range append(*bodies, *retried...) {
+		assert.True(t, body.drained)
+		assert.True(t, body.closed)
+	}
+	assert.Len(t, *bodies, 3)
+	assert.Len(t, *retried, 3)
+}
+
+func TestTitleValidation(t *testing.T) {
+	c := NewEngagespotClient("A", "B")
+	for title, expected := range map[string]string{
+		"":                                    "empty title string",
+		" \t\n":                               "empty title string",
+		strings.Repeat("é", MaxTitleLength+1): fmt.Sprintf("title is %d characters long, at most %d are accepted", MaxTitleLength+1, MaxTitleLength),
+	} {
+		_, err := c.NewNotification(title)
+		assert.EqualError(t, err, expected)
+
+		n := newTestNotification(t)
+		_, err = n.SetTitle(title)
+		assert.EqualError(t, err, expected)
+		assert.Equal(t, "Invoice", n.Notification.Title)
+	}
+
+	n, err := c.NewNotification(strings.Repeat("é", MaxTitleLength))
+	assert.NoError(t, err)
+	_, err = n.SetTitle("Receipt")
+	assert.NoError(t, err)
+	assert.Equal(t, "Receipt", n.Notification.Title)
+}
+
+func TestTitleValidatedOnSend(t *testing.T) {
+	c := NewEngagespotClient("A", "B")
+	c.httpClient.Transport = noNetwork(t)
+	n := newMessage(c)
+	n.Notification.Title = " "
+	_, err := n.SendCtx(context.Background())
+	assert.EqualError(t, err, "empty title string")
+}
@@ -0,0 +1,177 @@
+// Package engagespottest helps testing code using the SDK. FakeClient stands in for a client in
+// unit tests, and VerifyCompatibility checks that upgrading the SDK doesn't change the requests
+// sent to Engagespot, by comparing the requests built for common call patterns with golden records
+package engagespottest
+
+import (
+	"bytes"
+	"embed"
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"testing"
+	"time"
+
+	engagespot "github.com/ssiyad/engagespot-go"
+)
+
+// BaselineVersion is the version of the golden records shipped with the SDK which
+// VerifyCompatibility compares with. a new version is only added when the requests sent by the
+// SDK change on purpose
+const BaselineVersion = "v1"
+
+//go:embed golden
+var golden embed.FS
+
+// Client is the part of the engagespot client VerifyCompatibility needs
+type Client interface {
+	CompatibilityRecords() (map[string]engagespot.ContractRecord, error)
+}
+
+// placeholder of timestamps in normalized records
+const timestampPlaceholder = "<timestamp>"
+
+// Normalize replaces the volatile values of r, which change from one run to the next without
+// changing the shape of the request, with placeholders: every string of the body which is an
+// RFC 3339 timestamp. credentials are already left out of contract records
+func Normalize(r engagespot.ContractRecord) (engagespot.ContractRecord, error) {
+	if len(r.Body) == 0 {
+		return r, nil
+	}
+	var body interface{}
+	if err := json.Unmarshal(r.Body, &body); err != nil {
+		return r, err
+	}
+	normalized, err := marshal(normalizeValue(body), "")
+	if err != nil {
+		return r, err
+	}
+	r.Body = normalized
+	return r, nil
+}
+
+// json of v, without escaping the placeholders
+func marshal(v interface{}, indent string) ([]byte, error) {
+	var b bytes.Buffer
+	e := json.NewEncoder(&b)
+	e.SetEscapeHTML(false)
+	e.SetIndent("", indent)
+	if err := e.Encode(v); err != nil {
+		return nil, err
+	}
+	return bytes.TrimSuffix(b.Bytes(), []byte("\n")), nil
+}
+
+func normalizeValue(v interface{}) interface{} {
+	switch v := v.(type) {
+	case string:
+		if _, err := time.Parse(time.RFC3339, v); err == nil {
+			return timestampPlaceholder
+		}
+	case map[string]interface{}:
+		for key, child := range v {
+			v[key] = normalizeValue(child)
+		}
+	case []interface{}:
+		for i, child := range v {
+			v[i] = normalizeValue(child)
+		}
+	}
+	return v
+}
+
+// VerifyCompatibility fails t if the requests built by c differ from the golden records of
+// BaselineVersion, which are built by a client without options. run it with the options used in
+// production to catch options changing the bytes sent, along with changes of the SDK
+func VerifyCompatibility(t testing.TB, c Client) {
+	t.Helper()
+	sub, err := fs.Sub(golden, path.Join("golden", BaselineVersion))
+	if err != nil {
+		t.Fatalf("engagespottest: %s", err)
+	}
+	verify(t, c, sub, "")
+}
+
+// VerifyCompatibilityDir is VerifyCompatibility with golden records kept in dir, usually a
+// testdata directory of the caller, so requests are compared with those of the client
+// configuration of the caller. missing records are written, to be committed along with the tests
+func VerifyCompatibilityDir(t testing.TB, c Client, dir string) {
+	t.Helper()
+	verify(t, c, os.DirFS(dir), dir)
+}
+
+// compare the records of c with those of fsys. missing records are written to dir, if any
+func verify(t testing.TB, c Client, fsys fs.FS, dir string) {
+	t.Helper()
+	records, err := c.CompatibilityRecords()
+	if err != nil {
+		t.Fatalf("engagespottest: %s", err)
+	}
+
+	names := make([]string, 0, len(records))
+	for name := range records {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		got, err := Normalize(records[name])
+		if err != nil {
+			t.Fatalf("engagespottest: %s: %s", name, err)
+		}
+
+		b, err := fs.ReadFile(fsys, name+".json")
+		if errors.Is(err, fs.ErrNotExist) && dir != "" {
+			if err := writeRecord(filepath.Join(dir, name+".json"), got); err != nil {
+				t.Fatalf("engagespottest: %s: %s", name, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("engagespottest: %s: no golden record: %s", name, err)
+			continue
+		}
+
+		var want engagespot.ContractRecord
+		if err := json.Unmarshal(b, &want); err != nil {
+			t.Fatalf("engagespottest: %s: %s", name, err)
+		}
+		for _, d := range engagespot.CompareContractRecords(want, got) {
+			t.Errorf("engagespottest: %s: request changed, %s", name, d)
+		}
+	}
+}
+
+// WriteGolden writes the normalized records of c to dir, one file per scenario, replacing the
+// records already there
+func WriteGolden(c Client, dir string) error {
+	records, err := c.CompatibilityRecords()
+	if err != nil {
+		return err
+	}
+	for name, record := range records {
+		normalized, err := Normalize(record)
+		if err != nil {
+			return err
+		}
+		if err := writeRecord(filepath.Join(dir, name+".json"), normalized); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func writeRecord(file string, r engagespot.ContractRecord) error {
+	if err := os.MkdirAll(filepath.Dir(file), 0o755); err != nil {
+		return err
+	}
+	b, err := marshal(r, "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(file, append(b, '\n'), 0o644)
+}
@@ -0,0 +1,65 @@
+package engagespottest
+
+import (
+	"flag"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	engagespot "github.com/ssiyad/engagespot-go"
+	"github.com/stretchr/testify/assert"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden records of BaselineVersion")
+
+// TB recording failures instead of failing the test
+type recorder struct {
+	testing.TB
+	errors []string
+}
+
+func (r *recorder) Errorf(format string, args ...interface{}) {
+	r.errors = append(r.errors, fmt.Sprintf(format, args...))
+}
+
+func TestBaseline(t *testing.T) {
+	c := engagespot.NewEngagespotClient("A", "B")
+	if *update {
+		assert.NoError(t, WriteGolden(c, filepath.Join("golden", BaselineVersion)))
+	}
+	VerifyCompatibility(t, c)
+}
+
+func TestBaselineIgnoresCredentials(t *testing.T) {
+	VerifyCompatibility(t, engagespot.NewEngagespotClient("other key", "other secret", engagespot.WithHmac()))
+}
+
+func TestDetectedChange(t *testing.T) {
+	r := &recorder{TB: t}
+	VerifyCompatibility(r, engagespot.NewEngagespotClient("A", "B", engagespot.WithUserIDNormalizer(strings.ToUpper)))
+
+	assert.Equal(t, []string{
+		`engagespottest: minimal-send: request changed, body.recipients: ["user@example.com"] != ["USER@EXAMPLE.COM"]`,
+		`engagespottest: personalized-send: request changed, body.recipients: ["ada@example.com","grace@example.com"] != ["ADA@EXAMPLE.COM","GRACE@EXAMPLE.COM"]`,
+		`engagespottest: send-with-overrides: request changed, body.recipients: ["user@example.com"] != ["USER@EXAMPLE.COM"]`,
+	}, r.errors)
+}
+
+func TestVerifyCompatibilityDir(t *testing.T) {
+	dir := t.TempDir()
+	VerifyCompatibilityDir(t, engagespot.NewEngagespotClient("A", "B", engagespot.WithUserIDNormalizer(strings.ToUpper)), dir)
+	VerifyCompatibilityDir(t, engagespot.NewEngagespotClient("A", "B", engagespot.WithUserIDNormalizer(strings.ToUpper)), dir)
+
+	r := &recorder{TB: t}
+	defaults := engagespot.DefaultConfig()
+	defaults.DeviceType = "android"
+	VerifyCompatibilityDir(r, engagespot.NewEngagespotClient("A", "B", engagespot.WithDefaults(defaults), engagespot.WithUserIDNormalizer(strings.ToUpper)), dir)
+	assert.Equal(t, []string{`engagespottest: connect: request changed, headers.x-engagespot-device-type: "ios" != "android"`}, r.errors)
+}
+
+func TestNormalize(t *testing.T) {
+	r, err := Normalize(engagespot.ContractRecord{Body: []byte(`{"sendAt": "2030-01-01T09:00:00Z", "data": {"at": ["2022-01-01T00:00:00+01:00"], "note": "2030"}}`)})
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"sendAt": "<timestamp>", "data": {"at": ["<timestamp>"], "note": "2030"}}`, string(r.Body))
+}
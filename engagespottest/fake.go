@@ -0,0 +1,95 @@
+package engagespottest
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+
+	engagespot "github.com/ssiyad/engagespot-go"
+)
+
+// FakeClient is an engagespot.EngagespotClient which never reaches Engagespot. notifications go
+// through the same validation as with a real client, every request is answered with a success,
+// and what was sent is recorded for tests to assert on
+type FakeClient struct {
+	engagespot.EngagespotClient
+
+	mu        sync.Mutex
+	sent      []engagespot.NotificationSnapshot
+	connected []string
+	requests  int
+}
+
+// NewFakeClient returns a FakeClient built with opts, on top of the options it needs itself
+func NewFakeClient(opts ...engagespot.Option) *FakeClient {
+	f := &FakeClient{}
+	opts = append(opts,
+		engagespot.WithHTTPClient(&http.Client{Transport: f}),
+		engagespot.WithSendObserver(f.observe),
+	)
+	f.EngagespotClient = engagespot.NewEngagespotClient("fake-key", "fake-secret", opts...)
+	return f
+}
+
+func (f *FakeClient) observe(s engagespot.NotificationSnapshot) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.sent = append(f.sent, s)
+}
+
+// RoundTrip answers every request of the client with a success, sends get a fake id
+func (f *FakeClient) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Body != nil {
+		io.Copy(io.Discard, req.Body)
+		req.Body.Close()
+	}
+
+	f.mu.Lock()
+	f.requests++
+	body := fmt.Sprintf(`{"id":"fake-%d"}`, f.requests)
+	if strings.HasSuffix(req.URL.Path, "/sdk/connect") {
+		f.connected = append(f.connected, req.Header.Get("X-ENGAGESPOT-USER-ID"))
+	}
+	f.mu.Unlock()
+
+	return &http.Response{
+		StatusCode: http.StatusOK,
+		Header:     http.Header{"Content-Type": {"application/json"}},
+		Body:       io.NopCloser(strings.NewReader(body)),
+		Request:    req,
+	}, nil
+}
+
+// Sent returns the notifications sent so far, in order
+func (f *FakeClient) Sent() []engagespot.NotificationSnapshot {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]engagespot.NotificationSnapshot{}, f.sent...)
+}
+
+// LastSent returns the last notification sent, false if none was
+func (f *FakeClient) LastSent() (engagespot.NotificationSnapshot, bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if len(f.sent) == 0 {
+		return engagespot.NotificationSnapshot{}, false
+	}
+	return f.sent[len(f.sent)-1], true
+}
+
+// Connected returns the users connected so far, in order
+func (f *FakeClient) Connected() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]string{}, f.connected...)
+}
+
+// Reset forgets everything recorded so far
+func (f *FakeClient) Reset() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.sent = nil
+	f.connected = nil
+}
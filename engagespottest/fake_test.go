@@ -0,0 +1,74 @@
+package engagespottest
+
+import (
+	"context"
+	"testing"
+
+	engagespot "github.com/ssiyad/engagespot-go"
+	"github.com/stretchr/testify/assert"
+)
+
+// code under test, depending on the interface only
+func notifyOrderShipped(c engagespot.EngagespotClient, user string) error {
+	n, err := c.NewNotification("Order shipped")
+	if err != nil {
+		return err
+	}
+	n.Message("it's on its way").Recipient(user)
+	if _, err := n.SetChannels(engagespot.ChannelInApp, engagespot.ChannelEmail); err != nil {
+		return err
+	}
+	_, err = c.SendCtx(context.Background(), n)
+	return err
+}
+
+func TestFakeClientRecordsSends(t *testing.T) {
+	f := NewFakeClient()
+	assert.NoError(t, notifyOrderShipped(f, "ada@example.com"))
+
+	sent, ok := f.LastSent()
+	assert.True(t, ok)
+	assert.Equal(t, "Order shipped", sent.Title)
+	assert.Equal(t, "it's on its way", sent.Message)
+	assert.Equal(t, []string{"ada@example.com"}, sent.Recipients)
+	assert.Equal(t, []string{engagespot.ChannelInApp, engagespot.ChannelEmail}, sent.Channels)
+	assert.Len(t, f.Sent(), 1)
+
+	f.Reset()
+	assert.Empty(t, f.Sent())
+	_, ok = f.LastSent()
+	assert.False(t, ok)
+}
+
+func TestFakeClientResults(t *testing.T) {
+	f := NewFakeClient()
+	n, _ := f.NewNotification("Hello")
+	n.AddRecipient("a")
+
+	res, err := f.SendCtx(context.Background(), n)
+	assert.NoError(t, err)
+	assert.Equal(t, "fake-1", res.Id)
+
+	r := <-f.SendAsync(n)
+	assert.NoError(t, r.Err)
+	assert.Len(t, f.Sent(), 2)
+	assert.NoError(t, f.Close())
+}
+
+func TestFakeClientValidates(t *testing.T) {
+	f := NewFakeClient()
+	n, _ := f.NewNotification("Hello")
+
+	_, err := f.SendCtx(context.Background(), n)
+	assert.Error(t, err)
+	assert.Empty(t, f.Sent())
+}
+
+func TestFakeClientConnect(t *testing.T) {
+	f := NewFakeClient(engagespot.WithUserIDNormalizer(func(id string) string { return "user-" + id }))
+	res, err := f.ConnectCtx(context.Background(), "a")
+	assert.NoError(t, err)
+	res.Body.Close()
+
+	assert.Equal(t, []string{"user-a"}, f.Connected())
+}
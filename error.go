@@ -0,0 +1,117 @@
+package engagespot
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+// OperationError wraps a transport level error, like a DNS failure or a timeout, with the SDK
+// operation and the endpoint which caused it. the original error is still reachable using
+// errors.Is and errors.As
+type OperationError struct {
+	Op       string
+	Endpoint string
+	// idempotency or correlation key of the request, if any
+	Key string
+	// phase of the call which failed, like PhaseExpectContinue. empty if not known
+	Phase string
+	Err   error
+}
+
+func (e *OperationError) Error() string {
+	msg := "engagespot: " + e.Op + " " + e.Endpoint
+	if e.Key != "" {
+		msg += " (" + e.Key + ")"
+	}
+	if e.Phase != "" {
+		msg += " [" + e.Phase + "]"
+	}
+	return msg + ": " + e.Err.Error()
+}
+
+func (e *OperationError) Unwrap() error {
+	return e.Err
+}
+
+// sentinels matched by errors.Is against an APIError with the corresponding status
+var (
+	ErrUnauthorized = errors.New("unauthorized")
+	ErrForbidden    = errors.New("forbidden")
+	ErrNotFound     = errors.New("not found")
+	ErrRateLimited  = errors.New("rate limited")
+)
+
+var statusSentinels = map[int]error{
+	http.StatusUnauthorized:    ErrUnauthorized,
+	http.StatusForbidden:       ErrForbidden,
+	http.StatusNotFound:        ErrNotFound,
+	http.StatusTooManyRequests: ErrRateLimited,
+}
+
+// APIError is returned for requests the API answered with a non-2xx status
+type APIError struct {
+	Op       string
+	Endpoint string
+	// status of the response
+	StatusCode int
+	// machine readable code of the error body, if any
+	Code string
+	// message of the error body, the status text if the body has none
+	Message string
+}
+
+func (e *APIError) Error() string {
+	msg := fmt.Sprintf("engagespot: %s %s: unexpected status %d", e.Op, e.Endpoint, e.StatusCode)
+	if e.Code != "" {
+		msg += " (" + e.Code + ")"
+	}
+	if e.Message != "" {
+		msg += ": " + e.Message
+	}
+	return msg
+}
+
+// Is reports whether target is the sentinel for the status of e, like ErrUnauthorized for a 401
+func (e *APIError) Is(target error) bool {
+	sentinel, ok := statusSentinels[e.StatusCode]
+	return ok && sentinel == target
+}
+
+// error for a non-2xx response of e with the given body. the code and message are read from
+// bodies like {"code": "...", "message": "..."} or {"error": "..."}. bodies which aren't JSON fall
+// back to the status text
+func newAPIError(e endpoint, status int, body []byte) *APIError {
+	err := &APIError{Op: e.op, Endpoint: e.path, StatusCode: status}
+
+	var payload struct {
+		Code    json.RawMessage `json:"code"`
+		Message string          `json:"message"`
+		Error   string          `json:"error"`
+	}
+	if json.Unmarshal(body, &payload) == nil {
+		err.Code = errorCode(payload.Code)
+		err.Message = payload.Message
+		if err.Message == "" {
+			err.Message = payload.Error
+		}
+	}
+	if err.Message == "" {
+		err.Message = http.StatusText(status)
+	}
+	return err
+}
+
+// codes are strings on most endpoints but numbers on some
+func errorCode(raw json.RawMessage) string {
+	var code string
+	if json.Unmarshal(raw, &code) == nil {
+		return code
+	}
+	var n json.Number
+	if json.Unmarshal(raw, &n) == nil {
+		return n.String()
+	}
+	return ""
+}
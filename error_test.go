@@ -0,0 +1,139 @@
+package engagespot
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func clientFor(endpoint string, timeout time.Duration, opts ...Option) *Client {
+	d := DefaultConfig()
+	d.Endpoint = endpoint
+	d.Timeout = timeout
+	return NewEngagespotClient("A", "B", append(opts, WithDefaults(d))...)
+}
+
+func connectTo(c *Client) error {
+	_, err := c.Connect("hello@example.com")
+	return err
+}
+
+var operations = map[string]func(*Client) error{
+	"Send":    func(c *Client) error { return sendTo(c) },
+	"Connect": connectTo,
+}
+
+func TestOperationErrorDNSFailure(t *testing.T) {
+	c := clientFor("http://engagespot.invalid/v3/", 5*time.Second)
+	for op, call := range operations {
+		err := call(c)
+
+		var opErr *OperationError
+		assert.True(t, errors.As(err, &opErr), op)
+		assert.Equal(t, op, opErr.Op)
+		assert.True(t, strings.HasPrefix(err.Error(), "engagespot: "+op+" "+opErr.Endpoint+": "), err.Error())
+
+		var dnsErr *net.DNSError
+		assert.True(t, errors.As(err, &dnsErr), op)
+	}
+}
+
+func TestOperationErrorTimeout(t *testing.T) {
+	done := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-done
+	}))
+	defer server.Close()
+	defer close(done)
+
+	c := clientFor(server.URL+"/", 50*time.Millisecond)
+	for op, call := range operations {
+		err := call(c)
+
+		var opErr *OperationError
+		assert.True(t, errors.As(err, &opErr), op)
+		assert.Equal(t, op, opErr.Op)
+
+		var netErr net.Error
+		assert.True(t, errors.As(err, &netErr), op)
+		assert.True(t, netErr.Timeout(), op)
+	}
+}
+
+func TestOperationErrorConnectionRefused(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	addr := l.Addr().String()
+	l.Close()
+
+	c := clientFor("http://"+addr+"/", 5*time.Second)
+	for op, call := range operations {
+		err := call(c)
+
+		var opErr *OperationError
+		assert.True(t, errors.As(err, &opErr), op)
+		assert.Equal(t, op, opErr.Op)
+		assert.True(t, errors.Is(err, syscall.ECONNREFUSED), op)
+	}
+}
+
+func TestOperationErrorKey(t *testing.T) {
+	err := &OperationError{Op: "Send", Endpoint: "notifications", Key: "k1", Err: errors.New("boom")}
+	assert.Equal(t, "engagespot: Send notifications (k1): boom", err.Error())
+}
+
+func TestAPIErrorBody(t *testing.T) {
+	err := newAPIError(endpointSendNotification, 400, []byte(`{"code":"invalid_recipient","message":"recipient is not valid"}`))
+	assert.Equal(t, &APIError{Op: "Send", Endpoint: "notifications", StatusCode: 400, Code: "invalid_recipient", Message: "recipient is not valid"}, err)
+	assert.EqualError(t, err, "engagespot: Send notifications: unexpected status 400 (invalid_recipient): recipient is not valid")
+
+	assert.Equal(t, "4001", newAPIError(endpointSendNotification, 400, []byte(`{"code":4001}`)).Code)
+}
+
+func TestAPIErrorMalformedBody(t *testing.T) {
+	for _, body := range []string{``, `<html>oops</html>`, `{"message":`, `{"error":{"nested":true}}`, `{}`} {
+		err := newAPIError(endpointConnect, 500, []byte(body))
+		assert.Empty(t, err.Code, body)
+		assert.Equal(t, "Internal Server Error", err.Message, body)
+	}
+}
+
+func TestAPIErrorSentinels(t *testing.T) {
+	for status, sentinel := range statusSentinels {
+		err := error(newAPIError(endpointConnect, status, nil))
+		assert.True(t, errors.Is(fmt.Errorf("wrapped: %w", err), sentinel), status)
+	}
+	assert.False(t, errors.Is(newAPIError(endpointConnect, 400, nil), ErrUnauthorized))
+	assert.False(t, errors.Is(newAPIError(endpointConnect, 401, nil), ErrRateLimited))
+}
+
+func TestAPIErrorReturned(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(401)
+		w.Write([]byte(`{"code":"invalid_key","message":"bad api key"}`))
+	}))
+	defer server.Close()
+
+	c := clientFor(server.URL+"/", time.Second)
+	for op, call := range operations {
+		err := call(c)
+		assert.True(t, errors.Is(err, ErrUnauthorized), op)
+
+		var apiErr *APIError
+		assert.True(t, errors.As(err, &apiErr), op)
+		assert.Equal(t, op, apiErr.Op)
+		assert.Equal(t, "invalid_key", apiErr.Code)
+		assert.Equal(t, "bad api key", apiErr.Message)
+	}
+
+	_, err := c.GetAppInfo()
+	assert.True(t, errors.Is(err, ErrUnauthorized))
+}
@@ -0,0 +1,217 @@
+package engagespot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// notifications per page when ListOptions.Limit isn't set
+const defaultNotificationPageSize = 50
+
+// ErrNotificationNotFound is returned by the calls on a notification of a user for notifications
+// which don't exist. the *APIError of the 404 is still reachable with errors.As
+var ErrNotificationNotFound = errors.New("notification not found")
+
+// tell a 404 of a call on a notification apart from other errors
+func notificationNotFound(err error) error {
+	if errors.Is(err, ErrNotFound) {
+		return fmt.Errorf("%w: %w", ErrNotificationNotFound, err)
+	}
+	return err
+}
+
+// build a request for an endpoint of the feed of a user, made on behalf of the user. params fill
+// the segments of the path after the user id
+func (c *Client) newUserRequest(ctx context.Context, e endpoint, userId string, params ...string) (*http.Request, error) {
+	userId = c.NormalizeUserID(userId)
+	req, err := c.newRequestContext(ctx, e, nil, append([]string{userId}, params...)...)
+	if err != nil {
+		return nil, err
+	}
+	for name, values := range c.userHeaders(userId) {
+		req.Header[name] = values
+	}
+	return req, nil
+}
+
+// NotificationRecord is a notification received by a user, as listed by ListNotifications
+type NotificationRecord struct {
+	Response
+	Id       string `json:"id"`
+	Title    string `json:"title"`
+	Message  string `json:"message,omitempty"`
+	Url      string `json:"url,omitempty"`
+	Icon     string `json:"icon,omitempty"`
+	Category string `json:"category,omitempty"`
+	// nil until the user reads the notification
+	ReadAt    *time.Time `json:"readAt,omitempty"`
+	CreatedAt time.Time  `json:"createdAt"`
+}
+
+// Read tells if the user has read the notification
+func (r NotificationRecord) Read() bool {
+	return r.ReadAt != nil
+}
+
+// ListOptions selects a page of a listing. Limit defaults to 50
+type ListOptions struct {
+	Limit  int
+	Offset int
+}
+
+// NotificationPage is a page of the notifications of a user. TotalCount is the number of
+// notifications of the user across every page
+type NotificationPage struct {
+	Response
+	Notifications []NotificationRecord `json:"data"`
+	TotalCount    int                  `json:"totalCount"`
+}
+
+// ListNotifications can be used to list a page of the notifications received by a user, most
+// recent first. the request is made on behalf of the user, signed if HMAC is enabled, the same way
+// as Connect
+func (c *Client) ListNotifications(ctx context.Context, userId string, opts ListOptions, callOpts ...CallOption) (*NotificationPage, error) {
+	if opts.Limit < 0 || opts.Offset < 0 {
+		return nil, errors.New("negative limit or offset")
+	}
+	if opts.Limit == 0 {
+		opts.Limit = defaultNotificationPageSize
+	}
+
+	req, err := c.newUserRequest(applyCallOptions(ctx, callOpts), endpointListNotifications, userId)
+	if err != nil {
+		return nil, err
+	}
+	req.URL.RawQuery = url.Values{
+		"limit":  {strconv.Itoa(opts.Limit)},
+		"offset": {strconv.Itoa(opts.Offset)},
+	}.Encode()
+
+	page := &NotificationPage{}
+	if err := c.callJSON(endpointListNotifications, req, page); err != nil {
+		return nil, err
+	}
+	return page, nil
+}
+
+// NotificationIterator is an iterator over the notifications of a user. it returns false once
+// there are no more notifications
+type NotificationIterator func(ctx context.Context) (NotificationRecord, bool, error)
+
+// IterateNotifications can be used to go through every notification of a user, from opts onwards,
+// without keeping track of offsets. pages of opts.Limit notifications are fetched as they are
+// needed
+//
+//	next := c.IterateNotifications(userId, ListOptions{})
+//	for {
+//		record, ok, err := next(ctx)
+//		if err != nil || !ok {
+//			break
+//		}
+//	}
+func (c *Client) IterateNotifications(userId string, opts ListOptions, callOpts ...CallOption) NotificationIterator {
+	var page []NotificationRecord
+	done := false
+	return func(ctx context.Context) (NotificationRecord, bool, error) {
+		if len(page) == 0 && !done {
+			p, err := c.ListNotifications(ctx, userId, opts, callOpts...)
+			if err != nil {
+				return NotificationRecord{}, false, err
+			}
+			page = p.Notifications
+			opts.Offset += len(page)
+			done = len(page) == 0 || opts.Offset >= p.TotalCount
+		}
+		if len(page) == 0 {
+			return NotificationRecord{}, false, nil
+		}
+		record := page[0]
+		page = page[1:]
+		return record, true, nil
+	}
+}
+
+// MarkAsRead can be used to mark a notification of a user as read, like when it's dismissed in
+// another UI. the updated notification is returned. ErrNotificationNotFound is returned if the
+// user has no such notification
+func (c *Client) MarkAsRead(ctx context.Context, userId, notificationId string, opts ...CallOption) (*NotificationRecord, error) {
+	if notificationId == "" {
+		return nil, errors.New("empty notification id string")
+	}
+	req, err := c.newUserRequest(applyCallOptions(ctx, opts), endpointMarkAsRead, userId, notificationId)
+	if err != nil {
+		return nil, err
+	}
+
+	record := &NotificationRecord{}
+	if err := c.callJSON(endpointMarkAsRead, req, record); err != nil {
+		return nil, notificationNotFound(err)
+	}
+	return record, nil
+}
+
+// MarkAllResult is the outcome of MarkAllAsRead
+type MarkAllResult struct {
+	Response
+	// notifications which were unread
+	Updated int `json:"updatedCount"`
+}
+
+// MarkAllAsRead can be used to mark every notification of a user as read
+func (c *Client) MarkAllAsRead(ctx context.Context, userId string, opts ...CallOption) (*MarkAllResult, error) {
+	req, err := c.newUserRequest(applyCallOptions(ctx, opts), endpointMarkAllAsRead, userId)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &MarkAllResult{}
+	if err := c.callJSON(endpointMarkAllAsRead, req, result); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// DeleteNotification can be used to remove a notification from the feed of a user, like when
+// what it's about is deleted. the id of a notification is in the SendResult of its send.
+// ErrNotificationNotFound is returned if the user has no such notification
+func (c *Client) DeleteNotification(ctx context.Context, userId, notificationId string, opts ...CallOption) error {
+	if notificationId == "" {
+		return errors.New("empty notification id string")
+	}
+	req, err := c.newUserRequest(applyCallOptions(ctx, opts), endpointDeleteNotification, userId, notificationId)
+	if err != nil {
+		return err
+	}
+	return notificationNotFound(c.callJSON(endpointDeleteNotification, req, nil))
+}
+
+// response of `GET users/{userId}/notifications/unread-count`
+type unreadCount struct {
+	Count *int `json:"count"`
+}
+
+// GetUnreadCount can be used to get the number of unread notifications of a user, like for a badge,
+// without listing them. a response without a valid count is an error
+func (c *Client) GetUnreadCount(ctx context.Context, userId string, opts ...CallOption) (int, error) {
+	req, err := c.newUserRequest(applyCallOptions(ctx, opts), endpointGetUnreadCount, userId)
+	if err != nil {
+		return 0, err
+	}
+
+	unread := &unreadCount{}
+	if err := c.callJSON(endpointGetUnreadCount, req, unread); err != nil {
+		return 0, err
+	}
+	if unread.Count == nil {
+		return 0, errors.New("no unread count in response")
+	}
+	if *unread.Count < 0 {
+		return 0, fmt.Errorf("invalid unread count %d in response", *unread.Count)
+	}
+	return *unread.Count, nil
+}
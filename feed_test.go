@@ -0,0 +1,239 @@
+package engagespot
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// fake feed of a single user, paginated like the API
+type feedServer struct {
+	records  []NotificationRecord
+	requests []*http.Request
+}
+
+func (s *feedServer) RoundTrip(req *http.Request) (*http.Response, error) {
+	s.requests = append(s.requests, req)
+	limit, _ := strconv.Atoi(req.URL.Query().Get("limit"))
+	offset, _ := strconv.Atoi(req.URL.Query().Get("offset"))
+	from, to := offset, offset+limit
+	if from > len(s.records) {
+		from = len(s.records)
+	}
+	if to > len(s.records) {
+		to = len(s.records)
+	}
+	b, _ := json.Marshal(NotificationPage{Notifications: s.records[from:to], TotalCount: len(s.records)})
+	return respondWith(200, string(b)).RoundTrip(req)
+}
+
+func feedOf(count int) *feedServer {
+	s := &feedServer{}
+	created := time.Date(2022, 1, 2, 3, 4, 5, 0, time.UTC)
+	for i := 0; i < count; i++ {
+		s.records = append(s.records, NotificationRecord{Id: fmt.Sprintf("n-%d", i), Title: "Hello", CreatedAt: created})
+	}
+	return s
+}
+
+func TestListNotifications(t *testing.T) {
+	s := feedOf(3)
+	read := time.Date(2022, 1, 3, 0, 0, 0, 0, time.UTC)
+	s.records[1].ReadAt = &read
+	c := NewEngagespotClient("A", "B", WithHmac())
+	c.httpClient.Transport = s
+
+	page, err := c.ListNotifications(context.Background(), "a@example.com", ListOptions{Limit: 2, Offset: 1})
+	assert.NoError(t, err)
+	assert.Equal(t, 3, page.TotalCount)
+	assert.Equal(t, s.records[1:], page.Notifications)
+	assert.True(t, page.Notifications[0].Read())
+	assert.False(t, page.Notifications[1].Read())
+
+	req := s.requests[0]
+	assert.Equal(t, "/v3/users/a@example.com/notifications", req.URL.Path)
+	assert.Equal(t, "limit=2&offset=1", req.URL.RawQuery)
+	assert.Equal(t, "a@example.com", req.Header.Get("X-ENGAGESPOT-USER-ID"))
+	assert.Equal(t, c.GenHmac("a@example.com"), req.Header.Get("X-ENGAGESPOT-USER-SIGNATURE"))
+}
+
+func TestListNotificationsDefaults(t *testing.T) {
+	s := feedOf(0)
+	c := NewEngagespotClient("A", "B")
+	c.httpClient.Transport = s
+
+	page, err := c.ListNotifications(context.Background(), "a", ListOptions{})
+	assert.NoError(t, err)
+	assert.Empty(t, page.Notifications)
+	assert.Equal(t, "limit=50&offset=0", s.requests[0].URL.RawQuery)
+	assert.Empty(t, s.requests[0].Header.Get("X-ENGAGESPOT-USER-SIGNATURE"))
+
+	_, err = c.ListNotifications(context.Background(), "a", ListOptions{Offset: -1})
+	assert.EqualError(t, err, "negative limit or offset")
+}
+
+func TestIterateNotifications(t *testing.T) {
+	s := feedOf(7)
+	c := NewEngagespotClient("A", "B")
+	c.httpClient.Transport = s
+
+	next := c.IterateNotifications("a", ListOptions{Limit: 3})
+	var ids []string
+	for {
+		record, ok, err := next(context.Background())
+		assert.NoError(t, err)
+		if !ok {
+			break
+		}
+		ids = append(ids, record.Id)
+	}
+	assert.Equal(t, []string{"n-0", "n-1", "n-2", "n-3", "n-4", "n-5", "n-6"}, ids)
+	assert.Len(t, s.requests, 3)
+
+	_, ok, err := next(context.Background())
+	assert.False(t, ok)
+	assert.NoError(t, err)
+	assert.Len(t, s.requests, 3)
+}
+
+func TestIterateNotificationsError(t *testing.T) {
+	c := NewEngagespotClient("A", "B")
+	c.httpClient.Transport = respondWith(500, `{}`)
+
+	_, ok, err := c.IterateNotifications("a", ListOptions{})(context.Background())
+	assert.False(t, ok)
+	var apiErr *APIError
+	assert.ErrorAs(t, err, &apiErr)
+	assert.Equal(t, "ListNotifications", apiErr.Op)
+}
+
+func TestMarkAsRead(t *testing.T) {
+	var requests []*http.Request
+	c := NewEngagespotClient("A", "B", WithHmac())
+	c.httpClient.Transport = roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		requests = append(requests, req)
+		if req.URL.Path == "/v3/users/a@example.com/notifications/n-9/read" {
+			return respondWith(404, `{"message":"no such notification"}`).RoundTrip(req)
+		}
+		return respondWith(200, `{"id":"n-1","title":"Hello","readAt":"2022-01-03T00:00:00Z","createdAt":"2022-01-02T03:04:05Z"}`).RoundTrip(req)
+	})
+	ctx := context.Background()
+
+	record, err := c.MarkAsRead(ctx, "a@example.com", "n-1")
+	assert.NoError(t, err)
+	assert.Equal(t, "n-1", record.Id)
+	assert.True(t, record.Read())
+
+	req := requests[0]
+	assert.Equal(t, http.MethodPost, req.Method)
+	assert.Equal(t, "/v3/users/a@example.com/notifications/n-1/read", req.URL.Path)
+	assert.Equal(t, "a@example.com", req.Header.Get("X-ENGAGESPOT-USER-ID"))
+	assert.Equal(t, c.GenHmac("a@example.com"), req.Header.Get("X-ENGAGESPOT-USER-SIGNATURE"))
+	assert.Equal(t, "A", req.Header.Get("X-ENGAGESPOT-API-KEY"))
+
+	_, err = c.MarkAsRead(ctx, "a@example.com", "n-9")
+	assert.ErrorIs(t, err, ErrNotificationNotFound)
+	var apiErr *APIError
+	assert.ErrorAs(t, err, &apiErr)
+	assert.Equal(t, "no such notification", apiErr.Message)
+
+	_, err = c.MarkAsRead(ctx, "a@example.com", "")
+	assert.EqualError(t, err, "empty notification id string")
+	assert.Len(t, requests, 2)
+}
+
+func TestMarkAllAsRead(t *testing.T) {
+	var requests []*http.Request
+	c := NewEngagespotClient("A", "B")
+	c.httpClient.Transport = roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		requests = append(requests, req)
+		return respondWith(200, `{"updatedCount":4}`).RoundTrip(req)
+	})
+
+	result, err := c.MarkAllAsRead(context.Background(), "a@example.com")
+	assert.NoError(t, err)
+	assert.Equal(t, 4, result.Updated)
+
+	req := requests[0]
+	assert.Equal(t, http.MethodPost, req.Method)
+	assert.Equal(t, "/v3/users/a@example.com/notifications/read", req.URL.Path)
+	assert.Equal(t, "a@example.com", req.Header.Get("X-ENGAGESPOT-USER-ID"))
+	assert.Empty(t, req.Header.Get("X-ENGAGESPOT-USER-SIGNATURE"))
+}
+
+func TestDeleteNotification(t *testing.T) {
+	var requests []*http.Request
+	c := NewEngagespotClient("A", "B", WithHmac())
+	c.httpClient.Transport = roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		requests = append(requests, req)
+		if req.URL.Path == "/v3/notifications" {
+			return respondWith(200, `{"id":"n-1"}`).RoundTrip(req)
+		}
+		if len(requests) > 2 {
+			return respondWith(404, `{}`).RoundTrip(req)
+		}
+		return respondWith(204, ``).RoundTrip(req)
+	})
+	ctx := context.Background()
+
+	result, err := newMessage(c, "a@example.com").SendCtx(ctx)
+	assert.NoError(t, err)
+	assert.NoError(t, c.DeleteNotification(ctx, "a@example.com", result.Id))
+
+	req := requests[1]
+	assert.Equal(t, http.MethodDelete, req.Method)
+	assert.Equal(t, "/v3/users/a@example.com/notifications/n-1", req.URL.Path)
+	assert.Equal(t, "a@example.com", req.Header.Get("X-ENGAGESPOT-USER-ID"))
+	assert.Equal(t, c.GenHmac("a@example.com"), req.Header.Get("X-ENGAGESPOT-USER-SIGNATURE"))
+
+	err = c.DeleteNotification(ctx, "a@example.com", result.Id)
+	assert.ErrorIs(t, err, ErrNotificationNotFound)
+	assert.ErrorIs(t, err, ErrNotFound)
+	assert.EqualError(t, c.DeleteNotification(ctx, "a@example.com", ""), "empty notification id string")
+}
+
+func TestGetUnreadCount(t *testing.T) {
+	body := `{"count":3}`
+	var requests []*http.Request
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests = append(requests, r)
+		w.Write([]byte(body))
+	}))
+	defer server.Close()
+	c := clientFor(server.URL+"/v3/", time.Second, WithHmac())
+
+	count, err := c.GetUnreadCount(context.Background(), "a@example.com")
+	assert.NoError(t, err)
+	assert.Equal(t, 3, count)
+	assert.Equal(t, "GET /v3/users/a@example.com/notifications/unread-count", requests[0].Method+" "+requests[0].URL.Path)
+	assert.Equal(t, "a@example.com", requests[0].Header.Get("X-ENGAGESPOT-USER-ID"))
+	assert.Equal(t, c.GenHmac("a@example.com"), requests[0].Header.Get("X-ENGAGESPOT-USER-SIGNATURE"))
+
+	body = `{"count":0}`
+	count, err = c.GetUnreadCount(context.Background(), "a@example.com")
+	assert.NoError(t, err)
+	assert.Zero(t, count)
+}
+
+func TestGetUnreadCountInvalid(t *testing.T) {
+	for body, expected := range map[string]string{
+		`{}`:             "no unread count in response",
+		`{"count":null}`: "no unread count in response",
+		`{"count":-1}`:   "invalid unread count -1 in response",
+	} {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(body))
+		}))
+		c := clientFor(server.URL+"/v3/", time.Second)
+		_, err := c.GetUnreadCount(context.Background(), "a")
+		assert.EqualError(t, err, expected, body)
+		server.Close()
+	}
+}
@@ -0,0 +1,175 @@
+package engagespot
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Fingerprint is the structure of a data payload: every key path, nested keys joined by dots and
+// array elements marked with [], mapped to its JSON type. values don't take part in it
+type Fingerprint map[string]string
+
+// FingerprintStore keeps the last data payload fingerprint seen per category. implementations
+// must be safe for concurrent use
+type FingerprintStore interface {
+	Get(category string) (Fingerprint, bool)
+	Set(category string, f Fingerprint)
+}
+
+type memoryFingerprintStore struct {
+	fingerprints sync.Map
+}
+
+// NewMemoryFingerprintStore returns a FingerprintStore keeping fingerprints in memory
+func NewMemoryFingerprintStore() FingerprintStore {
+	return &memoryFingerprintStore{}
+}
+
+func (s *memoryFingerprintStore) Get(category string) (Fingerprint, bool) {
+	f, ok := s.fingerprints.Load(category)
+	if !ok {
+		return nil, false
+	}
+	return f.(Fingerprint), true
+}
+
+func (s *memoryFingerprintStore) Set(category string, f Fingerprint) {
+	s.fingerprints.Store(category, f)
+}
+
+// WithSchemaFingerprinting can be used to detect changes to the shape of the data payload sent
+// for a category, like renamed keys or type changes which break templates. the fingerprint of the
+// data of every send with a category is compared with the last one in store, and a warning listing
+// the changed keys is produced when they differ. see SkipSchemaFingerprint
+func WithSchemaFingerprinting(store FingerprintStore) Option {
+	return func(c *Client) {
+		c.fingerprints = store
+	}
+}
+
+// SkipSchemaFingerprint makes the next sends of the notification skip schema fingerprinting
+func (n *Notification) SkipSchemaFingerprint() *Notification {
+	n.skipFingerprint = true
+	return n
+}
+
+// FingerprintData returns the fingerprint of a data payload
+func FingerprintData(data map[string]interface{}) Fingerprint {
+	f := Fingerprint{}
+	fingerprintValue(f, "", data)
+	return f
+}
+
+func fingerprintValue(f Fingerprint, path string, v interface{}) {
+	// raw values are only decoded here, when fingerprinting is enabled
+	v = dataValue(v)
+	if path != "" {
+		f.add(path, jsonType(v))
+	}
+	switch v := v.(type) {
+	case map[string]interface{}:
+		for key, child := range v {
+			if path != "" {
+				key = path + "." + key
+			}
+			fingerprintValue(f, key, child)
+		}
+	case []interface{}:
+		for _, child := range v {
+			fingerprintValue(f, path+"[]", child)
+		}
+	}
+}
+
+// record t for path. arrays mixing types get every type, sorted
+func (f Fingerprint) add(path, t string) {
+	existing, ok := f[path]
+	if !ok {
+		f[path] = t
+		return
+	}
+	types := strings.Split(existing, "|")
+	if containsString(types, t) {
+		return
+	}
+	types = append(types, t)
+	sort.Strings(types)
+	f[path] = strings.Join(types, "|")
+}
+
+// name of the JSON type of v, for values as decoded by encoding/json or built by hand
+func jsonType(v interface{}) string {
+	switch v.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case string:
+		return "string"
+	case map[string]interface{}:
+		return "object"
+	case []interface{}:
+		return "array"
+	case float32, float64, int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64, json.Number:
+		return "number"
+	}
+	return fmt.Sprintf("%T", v)
+}
+
+// Hash returns a short, stable identifier of the fingerprint
+func (f Fingerprint) Hash() string {
+	paths := make([]string, 0, len(f))
+	for path, t := range f {
+		paths = append(paths, path+":"+t)
+	}
+	sort.Strings(paths)
+	sum := sha256.Sum256([]byte(strings.Join(paths, "\n")))
+	return hex.EncodeToString(sum[:8])
+}
+
+// changes from previous to f, one entry per key path, sorted
+func (f Fingerprint) diff(previous Fingerprint) []string {
+	var changes []string
+	for path, t := range f {
+		was, ok := previous[path]
+		switch {
+		case !ok:
+			changes = append(changes, fmt.Sprintf("added %s (%s)", path, t))
+		case was != t:
+			changes = append(changes, fmt.Sprintf("%s changed from %s to %s", path, was, t))
+		}
+	}
+	for path, t := range previous {
+		if _, ok := f[path]; !ok {
+			changes = append(changes, fmt.Sprintf("removed %s (%s)", path, t))
+		}
+	}
+	sort.Strings(changes)
+	return changes
+}
+
+// compare the fingerprint of the data of n with the last one of its category, which it replaces
+// when record is set
+func (c *Client) fingerprintWarnings(n *Notification, record bool) []Warning {
+	if c.fingerprints == nil || n.skipFingerprint || n.Category == "" {
+		return nil
+	}
+
+	f := FingerprintData(n.Notification.Data)
+	previous, seen := c.fingerprints.Get(n.Category)
+	if record {
+		c.fingerprints.Set(n.Category, f)
+	}
+	if !seen || previous.Hash() == f.Hash() {
+		return nil
+	}
+	return []Warning{{
+		Field:   "data",
+		Message: fmt.Sprintf("data schema of category %q changed: %s", n.Category, strings.Join(f.diff(previous), ", ")),
+	}}
+}
@@ -0,0 +1,88 @@
+package engagespot
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func fingerprintWarningsOf(t *testing.T, c *Client, data map[string]interface{}, skip bool) []Warning {
+	n, _ := c.NewNotification("Order shipped")
+	n.SetCategory("orders")
+	n.AddRecipient("a")
+	n.Notification.Data = data
+	if skip {
+		n.SkipSchemaFingerprint()
+	}
+	return c.fingerprintWarnings(n, true)
+}
+
+func TestFingerprintData(t *testing.T) {
+	f := FingerprintData(map[string]interface{}{
+		"orderId": 42,
+		"items":   []interface{}{map[string]interface{}{"sku": "a"}, map[string]interface{}{"sku": 1}},
+		"buyer":   map[string]interface{}{"name": "x", "vip": true},
+		"note":    nil,
+	})
+	assert.Equal(t, Fingerprint{
+		"orderId":     "number",
+		"items":       "array",
+		"items[]":     "object",
+		"items[].sku": "number|string",
+		"buyer":       "object",
+		"buyer.name":  "string",
+		"buyer.vip":   "boolean",
+		"note":        "null",
+	}, f)
+
+	// values don't matter
+	assert.Equal(t, f.Hash(), FingerprintData(map[string]interface{}{
+		"note":    nil,
+		"buyer":   map[string]interface{}{"vip": false, "name": "y"},
+		"items":   []interface{}{map[string]interface{}{"sku": 7}, map[string]interface{}{"sku": "b"}},
+		"orderId": 7.5,
+	}).Hash())
+}
+
+func TestSchemaFingerprinting(t *testing.T) {
+	c := NewEngagespotClient("A", "B", WithSchemaFingerprinting(NewMemoryFingerprintStore()))
+
+	// first seen
+	assert.Empty(t, fingerprintWarningsOf(t, c, map[string]interface{}{"orderId": 1, "total": 9.5}, false))
+	// identical shape
+	assert.Empty(t, fingerprintWarningsOf(t, c, map[string]interface{}{"orderId": 2, "total": 3.0}, false))
+
+	// added key
+	assert.Equal(t, []Warning{{Field: "data", Message: `data schema of category "orders" changed: added currency (string)`}},
+		fingerprintWarningsOf(t, c, map[string]interface{}{"orderId": 2, "total": 3.0, "currency": "INR"}, false))
+
+	// type change and rename
+	assert.Equal(t, []Warning{{Field: "data", Message: `data schema of category "orders" changed: added order_id (number), orderId changed from number to string, removed currency (string)`}},
+		fingerprintWarningsOf(t, c, map[string]interface{}{"orderId": "2", "order_id": 2, "total": 3.0}, false))
+}
+
+func TestSchemaFingerprintingSkipped(t *testing.T) {
+	c := NewEngagespotClient("A", "B", WithSchemaFingerprinting(NewMemoryFingerprintStore()))
+	fingerprintWarningsOf(t, c, map[string]interface{}{"orderId": 1}, false)
+
+	assert.Empty(t, fingerprintWarningsOf(t, c, map[string]interface{}{"orderId": "1"}, true))
+	// skipped sends don't replace the stored fingerprint
+	assert.Len(t, fingerprintWarningsOf(t, c, map[string]interface{}{"orderId": "1"}, false), 1)
+}
+
+func TestSchemaFingerprintingOnSend(t *testing.T) {
+	c := NewEngagespotClient("A", "B", WithSchemaFingerprinting(NewMemoryFingerprintStore()))
+	c.httpClient.Transport = respondWith(200, `{}`)
+
+	for _, data := range []map[string]interface{}{{"orderId": 1}, {"orderId": "1"}} {
+		n, _ := c.NewNotification("Order shipped")
+		n.SetCategory("orders")
+		n.AddRecipient("a")
+		n.Notification.Data = data
+		_, err := n.Send()
+		assert.NoError(t, err)
+		if data["orderId"] == "1" {
+			assert.Contains(t, n.Warnings(), Warning{Field: "data", Message: `data schema of category "orders" changed: orderId changed from number to string`})
+		}
+	}
+}
@@ -0,0 +1,60 @@
+package engagespot
+
+import "errors"
+
+// chainable variants of the setters, returning only the notification. an invalid value is kept as
+// a build error instead, and the next send fails with every build error gathered in a
+// ValidationErrors, before any request is made:
+//
+//	n, _ := c.NewNotification("Hello")
+//	_, err := n.Message("Let's go!").Url("https://example.com").Recipient("a").SendCtx(ctx)
+//
+// the field of the notification is left as it was for each rejected value
+
+// record the error of a setter, if any
+func (n *Notification) keep(_ *Notification, err error) *Notification {
+	if err != nil {
+		n.buildErrors = append(n.buildErrors, err)
+	}
+	return n
+}
+
+// the build errors of n as ValidationErrors, nil if there are none
+func (n *Notification) buildError() error {
+	if len(n.buildErrors) == 0 {
+		return nil
+	}
+	errs := make(ValidationErrors, len(n.buildErrors))
+	for i, err := range n.buildErrors {
+		if !errors.As(err, &errs[i]) {
+			errs[i] = &ValidationError{Reason: err.Error(), Err: err}
+		}
+	}
+	return errs
+}
+
+// Message is SetMessage for chained calls
+func (n *Notification) Message(message string) *Notification {
+	return n.keep(n.SetMessage(message))
+}
+
+// Url is SetUrl for chained calls
+func (n *Notification) Url(url string) *Notification {
+	return n.keep(n.SetUrl(url))
+}
+
+// Icon is SetIcon for chained calls
+func (n *Notification) Icon(iconUrl string) *Notification {
+	return n.keep(n.SetIcon(iconUrl))
+}
+
+// InCategory is SetCategory for chained calls. it can't be named Category, which is the field
+// holding the category
+func (n *Notification) InCategory(category string) *Notification {
+	return n.keep(n.SetCategory(category))
+}
+
+// Recipient is AddRecipient for chained calls
+func (n *Notification) Recipient(recipient string) *Notification {
+	return n.keep(n.AddRecipient(recipient))
+}
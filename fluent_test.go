@@ -0,0 +1,58 @@
+package engagespot
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFluentBuilder(t *testing.T) {
+	var bodies []map[string]interface{}
+	c := NewEngagespotClient("A", "B")
+	c.httpClient.Transport = recordBodies(&bodies)
+
+	n, _ := c.NewNotification("Invoice")
+	_, err := n.Message("Paid").Url("https://example.com").Icon("https://example.com/icon.png").InCategory("billing").Recipient("a").Recipient("b").SendCtx(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, []interface{}{"a", "b"}, bodies[0]["recipients"])
+	assert.Equal(t, "billing", bodies[0]["category"])
+	assert.Equal(t, "Paid", bodies[0]["notification"].(map[string]interface{})["message"])
+}
+
+func TestFluentBuilderJoinsErrors(t *testing.T) {
+	c := NewEngagespotClient("A", "B")
+	c.httpClient.Transport = noNetwork(t)
+
+	n, _ := c.NewNotification("Hello")
+	n.Message("").Url("").Icon("https://example.com/icon.png").Recipient("")
+	_, err := n.SendCtx(context.Background())
+	assert.EqualError(t, err, "empty message string\nempty url string\nempty recipient string")
+	assert.Equal(t, "https://example.com/icon.png", n.Notification.Icon)
+	assert.Empty(t, n.Notification.Message)
+}
+
+func TestFluentBuilderValidationErrors(t *testing.T) {
+	c := NewEngagespotClient("A", "B")
+	c.httpClient.Transport = noNetwork(t)
+
+	n, _ := c.NewNotification("Hello")
+	n.Message("").Url("ftp://example.com").Recipient("a").Recipient("a")
+	_, err := n.SendCtx(context.Background())
+
+	var errs ValidationErrors
+	assert.True(t, errors.As(err, &errs))
+	assert.Len(t, errs, 2)
+	assert.Equal(t, "message", errs[0].Field)
+	assert.Equal(t, "url", errs[1].Field)
+	assert.Equal(t, "ftp://example.com", errs[1].Value)
+	assert.Equal(t, `2 validation errors:
+  message: empty message string
+  url: invalid url "ftp://example.com": scheme "ftp" isn't http or https, see WithRelaxedValidation`, errs.Summary())
+
+	// the first error is found by errors.As too
+	var first *ValidationError
+	assert.True(t, errors.As(err, &first))
+	assert.Equal(t, "message", first.Field)
+}
@@ -1,8 +1,11 @@
 module github.com/ssiyad/engagespot-go
 
-go 1.17
+go 1.21
 
-require github.com/stretchr/testify v1.7.0
+require (
+	github.com/stretchr/testify v1.7.0
+	golang.org/x/text v0.14.0
+)
 
 require (
 	github.com/davecgh/go-spew v1.1.0 // indirect
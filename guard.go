@@ -0,0 +1,87 @@
+package engagespot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// ErrTooManyRecipients is returned by Send for a send over the limit set with
+// WithMaxRecipientsGuard which wasn't acknowledged with AcknowledgeLargeSend
+var ErrTooManyRecipients = errors.New("too many recipients")
+
+// MaxPayloadBytes is the size, in bytes, of the largest body the API accepts for a send. bigger
+// bodies are rejected with ErrPayloadTooLarge before any request is made, see WithMaxPayloadBytes
+const MaxPayloadBytes = 1 << 20
+
+// ErrPayloadTooLarge is returned by Send for a notification whose encoded body is over
+// MaxPayloadBytes, or the limit set with WithMaxPayloadBytes
+var ErrPayloadTooLarge = errors.New("payload too large")
+
+// WithMaxPayloadBytes replaces MaxPayloadBytes as the largest body sent by the client, in case the
+// limit of the API changes. max must be positive
+func WithMaxPayloadBytes(max int) Option {
+	return func(c *Client) {
+		c.declare(optMaxPayloadBytes)
+		if max < 1 {
+			c.rejectOption(optMaxPayloadBytes, fmt.Errorf("needs a positive size, got %d", max))
+			return
+		}
+		c.maxPayloadBytes = max
+	}
+}
+
+// check an encoded body against the payload limit of the client
+func (c *Client) checkPayloadSize(payload []byte) error {
+	max := c.maxPayloadBytes
+	if max == 0 {
+		max = MaxPayloadBytes
+	}
+	if len(payload) > max {
+		return fmt.Errorf("%w: %d bytes, at most %d are accepted", ErrPayloadTooLarge, len(payload), max)
+	}
+	return nil
+}
+
+// WithMaxRecipientsGuard makes every send to more than max recipients fail with
+// ErrTooManyRecipients, unless it carries AcknowledgeLargeSend. recipients are counted after
+// sanitization and opt-out filtering, as they are sent to the API. a broadcast to topics, whose
+// recipients can't be counted, is always taken as over the limit
+func WithMaxRecipientsGuard(max int) Option {
+	return func(c *Client) {
+		c.declare(optMaxRecipientsGuard)
+		c.maxRecipients = max
+	}
+}
+
+// AcknowledgeLargeSend lets a send go over the limit of WithMaxRecipientsGuard. count must be the
+// exact number of recipients sent to, so the caller has to state what they think they're sending.
+// for chunked sends, like notifications with a recipient source, and broadcasts to topics, count is
+// a cap on the recipients listed instead
+func AcknowledgeLargeSend(count int) CallOption {
+	return func(o *callOptions) {
+		o.acknowledgedRecipients = &count
+	}
+}
+
+type acknowledgedRecipientsKey struct{}
+
+// check count recipients against the guard. partial is true for one chunk of a chunked send,
+// where count is the number of recipients so far. broadcast is true for a send to topics
+func (c *Client) checkRecipientGuard(ctx context.Context, count int, partial, broadcast bool) error {
+	if c.maxRecipients <= 0 || (count <= c.maxRecipients && !broadcast) {
+		return nil
+	}
+
+	acknowledged, ok := ctx.Value(acknowledgedRecipientsKey{}).(int)
+	if !ok && broadcast {
+		return fmt.Errorf("%w: a broadcast to topics can't be counted against the limit of %d", ErrTooManyRecipients, c.maxRecipients)
+	}
+	if !ok {
+		return fmt.Errorf("%w: %d is over the limit of %d", ErrTooManyRecipients, count, c.maxRecipients)
+	}
+	if count > acknowledged || (count < acknowledged && !partial && !broadcast) {
+		return fmt.Errorf("%w: acknowledged %d, sending to %d", ErrTooManyRecipients, acknowledged, count)
+	}
+	return nil
+}
@@ -0,0 +1,148 @@
+package engagespot
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func sendToMany(c *Client, count int, opts ...CallOption) error {
+	n, _ := c.NewNotification("Hello")
+	for i := 0; i < count; i++ {
+		n.AddRecipient(string(rune('a' + i)))
+	}
+	_, err := n.Send(opts...)
+	return err
+}
+
+func TestMaxRecipientsGuard(t *testing.T) {
+	c := NewEngagespotClient("A", "B", WithMaxRecipientsGuard(3))
+	c.httpClient.Transport = respondWith(200, `{}`)
+
+	assert.NoError(t, sendToMany(c, 3))
+	assert.ErrorIs(t, sendToMany(c, 4), ErrTooManyRecipients)
+}
+
+func TestMaxRecipientsGuardAcknowledged(t *testing.T) {
+	c := NewEngagespotClient("A", "B", WithMaxRecipientsGuard(3))
+	c.httpClient.Transport = respondWith(200, `{}`)
+
+	assert.NoError(t, sendToMany(c, 5, AcknowledgeLargeSend(5)))
+
+	err := sendToMany(c, 5, AcknowledgeLargeSend(4))
+	assert.ErrorIs(t, err, ErrTooManyRecipients)
+	assert.EqualError(t, err, "too many recipients: acknowledged 4, sending to 5")
+	assert.ErrorIs(t, sendToMany(c, 5, AcknowledgeLargeSend(6)), ErrTooManyRecipients)
+}
+
+func TestMaxRecipientsGuardNoNetwork(t *testing.T) {
+	c := NewEngagespotClient("A", "B", WithMaxRecipientsGuard(1))
+	c.httpClient.Transport = noNetwork(t)
+	assert.ErrorIs(t, sendToMany(c, 2), ErrTooManyRecipients)
+}
+
+func TestMaxRecipientsGuardAfterSuppression(t *testing.T) {
+	s := &preferenceServer{optedOut: []string{"b"}}
+	c := newOptOutClient(s, WithCategoryOptOutFiltering(nil, time.Minute), WithMaxRecipientsGuard(2))
+
+	_, err := sendPromo(c, "a", "b", "c")
+	assert.NoError(t, err)
+	assert.Equal(t, [][]interface{}{{"a", "c"}}, s.sent)
+}
+
+func TestMaxRecipientsGuardSource(t *testing.T) {
+	var bodies []map[string]interface{}
+	c := chunkedClient(2, WithMaxRecipientsGuard(3))
+	c.httpClient.Transport = recordBodies(&bodies)
+
+	n, _ := c.NewNotification("Hello")
+	n.SetRecipientSource(sliceSource([]string{"a", "b", "c", "d", "e"}, -1, nil))
+	_, err := n.Send()
+
+	var partial *PartialSendError
+	assert.True(t, errors.As(err, &partial))
+	assert.ErrorIs(t, err, ErrTooManyRecipients)
+	assert.Equal(t, 2, partial.Submitted)
+
+	n, _ = c.NewNotification("Hello")
+	n.SetRecipientSource(sliceSource([]string{"a", "b", "c", "d", "e"}, -1, nil))
+	_, err = n.Send(AcknowledgeLargeSend(5))
+	assert.NoError(t, err)
+}
+
+func TestPayloadSizeGuard(t *testing.T) {
+	c := NewEngagespotClient("A", "B")
+	c.httpClient.Transport = noNetwork(t)
+
+	n := newMessage(c)
+	n.AddData("blob", "")
+	empty, _ := json.Marshal(n)
+	overhead := len(empty)
+
+	n.AddData("blob", strings.Repeat("x", MaxPayloadBytes))
+	_, err := n.SendCtx(context.Background())
+	assert.ErrorIs(t, err, ErrPayloadTooLarge)
+	assert.EqualError(t, err, fmt.Sprintf("payload too large: %d bytes, at most %d are accepted", overhead+MaxPayloadBytes, MaxPayloadBytes))
+
+	_, _, err = n.DryRun()
+	assert.ErrorIs(t, err, ErrPayloadTooLarge)
+
+	// a body of exactly the limit is sent
+	c.httpClient.Transport = respondWith(200, `{}`)
+	n.AddData("blob", strings.Repeat("x", MaxPayloadBytes-overhead))
+	_, err = n.SendCtx(context.Background())
+	assert.NoError(t, err)
+}
+
+func TestWithMaxPayloadBytes(t *testing.T) {
+	c := NewEngagespotClient("A", "B", WithMaxPayloadBytes(100))
+	c.httpClient.Transport = noNetwork(t)
+
+	n := newMessage(c)
+	n.AddData("note", strings.Repeat("x", 100))
+	_, err := n.SendCtx(context.Background())
+	assert.ErrorIs(t, err, ErrPayloadTooLarge)
+	assert.Contains(t, err.Error(), "at most 100 are accepted")
+
+	_, err = NewClient("A", "B", WithMaxPayloadBytes(0))
+	assert.EqualError(t, err, "engagespot: conflicting options: WithMaxPayloadBytes: needs a positive size, got 0")
+}
+
+func TestPayloadSizeGuardShrinksChunks(t *testing.T) {
+	s := &limitServer{}
+	// room for a chunk of about 10 recipients
+	c := chunkedClient(40, WithMaxPayloadBytes(120), WithAdaptiveChunkSize(ChunkTuning{}))
+	c.httpClient.Transport = s
+
+	ids := recipientIds(30)
+	assert.NoError(t, sendFromSource(c, "", ids))
+	// the oversized chunks never reached the server
+	for _, size := range s.attempts {
+		assert.LessOrEqual(t, size, 10)
+	}
+	assert.Equal(t, ids, s.delivered)
+}
+
+func TestMaxRecipientsGuardBroadcast(t *testing.T) {
+	c := NewEngagespotClient("A", "B", WithMaxRecipientsGuard(3))
+	c.httpClient.Transport = respondWith(200, `{}`)
+
+	n, _ := c.NewNotification("Hello")
+	n.AddTopic("all-beta-users")
+	_, err := n.Send()
+	assert.ErrorIs(t, err, ErrTooManyRecipients)
+	assert.EqualError(t, err, "too many recipients: a broadcast to topics can't be counted against the limit of 3")
+
+	// the acknowledged count caps the recipients listed alongside the topics
+	_, err = n.Send(AcknowledgeLargeSend(3))
+	assert.NoError(t, err)
+	n.AddRecipients("a", "b", "c", "d")
+	_, err = n.Send(AcknowledgeLargeSend(3))
+	assert.EqualError(t, err, "too many recipients: acknowledged 3, sending to 4")
+}
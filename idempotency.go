@@ -0,0 +1,79 @@
+package engagespot
+
+import (
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+)
+
+// IdempotencyKeyHeader is the header carrying the idempotency key of a send, which the API uses to
+// drop duplicates of a request it already handled
+const IdempotencyKeyHeader = "Idempotency-Key"
+
+// WithAutoIdempotency gives every send without a key of its own, see SetIdempotencyKey, a random
+// idempotency key. retried attempts of a send reuse its key, so a retry never sends twice
+func WithAutoIdempotency() Option {
+	return func(c *Client) {
+		c.autoIdempotency = true
+	}
+}
+
+// SetIdempotencyKey can be used to set the idempotency key of the sends of the notification. a
+// send retried by the caller with the same key, like after a timeout, is dropped by the API if
+// the first one went through
+func (n *Notification) SetIdempotencyKey(key string) (*Notification, error) {
+	if key == "" {
+		return nil, invalidField("idempotencyKey", key, errors.New("empty idempotency key string"))
+	}
+	n.idempotencyKey = key
+	return n, nil
+}
+
+// idempotency key of a send of n, if any
+func (c *Client) idempotencyKey(n *Notification) string {
+	if n.idempotencyKey != "" {
+		return n.idempotencyKey
+	}
+	if c.autoIdempotency {
+		return newUUID()
+	}
+	return ""
+}
+
+// random version 4 UUID
+func newUUID() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:])
+}
+
+// key identifying the request in errors, its idempotency key or else its correlation id
+func requestKey(req *http.Request) string {
+	if key := req.Header.Get(IdempotencyKeyHeader); key != "" {
+		return key
+	}
+	return correlationId(req.Context())
+}
+
+// set the idempotency key of the request sending n on req. a send split in several requests gets
+// the key suffixed in each of them, so they aren't taken for duplicates of one another: chunks
+// with the range of their recipients in the recipient stream, and channel rule groups but the
+// first with their position. the suffixes only depend on what the request sends, so a repeat of
+// the whole send gets the same keys, and a chunk sent again in smaller chunks gets new ones
+func (out *sendOutcome) setIdempotencyKey(req *http.Request, n *Notification) {
+	if out == nil || out.idempotencyKey == "" {
+		return
+	}
+	key := out.idempotencyKey
+	if n.chunked {
+		key += fmt.Sprintf("-%d-%d", n.chunkStart, n.chunkEnd)
+	}
+	if n.channelGroup > 0 {
+		key += "-" + strconv.Itoa(n.channelGroup+1)
+	}
+	req.Header.Set(IdempotencyKeyHeader, key)
+}
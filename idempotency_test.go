@@ -0,0 +1,171 @@
+package engagespot
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// server failing the first failures requests with a 503, recording the idempotency key of every
+// request
+func flakyKeyServer(t *testing.T, failures int) (*httptest.Server, *[]string) {
+	var mu sync.Mutex
+	var keys []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		keys = append(keys, r.Header.Get(IdempotencyKeyHeader))
+		if len(keys) <= failures {
+			w.WriteHeader(503)
+			return
+		}
+		w.Write([]byte(`{"id":"n-1"}`))
+	}))
+	t.Cleanup(server.Close)
+	return server, &keys
+}
+
+func idempotentClient(server *httptest.Server, opts ...Option) *Client {
+	d := DefaultConfig()
+	d.Endpoint = server.URL + "/v3/"
+	d.Retry = RetryPolicy{MaxAttempts: 3, BaseDelay: time.Millisecond}
+	return NewEngagespotClient("A", "B", append(opts, WithDefaults(d))...)
+}
+
+func TestAutoIdempotencyKeyReusedAcrossRetries(t *testing.T) {
+	server, keys := flakyKeyServer(t, 2)
+	c := idempotentClient(server, WithAutoIdempotency())
+
+	result, err := newMessage(c).SendCtx(context.Background())
+	assert.NoError(t, err)
+	assert.Regexp(t, regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`), result.IdempotencyKey)
+	assert.Equal(t, []string{result.IdempotencyKey, result.IdempotencyKey, result.IdempotencyKey}, *keys)
+
+	// every send gets a key of its own
+	second, err := newMessage(c).SendCtx(context.Background())
+	assert.NoError(t, err)
+	assert.NotEqual(t, result.IdempotencyKey, second.IdempotencyKey)
+}
+
+func TestIdempotencyKeySetByCaller(t *testing.T) {
+	server, keys := flakyKeyServer(t, 1)
+	c := idempotentClient(server, WithAutoIdempotency())
+
+	n := newMessage(c)
+	n.SetIdempotencyKey("order-1")
+	result, err := n.SendCtx(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, "order-1", result.IdempotencyKey)
+
+	// the same key again, the way a caller repeats a send which timed out
+	_, err = n.SendCtx(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"order-1", "order-1", "order-1"}, *keys)
+
+	_, err = n.SetIdempotencyKey("")
+	assert.EqualError(t, err, "empty idempotency key string")
+}
+
+func TestIdempotencyKeyPerChunk(t *testing.T) {
+	var keys []string
+	c := chunkedClient(2)
+	c.httpClient.Transport = roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		keys = append(keys, req.Header.Get(IdempotencyKeyHeader))
+		return respondWith(200, `{}`).RoundTrip(req)
+	})
+
+	n, _ := c.NewNotification("Hello")
+	n.SetRecipientSource(sliceSource(recipientIds(5), -1, nil))
+	n.SetIdempotencyKey("digest-7")
+	_, err := n.SendCtx(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"digest-7-0-2", "digest-7-2-4", "digest-7-4-5"}, keys)
+}
+
+func TestIdempotencyKeyOfResentChunks(t *testing.T) {
+	s := &limitServer{limit: 2}
+	var keys []string
+	c := chunkedClient(4, WithAdaptiveChunkSize(ChunkTuning{Successes: 100}))
+	c.httpClient.Transport = roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		keys = append(keys, req.Header.Get(IdempotencyKeyHeader))
+		return s.RoundTrip(req)
+	})
+
+	n, _ := c.NewNotification("Hello")
+	n.SetRecipientSource(sliceSource(recipientIds(6), -1, nil))
+	n.SetIdempotencyKey("digest-7")
+	_, err := n.SendCtx(context.Background())
+	assert.NoError(t, err)
+	// the chunk rejected as too large is sent again in halves with keys of their own
+	assert.Equal(t, []int{4, 2, 2, 2}, s.attempts)
+	assert.Equal(t, []string{"digest-7-0-4", "digest-7-0-2", "digest-7-2-4", "digest-7-4-6"}, keys)
+}
+
+func TestIdempotencyKeyAfterSuppressedChunk(t *testing.T) {
+	var keys []string
+	s := &preferenceServer{optedOut: []string{"user-0", "user-1"}}
+	d := DefaultConfig()
+	d.ChunkSize = 2
+	c := newOptOutClient(s, WithCategoryOptOutFiltering(nil, time.Minute), WithDefaults(d))
+	c.httpClient.Transport = roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		if req.Method == http.MethodPost {
+			keys = append(keys, req.Header.Get(IdempotencyKeyHeader))
+		}
+		return s.RoundTrip(req)
+	})
+
+	n, _ := c.NewNotification("Hello")
+	n.SetCategory("promo")
+	n.SetRecipientSource(sliceSource(recipientIds(4), -1, nil))
+	n.SetIdempotencyKey("digest-7")
+	_, err := n.SendCtx(context.Background())
+	assert.NoError(t, err)
+	// the first chunk is never sent, the second keeps the key of its place in the stream
+	assert.Equal(t, []string{"digest-7-2-4"}, keys)
+}
+
+func TestIdempotencyKeyPerChannelGroup(t *testing.T) {
+	var keys []string
+	c := NewEngagespotClient("A", "B", WithDeviceLookup(devicesOf("b"), nil))
+	c.httpClient.Transport = roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		keys = append(keys, req.Header.Get(IdempotencyKeyHeader))
+		return respondWith(200, `{}`).RoundTrip(req)
+	})
+
+	n := newRuledNotification(t, c, "a", "b")
+	n.SetIdempotencyKey("order-1")
+	_, err := n.SendCtx(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"order-1", "order-1-2"}, keys)
+}
+
+func TestNoIdempotencyKeyByDefault(t *testing.T) {
+	server, keys := flakyKeyServer(t, 0)
+	c := idempotentClient(server)
+
+	result, err := newMessage(c).SendCtx(context.Background())
+	assert.NoError(t, err)
+	assert.Empty(t, result.IdempotencyKey)
+	assert.Equal(t, []string{""}, *keys)
+}
+
+func TestIdempotencyKeyInOperationError(t *testing.T) {
+	c := NewEngagespotClient("A", "B")
+	c.httpClient.Transport = roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		return nil, errors.New("connection reset")
+	})
+
+	n := newMessage(c)
+	n.SetIdempotencyKey("order-1")
+	_, err := n.SendCtx(context.Background())
+	var opErr *OperationError
+	assert.ErrorAs(t, err, &opErr)
+	assert.Equal(t, "order-1", opErr.Key)
+}
@@ -0,0 +1,240 @@
+package engagespot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// integration tests run against a real Engagespot app. they are skipped unless
+// ENGAGESPOT_INTEGRATION=1 and credentials of a sandbox app are provided:
+//
+//	ENGAGESPOT_INTEGRATION=1 \
+//	ENGAGESPOT_API_KEY=... \
+//	ENGAGESPOT_API_SECRET=... \
+//	go test -run Integration ./...
+//
+// every test connects users and sends notifications of its own, which are deleted when it ends
+
+const integrationTimeout = 60 * time.Second
+
+// how long a notification sent by a test can take to show in the feed of its user
+const integrationFeedDelay = 30 * time.Second
+
+func integrationClient(t *testing.T) *Client {
+	t.Helper()
+
+	if os.Getenv("ENGAGESPOT_INTEGRATION") != "1" {
+		t.Skip("set ENGAGESPOT_INTEGRATION=1 to run integration tests")
+	}
+	apiKey := os.Getenv("ENGAGESPOT_API_KEY")
+	apiSecret := os.Getenv("ENGAGESPOT_API_SECRET")
+	if apiKey == "" || apiSecret == "" {
+		t.Skip("set ENGAGESPOT_API_KEY and ENGAGESPOT_API_SECRET to run integration tests")
+	}
+
+	c := NewEngagespotClient(apiKey, apiSecret)
+	c.httpClient.Timeout = integrationTimeout
+	return c
+}
+
+// run f once t ends, with a fresh context. an error other than notFound fails t
+func integrationCleanup(t *testing.T, what string, notFound error, f func(ctx context.Context) error) {
+	t.Cleanup(func() {
+		ctx, cancel := context.WithTimeout(context.Background(), integrationTimeout)
+		defer cancel()
+		if err := f(ctx); err != nil && !errors.Is(err, notFound) {
+			t.Errorf("cleaning up %s: %v", what, err)
+		}
+	})
+}
+
+// connect a user of t's own, deleted along with their notifications when t ends
+func integrationUser(t *testing.T, c *Client) (string, error) {
+	t.Helper()
+
+	userId := fmt.Sprintf("engagespot-go-integration-%s-%d", t.Name(), time.Now().UnixNano())
+	ctx, cancel := context.WithTimeout(context.Background(), integrationTimeout)
+	defer cancel()
+	if _, err := c.ConnectUser(ctx, userId, ConnectOptions{}); err != nil {
+		return "", err
+	}
+	integrationCleanup(t, "user "+userId, ErrUserNotFound, func(ctx context.Context) error {
+		return c.DeleteUser(ctx, userId)
+	})
+	return userId, nil
+}
+
+// send a notification to userId, deleted when t ends
+func integrationSend(t *testing.T, c *Client, userId string) (*SendResult, error) {
+	t.Helper()
+
+	n, err := c.NewNotification("engagespot-go integration test")
+	if err != nil {
+		return nil, err
+	}
+	n.SetMessage("sent at " + time.Now().UTC().Format(time.RFC3339))
+	n.SetCategory("engagespot-go-integration")
+	n.AddRecipient(userId)
+
+	ctx, cancel := context.WithTimeout(context.Background(), integrationTimeout)
+	defer cancel()
+	result, err := n.SendCtx(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if result.Id != "" {
+		// cleanups run last in first out, so before the user is deleted
+		integrationCleanup(t, "notification "+result.Id, ErrNotificationNotFound, func(ctx context.Context) error {
+			return c.DeleteNotification(ctx, userId, result.Id)
+		})
+	}
+	return result, nil
+}
+
+// wait for the notification id to show in the feed of userId
+func waitForNotification(ctx context.Context, c *Client, userId, id string) error {
+	deadline := time.Now().Add(integrationFeedDelay)
+	for {
+		page, err := c.ListNotifications(ctx, userId, ListOptions{Limit: 5})
+		if err != nil {
+			return err
+		}
+		for _, record := range page.Notifications {
+			if record.Id == id {
+				return nil
+			}
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("notification %s not in the feed after %s", id, integrationFeedDelay)
+		}
+		time.Sleep(time.Second)
+	}
+}
+
+// run scenario, retrying it once as a whole if it fails, to absorb flakiness of the real API
+func retryScenario(t *testing.T, scenario func() error) {
+	t.Helper()
+
+	err := scenario()
+	if err != nil {
+		t.Logf("scenario failed, retrying once: %v", err)
+		err = scenario()
+	}
+	assert.NoError(t, err)
+}
+
+func TestIntegrationAppInfo(t *testing.T) {
+	c := integrationClient(t)
+
+	retryScenario(t, func() error {
+		info, err := c.GetAppInfo()
+		if err != nil {
+			return err
+		}
+		if info.Id == "" {
+			return errors.New("app info has no id")
+		}
+		return nil
+	})
+}
+
+func TestIntegrationConnect(t *testing.T) {
+	c := integrationClient(t)
+
+	retryScenario(t, func() error {
+		userId, err := integrationUser(t, c)
+		if err != nil {
+			return err
+		}
+		// the connected user can be read back, through decoders of their own
+		if _, err := c.GetPreferences(context.Background(), userId); err != nil {
+			return fmt.Errorf("reading preferences: %w", err)
+		}
+		if _, err := c.ListDevices(context.Background(), userId); err != nil {
+			return fmt.Errorf("listing devices: %w", err)
+		}
+		return nil
+	})
+}
+
+func TestIntegrationSend(t *testing.T) {
+	c := integrationClient(t)
+
+	retryScenario(t, func() error {
+		userId, err := integrationUser(t, c)
+		if err != nil {
+			return err
+		}
+		result, err := integrationSend(t, c, userId)
+		if err != nil {
+			return err
+		}
+		if !result.Success {
+			return fmt.Errorf("send not successful: %q", result.Message)
+		}
+		if result.State != StateAccepted && result.State != StateDelivered {
+			return fmt.Errorf("unexpected send state %s", result.State)
+		}
+		return nil
+	})
+}
+
+func TestIntegrationFeed(t *testing.T) {
+	c := integrationClient(t)
+	ctx := context.Background()
+
+	retryScenario(t, func() error {
+		userId, err := integrationUser(t, c)
+		if err != nil {
+			return err
+		}
+		result, err := integrationSend(t, c, userId)
+		if err != nil {
+			return err
+		}
+		if err := waitForNotification(ctx, c, userId, result.Id); err != nil {
+			return err
+		}
+		page, err := c.ListNotifications(ctx, userId, ListOptions{Limit: 5})
+		if err != nil {
+			return err
+		}
+		if len(page.Notifications) > page.TotalCount {
+			return fmt.Errorf("page of %d notifications out of %d", len(page.Notifications), page.TotalCount)
+		}
+		return nil
+	})
+}
+
+func TestIntegrationMarkRead(t *testing.T) {
+	c := integrationClient(t)
+	ctx := context.Background()
+
+	retryScenario(t, func() error {
+		userId, err := integrationUser(t, c)
+		if err != nil {
+			return err
+		}
+		result, err := integrationSend(t, c, userId)
+		if err != nil {
+			return err
+		}
+		if err := waitForNotification(ctx, c, userId, result.Id); err != nil {
+			return err
+		}
+		record, err := c.MarkAsRead(ctx, userId, result.Id)
+		if err != nil {
+			return err
+		}
+		if !record.Read() {
+			return fmt.Errorf("notification %s not read after MarkAsRead", record.Id)
+		}
+		return nil
+	})
+}
@@ -0,0 +1,92 @@
+package engagespot
+
+import (
+	"context"
+	"net/http"
+	"time"
+)
+
+// EngagespotClient is the part of the client services usually depend on. the client returned by
+// NewEngagespotClient and NewClient implements it, code depending on the interface can be tested
+// with engagespottest.FakeClient instead of a server
+type EngagespotClient interface {
+	NewNotification(title string) (*Notification, error)
+	Send(n *Notification, opts ...CallOption) (*SendResult, error)
+	SendCtx(ctx context.Context, n *Notification, opts ...CallOption) (*SendResult, error)
+	SendAsync(n *Notification) <-chan AsyncResult
+	SendAll(ctx context.Context, ns []*Notification, opts ...CallOption) ([]*SendResult, error)
+	Connect(userId string) (*http.Response, error)
+	ConnectCtx(ctx context.Context, userId string) (*http.Response, error)
+	ConnectWithDevice(ctx context.Context, userId string, opts ConnectOptions) (*http.Response, error)
+	ConnectUser(ctx context.Context, userId string, opts ConnectOptions) (*ConnectResult, error)
+	NewWorkflowTrigger(key string) (*WorkflowTrigger, error)
+	TriggerWorkflow(ctx context.Context, key string, recipients []string, data map[string]interface{}, opts ...CallOption) (*WorkflowRun, error)
+	Close() error
+}
+
+var _ EngagespotClient = (*Client)(nil)
+
+// NotificationSnapshot is a copy of the content of a notification, see Snapshot. changing it
+// doesn't change the notification
+type NotificationSnapshot struct {
+	Title      string
+	Message    string
+	Url        string
+	Icon       string
+	Actions    []Action
+	Image      string
+	HTMLBody   string
+	Category   string
+	Recipients []string
+	Topics     []string
+	Channels   []string
+	Data       map[string]interface{}
+	SendAt     *time.Time
+	ExpiresAt  *time.Time
+}
+
+// Snapshot returns a copy of the content of n, to inspect it without encoding it. recipients of a
+// RecipientSource are only known at send, and aren't part of it
+func (n *Notification) Snapshot() NotificationSnapshot {
+	s := NotificationSnapshot{
+		Category:   n.Category,
+		Recipients: append([]string(nil), n.Recipients...),
+		Topics:     append([]string(nil), n.Topics...),
+	}
+	if n.Notification != nil {
+		s.Title = n.Notification.Title
+		s.Message = n.Notification.Message
+		s.Url = n.Notification.Url
+		s.Icon = n.Notification.Icon
+		s.Actions = append([]Action(nil), n.Notification.Actions...)
+		s.Image = n.Notification.Image
+		s.HTMLBody = n.Notification.HTMLBody
+		if n.Notification.Data != nil {
+			s.Data = make(map[string]interface{}, len(n.Notification.Data))
+			for k, v := range n.Notification.Data {
+				s.Data[k] = v
+			}
+		}
+	}
+	if n.Override != nil {
+		s.Channels = append([]string(nil), n.Override.Channels...)
+	}
+	if n.SendAt != nil {
+		at := *n.SendAt
+		s.SendAt = &at
+	}
+	if n.ExpiresAt != nil {
+		at := *n.ExpiresAt
+		s.ExpiresAt = &at
+	}
+	return s
+}
+
+// WithSendObserver sets a function given the snapshot of every notification sent by the client,
+// once it's validated and before any request is made
+func WithSendObserver(observer func(NotificationSnapshot)) Option {
+	return func(c *Client) {
+		c.declare(optSendObserver)
+		c.sendObserver = observer
+	}
+}
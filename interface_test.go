@@ -0,0 +1,58 @@
+package engagespot
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSnapshot(t *testing.T) {
+	c := NewEngagespotClient("A", "B")
+	n, _ := c.NewNotification("Hello")
+	n.Message("world").Url("https://example.com").InCategory("news").Recipient("a")
+	n.AddData("plan", "pro")
+	n.AddTopic("weekly")
+	n.SetChannels(ChannelEmail)
+	at := time.Now().Add(time.Hour)
+	n.SetSendAt(at)
+
+	s := n.Snapshot()
+	assert.Equal(t, NotificationSnapshot{
+		Title:      "Hello",
+		Message:    "world",
+		Url:        "https://example.com",
+		Category:   "news",
+		Recipients: []string{"a"},
+		Topics:     []string{"weekly"},
+		Channels:   []string{ChannelEmail},
+		Data:       map[string]interface{}{"plan": "pro"},
+		SendAt:     &at,
+	}, s)
+
+	// the snapshot is a copy
+	s.Recipients[0] = "b"
+	s.Channels[0] = ChannelSMS
+	s.Data["plan"] = "free"
+	*s.SendAt = at.Add(time.Hour)
+	assert.Equal(t, "a", n.Recipients[0])
+	assert.Equal(t, ChannelEmail, n.Override.Channels[0])
+	assert.Equal(t, "pro", n.Notification.Data["plan"])
+	assert.Equal(t, at, *n.SendAt)
+}
+
+func TestSendObserver(t *testing.T) {
+	var observed []NotificationSnapshot
+	c := NewEngagespotClient("A", "B", WithSendObserver(func(s NotificationSnapshot) {
+		observed = append(observed, s)
+	}))
+	c.httpClient.Transport = respondWith(200, `{}`)
+
+	assert.NoError(t, sendTo(c, "a"))
+	invalid, _ := c.NewNotification("Hello")
+	_, err := invalid.SendCtx(context.Background())
+	assert.Error(t, err)
+	assert.Len(t, observed, 1)
+	assert.Equal(t, []string{"a"}, observed[0].Recipients)
+}
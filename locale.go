@@ -0,0 +1,56 @@
+package engagespot
+
+import (
+	"fmt"
+
+	"golang.org/x/text/language"
+)
+
+// LocalizedContent is the title and message of a notification in a locale, see
+// SetLocalizedContent
+type LocalizedContent struct {
+	Title   string
+	Message string
+}
+
+// SetLocalizedContent can be used to set the title and message of the notification in locale, a
+// BCP 47 tag like "fr" or "pt-BR". the localized content isn't sent as is, use ForLocale to get the
+// notification to send for a locale. an empty message falls back to the message of n
+func (n *Notification) SetLocalizedContent(locale, title, message string) (*Notification, error) {
+	tag, err := language.Parse(locale)
+	if err != nil {
+		return nil, invalidField("locale", locale, fmt.Errorf("invalid locale %q: %w", locale, err))
+	}
+	if err := validTitle(title); err != nil {
+		return nil, invalidField("title", title, err)
+	}
+	if n.localized == nil {
+		n.localized = map[string]LocalizedContent{}
+	}
+	n.localized[tag.String()] = LocalizedContent{Title: title, Message: message}
+	return n, nil
+}
+
+// ForLocale returns a copy of n, see Clone, with the content of locale set with
+// SetLocalizedContent. a locale without content falls back to its parent, like "fr" for "fr-CA",
+// then to the content of n itself
+func (n *Notification) ForLocale(locale string) (*Notification, error) {
+	tag, err := language.Parse(locale)
+	if err != nil {
+		return nil, fmt.Errorf("invalid locale %q: %w", locale, err)
+	}
+	c := n.Clone()
+	c.localized = nil
+	for ; tag != language.Und; tag = tag.Parent() {
+		content, ok := n.localized[tag.String()]
+		if !ok {
+			continue
+		}
+		c.Notification.Title = content.Title
+		if content.Message != "" {
+			c.Notification.Message = content.Message
+		}
+		break
+	}
+	return c, nil
+}
@@ -0,0 +1,66 @@
+package engagespot
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func localizedNotification(t *testing.T) *Notification {
+	n := newTestNotification(t)
+	n.Message("Your invoice is ready").Recipient("a")
+	_, err := n.SetLocalizedContent("fr", "Facture", "Votre facture est prête")
+	assert.NoError(t, err)
+	_, err = n.SetLocalizedContent("pt-br", "Fatura", "")
+	assert.NoError(t, err)
+	return n
+}
+
+func TestForLocale(t *testing.T) {
+	n := localizedNotification(t)
+	for _, tc := range []struct {
+		locale, title, message string
+	}{
+		{"fr", "Facture", "Votre facture est prête"},
+		{"fr-CA", "Facture", "Votre facture est prête"},
+		{"pt-BR", "Fatura", "Your invoice is ready"},
+		{"pt", "Invoice", "Your invoice is ready"},
+		{"de", "Invoice", "Your invoice is ready"},
+	} {
+		localized, err := n.ForLocale(tc.locale)
+		assert.NoError(t, err)
+		assert.Equal(t, tc.title, localized.Notification.Title, tc.locale)
+		assert.Equal(t, tc.message, localized.Notification.Message, tc.locale)
+		assert.Equal(t, []string{"a"}, localized.Recipients)
+	}
+}
+
+func TestForLocaleIndependent(t *testing.T) {
+	n := localizedNotification(t)
+	fr, _ := n.ForLocale("fr")
+	fr.SetTitle("Changée")
+	fr.AddRecipient("b")
+	fr.SetLocalizedContent("fr", "Autre", "")
+
+	assert.Equal(t, "Invoice", n.Notification.Title)
+	assert.Equal(t, []string{"a"}, n.Recipients)
+	again, _ := n.ForLocale("fr")
+	assert.Equal(t, "Facture", again.Notification.Title)
+
+	// localizing the original after the fact doesn't change a copy
+	n.SetLocalizedContent("de", "Rechnung", "")
+	clone := n.Clone()
+	n.SetLocalizedContent("de", "Neu", "")
+	de, _ := clone.ForLocale("de")
+	assert.Equal(t, "Rechnung", de.Notification.Title)
+}
+
+func TestLocalizedContentValidation(t *testing.T) {
+	n := newTestNotification(t)
+	_, err := n.SetLocalizedContent("english please", "Invoice", "")
+	assert.Contains(t, err.Error(), `invalid locale "english please"`)
+	_, err = n.SetLocalizedContent("fr", " ", "")
+	assert.EqualError(t, err, "empty title string")
+	_, err = n.ForLocale("x!")
+	assert.Contains(t, err.Error(), `invalid locale "x!"`)
+}
@@ -0,0 +1,67 @@
+package engagespot
+
+import (
+	"log/slog"
+	"net/http"
+	"time"
+)
+
+// WithLogger sets the logger used by the client. slog.Default() is used otherwise
+func WithLogger(l *slog.Logger) Option {
+	return func(c *Client) {
+		c.declare(optLogger)
+		c.logger = l
+	}
+}
+
+func (c *Client) log() *slog.Logger {
+	if c.logger == nil {
+		return slog.Default()
+	}
+	return c.logger
+}
+
+// WithDebug logs every attempt of every request made by the client to the logger set with
+// WithLogger: the method, url, headers and body of the request, then the status of the response
+// and how long it took. the api secret and the signatures are redacted
+func WithDebug(debug bool) Option {
+	return func(c *Client) {
+		c.declare(optDebug)
+		c.debug = debug
+	}
+}
+
+// WithRequestHook sets a function called with every request right before it is sent, once per
+// attempt. the request carries the credentials of the client, the hook must not log it as is
+func WithRequestHook(hook func(*http.Request)) Option {
+	return func(c *Client) {
+		c.declare(optRequestHook)
+		c.requestHook = hook
+	}
+}
+
+// WithResponseHook sets a function called with every response received, once per attempt, along
+// with the time it took. it isn't called for attempts which failed without a response. the body
+// must be left unread, the client decodes it after the hook
+func WithResponseHook(hook func(*http.Response, time.Duration)) Option {
+	return func(c *Client) {
+		c.declare(optResponseHook)
+		c.responseHook = hook
+	}
+}
+
+// log a single attempt of req, see WithDebug
+func (c *Client) logAttempt(req *http.Request, res *http.Response, latency time.Duration, err error) {
+	attrs := []any{
+		"method", req.Method,
+		"url", req.URL.String(),
+		"headers", redactHeaders(req.Header),
+		"body", string(requestBody(req)),
+		"latency", latency,
+	}
+	if err != nil {
+		c.log().Info("engagespot: request failed", append(attrs, "error", err)...)
+		return
+	}
+	c.log().Info("engagespot: request", append(attrs, "status", res.StatusCode)...)
+}
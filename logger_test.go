@@ -0,0 +1,106 @@
+package engagespot
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDebugLogging(t *testing.T) {
+	var buf bytes.Buffer
+	c := NewEngagespotClient("A", "supersecret", WithHmac(), WithDebug(true),
+		WithLogger(slog.New(slog.NewTextHandler(&buf, nil))))
+	c.httpClient.Transport = respondWith(201, `{}`)
+
+	assert.NoError(t, sendTo(c, "a"))
+
+	out := buf.String()
+	assert.Contains(t, out, `msg="engagespot: request"`)
+	assert.Contains(t, out, "method=POST")
+	assert.Contains(t, out, "url=https://api.engagespot.co/v3/notifications")
+	assert.Contains(t, out, `\"recipients\":[\"a\"]`)
+	assert.Contains(t, out, "status=201")
+	assert.Contains(t, out, "latency=")
+	assert.Contains(t, out, "X-Engagespot-Api-Secret:[REDACTED]")
+	assert.Contains(t, out, "X-Engagespot-Signature:[REDACTED]")
+}
+
+func TestDebugLoggingRedactsSignatures(t *testing.T) {
+	var buf bytes.Buffer
+	c := NewEngagespotClient("A", "supersecret", WithHmac(), WithDebug(true),
+		WithLogger(slog.New(slog.NewTextHandler(&buf, nil))))
+	c.httpClient.Transport = feedOf(1)
+
+	_, err := c.ListNotifications(context.Background(), "a", ListOptions{})
+	assert.NoError(t, err)
+	assert.NoError(t, sendTo(c, "a"))
+
+	out := buf.String()
+	assert.Contains(t, out, "X-Engagespot-User-Signature:[REDACTED]")
+	assert.NotContains(t, out, "supersecret")
+	assert.NotContains(t, out, c.GenHmac("a"))
+	// the secret and signature of both requests
+	assert.Equal(t, 4, strings.Count(out, "[REDACTED]"))
+}
+
+func TestDebugLoggingFailure(t *testing.T) {
+	var buf bytes.Buffer
+	c := NewEngagespotClient("A", "B", WithDebug(true), WithLogger(slog.New(slog.NewTextHandler(&buf, nil))))
+	c.httpClient.Transport = roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		return nil, errors.New("connection refused")
+	})
+
+	assert.Error(t, sendTo(c, "a"))
+	assert.Contains(t, buf.String(), `msg="engagespot: request failed"`)
+	assert.Contains(t, buf.String(), "connection refused")
+}
+
+func TestDebugLoggingOff(t *testing.T) {
+	var buf bytes.Buffer
+	c := NewEngagespotClient("A", "B", WithLogger(slog.New(slog.NewTextHandler(&buf, nil))))
+	c.httpClient.Transport = respondWith(200, `{}`)
+
+	assert.NoError(t, sendTo(c, "a"))
+	assert.Empty(t, buf.String())
+}
+
+func TestRequestResponseHooks(t *testing.T) {
+	var requests []*http.Request
+	var statuses []int
+	var latencies []time.Duration
+	c := NewEngagespotClient("A", "B",
+		WithRequestHook(func(req *http.Request) { requests = append(requests, req) }),
+		WithResponseHook(func(res *http.Response, latency time.Duration) {
+			statuses = append(statuses, res.StatusCode)
+			latencies = append(latencies, latency)
+		}))
+	c.httpClient.Transport = respondWith(200, `{}`)
+
+	assert.NoError(t, sendTo(c, "a"))
+	assert.Len(t, requests, 1)
+	assert.Equal(t, "A", requests[0].Header.Get("X-ENGAGESPOT-API-KEY"))
+	assert.Equal(t, []int{200}, statuses)
+	assert.Len(t, latencies, 1)
+	assert.GreaterOrEqual(t, latencies[0], time.Duration(0))
+}
+
+func TestHooksCalledPerAttempt(t *testing.T) {
+	var waits []time.Duration
+	var bodies []string
+	requests, responses := 0, 0
+	c := retryingClient(newFakeClock(), &waits,
+		WithRequestHook(func(*http.Request) { requests++ }),
+		WithResponseHook(func(*http.Response, time.Duration) { responses++ }))
+	c.httpClient.Transport = flaky(2, 503, &bodies)
+
+	assert.NoError(t, sendTo(c, "a"))
+	assert.Equal(t, 3, requests)
+	assert.Equal(t, 3, responses)
+}
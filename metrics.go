@@ -0,0 +1,82 @@
+package engagespot
+
+import (
+	"sync"
+	"time"
+)
+
+// Metrics is told about every request made by a client, see WithMetrics. its methods are called
+// concurrently by every send in flight, implementations must be safe for concurrent use and should
+// return quickly, they run on the path of the request
+type Metrics interface {
+	// ObserveSend is called after every attempt of a request, retries included. status is 0 when
+	// the attempt got no response, err is set when it failed without one. state is the one read
+	// from the response of a send, telling a queued notification from a processed one, and
+	// StateUnknown for any other request
+	ObserveSend(status int, state SendState, duration time.Duration, err error)
+	// ObserveRetry is called before a request is tried again, attempt being the number of the
+	// attempt about to be made, starting at 2
+	ObserveRetry(attempt int)
+}
+
+// WithMetrics sets the Metrics told about the requests of the client. nothing is observed otherwise
+func WithMetrics(m Metrics) Option {
+	return func(c *Client) {
+		c.declare(optMetrics)
+		c.metrics = m
+	}
+}
+
+func (c *Client) observer() Metrics {
+	if c.metrics == nil {
+		return NoopMetrics{}
+	}
+	return c.metrics
+}
+
+// NoopMetrics is a Metrics observing nothing
+type NoopMetrics struct{}
+
+func (NoopMetrics) ObserveSend(int, SendState, time.Duration, error) {}
+func (NoopMetrics) ObserveRetry(int)                                 {}
+
+// SendObservation is an attempt observed by MemoryMetrics
+type SendObservation struct {
+	Status   int
+	State    SendState
+	Duration time.Duration
+	Err      error
+}
+
+// MemoryMetrics is a Metrics keeping everything it observes in memory, meant for tests
+type MemoryMetrics struct {
+	mu      sync.Mutex
+	sends   []SendObservation
+	retries []int
+}
+
+func (m *MemoryMetrics) ObserveSend(status int, state SendState, duration time.Duration, err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.sends = append(m.sends, SendObservation{Status: status, State: state, Duration: duration, Err: err})
+}
+
+func (m *MemoryMetrics) ObserveRetry(attempt int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.retries = append(m.retries, attempt)
+}
+
+// Sends is every attempt observed so far, in order
+func (m *MemoryMetrics) Sends() []SendObservation {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]SendObservation{}, m.sends...)
+}
+
+// Retries is the attempt number of every retry observed so far, in order
+func (m *MemoryMetrics) Retries() []int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]int{}, m.retries...)
+}
@@ -0,0 +1,79 @@
+package engagespot
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMetricsObserveRetries(t *testing.T) {
+	var waits []time.Duration
+	var bodies []string
+	m := &MemoryMetrics{}
+	c := retryingClient(newFakeClock(), &waits, WithMetrics(m))
+	c.httpClient.Transport = flaky(2, 503, &bodies)
+
+	assert.NoError(t, sendTo(c, "a"))
+	assert.Equal(t, []SendObservation{{Status: 503}, {Status: 503}, {Status: 200, State: StateDelivered}}, m.Sends())
+	assert.Equal(t, []int{2, 3}, m.Retries())
+}
+
+func TestMetricsObserveTransportError(t *testing.T) {
+	m := &MemoryMetrics{}
+	c := NewEngagespotClient("A", "B", WithMetrics(m))
+	c.httpClient.Transport = roundTripFunc(func(*http.Request) (*http.Response, error) {
+		return nil, errors.New("connection refused")
+	})
+
+	assert.Error(t, sendTo(c, "a"))
+	sends := m.Sends()
+	assert.Len(t, sends, 1)
+	assert.Zero(t, sends[0].Status)
+	assert.Contains(t, sends[0].Err.Error(), "connection refused")
+	assert.Empty(t, m.Retries())
+}
+
+func TestMetricsObserveSendState(t *testing.T) {
+	m := &MemoryMetrics{}
+	c := NewEngagespotClient("A", "B", WithMetrics(m))
+	c.httpClient.Transport = respondWith(202, `{"id":"n1","status":"queued"}`)
+	assert.NoError(t, sendTo(c, "a"))
+
+	// requests other than sends have no state
+	c.httpClient.Transport = respondWith(200, `{}`)
+	_, err := c.GetUser(context.Background(), "a")
+	assert.NoError(t, err)
+
+	sends := m.Sends()
+	assert.Len(t, sends, 2)
+	assert.Equal(t, StateAccepted, sends[0].State)
+	assert.Equal(t, 200, sends[1].Status)
+	assert.Equal(t, StateUnknown, sends[1].State)
+}
+
+func TestMetricsConcurrentSends(t *testing.T) {
+	m := &MemoryMetrics{}
+	c := NewEngagespotClient("A", "B", WithMetrics(m))
+	c.httpClient.Transport = respondWith(200, `{}`)
+
+	var wg sync.WaitGroup
+	for _, id := range recipientIds(50) {
+		wg.Add(1)
+		go func(id string) {
+			defer wg.Done()
+			assert.NoError(t, sendTo(c, id))
+		}(id)
+	}
+	wg.Wait()
+	assert.Len(t, m.Sends(), 50)
+}
+
+func TestMetricsDefaultNoop(t *testing.T) {
+	c := NewEngagespotClient("A", "B")
+	assert.Equal(t, NoopMetrics{}, c.observer())
+}
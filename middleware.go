@@ -0,0 +1,72 @@
+package engagespot
+
+import (
+	"net/http"
+	"sync/atomic"
+)
+
+// Doer sends a single request. *http.Client is a Doer
+type Doer interface {
+	Do(*http.Request) (*http.Response, error)
+}
+
+// DoerFunc adapts a function to a Doer
+type DoerFunc func(*http.Request) (*http.Response, error)
+
+func (f DoerFunc) Do(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+// Middleware wraps the Doer sending the requests of a client, see Use
+type Middleware func(Doer) Doer
+
+// Use wraps the http client of c with the given middlewares, every attempt of every request goes
+// through them. the first middleware is the outermost, it's given the request first and the response
+// last. requests reach the middlewares fully formed, with their auth headers, signatures and
+// idempotency key already set. Use is meant to be called right after the client is created, if
+// it's called on a client in use the middlewares apply from the next call on
+func (c *Client) Use(mw ...Middleware) *Client {
+	c.liveMu.Lock()
+	defer c.liveMu.Unlock()
+	next := *c.settings()
+	next.middleware = append(copySlice(next.middleware), mw...)
+	c.live.Store(&next)
+	return c
+}
+
+// the http client of c, wrapped by the middlewares of s
+func (c *Client) doer(s *liveSettings) Doer {
+	var d Doer = c.httpClient
+	for i := len(s.middleware) - 1; i >= 0; i-- {
+		d = s.middleware[i](d)
+	}
+	return d
+}
+
+// RequestCounter is a Middleware counting the requests sent through it. it can be shared by clients
+type RequestCounter struct {
+	requests atomic.Int64
+	failures atomic.Int64
+}
+
+// Wrap is the Middleware of the counter, pass it to Use
+func (rc *RequestCounter) Wrap(next Doer) Doer {
+	return DoerFunc(func(req *http.Request) (*http.Response, error) {
+		rc.requests.Add(1)
+		res, err := next.Do(req)
+		if err != nil {
+			rc.failures.Add(1)
+		}
+		return res, err
+	})
+}
+
+// Requests is the number of requests sent through the counter, retries included
+func (rc *RequestCounter) Requests() int64 {
+	return rc.requests.Load()
+}
+
+// Failures is the number of requests which got no response at all
+func (rc *RequestCounter) Failures() int64 {
+	return rc.failures.Load()
+}
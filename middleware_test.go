@@ -0,0 +1,89 @@
+package engagespot
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// middleware recording its name in order, before and after the request
+func tracing(name string, trace *[]string) Middleware {
+	return func(next Doer) Doer {
+		return DoerFunc(func(req *http.Request) (*http.Response, error) {
+			*trace = append(*trace, name+" in")
+			res, err := next.Do(req)
+			*trace = append(*trace, name+" out")
+			return res, err
+		})
+	}
+}
+
+func TestMiddlewareOrder(t *testing.T) {
+	var trace []string
+	c := NewEngagespotClient("A", "B").Use(tracing("first", &trace), tracing("second", &trace))
+	c.Use(tracing("third", &trace))
+	c.httpClient.Transport = roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		trace = append(trace, "transport")
+		return respondWith(200, `{}`).RoundTrip(req)
+	})
+
+	assert.NoError(t, sendTo(c, "a"))
+	assert.Equal(t, []string{
+		"first in", "second in", "third in",
+		"transport",
+		"third out", "second out", "first out",
+	}, trace)
+}
+
+func TestMiddlewareSeesSignedRequest(t *testing.T) {
+	var seen http.Header
+	c := NewEngagespotClient("A", "B", WithHmac(), WithAutoIdempotency()).Use(func(next Doer) Doer {
+		return DoerFunc(func(req *http.Request) (*http.Response, error) {
+			seen = req.Header.Clone()
+			return next.Do(req)
+		})
+	})
+	c.httpClient.Transport = respondWith(200, `{}`)
+
+	assert.NoError(t, sendTo(c, "a"))
+	assert.Equal(t, "A", seen.Get("X-ENGAGESPOT-API-KEY"))
+	assert.Equal(t, "B", seen.Get("X-ENGAGESPOT-API-SECRET"))
+	assert.NotEmpty(t, seen.Get(BodySignatureHeader))
+	assert.NotEmpty(t, seen.Get(IdempotencyKeyHeader))
+}
+
+func TestMiddlewareShortCircuit(t *testing.T) {
+	c := NewEngagespotClient("A", "B").Use(func(Doer) Doer {
+		return DoerFunc(func(req *http.Request) (*http.Response, error) {
+			return respondWith(202, `{"id":"cached"}`).RoundTrip(req)
+		})
+	})
+	c.httpClient.Transport = noNetwork(t)
+
+	res, err := newMessage(c, "a").SendCtx(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, "cached", res.Id)
+}
+
+func TestRequestCounter(t *testing.T) {
+	var waits []time.Duration
+	var bodies []string
+	counter := &RequestCounter{}
+	c := retryingClient(newFakeClock(), &waits).Use(counter.Wrap)
+	c.httpClient.Transport = flaky(2, 503, &bodies)
+
+	assert.NoError(t, sendTo(c, "a"))
+	assert.Equal(t, int64(3), counter.Requests())
+	assert.Zero(t, counter.Failures())
+
+	c.httpClient.Transport = roundTripFunc(func(*http.Request) (*http.Response, error) {
+		return nil, errors.New("connection refused")
+	})
+	assert.Error(t, sendTo(c, "a"))
+	assert.Equal(t, int64(3+testRetryPolicy.MaxAttempts), counter.Requests())
+	assert.Equal(t, int64(testRetryPolicy.MaxAttempts), counter.Failures())
+}
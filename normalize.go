@@ -0,0 +1,28 @@
+package engagespot
+
+import "strings"
+
+// EmailNormalizer is a user id normalizer for apps identifying users by email address. it trims
+// surrounding whitespace and lowercases the address
+func EmailNormalizer(userId string) string {
+	return strings.ToLower(strings.TrimSpace(userId))
+}
+
+// WithUserIDNormalizer can be used to canonicalize user ids before they are used anywhere, so
+// variant spellings of the same id don't end up as different users with different HMACs. the
+// normalizer is applied in Connect, GenHmac, AddRecipient and every other user-scoped call. it is
+// disabled by default since enabling it changes ids of existing users
+func WithUserIDNormalizer(normalizer func(string) string) Option {
+	return func(c *Client) {
+		c.normalizer = normalizer
+	}
+}
+
+// NormalizeUserID returns the canonical form of userId used by the client. without a normalizer
+// the id is returned unchanged
+func (c *Client) NormalizeUserID(userId string) string {
+	if c.normalizer == nil {
+		return userId
+	}
+	return c.normalizer(userId)
+}
@@ -0,0 +1,75 @@
+package engagespot
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var userIdVariants = []string{"user@example.com", "User@Example.com", "  USER@example.COM\n"}
+
+func TestEmailNormalizer(t *testing.T) {
+	for _, v := range userIdVariants {
+		assert.Equal(t, "user@example.com", EmailNormalizer(v))
+	}
+}
+
+func TestNormalizedHmac(t *testing.T) {
+	c := NewEngagespotClient("A", "B", WithUserIDNormalizer(EmailNormalizer))
+	want := NewEngagespotClient("A", "B").GenHmac("user@example.com")
+	for _, v := range userIdVariants {
+		assert.Equal(t, want, c.GenHmac(v))
+		assert.Equal(t, "user@example.com", c.NormalizeUserID(v))
+	}
+}
+
+func TestNormalizationDisabledByDefault(t *testing.T) {
+	c := NewEngagespotClient("A", "B")
+	assert.NotEqual(t, c.GenHmac("user@example.com"), c.GenHmac("User@Example.com"))
+	assert.Equal(t, "User@Example.com", c.NormalizeUserID("User@Example.com"))
+}
+
+func TestNormalizedConnectHeaders(t *testing.T) {
+	var headers []http.Header
+	c := NewEngagespotClient("A", "B", WithUserIDNormalizer(EmailNormalizer), WithHmac())
+	c.httpClient.Transport = roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		headers = append(headers, req.Header)
+		return respondWith(200, `{}`).RoundTrip(req)
+	})
+
+	for _, v := range userIdVariants {
+		_, err := c.Connect(v)
+		assert.NoError(t, err)
+	}
+
+	for _, h := range headers {
+		assert.Equal(t, "user@example.com", h.Get("X-ENGAGESPOT-USER-ID"))
+		assert.Equal(t, c.GenHmac("user@example.com"), h.Get("X-ENGAGESPOT-USER-SIGNATURE"))
+	}
+}
+
+func TestNormalizedRecipients(t *testing.T) {
+	c := NewEngagespotClient("A", "B", WithUserIDNormalizer(EmailNormalizer))
+	n, _ := c.NewNotification("Hello")
+	for _, v := range userIdVariants {
+		_, err := n.AddRecipient(v)
+		assert.NoError(t, err)
+	}
+	assert.Equal(t, []string{"user@example.com", "user@example.com", "user@example.com"}, n.Recipients)
+
+	_, err := n.AddRecipient("   ")
+	assert.Error(t, err)
+}
+
+func TestNormalizedSendResult(t *testing.T) {
+	c := NewEngagespotClient("A", "B", WithUserIDNormalizer(EmailNormalizer))
+	c.httpClient.Transport = respondWith(200, `{}`)
+	n, _ := c.NewNotification("Hello")
+	n.AddRecipient(" Someone@Example.com")
+
+	result, err := n.SendCtx(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"someone@example.com"}, result.Recipients)
+}
@@ -0,0 +1,219 @@
+package engagespot
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// Option can be passed to NewEngagespotClient to configure the client
+type Option func(*Client)
+
+// WithReadOnly makes the client reject every call which would change state on the API, like
+// sending notifications or connecting users, with ErrReadOnlyClient. reads are allowed as usual.
+// useful for replica or disaster-recovery environments which must never notify anyone
+func WithReadOnly() Option {
+	return func(c *Client) {
+		c.declare(optReadOnly)
+		c.config.readOnly = true
+	}
+}
+
+// WithHmac can be used to enable an extra layer of security. calls made on behalf of a user, like
+// Connect, then sign the user id, and every request with a body carries the signature of its body
+// in BodySignatureHeader.
+// Read more: https://documentation.engagespot.co/docs/HMAC-authentication/enabling-HMAC-authentication
+func WithHmac() Option {
+	return func(c *Client) {
+		c.initialSettings().hmac = true
+	}
+}
+
+// identifies an option in optionSpecs. the name of the option, as shown in errors
+type optionID string
+
+const (
+	optReadOnly              optionID = "WithReadOnly"
+	optDefaults              optionID = "WithDefaults"
+	optLogger                optionID = "WithLogger"
+	optDebug                 optionID = "WithDebug"
+	optRequestHook           optionID = "WithRequestHook"
+	optResponseHook          optionID = "WithResponseHook"
+	optMetrics               optionID = "WithMetrics"
+	optSendObserver          optionID = "WithSendObserver"
+	optApprovalGate          optionID = "WithApprovalGate"
+	optMaxRecipientsGuard    optionID = "WithMaxRecipientsGuard"
+	optAdaptiveChunkSize     optionID = "WithAdaptiveChunkSize"
+	optSkewCorrection        optionID = "WithSkewCorrection"
+	optArchiver              optionID = "WithArchiver"
+	optArchiveFieldFilter    optionID = "WithArchiveFieldFilter"
+	optArchiveBuffer         optionID = "WithArchiveBuffer"
+	optArchiveEncryption     optionID = "WithArchiveEncryption"
+	optRateLimit             optionID = "WithRateLimit"
+	optRateLimitHandler      optionID = "WithRateLimitHandler"
+	optDebugSampling         optionID = "WithDebugSampling"
+	optDebugSampleSink       optionID = "WithDebugSampleSink"
+	optOptOutFiltering       optionID = "WithCategoryOptOutFiltering"
+	optOptOutFailClosed      optionID = "WithOptOutFailClosed"
+	optPreferenceCache       optionID = "WithPreferenceCachePolicy"
+	optExpectContinue        optionID = "WithExpectContinueTimeout"
+	optWithoutExpectContinue optionID = "WithoutExpectContinue"
+	optHTTPClient            optionID = "WithHTTPClient"
+	optTimeout               optionID = "WithTimeout"
+	optBaseURL               optionID = "WithBaseURL"
+	optRetry                 optionID = "WithRetry"
+	optWorkers               optionID = "WithWorkers"
+	optAPIVersion            optionID = "WithAPIVersion"
+	optMaxPayloadBytes       optionID = "WithMaxPayloadBytes"
+	optDryRun                optionID = "WithDryRun"
+	optDeviceLookup          optionID = "WithDeviceLookup"
+	optDeviceCachePolicy     optionID = "WithDeviceCachePolicy"
+)
+
+// what an option can't be combined with. options declare their id when applied, see declare, and
+// NewClient checks the declared ids against this table. an option only needs an entry here, and a
+// call to declare, if it takes part in a conflict
+type optionSpec struct {
+	// options which don't make sense along with this one, with the reason. a conflict only needs
+	// to be listed on one side
+	conflicts map[optionID]string
+	// options this one has no effect without
+	requires []optionID
+	// applying the option again replaces what was set before
+	single bool
+}
+
+var optionSpecs = map[optionID]optionSpec{
+	optReadOnly: {conflicts: map[optionID]string{
+		optApprovalGate:       "a read-only client never sends",
+		optMaxRecipientsGuard: "a read-only client never sends",
+		optAdaptiveChunkSize:  "a read-only client never sends",
+		optSkewCorrection:     "a read-only client never sends",
+		optDryRun:             "a read-only client never sends, every dry run send fails with ErrReadOnlyClient",
+	}},
+	optDefaults:           {single: true},
+	optLogger:             {single: true},
+	optDebug:              {single: true},
+	optRequestHook:        {single: true},
+	optResponseHook:       {single: true},
+	optMetrics:            {single: true},
+	optSendObserver:       {single: true},
+	optApprovalGate:       {single: true},
+	optMaxRecipientsGuard: {single: true},
+	optAdaptiveChunkSize:  {single: true},
+	optArchiver:           {single: true},
+	optArchiveFieldFilter: {requires: []optionID{optArchiver}},
+	optArchiveBuffer:      {requires: []optionID{optArchiver}, single: true},
+	optArchiveEncryption:  {requires: []optionID{optArchiver}, single: true},
+	optRateLimit:          {single: true},
+	optRateLimitHandler:   {requires: []optionID{optRateLimit}, single: true},
+	optDebugSampling:      {single: true},
+	optDebugSampleSink:    {requires: []optionID{optDebugSampling}, single: true},
+	optOptOutFiltering:    {single: true},
+	optOptOutFailClosed:   {requires: []optionID{optOptOutFiltering}},
+	optPreferenceCache:    {requires: []optionID{optOptOutFiltering}, single: true},
+	optExpectContinue: {conflicts: map[optionID]string{
+		optWithoutExpectContinue: "the timeout is never used once Expect: 100-continue is disabled",
+	}},
+	optHTTPClient:        {single: true},
+	optTimeout:           {single: true},
+	optBaseURL:           {single: true},
+	optRetry:             {single: true},
+	optWorkers:           {single: true},
+	optAPIVersion:        {single: true},
+	optMaxPayloadBytes:   {single: true},
+	optDryRun:            {single: true},
+	optDeviceLookup:      {single: true},
+	optDeviceCachePolicy: {requires: []optionID{optDeviceLookup}, single: true},
+}
+
+// record that the option id was applied to c
+func (c *Client) declare(id optionID) {
+	c.declaredOptions = append(c.declaredOptions, id)
+}
+
+// record that the option id was given an invalid value, and wasn't applied
+func (c *Client) rejectOption(id optionID, err error) {
+	c.rejectedOptions = append(c.rejectedOptions, fmt.Sprintf("%s: %v", id, err))
+}
+
+// OptionConflictError is returned by NewClient for options which conflict with each other, don't
+// have any effect or were given an invalid value, one entry per problem
+type OptionConflictError struct {
+	Conflicts []string
+}
+
+func (e *OptionConflictError) Error() string {
+	return "engagespot: conflicting options: " + strings.Join(e.Conflicts, "; ")
+}
+
+// check the options applied to c against optionSpecs. every problem is reported, in the order
+// the options were applied
+func (c *Client) checkOptions() error {
+	count := map[optionID]int{}
+	var applied []optionID
+	for _, id := range c.declaredOptions {
+		if count[id] == 0 {
+			applied = append(applied, id)
+		}
+		count[id]++
+	}
+
+	conflicts := append([]string(nil), c.rejectedOptions...)
+	for _, id := range applied {
+		spec := optionSpecs[id]
+		if spec.single && count[id] > 1 {
+			conflicts = append(conflicts, fmt.Sprintf("%s applied %d times, only the last one applies", id, count[id]))
+		}
+		for _, required := range spec.requires {
+			if count[required] == 0 {
+				conflicts = append(conflicts, fmt.Sprintf("%s has no effect without %s", id, required))
+			}
+		}
+		for _, other := range applied {
+			if reason, ok := spec.conflicts[other]; ok {
+				conflicts = append(conflicts, fmt.Sprintf("%s and %s: %s", id, other, reason))
+			}
+		}
+	}
+
+	if len(conflicts) > 0 {
+		return &OptionConflictError{Conflicts: conflicts}
+	}
+	return nil
+}
+
+// CallOption changes how a single call is made
+type CallOption func(*callOptions)
+
+type callOptions struct {
+	asUser                 *string
+	approvalToken          *string
+	acknowledgedRecipients *int
+	abortInFlight          bool
+	batchSize              *int
+}
+
+// carry the call options on ctx, down to the request builder and the call helper
+func applyCallOptions(ctx context.Context, opts []CallOption) context.Context {
+	o := callOptions{}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if o.asUser != nil {
+		ctx = context.WithValue(ctx, userScopeKey{}, *o.asUser)
+	}
+	if o.approvalToken != nil {
+		ctx = context.WithValue(ctx, approvalTokenKey{}, *o.approvalToken)
+	}
+	if o.acknowledgedRecipients != nil {
+		ctx = context.WithValue(ctx, acknowledgedRecipientsKey{}, *o.acknowledgedRecipients)
+	}
+	if o.abortInFlight {
+		ctx = context.WithValue(ctx, abortInFlightKey{}, true)
+	}
+	if o.batchSize != nil {
+		ctx = context.WithValue(ctx, batchSizeKey{}, *o.batchSize)
+	}
+	return ctx
+}
@@ -0,0 +1,61 @@
+package engagespot
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewClientNoConflicts(t *testing.T) {
+	c, err := NewClient("A", "B", WithReadOnly(), WithArchiver(&recordingArchiver{}, ArchiveSync), WithArchiveBuffer(10))
+	assert.NoError(t, err)
+	assert.True(t, c.IsReadOnly())
+}
+
+func TestNewClientConflicts(t *testing.T) {
+	approve := func(ctx context.Context, summary SendSummary) (bool, error) { return true, nil }
+	_, err := NewClient("A", "B",
+		WithReadOnly(),
+		WithApprovalGate(10, approve),
+		WithExpectContinueTimeout(time.Second),
+		WithoutExpectContinue(),
+		WithRateLimitHandler(func(RateLimitAdjustment) {}),
+		WithLogger(slog.Default()),
+		WithLogger(slog.Default()),
+		WithSkewCorrection(),
+		WithDryRun(),
+		WithDeviceCachePolicy(CachePolicy{TTL: time.Minute}),
+	)
+
+	var conflict *OptionConflictError
+	assert.True(t, errors.As(err, &conflict))
+	assert.Equal(t, []string{
+		"WithReadOnly and WithApprovalGate: a read-only client never sends",
+		"WithReadOnly and WithSkewCorrection: a read-only client never sends",
+		"WithReadOnly and WithDryRun: a read-only client never sends, every dry run send fails with ErrReadOnlyClient",
+		"WithExpectContinueTimeout and WithoutExpectContinue: the timeout is never used once Expect: 100-continue is disabled",
+		"WithRateLimitHandler has no effect without WithRateLimit",
+		"WithLogger applied 2 times, only the last one applies",
+		"WithDeviceCachePolicy has no effect without WithDeviceLookup",
+	}, conflict.Conflicts)
+	assert.Contains(t, err.Error(), "engagespot: conflicting options: WithReadOnly and WithApprovalGate")
+}
+
+func TestNewClientConflictOrderIndependent(t *testing.T) {
+	_, err := NewClient("A", "B", WithoutExpectContinue(), WithArchiveEncryption(nil), WithExpectContinueTimeout(time.Second))
+	assert.EqualError(t, err, "engagespot: conflicting options: "+
+		"WithArchiveEncryption has no effect without WithArchiver; "+
+		"WithExpectContinueTimeout and WithoutExpectContinue: the timeout is never used once Expect: 100-continue is disabled")
+}
+
+func TestNewEngagespotClientLogsConflicts(t *testing.T) {
+	var logs bytes.Buffer
+	c := NewEngagespotClient("A", "B", WithLogger(slog.New(slog.NewTextHandler(&logs, nil))), WithOptOutFailClosed())
+	assert.NotNil(t, c)
+	assert.Contains(t, logs.String(), "WithOptOutFailClosed has no effect without WithCategoryOptOutFiltering")
+}
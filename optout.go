@@ -0,0 +1,200 @@
+package engagespot
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ErrAllRecipientsSuppressed is returned by Send when every recipient was filtered out before
+// sending, so no request has been made
+var ErrAllRecipientsSuppressed = errors.New("all recipients suppressed")
+
+// number of preference lookups made in parallel while filtering a send
+const optOutLookupConcurrency = 8
+
+// PreferenceCache stores whether users opted out of categories. implementations must be safe for
+// concurrent use
+type PreferenceCache interface {
+	Get(userId, category string) (optedOut bool, storedAt time.Time, ok bool)
+	Set(userId, category string, optedOut bool, storedAt time.Time)
+}
+
+type preferenceEntry struct {
+	optedOut bool
+	storedAt time.Time
+}
+
+// in-memory PreferenceCache
+type memoryPreferenceCache struct {
+	m sync.Map
+}
+
+// NewMemoryPreferenceCache creates a PreferenceCache keeping entries in memory. entries are never
+// evicted, staleness is decided by the ttl given to WithCategoryOptOutFiltering
+func NewMemoryPreferenceCache() PreferenceCache {
+	return &memoryPreferenceCache{}
+}
+
+func (c *memoryPreferenceCache) Get(userId, category string) (bool, time.Time, bool) {
+	v, ok := c.m.Load(userId + "\x00" + category)
+	if !ok {
+		return false, time.Time{}, false
+	}
+	e := v.(preferenceEntry)
+	return e.optedOut, e.storedAt, true
+}
+
+func (c *memoryPreferenceCache) Set(userId, category string, optedOut bool, storedAt time.Time) {
+	c.m.Store(userId+"\x00"+category, preferenceEntry{optedOut: optedOut, storedAt: storedAt})
+}
+
+// WithCategoryOptOutFiltering checks, before every send with a category, whether each recipient
+// opted out of the category and leaves them out of the send if so. preferences are kept in cache
+// for ttl, see WithPreferenceCachePolicy for finer control. if a preference can't be fetched the
+// recipient is kept, see WithOptOutFailClosed
+func WithCategoryOptOutFiltering(cache PreferenceCache, ttl time.Duration) Option {
+	return func(c *Client) {
+		c.declare(optOptOutFiltering)
+		if cache == nil {
+			cache = NewMemoryPreferenceCache()
+		}
+		c.optOutCache = cache
+		c.optOutPolicy.TTL = ttl
+	}
+}
+
+// WithOptOutFailClosed makes opt-out filtering leave out recipients whose preferences can't be
+// fetched, instead of sending to them anyway
+func WithOptOutFailClosed() Option {
+	return func(c *Client) {
+		c.declare(optOptOutFailClosed)
+		c.optOutFailClosed = true
+	}
+}
+
+// SuppressionReport describes what opt-out filtering did during the last send of a notification
+type SuppressionReport struct {
+	// recipients left out of the send
+	Suppressed   []string
+	CacheHits    int
+	Lookups      int
+	LookupErrors int
+}
+
+// Suppression can be used to get the report of opt-out filtering of the last send
+func (n *Notification) Suppression() SuppressionReport {
+	return n.last.get().suppression
+}
+
+// copy of recipients without those suppressed in report
+func unsuppressed(recipients []string, report SuppressionReport) []string {
+	left := make([]string, 0, len(recipients))
+	for _, r := range recipients {
+		if !containsString(report.Suppressed, r) {
+			left = append(left, r)
+		}
+	}
+	return left
+}
+
+// result of checking a single recipient
+type optOutCheck struct {
+	optedOut bool
+	cacheHit bool
+	err      error
+}
+
+func (c *Client) checkOptOut(ctx context.Context, userId, category string) optOutCheck {
+	if optedOut, storedAt, ok := c.optOutCache.Get(userId, category); ok {
+		switch c.optOutPolicy.freshness(c.now().Sub(storedAt)) {
+		case fresh:
+			return optOutCheck{optedOut: optedOut, cacheHit: true}
+		case stale:
+			atomic.AddUint64(&c.optOutRefresher.staleServes, 1)
+			// the refresh outlives the send, it only keeps the values of its context
+			refreshCtx := context.WithoutCancel(ctx)
+			c.optOutRefresher.refresh(c.optOutPolicy, userId+"\x00"+category, func() error {
+				_, err := c.lookupOptOut(refreshCtx, userId, category)
+				return err
+			})
+			return optOutCheck{optedOut: optedOut, cacheHit: true}
+		}
+	}
+
+	optedOut, err := c.lookupOptOut(ctx, userId, category)
+	if err != nil {
+		return optOutCheck{err: err}
+	}
+	return optOutCheck{optedOut: optedOut}
+}
+
+// fetch the preference of a user for category and cache it
+func (c *Client) lookupOptOut(ctx context.Context, userId, category string) (bool, error) {
+	p, err := c.GetPreferences(ctx, userId)
+	if err != nil {
+		return false, err
+	}
+	optedOut := p.OptedOut(category)
+	c.optOutCache.Set(userId, category, optedOut, c.now())
+	return optedOut, nil
+}
+
+// filter out recipients who opted out of the category of n. the returned notification is n itself
+// if nothing has been filtered, a copy otherwise. the report is returned whatever the error. no
+// more lookups are started once ctx is done, and its error is returned
+func (c *Client) filterOptOuts(ctx context.Context, n *Notification) (*Notification, SuppressionReport, error) {
+	checks := make([]optOutCheck, len(n.Recipients))
+
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, optOutLookupConcurrency)
+lookups:
+	for i, recipient := range n.Recipients {
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+			break lookups
+		}
+		wg.Add(1)
+		go func(i int, recipient string) {
+			defer wg.Done()
+			checks[i] = c.checkOptOut(ctx, recipient, n.Category)
+			<-sem
+		}(i, recipient)
+	}
+	wg.Wait()
+	if err := ctx.Err(); err != nil {
+		return nil, SuppressionReport{}, err
+	}
+
+	report := SuppressionReport{}
+	var recipients []string
+	for i, check := range checks {
+		switch {
+		case check.err != nil:
+			report.LookupErrors++
+		case check.cacheHit:
+			report.CacheHits++
+		default:
+			report.Lookups++
+		}
+
+		if check.optedOut || (check.err != nil && c.optOutFailClosed) {
+			report.Suppressed = append(report.Suppressed, n.Recipients[i])
+			continue
+		}
+		recipients = append(recipients, n.Recipients[i])
+	}
+	if len(recipients) == 0 && len(n.Topics) == 0 {
+		return nil, report, ErrAllRecipientsSuppressed
+	}
+	if len(report.Suppressed) == 0 {
+		return n, report, nil
+	}
+
+	filtered := *n
+	filtered.Recipients = recipients
+	return &filtered, report, nil
+}
@@ -0,0 +1,198 @@
+package engagespot
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// fake API serving preferences of users opted out of "promo", failing for users in failing
+type preferenceServer struct {
+	mu       sync.Mutex
+	optedOut []string
+	failing  []string
+	lookups  int
+	sent     [][]interface{}
+}
+
+func (s *preferenceServer) RoundTrip(req *http.Request) (*http.Response, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	respond := func(status int, body string) (*http.Response, error) {
+		return &http.Response{StatusCode: status, Body: io.NopCloser(strings.NewReader(body)), Request: req}, nil
+	}
+
+	if req.Method == http.MethodPost {
+		var body map[string]interface{}
+		json.NewDecoder(req.Body).Decode(&body)
+		recipients, _ := body["recipients"].([]interface{})
+		s.sent = append(s.sent, recipients)
+		return respond(200, `{}`)
+	}
+
+	s.lookups++
+	userId := strings.Split(strings.TrimPrefix(req.URL.Path, "/v3/users/"), "/")[0]
+	if containsString(s.failing, userId) {
+		return respond(500, `{}`)
+	}
+	enabled := !containsString(s.optedOut, userId)
+	return respond(200, `{"preferences":[{"category":"promo","enabled":`+map[bool]string{true: "true", false: "false"}[enabled]+`}]}`)
+}
+
+func newOptOutClient(s *preferenceServer, opts ...Option) *Client {
+	c := NewEngagespotClient("A", "B", opts...)
+	c.httpClient.Transport = s
+	return c
+}
+
+func sendPromo(c *Client, recipients ...string) (*Notification, error) {
+	n := newMessage(c, recipients...)
+	n.SetCategory("promo")
+	_, err := n.SendCtx(context.Background())
+	return n, err
+}
+
+func TestOptOutFiltering(t *testing.T) {
+	s := &preferenceServer{optedOut: []string{"b"}}
+	c := newOptOutClient(s, WithCategoryOptOutFiltering(nil, time.Minute))
+
+	n, err := sendPromo(c, "a", "b", "c")
+	assert.NoError(t, err)
+	assert.Equal(t, [][]interface{}{{"a", "c"}}, s.sent)
+	assert.Equal(t, SuppressionReport{Suppressed: []string{"b"}, Lookups: 3}, n.Suppression())
+	// the notification keeps every recipient
+	assert.Equal(t, []string{"a", "b", "c"}, n.Recipients)
+
+	result, err := n.Send()
+	assert.NoError(t, err)
+	assert.Equal(t, SuppressionReport{Suppressed: []string{"b"}, CacheHits: 3}, result.Suppression)
+	assert.Equal(t, []string{"a", "c"}, result.Recipients)
+}
+
+func TestOptOutCacheHits(t *testing.T) {
+	now := time.Now()
+	s := &preferenceServer{optedOut: []string{"b"}}
+	c := newOptOutClient(s, WithCategoryOptOutFiltering(NewMemoryPreferenceCache(), time.Minute))
+	c.now = func() time.Time { return now }
+
+	_, err := sendPromo(c, "a", "b")
+	assert.NoError(t, err)
+	assert.Equal(t, 2, s.lookups)
+
+	n, err := sendPromo(c, "a", "b")
+	assert.NoError(t, err)
+	assert.Equal(t, 2, s.lookups)
+	assert.Equal(t, SuppressionReport{Suppressed: []string{"b"}, CacheHits: 2}, n.Suppression())
+
+	// stale entries are looked up again
+	now = now.Add(time.Minute)
+	n, err = sendPromo(c, "a", "b")
+	assert.NoError(t, err)
+	assert.Equal(t, 4, s.lookups)
+	assert.Equal(t, 2, n.Suppression().Lookups)
+}
+
+func TestOptOutFailOpen(t *testing.T) {
+	s := &preferenceServer{failing: []string{"a"}}
+	c := newOptOutClient(s, WithCategoryOptOutFiltering(nil, time.Minute))
+
+	n, err := sendPromo(c, "a", "b")
+	assert.NoError(t, err)
+	assert.Equal(t, [][]interface{}{{"a", "b"}}, s.sent)
+	assert.Equal(t, SuppressionReport{Lookups: 1, LookupErrors: 1}, n.Suppression())
+}
+
+func TestOptOutFailClosed(t *testing.T) {
+	s := &preferenceServer{failing: []string{"a"}}
+	c := newOptOutClient(s, WithCategoryOptOutFiltering(nil, time.Minute), WithOptOutFailClosed())
+
+	n, err := sendPromo(c, "a", "b")
+	assert.NoError(t, err)
+	assert.Equal(t, [][]interface{}{{"b"}}, s.sent)
+	assert.Equal(t, SuppressionReport{Suppressed: []string{"a"}, Lookups: 1, LookupErrors: 1}, n.Suppression())
+}
+
+func TestOptOutAllSuppressed(t *testing.T) {
+	s := &preferenceServer{optedOut: []string{"a"}}
+	c := newOptOutClient(s, WithCategoryOptOutFiltering(nil, time.Minute))
+
+	_, err := sendPromo(c, "a")
+	assert.ErrorIs(t, err, ErrAllRecipientsSuppressed)
+	assert.Empty(t, s.sent)
+}
+
+func TestOptOutAllSuppressedWithTopic(t *testing.T) {
+	s := &preferenceServer{optedOut: []string{"a"}}
+	c := newOptOutClient(s, WithCategoryOptOutFiltering(nil, time.Minute))
+
+	n := newMessage(c, "a")
+	n.SetCategory("promo")
+	n.AddTopic("eu")
+	_, err := n.SendCtx(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, [][]interface{}{nil}, s.sent)
+	assert.Equal(t, []string{"a"}, n.Suppression().Suppressed)
+}
+
+func TestOptOutSkippedWithoutCategory(t *testing.T) {
+	s := &preferenceServer{optedOut: []string{"a"}}
+	c := newOptOutClient(s, WithCategoryOptOutFiltering(nil, time.Minute))
+
+	n, _ := c.NewNotification("Hello")
+	n.AddRecipient("a")
+	_, err := n.Send()
+	assert.NoError(t, err)
+	assert.Equal(t, 0, s.lookups)
+}
+
+func TestGetPreferences(t *testing.T) {
+	var path string
+	c := NewEngagespotClient("A", "B")
+	c.httpClient.Transport = roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		path = req.URL.EscapedPath()
+		return respondWith(200, `{"preferences":[{"category":"promo","enabled":false}],"updatedAt":"x"}`).RoundTrip(req)
+	})
+
+	p, err := c.GetPreferences(context.Background(), "a+b@example.com")
+	assert.NoError(t, err)
+	assert.Equal(t, "/v3/users/a+b@example.com/preferences", path)
+	assert.True(t, p.OptedOut("promo"))
+	assert.False(t, p.OptedOut("other"))
+	assert.Contains(t, p.RawExtra, "updatedAt")
+}
+
+func TestOptOutFilteringCancelled(t *testing.T) {
+	var lookups, posts int32
+	c := NewEngagespotClient("A", "B", WithCategoryOptOutFiltering(nil, time.Minute))
+	c.httpClient.Transport = roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		if req.Method == http.MethodPost {
+			atomic.AddInt32(&posts, 1)
+			return respondWith(200, `{}`).RoundTrip(req)
+		}
+		// a preferences endpoint which never answers
+		atomic.AddInt32(&lookups, 1)
+		<-req.Context().Done()
+		return nil, req.Context().Err()
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	n := newMessage(c, recipientIds(4*optOutLookupConcurrency)...)
+	n.SetCategory("promo")
+	started := time.Now()
+	_, err := n.SendCtx(ctx)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Less(t, time.Since(started), 5*time.Second)
+	// the lookups after the cancellation aren't started
+	assert.LessOrEqual(t, atomic.LoadInt32(&lookups), int32(optOutLookupConcurrency))
+	assert.Zero(t, atomic.LoadInt32(&posts))
+}
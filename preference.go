@@ -0,0 +1,40 @@
+package engagespot
+
+import "context"
+
+// CategoryPreference is whether a user wants to receive notifications of a category
+type CategoryPreference struct {
+	Category string `json:"category"`
+	Enabled  bool   `json:"enabled"`
+}
+
+// Preferences of a user, as returned by `GET users/{userId}/preferences`
+type Preferences struct {
+	Response
+	Preferences []CategoryPreference `json:"preferences"`
+}
+
+// OptedOut can be used to check if the user unsubscribed from category. categories without a
+// preference are considered subscribed
+func (p *Preferences) OptedOut(category string) bool {
+	for _, pref := range p.Preferences {
+		if pref.Category == category {
+			return !pref.Enabled
+		}
+	}
+	return false
+}
+
+// GetPreferences can be used to fetch the per-category notification preferences of a user
+func (c *Client) GetPreferences(ctx context.Context, userId string, opts ...CallOption) (*Preferences, error) {
+	req, err := c.newRequestContext(applyCallOptions(ctx, opts), endpointGetPreferences, nil, c.NormalizeUserID(userId))
+	if err != nil {
+		return nil, err
+	}
+
+	p := &Preferences{}
+	if err := c.callJSON(endpointGetPreferences, req, p); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
@@ -0,0 +1,170 @@
+package engagespot
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// priorities of FcmOverride
+const (
+	FcmPriorityNormal = "normal"
+	FcmPriorityHigh   = "high"
+)
+
+// longest collapse id APNs takes, in bytes
+const maxApnsCollapseId = 64
+
+// ApnsOverride holds what can be overridden of the APNs mobile push of a single notification.
+// empty fields keep the dashboard value
+type ApnsOverride struct {
+	// sound played on delivery, a sound file of the app or "default"
+	Sound string
+	// number shown on the app icon. nil leaves it as is, 0 clears it
+	Badge *int
+	// a notification replaces the one of the same collapse id still shown on the device
+	CollapseId string
+}
+
+// FcmOverride holds what can be overridden of the FCM mobile push of a single notification.
+// empty fields keep the dashboard value
+type FcmOverride struct {
+	// FcmPriorityNormal or FcmPriorityHigh
+	Priority string
+	// android notification channel the notification is posted to
+	ChannelId string
+}
+
+// SetApnsOverride can be used to override the APNs fields of the mobile push for this
+// notification. it replaces the APNs override of a previous call
+func (n *Notification) SetApnsOverride(o ApnsOverride) (*Notification, error) {
+	if o == (ApnsOverride{}) {
+		return nil, invalidField("apns", o, errors.New("empty apns override"))
+	}
+	if o.Badge != nil {
+		if *o.Badge < 0 {
+			return nil, invalidField("apns", *o.Badge, fmt.Errorf("negative apns badge %d", *o.Badge))
+		}
+		// the caller can't change the badge sent through its pointer
+		badge := *o.Badge
+		o.Badge = &badge
+	}
+	if o.CollapseId != "" {
+		if strings.TrimSpace(o.CollapseId) == "" {
+			return nil, invalidField("apns", o.CollapseId, errors.New("empty apns collapse id string"))
+		}
+		if len(o.CollapseId) > maxApnsCollapseId {
+			return nil, invalidField("apns", o.CollapseId, fmt.Errorf("apns collapse id is over %d bytes", maxApnsCollapseId))
+		}
+	}
+	n.Override.apns = &o
+	return n, nil
+}
+
+// SetFcmOverride can be used to override the FCM fields of the mobile push for this notification.
+// it replaces the FCM override of a previous call
+func (n *Notification) SetFcmOverride(o FcmOverride) (*Notification, error) {
+	if o == (FcmOverride{}) {
+		return nil, invalidField("fcm", o, errors.New("empty fcm override"))
+	}
+	if o.Priority != "" && o.Priority != FcmPriorityNormal && o.Priority != FcmPriorityHigh {
+		return nil, invalidField("fcm", o.Priority, fmt.Errorf("invalid fcm priority %q", o.Priority))
+	}
+	if o.ChannelId != "" && strings.TrimSpace(o.ChannelId) == "" {
+		return nil, invalidField("fcm", o.ChannelId, errors.New("empty fcm channel id string"))
+	}
+	n.Override.fcm = &o
+	return n, nil
+}
+
+// fields of the override for FCM, APNs and web push, as the providers take them
+type pushFields struct {
+	Fcm     *fcmFields     `json:"fcm,omitempty"`
+	Apns    *apnsFields    `json:"apns,omitempty"`
+	WebPush *webPushFields `json:"webpush,omitempty"`
+}
+
+type fcmFields struct {
+	Android androidFields `json:"android"`
+}
+
+type androidFields struct {
+	Priority     string               `json:"priority,omitempty"`
+	TTL          string               `json:"ttl,omitempty"`
+	Notification *androidNotification `json:"notification,omitempty"`
+}
+
+type androidNotification struct {
+	ChannelId string `json:"channel_id"`
+}
+
+type apnsFields struct {
+	Headers map[string]string `json:"headers,omitempty"`
+	Payload *apnsPayload      `json:"payload,omitempty"`
+}
+
+type apnsPayload struct {
+	Aps struct {
+		Sound string `json:"sound,omitempty"`
+		Badge *int   `json:"badge,omitempty"`
+	} `json:"aps"`
+}
+
+type webPushFields struct {
+	Headers map[string]string `json:"headers"`
+}
+
+func (o *Override) pushFields() pushFields {
+	var f pushFields
+	if o.fcm != nil {
+		f.Fcm = &fcmFields{Android: androidFields{Priority: o.fcm.Priority}}
+		if o.fcm.ChannelId != "" {
+			f.Fcm.Android.Notification = &androidNotification{ChannelId: o.fcm.ChannelId}
+		}
+	}
+	if o.apns != nil {
+		f.Apns = &apnsFields{}
+		if o.apns.CollapseId != "" {
+			f.Apns.Headers = map[string]string{"apns-collapse-id": o.apns.CollapseId}
+		}
+		if o.apns.Sound != "" || o.apns.Badge != nil {
+			f.Apns.Payload = &apnsPayload{}
+			f.Apns.Payload.Aps.Sound = o.apns.Sound
+			f.Apns.Payload.Aps.Badge = o.apns.Badge
+		}
+	}
+
+	e := o.expiry
+	if e == nil {
+		return f
+	}
+	if e.mobile {
+		if f.Fcm == nil {
+			f.Fcm = &fcmFields{}
+		}
+		f.Fcm.Android.TTL = e.seconds() + "s"
+		if f.Apns == nil {
+			f.Apns = &apnsFields{}
+		}
+		if f.Apns.Headers == nil {
+			f.Apns.Headers = map[string]string{}
+		}
+		f.Apns.Headers["apns-expiration"] = strconv.FormatInt(e.at.Unix(), 10)
+	}
+	if e.web {
+		f.WebPush = &webPushFields{Headers: map[string]string{"TTL": e.seconds()}}
+	}
+	return f
+}
+
+// MarshalJSON adds the push provider fields to the override, see SetApnsOverride, SetFcmOverride
+// and SetTTL
+func (o Override) MarshalJSON() ([]byte, error) {
+	type plain Override
+	return json.Marshal(struct {
+		plain
+		pushFields
+	}{plain(o), o.pushFields()})
+}
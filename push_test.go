@@ -0,0 +1,106 @@
+package engagespot
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func badge(b int) *int {
+	return &b
+}
+
+func TestPushOverridesGolden(t *testing.T) {
+	n := newTestNotification(t)
+	n.AddRecipient("a")
+	n.Override.AddChannel(ChannelMobilePush)
+	n.Override.AddChannel(ChannelInApp)
+	_, err := n.SetApnsOverride(ApnsOverride{Sound: "chime.caf", Badge: badge(3), CollapseId: "order-42"})
+	assert.NoError(t, err)
+	_, err = n.SetFcmOverride(FcmOverride{Priority: FcmPriorityHigh, ChannelId: "orders"})
+	assert.NoError(t, err)
+
+	assertGolden(t, "push-overrides", n)
+}
+
+func TestPushOverridesOmittedWhenUnused(t *testing.T) {
+	n := newTestNotification(t)
+	n.AddRecipient("a")
+	n.Override.AddChannel(ChannelMobilePush)
+	b, err := json.Marshal(n)
+	assert.NoError(t, err)
+	for _, key := range []string{"apns", "fcm", "webpush"} {
+		assert.NotContains(t, string(b), key)
+	}
+
+	// only the fields set are sent
+	n.SetApnsOverride(ApnsOverride{Badge: badge(0)})
+	n.SetFcmOverride(FcmOverride{Priority: FcmPriorityNormal})
+	override := encodedPayload(t, n)["override"].(map[string]interface{})
+	assert.Equal(t, map[string]interface{}{"payload": map[string]interface{}{"aps": map[string]interface{}{"badge": float64(0)}}}, override["apns"])
+	assert.Equal(t, map[string]interface{}{"android": map[string]interface{}{"priority": "normal"}}, override["fcm"])
+}
+
+func TestPushOverridesWithoutChannels(t *testing.T) {
+	n := newTestNotification(t)
+	n.AddRecipient("a")
+	n.SetFcmOverride(FcmOverride{ChannelId: "orders"})
+	override := encodedPayload(t, n)["override"].(map[string]interface{})
+	assert.Equal(t, map[string]interface{}{
+		"fcm": map[string]interface{}{"android": map[string]interface{}{"notification": map[string]interface{}{"channel_id": "orders"}}},
+	}, override)
+}
+
+func TestPushOverridesWithExpiry(t *testing.T) {
+	c, _ := ttlClient()
+	n := newMessage(c)
+	n.Override.AddChannel(ChannelMobilePush)
+	n.SetTTL(time.Minute)
+	n.SetApnsOverride(ApnsOverride{CollapseId: "order-42"})
+	n.SetFcmOverride(FcmOverride{Priority: FcmPriorityHigh})
+
+	override := sentPayload(t, n)["override"].(map[string]interface{})
+	assert.Equal(t, map[string]interface{}{
+		"headers": map[string]interface{}{"apns-collapse-id": "order-42", "apns-expiration": "1640995260"},
+	}, override["apns"])
+	assert.Equal(t, map[string]interface{}{
+		"android": map[string]interface{}{"priority": "high", "ttl": "60s"},
+	}, override["fcm"])
+}
+
+func TestPushOverrideValidation(t *testing.T) {
+	n := newTestNotification(t)
+	for _, tc := range []struct {
+		err    error
+		field  string
+		reason string
+	}{
+		{second(n.SetApnsOverride(ApnsOverride{})), "apns", "empty apns override"},
+		{second(n.SetApnsOverride(ApnsOverride{Badge: badge(-1)})), "apns", "negative apns badge -1"},
+		{second(n.SetApnsOverride(ApnsOverride{CollapseId: " "})), "apns", "empty apns collapse id string"},
+		{second(n.SetApnsOverride(ApnsOverride{CollapseId: strings.Repeat("a", 65)})), "apns", "apns collapse id is over 64 bytes"},
+		{second(n.SetFcmOverride(FcmOverride{})), "fcm", "empty fcm override"},
+		{second(n.SetFcmOverride(FcmOverride{Priority: "urgent"})), "fcm", `invalid fcm priority "urgent"`},
+		{second(n.SetFcmOverride(FcmOverride{ChannelId: " "})), "fcm", "empty fcm channel id string"},
+	} {
+		var v *ValidationError
+		if assert.True(t, errors.As(tc.err, &v), tc.reason) {
+			assert.Equal(t, tc.field, v.Field)
+			assert.Equal(t, tc.reason, v.Reason)
+		}
+	}
+	assert.Nil(t, n.Override.apns)
+	assert.Nil(t, n.Override.fcm)
+}
+
+func TestApnsBadgeCopied(t *testing.T) {
+	n := newTestNotification(t)
+	b := 3
+	n.SetApnsOverride(ApnsOverride{Badge: &b})
+	b = 7
+	assert.Equal(t, 3, *n.Override.apns.Badge)
+}
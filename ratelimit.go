@@ -0,0 +1,192 @@
+package engagespot
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// headers the API reports its rate limit with. reset is the number of seconds until the window
+// resets, or a unix timestamp for large values
+const (
+	headerRateLimitRemaining = "X-RateLimit-Remaining"
+	headerRateLimitReset     = "X-RateLimit-Reset"
+)
+
+// the adaptive rate never goes below this fraction of the configured ceiling, so a client which
+// exhausted its quota still probes the API once the window resets
+const minRateFraction = 0.01
+
+// how much the rate is relaxed by on each response with enough headroom
+const relaxFactor = 1.5
+
+// RateLimitAdjustment is emitted every time the rate of the client is changed to follow the quota
+// reported by the API, see WithRateLimitHandler
+type RateLimitAdjustment struct {
+	Previous  float64
+	Current   float64
+	Remaining int
+	Reset     time.Duration
+}
+
+// token bucket shared by every call of a client. the rate adapts to the rate limit headers of
+// responses, between a floor and the configured ceiling
+type rateLimiter struct {
+	mu      sync.Mutex
+	ceiling float64
+	rate    float64
+	burst   float64
+	tokens  float64
+	last    time.Time
+}
+
+// WithRateLimit limits requests of the client to rps per second, with bursts of up to burst
+// requests. rps is a ceiling: the rate is lowered when the rate limit headers of the API tell
+// requests are made faster than the remaining quota allows, and raised back when there is enough
+// headroom. see EffectiveRateLimit. rps must be a positive number. the limit applies to every
+// request of the client, whatever the endpoint, and can be changed with ApplyDynamicConfig. a
+// request waiting for its turn returns early once its context is done
+func WithRateLimit(rps float64, burst int) Option {
+	return func(c *Client) {
+		c.declare(optRateLimit)
+		if rps <= 0 || math.IsNaN(rps) || math.IsInf(rps, 0) {
+			c.rejectOption(optRateLimit, fmt.Errorf("rps must be a positive number, got %v", rps))
+			return
+		}
+		c.initialSettings().limiter = newRateLimiter(rps, burst)
+	}
+}
+
+func newRateLimiter(rps float64, burst int) *rateLimiter {
+	if burst < 1 {
+		burst = 1
+	}
+	return &rateLimiter{ceiling: rps, rate: rps, burst: float64(burst), tokens: float64(burst)}
+}
+
+// limiter replacing l with a new ceiling and burst, see ApplyDynamicConfig. the tokens of l carry
+// over, capped by the new burst, so a change of limit doesn't let an extra burst through. a rate
+// which was tightened to follow the quota stays tightened. nil if rps is 0
+func (l *rateLimiter) resized(rps float64, burst int) *rateLimiter {
+	if rps == 0 {
+		return nil
+	}
+	next := newRateLimiter(rps, burst)
+	if l == nil {
+		return next
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	next.last = l.last
+	next.tokens = math.Min(l.tokens, next.burst)
+	if l.rate < l.ceiling {
+		next.rate = math.Min(l.rate, rps)
+	}
+	return next
+}
+
+// WithRateLimitHandler can be used to be told about every adjustment of the rate set with
+// WithRateLimit
+func WithRateLimitHandler(handler func(RateLimitAdjustment)) Option {
+	return func(c *Client) {
+		c.declare(optRateLimitHandler)
+		c.rateLimitHandler = handler
+	}
+}
+
+// EffectiveRateLimit returns the current rate of the client, in requests per second. 0 means the
+// client isn't rate limited
+func (c *Client) EffectiveRateLimit() float64 {
+	l := c.settings().limiter
+	if l == nil {
+		return 0
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.rate
+}
+
+// take a token, returning how long to wait before using it
+func (l *rateLimiter) reserve(now time.Time) time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if !l.last.IsZero() {
+		l.tokens += now.Sub(l.last).Seconds() * l.rate
+		if l.tokens > l.burst {
+			l.tokens = l.burst
+		}
+	}
+	l.last = now
+
+	l.tokens--
+	if l.tokens >= 0 {
+		return 0
+	}
+	return time.Duration(-l.tokens / l.rate * float64(time.Second))
+}
+
+// parse the rate limit headers of a response. ok is false if they're missing or invalid
+func parseRateLimit(h http.Header, now time.Time) (remaining int, reset time.Duration, ok bool) {
+	remaining, err := strconv.Atoi(h.Get(headerRateLimitRemaining))
+	if err != nil || remaining < 0 {
+		return 0, 0, false
+	}
+	seconds, err := strconv.ParseInt(h.Get(headerRateLimitReset), 10, 64)
+	if err != nil || seconds < 0 {
+		return 0, 0, false
+	}
+	// any value past 2001 is a timestamp rather than a number of seconds
+	if seconds > 1e9 {
+		return remaining, time.Unix(seconds, 0).Sub(now), true
+	}
+	return remaining, time.Duration(seconds) * time.Second, true
+}
+
+// adapt the rate to the quota left. the rate is tightened as soon as the remaining quota can't
+// sustain it, and only relaxed, gradually, once the quota can sustain twice the rate. the band in
+// between keeps the rate steady when the quota hovers around it
+func (l *rateLimiter) observe(remaining int, reset time.Duration) (RateLimitAdjustment, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	sustainable := l.ceiling
+	if reset > 0 {
+		sustainable = float64(remaining) / reset.Seconds()
+	}
+
+	previous := l.rate
+	switch {
+	case sustainable < l.rate:
+		l.rate = sustainable
+	case sustainable >= 2*l.rate && l.rate < l.ceiling:
+		l.rate *= relaxFactor
+	}
+	if floor := l.ceiling * minRateFraction; l.rate < floor {
+		l.rate = floor
+	}
+	if l.rate > l.ceiling {
+		l.rate = l.ceiling
+	}
+
+	if l.rate == previous {
+		return RateLimitAdjustment{}, false
+	}
+	return RateLimitAdjustment{Previous: previous, Current: l.rate, Remaining: remaining, Reset: reset}, true
+}
+
+// feed the rate limit headers of res back into the limiter l
+func (c *Client) observeRateLimit(l *rateLimiter, res *http.Response) {
+	remaining, reset, ok := parseRateLimit(res.Header, c.now())
+	if !ok {
+		return
+	}
+	adjustment, changed := l.observe(remaining, reset)
+	if changed && c.rateLimitHandler != nil {
+		c.rateLimitHandler(adjustment)
+	}
+}
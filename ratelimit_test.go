@@ -0,0 +1,193 @@
+package engagespot
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type quota struct {
+	remaining int
+	reset     int
+}
+
+// client rate limited to ceiling, answered by a fake server reporting the scripted quotas in
+// order. waits of the limiter advance the fake clock
+func scriptedQuotaClient(ceiling float64, script []quota, adjustments *[]RateLimitAdjustment) (*Client, *[]time.Duration) {
+	clock := newFakeClock()
+	var waits []time.Duration
+	c := NewEngagespotClient("A", "B", WithRateLimit(ceiling, 1), WithRateLimitHandler(func(a RateLimitAdjustment) {
+		*adjustments = append(*adjustments, a)
+	}))
+	c.now = clock.Now
+	c.sleep = func(ctx context.Context, d time.Duration) error {
+		waits = append(waits, d)
+		clock.Advance(d)
+		return nil
+	}
+
+	i := 0
+	c.httpClient.Transport = roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		res, _ := respondWith(200, `{}`).RoundTrip(req)
+		res.Header = http.Header{}
+		if i < len(script) {
+			res.Header.Set("X-RateLimit-Remaining", strconv.Itoa(script[i].remaining))
+			res.Header.Set("X-RateLimit-Reset", strconv.Itoa(script[i].reset))
+		}
+		i++
+		return res, nil
+	})
+	return c, &waits
+}
+
+func repeatQuota(q quota, n int) []quota {
+	script := make([]quota, n)
+	for i := range script {
+		script[i] = q
+	}
+	return script
+}
+
+func TestWithRateLimitInvalid(t *testing.T) {
+	for _, rps := range []float64{0, -1, math.NaN(), math.Inf(1)} {
+		_, err := NewClient("A", "B", WithRateLimit(rps, 1))
+		assert.EqualError(t, err, fmt.Sprintf("engagespot: conflicting options: WithRateLimit: rps must be a positive number, got %v", rps))
+
+		// logged by NewEngagespotClient, which isn't rate limited then
+		assert.Zero(t, NewEngagespotClient("A", "B", WithRateLimit(rps, 1)).EffectiveRateLimit())
+	}
+}
+
+func TestRateLimitTightens(t *testing.T) {
+	var adjustments []RateLimitAdjustment
+	c, _ := scriptedQuotaClient(100, repeatQuota(quota{50, 10}, 20), &adjustments)
+	assert.Equal(t, 100.0, c.EffectiveRateLimit())
+
+	for i := 0; i < 20; i++ {
+		_, err := newMessage(c).Send()
+		assert.NoError(t, err)
+	}
+
+	assert.Equal(t, 5.0, c.EffectiveRateLimit())
+	assert.Equal(t, []RateLimitAdjustment{{Previous: 100, Current: 5, Remaining: 50, Reset: 10 * time.Second}}, adjustments)
+}
+
+func TestRateLimitRelaxesToCeiling(t *testing.T) {
+	var adjustments []RateLimitAdjustment
+	script := append(repeatQuota(quota{50, 10}, 1), repeatQuota(quota{5000, 10}, 20)...)
+	c, _ := scriptedQuotaClient(100, script, &adjustments)
+
+	for range script {
+		newMessage(c).Send()
+	}
+
+	assert.Equal(t, 100.0, c.EffectiveRateLimit())
+	for i := 1; i < len(adjustments); i++ {
+		assert.Greater(t, adjustments[i].Current, adjustments[i].Previous)
+		assert.LessOrEqual(t, adjustments[i].Current, 100.0)
+	}
+	// relaxed gradually, not in a single jump
+	assert.Greater(t, len(adjustments), 3)
+}
+
+func TestRateLimitSteadyAroundQuota(t *testing.T) {
+	var adjustments []RateLimitAdjustment
+	var script []quota
+	for i := 0; i < 50; i++ {
+		script = append(script, quota{48 + 4*(i%2), 10})
+	}
+	c, _ := scriptedQuotaClient(100, script, &adjustments)
+
+	for range script {
+		newMessage(c).Send()
+		rate := c.EffectiveRateLimit()
+		assert.InDelta(t, 5, rate, 0.25)
+	}
+	assert.LessOrEqual(t, len(adjustments), 2)
+}
+
+func TestRateLimitFloor(t *testing.T) {
+	var adjustments []RateLimitAdjustment
+	c, _ := scriptedQuotaClient(100, repeatQuota(quota{0, 60}, 3), &adjustments)
+	for i := 0; i < 3; i++ {
+		newMessage(c).Send()
+	}
+	assert.Equal(t, 1.0, c.EffectiveRateLimit())
+}
+
+func TestRateLimitPaces(t *testing.T) {
+	var adjustments []RateLimitAdjustment
+	c, waits := scriptedQuotaClient(10, nil, &adjustments)
+	for i := 0; i < 5; i++ {
+		newMessage(c).Send()
+	}
+
+	// the first request uses the burst, every other one waits for a token
+	assert.Len(t, *waits, 4)
+	for _, w := range *waits {
+		assert.Equal(t, 100*time.Millisecond, w)
+	}
+	assert.Empty(t, adjustments)
+}
+
+func TestRateLimitEveryEndpoint(t *testing.T) {
+	var adjustments []RateLimitAdjustment
+	c, waits := scriptedQuotaClient(10, nil, &adjustments)
+	ctx := context.Background()
+	newMessage(c).SendCtx(ctx)
+	res, _ := c.ConnectCtx(ctx, "a")
+	discard(res)
+	c.GetUser(ctx, "a")
+	c.ListCategories(ctx)
+
+	assert.Len(t, *waits, 3)
+}
+
+func TestRateLimitCancelledWait(t *testing.T) {
+	c := NewEngagespotClient("A", "B", WithRateLimit(0.001, 1))
+	c.httpClient.Transport = respondWith(200, `{}`)
+	assert.NoError(t, sendTo(c, "a"))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	started := time.Now()
+	_, err := newMessage(c, "b").SendCtx(ctx)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Less(t, time.Since(started), time.Second)
+}
+
+func TestRateLimitAgainstServer(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{}`))
+	}))
+	defer server.Close()
+	c := clientFor(server.URL+"/v3/", time.Second, WithRateLimit(50, 1))
+
+	started := time.Now()
+	for i := 0; i < 6; i++ {
+		assert.NoError(t, sendTo(c, "a"))
+	}
+	// the first send uses the burst, the other 5 wait 1/50s each
+	assert.GreaterOrEqual(t, time.Since(started), 100*time.Millisecond)
+}
+
+func TestParseRateLimitTimestamp(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	h := http.Header{}
+	h.Set("X-RateLimit-Remaining", "10")
+	h.Set("X-RateLimit-Reset", "1700000030")
+	remaining, reset, ok := parseRateLimit(h, now)
+	assert.True(t, ok)
+	assert.Equal(t, 10, remaining)
+	assert.Equal(t, 30*time.Second, reset)
+
+	_, _, ok = parseRateLimit(http.Header{}, now)
+	assert.False(t, ok)
+}
@@ -0,0 +1,160 @@
+package engagespot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"sync/atomic"
+	"time"
+)
+
+// number of errors kept by RecentErrors, unless changed with WithRecentErrors
+const defaultRecentErrors = 20
+
+// ErrorClass is the kind of failure of an ErrorRecord
+type ErrorClass string
+
+const (
+	ClassCanceled    ErrorClass = "canceled"
+	ClassTimeout     ErrorClass = "timeout"
+	ClassNetwork     ErrorClass = "network"
+	ClassRateLimited ErrorClass = "rate_limited"
+	ClassClient      ErrorClass = "client"
+	ClassServer      ErrorClass = "server"
+	ClassStatus      ErrorClass = "unexpected_status"
+)
+
+// ErrorRecord is a failed request made by the client, see RecentErrors. it never holds the payload
+// or the response body
+type ErrorRecord struct {
+	Time     time.Time
+	Op       string
+	Endpoint string
+	// 0 if the request failed before getting a response
+	StatusCode int
+	Class      ErrorClass
+	// correlation key of the call, like the key of a Dispatcher item, if any
+	CorrelationId string
+	Attempt       int
+	// the request was retried after this failure
+	Retried bool
+	Err     string
+
+	seq uint64
+}
+
+// fixed size buffer of the last errors. writers only contend on an atomic counter, so recording
+// doesn't slow down concurrent calls
+type errorRing struct {
+	slots []atomic.Pointer[ErrorRecord]
+	next  atomic.Uint64
+	// records before this sequence number were cleared
+	floor atomic.Uint64
+}
+
+func newErrorRing(size int) *errorRing {
+	if size <= 0 {
+		return nil
+	}
+	return &errorRing{slots: make([]atomic.Pointer[ErrorRecord], size)}
+}
+
+func (r *errorRing) add(record ErrorRecord) {
+	record.seq = r.next.Add(1) - 1
+	r.slots[record.seq%uint64(len(r.slots))].Store(&record)
+}
+
+// records still in the buffer, oldest first
+func (r *errorRing) snapshot() []ErrorRecord {
+	// floor is set from next, so reading it first keeps start at or below next even when the
+	// buffer is cleared in between
+	start := r.floor.Load()
+	next := r.next.Load()
+	if size := uint64(len(r.slots)); next > size && next-size > start {
+		start = next - size
+	}
+
+	records := make([]ErrorRecord, 0, next-start)
+	for seq := start; seq < next; seq++ {
+		record := r.slots[seq%uint64(len(r.slots))].Load()
+		// overwritten by a newer record since next was read, or not stored yet
+		if record == nil || record.seq != seq {
+			continue
+		}
+		records = append(records, *record)
+	}
+	return records
+}
+
+// WithRecentErrors changes how many of the last failed requests are kept for RecentErrors, 20 by
+// default. 0 disables recording
+func WithRecentErrors(size int) Option {
+	return func(c *Client) {
+		c.recentErrors = newErrorRing(size)
+	}
+}
+
+// RecentErrors returns the last failed requests made by the client, oldest first, like for a
+// health endpoint. every failed attempt is recorded, including those which were retried
+func (c *Client) RecentErrors() []ErrorRecord {
+	if c.recentErrors == nil {
+		return nil
+	}
+	return c.recentErrors.snapshot()
+}
+
+// ClearRecentErrors empties the buffer of RecentErrors
+func (c *Client) ClearRecentErrors() {
+	if c.recentErrors != nil {
+		c.recentErrors.floor.Store(c.recentErrors.next.Load())
+	}
+}
+
+// record a failed attempt of a call to e, if it failed
+func (c *Client) recordError(ctx context.Context, e endpoint, attempt int, res *http.Response, err error, retried bool) {
+	if c.recentErrors == nil || (err == nil && res.StatusCode >= 200 && res.StatusCode <= 299) {
+		return
+	}
+	record := ErrorRecord{
+		Time:          c.now(),
+		Op:            e.op,
+		Endpoint:      e.path,
+		CorrelationId: correlationId(ctx),
+		Attempt:       attempt,
+		Retried:       retried,
+	}
+	if err != nil {
+		record.Class = errorClass(err)
+		record.Err = err.Error()
+	} else {
+		record.StatusCode = res.StatusCode
+		record.Class = statusClass(res.StatusCode)
+		record.Err = fmt.Sprintf("unexpected status %d", res.StatusCode)
+	}
+	c.recentErrors.add(record)
+}
+
+func errorClass(err error) ErrorClass {
+	var netErr net.Error
+	switch {
+	case errors.Is(err, context.Canceled):
+		return ClassCanceled
+	case errors.Is(err, context.DeadlineExceeded), errors.As(err, &netErr) && netErr.Timeout():
+		return ClassTimeout
+	}
+	return ClassNetwork
+}
+
+func statusClass(status int) ErrorClass {
+	switch {
+	case status == http.StatusTooManyRequests:
+		return ClassRateLimited
+	case status >= 500:
+		return ClassServer
+	case status >= 400:
+		return ClassClient
+	}
+	return ClassStatus
+}
@@ -0,0 +1,159 @@
+package engagespot
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// transport answering with the given statuses in turn, 0 failing the request
+func scripted(statuses ...int) http.RoundTripper {
+	var mu sync.Mutex
+	return roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		mu.Lock()
+		status := statuses[0]
+		if len(statuses) > 1 {
+			statuses = statuses[1:]
+		}
+		mu.Unlock()
+		if status == 0 {
+			return nil, errors.New("connection reset by peer")
+		}
+		return respondWith(status, `{"secret":"payload"}`).RoundTrip(req)
+	})
+}
+
+func TestRecentErrors(t *testing.T) {
+	var waits []time.Duration
+	clock := newFakeClock()
+	c := retryingClient(clock, &waits)
+	c.httpClient.Transport = scripted(503, 429, 200, 404, 0, 0, 0, 0)
+	start := clock.Now()
+
+	_, err := newMessage(c).Send()
+	assert.NoError(t, err)
+	newMessage(c).Send()
+	_, err = newMessage(c).SendCtx(withCorrelationId(context.Background(), "item-1"))
+	assert.Error(t, err)
+
+	records := c.RecentErrors()
+	assert.Len(t, records, 7)
+	for _, r := range records {
+		assert.Equal(t, "Send", r.Op)
+		assert.Equal(t, "notifications", r.Endpoint)
+		assert.NotContains(t, r.Err, "payload")
+	}
+	assert.Equal(t, ErrorRecord{Time: start, Op: "Send", Endpoint: "notifications", StatusCode: 503, Class: ClassServer, Attempt: 1, Retried: true, Err: "unexpected status 503"}, records[0])
+	assert.Equal(t, []ErrorClass{ClassServer, ClassRateLimited, ClassClient, ClassNetwork, ClassNetwork, ClassNetwork, ClassNetwork}, classes(records))
+	assert.Equal(t, []bool{true, true, false, true, true, true, false}, retried(records))
+	assert.Equal(t, 2, records[1].Attempt)
+	assert.Equal(t, "item-1", records[6].CorrelationId)
+	assert.Equal(t, 4, records[6].Attempt)
+	assert.Contains(t, records[6].Err, "connection reset by peer")
+}
+
+func classes(records []ErrorRecord) []ErrorClass {
+	var c []ErrorClass
+	for _, r := range records {
+		c = append(c, r.Class)
+	}
+	return c
+}
+
+func retried(records []ErrorRecord) []bool {
+	var b []bool
+	for _, r := range records {
+		b = append(b, r.Retried)
+	}
+	return b
+}
+
+func TestRecentErrorsBound(t *testing.T) {
+	c := NewEngagespotClient("A", "B", WithRecentErrors(3))
+	c.httpClient.Transport = scripted(500, 501, 502, 503, 504)
+	for i := 0; i < 5; i++ {
+		newMessage(c).Send()
+	}
+
+	records := c.RecentErrors()
+	assert.Len(t, records, 3)
+	assert.Equal(t, []int{502, 503, 504}, []int{records[0].StatusCode, records[1].StatusCode, records[2].StatusCode})
+
+	c.ClearRecentErrors()
+	assert.Empty(t, c.RecentErrors())
+	newMessage(c).Send()
+	records = c.RecentErrors()
+	assert.Len(t, records, 1)
+	assert.Equal(t, 504, records[0].StatusCode)
+}
+
+func TestRecentErrorsTimeout(t *testing.T) {
+	c := NewEngagespotClient("A", "B")
+	c.httpClient.Transport = roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		<-req.Context().Done()
+		return nil, req.Context().Err()
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	newMessage(c).SendCtx(ctx)
+
+	records := c.RecentErrors()
+	assert.Len(t, records, 1)
+	assert.Equal(t, ClassTimeout, records[0].Class)
+}
+
+func TestRecentErrorsDisabled(t *testing.T) {
+	c := NewEngagespotClient("A", "B", WithRecentErrors(0))
+	c.httpClient.Transport = scripted(500)
+	newMessage(c).Send()
+	assert.Nil(t, c.RecentErrors())
+	c.ClearRecentErrors()
+}
+
+func TestRecentErrorsConcurrent(t *testing.T) {
+	c := NewEngagespotClient("A", "B", WithRecentErrors(8))
+	c.httpClient.Transport = scripted(500)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 20; j++ {
+				newMessage(c).Send()
+				c.RecentErrors()
+			}
+		}()
+	}
+	wg.Wait()
+	assert.Len(t, c.RecentErrors(), 8)
+}
+
+func TestRecentErrorsClearedWhileRead(t *testing.T) {
+	c := NewEngagespotClient("A", "B", WithRecentErrors(4))
+	r := c.recentErrors
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 10000; i++ {
+			r.add(ErrorRecord{})
+			c.ClearRecentErrors()
+		}
+	}()
+	for {
+		select {
+		case <-done:
+			assert.Empty(t, c.RecentErrors())
+			return
+		default:
+			assert.LessOrEqual(t, len(c.RecentErrors()), 4)
+		}
+	}
+}
@@ -0,0 +1,234 @@
+package engagespot
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/mail"
+	"strings"
+
+	"golang.org/x/text/language"
+)
+
+// MaxRecipients is the number of recipients the API accepts in a single request. Send fails with
+// ErrRecipientLimit over it, SendBulk and recipient sources split larger sends into chunks
+const MaxRecipients = 1000
+
+var (
+	// ErrRecipientLimit is returned by Send for a notification with more than MaxRecipients
+	ErrRecipientLimit = errors.New("over the recipient limit of a single request")
+	// ErrDuplicateRecipient is returned by AddRecipients and SetRecipients for a recipient which
+	// is already in the list
+	ErrDuplicateRecipient = errors.New("duplicate recipient")
+)
+
+// RecipientError is returned by AddRecipients and SetRecipients for the first invalid recipient,
+// at Index of the given list. nothing is added when it is returned
+type RecipientError struct {
+	Index     int
+	Recipient string
+	Err       error
+}
+
+func (e *RecipientError) Error() string {
+	return fmt.Sprintf("recipient %d (%q): %s", e.Index, e.Recipient, e.Err)
+}
+
+func (e *RecipientError) Unwrap() error {
+	return e.Err
+}
+
+// AddRecipients can be used to add several recipients at once. every recipient is trimmed and
+// checked like with AddRecipient, and rejected if it is already in the list. either every
+// recipient is added or, on a *RecipientError, none is
+func (n *Notification) AddRecipients(recipients ...string) (*Notification, error) {
+	if n.recipientSource != nil {
+		return nil, invalidField("recipients", recipients, errMixedRecipients)
+	}
+	cleaned, err := n.cleanRecipients(n.Recipients, recipients)
+	if err != nil {
+		return nil, invalidField("recipients", recipients, err)
+	}
+	n.Recipients = append(n.Recipients, cleaned...)
+	return n, nil
+}
+
+// SetRecipients can be used to replace the recipient list, checked like with AddRecipients.
+// details of recipients added with AddRecipientDetailed are dropped along with the old list
+func (n *Notification) SetRecipients(recipients []string) (*Notification, error) {
+	if n.recipientSource != nil {
+		return nil, invalidField("recipients", recipients, errMixedRecipients)
+	}
+	sanitization := n.sanitization
+	n.sanitization = nil
+	cleaned, err := n.cleanRecipients(nil, recipients)
+	if err != nil {
+		n.sanitization = sanitization
+		return nil, invalidField("recipients", recipients, err)
+	}
+	n.Recipients = cleaned
+	n.recipientDetails = nil
+	return n, nil
+}
+
+// cleaned recipients, rejecting the ones already in existing. corrections recorded by sanitization
+// are rolled back on error
+func (n *Notification) cleanRecipients(existing, recipients []string) ([]string, error) {
+	seen := make(map[string]bool, len(existing)+len(recipients))
+	for _, r := range existing {
+		seen[r] = true
+	}
+	corrected := len(n.sanitization)
+	cleaned := make([]string, 0, len(recipients))
+	for i, r := range recipients {
+		recipient, err := n.cleanRecipient(strings.TrimSpace(r))
+		if err == nil && seen[recipient] {
+			err = ErrDuplicateRecipient
+		}
+		if err != nil {
+			n.sanitization = n.sanitization[:corrected]
+			return nil, &RecipientError{Index: i, Recipient: r, Err: err}
+		}
+		seen[recipient] = true
+		cleaned = append(cleaned, recipient)
+	}
+	return cleaned, nil
+}
+
+// Recipient is a recipient along with the details used to reach them when they have no profile on
+// Engagespot yet, like a display name and locale for email templates, or their template data. see
+// AddRecipientDetailed and AddRecipientWithData
+type Recipient struct {
+	// user id, or email address of a recipient without an account
+	Identifier string `json:"identifier"`
+	Email      string `json:"email,omitempty"`
+	Name       string `json:"name,omitempty"`
+	// BCP 47 language tag, like "en-US"
+	Locale string `json:"locale,omitempty"`
+	// data of the recipient for the personalization of templates, see AddRecipientWithData
+	Data map[string]interface{} `json:"data,omitempty"`
+}
+
+// AddRecipientWithData can be used to add a recipient along with data used to personalize the
+// templates for them, sent as a recipient object. unlike AddRecipientDetailed, any identifier is
+// accepted. recipients added with AddRecipient stay plain strings
+func (n *Notification) AddRecipientWithData(recipient string, data map[string]interface{}) (*Notification, error) {
+	if n.recipientSource != nil {
+		return nil, invalidField("recipients", recipient, errMixedRecipients)
+	}
+	if len(data) == 0 {
+		return nil, invalidField("recipients", data, errors.New("empty recipient data"))
+	}
+	copied := make(map[string]interface{}, len(data))
+	for key, value := range data {
+		if key == "" {
+			return nil, invalidField("recipients", key, errors.New("empty data key string"))
+		}
+		copied[key] = value
+	}
+	identifier, err := n.cleanRecipient(recipient)
+	if err != nil {
+		return nil, invalidField("recipients", recipient, err)
+	}
+
+	if n.recipientDetails == nil {
+		n.recipientDetails = map[string]Recipient{}
+	}
+	n.recipientDetails[identifier] = Recipient{Identifier: identifier, Data: copied}
+	n.Recipients = append(n.Recipients, identifier)
+	return n, nil
+}
+
+// AddRecipientDetailed can be used to add a recipient with profile details, sent as a recipient
+// object instead of a plain identifier. recipients added with AddRecipient stay plain strings.
+//
+// the API only uses the details when Identifier isn't a known user: the stored profile of a known
+// user always takes precedence, and isn't updated by them, see CreateOrUpdateUser for that. since
+// the details are only ever used to reach an ad-hoc recipient by email, either Identifier or Email
+// must be an email address
+func (n *Notification) AddRecipientDetailed(r Recipient) error {
+	if n.recipientSource != nil {
+		return invalidField("recipients", r.Identifier, errMixedRecipients)
+	}
+	identifier, err := n.cleanRecipient(r.Identifier)
+	if err != nil {
+		return invalidField("recipients", r.Identifier, err)
+	}
+	r.Identifier = identifier
+
+	if r.Email != "" {
+		if !isEmailAddress(r.Email) {
+			return invalidField("recipients", r.Email, fmt.Errorf("invalid recipient email %q", r.Email))
+		}
+	} else if !isEmailAddress(identifier) {
+		return invalidField("recipients", identifier, fmt.Errorf("recipient %q is not an email address, details need an Email to reach them", identifier))
+	}
+	if r.Locale != "" {
+		tag, err := language.Parse(r.Locale)
+		if err != nil {
+			return invalidField("recipients", r.Locale, fmt.Errorf("invalid recipient locale %q: %w", r.Locale, err))
+		}
+		r.Locale = tag.String()
+	}
+
+	if n.recipientDetails == nil {
+		n.recipientDetails = map[string]Recipient{}
+	}
+	n.recipientDetails[identifier] = r
+	n.Recipients = append(n.Recipients, identifier)
+	return nil
+}
+
+// bare email address, without display name
+func isEmailAddress(s string) bool {
+	addr, err := mail.ParseAddress(s)
+	return err == nil && addr.Address == s
+}
+
+// recipient r of n, with its details if any
+func (n *Notification) recipient(r string) Recipient {
+	if details, ok := n.recipientDetails[r]; ok {
+		return details
+	}
+	return Recipient{Identifier: r}
+}
+
+// recipient of the payload of a notification, an object if it has details or data and a plain
+// identifier otherwise
+type payloadRecipient struct {
+	Recipient
+	detailed bool
+}
+
+func (r payloadRecipient) MarshalJSON() ([]byte, error) {
+	if r.detailed {
+		return json.Marshal(r.Recipient)
+	}
+	return json.Marshal(r.Identifier)
+}
+
+// payload of the notification. recipients with details are sent as objects, plain ones as strings.
+// an override without anything set is left out, so the dashboard configuration applies
+func (n *Notification) MarshalJSON() ([]byte, error) {
+	type plain Notification
+	payload := struct {
+		*plain
+		Recipients []payloadRecipient `json:"recipients,omitempty"`
+		Override   *Override          `json:"override,omitempty"`
+	}{plain: (*plain)(n)}
+
+	if n.Recipients != nil {
+		payload.Recipients = make([]payloadRecipient, len(n.Recipients))
+		for i, r := range n.Recipients {
+			details, ok := n.recipientDetails[r]
+			if !ok {
+				details = Recipient{Identifier: r}
+			}
+			payload.Recipients[i] = payloadRecipient{Recipient: details, detailed: ok}
+		}
+	}
+	if !n.Override.empty() {
+		payload.Override = n.Override
+	}
+	return json.Marshal(payload)
+}
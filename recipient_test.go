@@ -0,0 +1,207 @@
+package engagespot
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func encodedRecipients(t *testing.T, n *Notification) []interface{} {
+	b, err := json.Marshal(n)
+	assert.NoError(t, err)
+	var payload struct {
+		Recipients []interface{} `json:"recipients"`
+	}
+	assert.NoError(t, json.Unmarshal(b, &payload))
+	return payload.Recipients
+}
+
+func TestAddRecipientDetailed(t *testing.T) {
+	n := newTestNotification(t)
+	assert.NoError(t, n.AddRecipientDetailed(Recipient{Identifier: "ada@example.com", Name: "Ada Lovelace", Locale: "en-gb"}))
+	assert.NoError(t, n.AddRecipientDetailed(Recipient{Identifier: "user-42", Email: "grace@example.com", Name: "Grace"}))
+
+	assert.Equal(t, []interface{}{
+		map[string]interface{}{"identifier": "ada@example.com", "name": "Ada Lovelace", "locale": "en-GB"},
+		map[string]interface{}{"identifier": "user-42", "email": "grace@example.com", "name": "Grace"},
+	}, encodedRecipients(t, n))
+	assert.Equal(t, []string{"ada@example.com", "user-42"}, n.Recipients)
+}
+
+func TestAddRecipientDetailedMixed(t *testing.T) {
+	n := newTestNotification(t)
+	n.AddRecipient("user-1")
+	assert.NoError(t, n.AddRecipientDetailed(Recipient{Identifier: "ada@example.com", Locale: "fr"}))
+	n.AddRecipient("user-2")
+
+	assert.Equal(t, []interface{}{
+		"user-1",
+		map[string]interface{}{"identifier": "ada@example.com", "locale": "fr"},
+		"user-2",
+	}, encodedRecipients(t, n))
+}
+
+func TestPlainRecipientsUnchanged(t *testing.T) {
+	n := newTestNotification(t)
+	n.AddRecipient("user-1")
+	b, err := json.Marshal(n)
+	assert.NoError(t, err)
+	assert.Contains(t, string(b), `"recipients":["user-1"]`)
+}
+
+func TestAddRecipientWithData(t *testing.T) {
+	n := newTestNotification(t)
+	n.AddRecipient("user-1")
+	data := map[string]interface{}{"firstName": "Ada", "plan": map[string]interface{}{"name": "pro"}}
+	_, err := n.AddRecipientWithData("user-2", data)
+	assert.NoError(t, err)
+	assert.NoError(t, n.AddRecipientDetailed(Recipient{Identifier: "grace@example.com", Name: "Grace"}))
+	n.AddRecipient("user-3")
+	data["firstName"] = "changed"
+
+	assert.Equal(t, []interface{}{
+		"user-1",
+		map[string]interface{}{"identifier": "user-2", "data": map[string]interface{}{"firstName": "Ada", "plan": map[string]interface{}{"name": "pro"}}},
+		map[string]interface{}{"identifier": "grace@example.com", "name": "Grace"},
+		"user-3",
+	}, encodedRecipients(t, n))
+	assert.Equal(t, []string{"user-1", "user-2", "grace@example.com", "user-3"}, n.Recipients)
+}
+
+func TestAddRecipientWithDataInvalid(t *testing.T) {
+	n := newTestNotification(t)
+	_, err := n.AddRecipientWithData("user-1", nil)
+	assert.EqualError(t, err, "empty recipient data")
+	_, err = n.AddRecipientWithData("user-1", map[string]interface{}{"": 1})
+	assert.EqualError(t, err, "empty data key string")
+	_, err = n.AddRecipientWithData("", map[string]interface{}{"a": 1})
+	assert.EqualError(t, err, "empty recipient string")
+	assert.Empty(t, n.Recipients)
+}
+
+func TestAddRecipientDetailedInvalid(t *testing.T) {
+	n := newTestNotification(t)
+	err := n.AddRecipientDetailed(Recipient{Identifier: "ada@example.com", Locale: "english please"})
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), `invalid recipient locale "english please"`)
+
+	assert.EqualError(t, n.AddRecipientDetailed(Recipient{Identifier: "user-42", Email: "Grace <grace@example.com>"}), `invalid recipient email "Grace <grace@example.com>"`)
+	assert.EqualError(t, n.AddRecipientDetailed(Recipient{Identifier: "user-42", Name: "Grace"}), `recipient "user-42" is not an email address, details need an Email to reach them`)
+	assert.EqualError(t, n.AddRecipientDetailed(Recipient{}), "empty recipient string")
+	assert.Empty(t, n.Recipients)
+
+	n.SetRecipientSource(sliceSource(nil, -1, nil))
+	assert.ErrorIs(t, n.AddRecipientDetailed(Recipient{Identifier: "ada@example.com"}), errMixedRecipients)
+}
+
+func TestRecipientDetailsWorkflow(t *testing.T) {
+	var requests []capturedRequest
+	c := NewEngagespotClient("A", "B", WithWorkflowMapping(map[string]string{"orders": "order-shipped"}))
+	c.httpClient.Transport = captureRequests(&requests)
+
+	n, _ := c.NewNotification("Order shipped")
+	n.SetCategory("orders")
+	n.AddRecipient("user-1")
+	n.AddRecipientDetailed(Recipient{Identifier: "ada@example.com", Name: "Ada"})
+	_, err := n.Send()
+	assert.NoError(t, err)
+
+	var trigger map[string]interface{}
+	assert.NoError(t, json.Unmarshal(requests[0].body, &trigger))
+	assert.Equal(t, map[string]interface{}{"recipients": []interface{}{
+		map[string]interface{}{"identifier": "user-1"},
+		map[string]interface{}{"identifier": "ada@example.com", "name": "Ada"},
+	}}, trigger["sendTo"])
+}
+
+func TestAddRecipients(t *testing.T) {
+	n := newTestNotification(t)
+	n.AddRecipient("a")
+	_, err := n.AddRecipients(" b ", "c\t", "d")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"a", "b", "c", "d"}, n.Recipients)
+}
+
+func TestAddRecipientsInvalid(t *testing.T) {
+	for name, tc := range map[string]struct {
+		recipients []string
+		index      int
+		err        string
+	}{
+		"empty":              {[]string{"b", "  "}, 1, "empty recipient string"},
+		"already in list":    {[]string{"b", "a"}, 1, "duplicate recipient"},
+		"duplicate in batch": {[]string{"b", "c", " b"}, 2, "duplicate recipient"},
+	} {
+		n := newTestNotification(t)
+		n.AddRecipient("a")
+		_, err := n.AddRecipients(tc.recipients...)
+
+		var recipientErr *RecipientError
+		assert.True(t, errors.As(err, &recipientErr), name)
+		assert.Equal(t, tc.index, recipientErr.Index, name)
+		assert.Equal(t, tc.recipients[tc.index], recipientErr.Recipient, name)
+		assert.EqualError(t, recipientErr.Err, tc.err, name)
+		assert.Equal(t, []string{"a"}, n.Recipients, name)
+	}
+
+	n := newTestNotification(t)
+	_, err := n.AddRecipients("a", "a")
+	assert.True(t, errors.Is(err, ErrDuplicateRecipient))
+	assert.EqualError(t, err, `recipient 1 ("a"): duplicate recipient`)
+}
+
+func TestAddRecipientsNormalized(t *testing.T) {
+	c := NewEngagespotClient("A", "B", WithRecipientSanitization())
+	n, _ := c.NewNotification("Hello")
+	_, err := n.AddRecipients("a@Example.com", "a@example.com")
+	assert.True(t, errors.Is(err, ErrDuplicateRecipient))
+	// the correction of the first recipient is rolled back with it
+	assert.Empty(t, n.sanitizationWarnings())
+
+	c = NewEngagespotClient("A", "B", WithUserIDNormalizer(strings.ToLower))
+	n, _ = c.NewNotification("Hello")
+	_, err = n.AddRecipients("User-1", "user-1")
+	assert.True(t, errors.Is(err, ErrDuplicateRecipient))
+}
+
+func TestSetRecipients(t *testing.T) {
+	n := newTestNotification(t)
+	n.AddRecipient("a")
+	assert.NoError(t, n.AddRecipientDetailed(Recipient{Identifier: "b@example.com", Name: "B"}))
+
+	_, err := n.SetRecipients([]string{"c", "b@example.com"})
+	assert.NoError(t, err)
+	assert.Equal(t, []interface{}{"c", "b@example.com"}, encodedRecipients(t, n))
+
+	_, err = n.SetRecipients([]string{"d", ""})
+	assert.Error(t, err)
+	assert.Equal(t, []string{"c", "b@example.com"}, n.Recipients)
+}
+
+func TestRecipientLimit(t *testing.T) {
+	var requests []capturedRequest
+	c := NewEngagespotClient("A", "B")
+	c.httpClient.Transport = captureRequests(&requests)
+	n, _ := c.NewNotification("Hello")
+
+	_, err := n.SetRecipients(recipientIds(MaxRecipients))
+	assert.NoError(t, err)
+	_, err = n.Send()
+	assert.NoError(t, err)
+
+	n.AddRecipients("one-more")
+	_, err = n.Send()
+	assert.True(t, errors.Is(err, ErrRecipientLimit))
+	assert.EqualError(t, err, "over the recipient limit of a single request: 1001 recipients, at most 1000 can be sent without SendBulk")
+	assert.Len(t, requests, 1)
+
+	// bulk sends are split into chunks
+	h := n.SendBulk(context.Background())
+	<-h.Done()
+	assert.NoError(t, h.Report().Err)
+	assert.Len(t, requests, 3)
+}
@@ -0,0 +1,57 @@
+package engagespot
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+)
+
+// ErrUnsafeRedirect is returned when the API redirects to a host other than the one of the
+// configured endpoint, which isn't allowed by WithRedirectAllowlist. the redirect isn't followed
+// so that credentials are never sent to an unintended host
+var ErrUnsafeRedirect = errors.New("unsafe redirect")
+
+// same limit as the default policy of net/http
+const maxRedirects = 10
+
+// headers carrying credentials, forwarded on redirects to trusted hosts only
+var authHeaders = []string{
+	"X-ENGAGESPOT-API-KEY",
+	"X-ENGAGESPOT-API-SECRET",
+	"X-ENGAGESPOT-USER-ID",
+	"X-ENGAGESPOT-USER-SIGNATURE",
+}
+
+// WithRedirectAllowlist can be used to trust redirects to the given hosts, on top of the host of
+// the configured endpoint. hosts are matched with or without port, like "eu.api.example.com" or
+// "127.0.0.1:8080"
+func WithRedirectAllowlist(hosts ...string) Option {
+	return func(c *Client) {
+		c.redirectAllowlist = append(c.redirectAllowlist, hosts...)
+	}
+}
+
+// tells if credentials can be sent to the host of u
+func (c *Client) trustedHost(u *url.URL) bool {
+	if base, err := url.Parse(c.defaults.Endpoint); err == nil && base.Host == u.Host {
+		return true
+	}
+	return containsString(c.redirectAllowlist, u.Host) || containsString(c.redirectAllowlist, u.Hostname())
+}
+
+// redirect policy of the http client built by NewEngagespotClient. redirects to trusted hosts
+// keep the credentials of the original request, any other redirect fails
+func (c *Client) checkRedirect(req *http.Request, via []*http.Request) error {
+	if len(via) >= maxRedirects {
+		return errors.New("stopped after 10 redirects")
+	}
+	if !c.trustedHost(req.URL) {
+		return ErrUnsafeRedirect
+	}
+	for _, h := range authHeaders {
+		if v := via[0].Header.Get(h); v != "" {
+			req.Header.Set(h, v)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,81 @@
+package engagespot
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// server recording the headers of the requests it gets, redirecting to redirect if set
+func redirectServer(t *testing.T, redirect func(r *http.Request) string) (*httptest.Server, *[]http.Header) {
+	var headers []http.Header
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		headers = append(headers, r.Header.Clone())
+		if redirect != nil {
+			if to := redirect(r); to != "" {
+				http.Redirect(w, r, to, http.StatusTemporaryRedirect)
+				return
+			}
+		}
+		w.Write([]byte(`{}`))
+	}))
+	t.Cleanup(s.Close)
+	return s, &headers
+}
+
+func TestRedirectSameHost(t *testing.T) {
+	api, headers := redirectServer(t, func(r *http.Request) string {
+		if r.URL.Path == "/v3/notifications" {
+			return "/v3/moved"
+		}
+		return ""
+	})
+
+	c := clientFor(api.URL+"/v3/", time.Second)
+	res, err := newMessage(c).SendRaw()
+	assert.NoError(t, err)
+	assert.Equal(t, "/v3/moved", res.Request.URL.Path)
+
+	assert.Len(t, *headers, 2)
+	assert.Equal(t, "B", (*headers)[1].Get("X-ENGAGESPOT-API-SECRET"))
+	assert.Equal(t, "A", (*headers)[1].Get("X-ENGAGESPOT-API-KEY"))
+}
+
+func TestRedirectAllowlisted(t *testing.T) {
+	other, otherHeaders := redirectServer(t, nil)
+	api, _ := redirectServer(t, func(r *http.Request) string { return other.URL + "/v3/notifications" })
+
+	c := clientFor(api.URL+"/v3/", time.Second, WithRedirectAllowlist(strings.TrimPrefix(other.URL, "http://")))
+	_, err := newMessage(c).Send()
+	assert.NoError(t, err)
+
+	assert.Len(t, *otherHeaders, 1)
+	assert.Equal(t, "B", (*otherHeaders)[0].Get("X-ENGAGESPOT-API-SECRET"))
+}
+
+func TestRedirectDisallowed(t *testing.T) {
+	other, otherHeaders := redirectServer(t, nil)
+	api, _ := redirectServer(t, func(r *http.Request) string { return other.URL + "/v3/notifications" })
+
+	c := clientFor(api.URL+"/v3/", time.Second)
+	_, err := newMessage(c).Send()
+	assert.ErrorIs(t, err, ErrUnsafeRedirect)
+	assert.Empty(t, *otherHeaders)
+}
+
+func TestRedirectDisallowedNotRetried(t *testing.T) {
+	other, _ := redirectServer(t, nil)
+	api, headers := redirectServer(t, func(r *http.Request) string { return other.URL })
+
+	d := DefaultConfig()
+	d.Endpoint = api.URL + "/v3/"
+	d.Retry = RetryPolicy{MaxAttempts: 3, BaseDelay: time.Millisecond}
+	c := NewEngagespotClient("A", "B", WithDefaults(d))
+	_, err := newMessage(c).Send()
+	assert.ErrorIs(t, err, ErrUnsafeRedirect)
+	assert.Len(t, *headers, 1)
+}
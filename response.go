@@ -0,0 +1,267 @@
+package engagespot
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"reflect"
+	"strings"
+	"sync"
+)
+
+// Response is embedded by every typed response struct. the API adds response fields frequently,
+// so any top-level field which the struct doesn't declare is kept in RawExtra instead of being
+// silently dropped
+type Response struct {
+	RawExtra map[string]json.RawMessage `json:"-"`
+}
+
+// Extra can be used to decode an unknown top-level field of the response into `into`
+func (r *Response) Extra(key string, into interface{}) error {
+	raw, ok := r.RawExtra[key]
+	if !ok {
+		return errors.New("no extra field " + key)
+	}
+	return json.Unmarshal(raw, into)
+}
+
+func (r *Response) setExtra(extra map[string]json.RawMessage) {
+	r.RawExtra = extra
+}
+
+// implemented by every struct embedding Response
+type extraSetter interface {
+	setExtra(map[string]json.RawMessage)
+}
+
+// known json field names per struct type, computed once
+var knownFieldsCache sync.Map
+
+// decodeResponse is the shared decode helper for typed responses. v must be a pointer to a struct
+// embedding Response. known fields are decoded as usual and everything else ends up in RawExtra.
+// the elements of lists of typed responses, like the devices of ListDevices, keep their own extra
+// fields, whether v embeds Response or not
+func decodeResponse(data []byte, v interface{}) error {
+	if err := json.Unmarshal(data, v); err != nil {
+		return err
+	}
+
+	var all map[string]json.RawMessage
+	if err := json.Unmarshal(data, &all); err != nil {
+		// not an object, nothing extra to capture
+		return nil
+	}
+
+	if setter, ok := v.(extraSetter); ok {
+		known := knownFields(reflect.TypeOf(v).Elem())
+		extra := map[string]json.RawMessage{}
+		for key, raw := range all {
+			if !isKnownField(known, key) {
+				extra[key] = raw
+			}
+		}
+		if len(extra) > 0 {
+			setter.setExtra(extra)
+		}
+	}
+
+	return decodeListExtras(all, reflect.ValueOf(v).Elem())
+}
+
+var extraSetterType = reflect.TypeOf((*extraSetter)(nil)).Elem()
+
+// decode every element of the lists of typed responses of v again, from the fields all of the
+// response, to capture their extra fields
+func decodeListExtras(all map[string]json.RawMessage, v reflect.Value) error {
+	if v.Kind() != reflect.Struct {
+		return nil
+	}
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if f.PkgPath != "" || f.Type.Kind() != reflect.Slice || !reflect.PointerTo(f.Type.Elem()).Implements(extraSetterType) {
+			continue
+		}
+		name := strings.Split(f.Tag.Get("json"), ",")[0]
+		if name == "" {
+			name = f.Name
+		}
+		var items []json.RawMessage
+		for key, raw := range all {
+			if strings.EqualFold(key, name) {
+				json.Unmarshal(raw, &items)
+			}
+		}
+		list := v.Field(i)
+		if len(items) != list.Len() {
+			continue
+		}
+		for j, item := range items {
+			if err := decodeResponse(item, list.Index(j).Addr().Interface()); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// encoding/json matches keys case-insensitively, so do the same here
+func isKnownField(known []string, key string) bool {
+	for _, k := range known {
+		if strings.EqualFold(k, key) {
+			return true
+		}
+	}
+	return false
+}
+
+// collect json names of t's fields, including the ones promoted from embedded structs
+func knownFields(t reflect.Type) []string {
+	if cached, ok := knownFieldsCache.Load(t); ok {
+		return cached.([]string)
+	}
+
+	var names []string
+	if t.Kind() == reflect.Struct {
+		for i := 0; i < t.NumField(); i++ {
+			f := t.Field(i)
+			tag := f.Tag.Get("json")
+			if tag == "-" {
+				continue
+			}
+			name := strings.Split(tag, ",")[0]
+			if f.Anonymous && name == "" {
+				ft := f.Type
+				if ft.Kind() == reflect.Ptr {
+					ft = ft.Elem()
+				}
+				names = append(names, knownFields(ft)...)
+				continue
+			}
+			if f.PkgPath != "" {
+				continue
+			}
+			if name == "" {
+				name = f.Name
+			}
+			names = append(names, name)
+		}
+	}
+
+	knownFieldsCache.Store(t, names)
+	return names
+}
+
+// SendResult is the response of the API to a send
+type SendResult struct {
+	Response
+	// whether the API reports the send as successful, from the status when the body doesn't say
+	Success bool `json:"success"`
+	// id of the created notification, see GetNotificationStatus, CancelNotification and
+	// DeleteNotification
+	Id string `json:"id"`
+	// message of the API about the send, if any
+	Message string `json:"message"`
+	// status of the response
+	StatusCode int `json:"-"`
+
+	// outcome of the send on the client side, not part of the response body
+	Warnings    []Warning         `json:"-"`
+	State       SendState         `json:"-"`
+	SendPath    SendPath          `json:"-"`
+	Suppression SuppressionReport `json:"-"`
+	// recipients the send went to, normalized as set up with WithUserIDNormalizer and without those
+	// suppressed by opt-out filtering. nil for sends pulled from a recipient source, which aren't
+	// kept in memory
+	Recipients []string `json:"-"`
+	// idempotency key of the send, if any, see SetIdempotencyKey and WithAutoIdempotency
+	IdempotencyKey string `json:"-"`
+	// the send was answered by the client itself, see WithDryRun
+	DryRun bool `json:"-"`
+}
+
+// outcome of a single send, kept apart from the notification so that a notification can be sent
+// from several goroutines at once
+type sendOutcome struct {
+	warnings    []Warning
+	path        SendPath
+	state       SendState
+	suppression SuppressionReport
+	recipients  []string
+	// key of the send, see setIdempotencyKey
+	idempotencyKey string
+	// nothing was sent, see WithDryRun
+	dryRun bool
+}
+
+type sendOutcomeKey struct{}
+
+// ctx carrying the outcome of the send it is used for, read by the requests made for the send
+func withSendOutcome(ctx context.Context, out *sendOutcome) context.Context {
+	return context.WithValue(ctx, sendOutcomeKey{}, out)
+}
+
+func sendOutcomeFrom(ctx context.Context) *sendOutcome {
+	out, _ := ctx.Value(sendOutcomeKey{}).(*sendOutcome)
+	return out
+}
+
+// outcome of the last send of a notification, read by accessors like State. shared by the copies
+// of the notification made while sending it
+type lastSend struct {
+	mu      sync.Mutex
+	outcome sendOutcome
+}
+
+func (l *lastSend) set(out sendOutcome) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.outcome = out
+}
+
+func (l *lastSend) get() sendOutcome {
+	if l == nil {
+		return sendOutcome{}
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.outcome
+}
+
+// decode the response of a send with outcome out, closing its body. non-2xx statuses are turned
+// into errors
+func sendResult(out *sendOutcome, res *http.Response) (*SendResult, error) {
+	defer res.Body.Close()
+	e := out.path.endpoint()
+	body, err := readBody(res)
+	ok := res.StatusCode >= 200 && res.StatusCode <= 299
+	if !ok {
+		return nil, newAPIError(e, res.StatusCode, body)
+	}
+	if err != nil {
+		return nil, newDecodeError(e, res.StatusCode, body, err)
+	}
+
+	result := &SendResult{
+		Success:     true,
+		StatusCode:  res.StatusCode,
+		Warnings:    out.warnings,
+		State:       out.state,
+		SendPath:    out.path,
+		Suppression: out.suppression,
+		Recipients:  out.recipients,
+
+		IdempotencyKey: out.idempotencyKey,
+		DryRun:         out.dryRun,
+	}
+	if len(bytes.TrimSpace(body)) == 0 {
+		return result, nil
+	}
+	// Success keeps what the status says when the body has no success flag
+	if err := decodeResponse(body, result); err != nil {
+		return nil, newDecodeError(e, res.StatusCode, body, err)
+	}
+	return result, nil
+}
@@ -0,0 +1,181 @@
+package engagespot
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type fixtureResponse struct {
+	Response
+	Id    string `json:"id"`
+	Count int    `json:"count,omitempty"`
+	Name  string
+}
+
+func TestDecodeResponseCapturesUnknownFields(t *testing.T) {
+	body := []byte(`{"id":"42","count":3,"name":"a","priority":"high","meta":{"region":"eu","shards":[1,2]}}`)
+
+	var r fixtureResponse
+	assert.NoError(t, decodeResponse(body, &r))
+	assert.Equal(t, "42", r.Id)
+	assert.Equal(t, 3, r.Count)
+	assert.Equal(t, "a", r.Name)
+
+	var priority string
+	assert.NoError(t, r.Extra("priority", &priority))
+	assert.Equal(t, "high", priority)
+
+	var meta struct {
+		Region string `json:"region"`
+		Shards []int  `json:"shards"`
+	}
+	assert.NoError(t, r.Extra("meta", &meta))
+	assert.Equal(t, "eu", meta.Region)
+	assert.Equal(t, []int{1, 2}, meta.Shards)
+}
+
+func TestDecodeResponseKnownFieldsNotDuplicated(t *testing.T) {
+	body := []byte(`{"id":"42","count":3,"Name":"a","new":true}`)
+
+	var r fixtureResponse
+	assert.NoError(t, decodeResponse(body, &r))
+	assert.Len(t, r.RawExtra, 1)
+	assert.Contains(t, r.RawExtra, "new")
+	assert.NotContains(t, r.RawExtra, "id")
+	assert.NotContains(t, r.RawExtra, "count")
+	assert.NotContains(t, r.RawExtra, "Name")
+}
+
+func TestDecodeResponseNoExtra(t *testing.T) {
+	var r fixtureResponse
+	assert.NoError(t, decodeResponse([]byte(`{"id":"1"}`), &r))
+	assert.Nil(t, r.RawExtra)
+	assert.Error(t, r.Extra("missing", new(string)))
+}
+
+// send a message through a server answering with status and body
+func sendAnswered(t *testing.T, status int, body string) (*SendResult, error) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(status)
+		w.Write([]byte(body))
+	}))
+	defer server.Close()
+	return newMessage(clientFor(server.URL+"/v3/", time.Second)).Send()
+}
+
+func TestSendResult(t *testing.T) {
+	result, err := sendAnswered(t, 201, `{"id":"n-1","success":true,"message":"queued for delivery","region":"eu"}`)
+	assert.NoError(t, err)
+	assert.True(t, result.Success)
+	assert.Equal(t, "n-1", result.Id)
+	assert.Equal(t, "queued for delivery", result.Message)
+	assert.Equal(t, 201, result.StatusCode)
+	assert.Contains(t, result.RawExtra, "region")
+}
+
+func TestSendResultWithoutBody(t *testing.T) {
+	result, err := sendAnswered(t, 202, ``)
+	assert.NoError(t, err)
+	assert.True(t, result.Success)
+	assert.Equal(t, 202, result.StatusCode)
+	assert.Equal(t, StateAccepted, result.State)
+
+	result, err = sendAnswered(t, 200, `{"id":"n-2"}`)
+	assert.NoError(t, err)
+	assert.True(t, result.Success)
+}
+
+func TestSendResultOutcome(t *testing.T) {
+	c := NewEngagespotClient("A", "B", WithWorkflowMapping(map[string]string{"orders": "order-shipped"}))
+	c.httpClient.Transport = respondWith(200, `{}`)
+
+	result, err := orderShipped(c, "orders").Send()
+	assert.NoError(t, err)
+	assert.Equal(t, PathWorkflow, result.SendPath)
+	assert.Equal(t, StateDelivered, result.State)
+	assert.Contains(t, result.Warnings, Warning{Field: "icon", Message: "no icon set"})
+}
+
+func TestConcurrentSendsKeepTheirOutcome(t *testing.T) {
+	c := NewEngagespotClient("A", "B")
+	c.httpClient.Transport = respondWith(200, `{}`)
+	n := newMessage(c)
+
+	// run with -race, the outcome of a send is never written to the shared notification
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			result, err := n.Send()
+			assert.NoError(t, err)
+			assert.Equal(t, StateDelivered, result.State)
+			assert.Equal(t, PathLegacy, result.SendPath)
+		}()
+	}
+	wg.Wait()
+	assert.Equal(t, StateDelivered, n.State())
+}
+
+func TestSendResultNotSuccessful(t *testing.T) {
+	result, err := sendAnswered(t, 200, `{"success":false,"message":"no recipient could be reached"}`)
+	assert.NoError(t, err)
+	assert.False(t, result.Success)
+	assert.Equal(t, "no recipient could be reached", result.Message)
+}
+
+func TestSendResultMalformed(t *testing.T) {
+	_, err := sendAnswered(t, 200, `{"id":`)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "engagespot: Send notifications: decoding response")
+}
+
+func TestSendAPIError(t *testing.T) {
+	_, err := sendAnswered(t, 400, `{"message":"invalid recipient"}`)
+	var apiErr *APIError
+	assert.True(t, errors.As(err, &apiErr))
+	assert.Equal(t, &APIError{Op: "Send", Endpoint: "notifications", StatusCode: 400, Message: "invalid recipient"}, apiErr)
+	assert.EqualError(t, err, "engagespot: Send notifications: unexpected status 400: invalid recipient")
+
+	_, err = sendAnswered(t, 401, `{"error":"bad api key"}`)
+	assert.EqualError(t, err, "engagespot: Send notifications: unexpected status 401: bad api key")
+
+	_, err = sendAnswered(t, 502, `<html>bad gateway</html>`)
+	assert.EqualError(t, err, "engagespot: Send notifications: unexpected status 502: Bad Gateway")
+}
+
+func TestSendRaw(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(400)
+		w.Write([]byte(`{"message":"invalid recipient"}`))
+	}))
+	defer server.Close()
+
+	res, err := newMessage(clientFor(server.URL+"/v3/", time.Second)).SendRaw()
+	assert.NoError(t, err)
+	defer res.Body.Close()
+	assert.Equal(t, 400, res.StatusCode)
+}
+
+func TestDecodeResponseListElements(t *testing.T) {
+	body := []byte(`{"devices":[{"id":"d1","pushEnabled":true},{"id":"d2"}],"cursor":"c2"}`)
+
+	// the page itself doesn't embed Response, its elements still keep their extra fields
+	var list deviceList
+	assert.NoError(t, decodeResponse(body, &list))
+	assert.Equal(t, "d1", list.Devices[0].Id)
+	assert.Equal(t, map[string]json.RawMessage{"pushEnabled": json.RawMessage("true")}, list.Devices[0].RawExtra)
+	assert.Nil(t, list.Devices[1].RawExtra)
+
+	var page NotificationPage
+	assert.NoError(t, decodeResponse([]byte(`{"data":[{"id":"n1","priority":"high"}],"totalCount":1,"cursor":"c2"}`), &page))
+	assert.Equal(t, map[string]json.RawMessage{"cursor": json.RawMessage(`"c2"`)}, page.RawExtra)
+	assert.Equal(t, map[string]json.RawMessage{"priority": json.RawMessage(`"high"`)}, page.Notifications[0].RawExtra)
+}
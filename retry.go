@@ -0,0 +1,239 @@
+package engagespot
+
+import (
+	"context"
+	crand "crypto/rand"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// longest Retry-After honored. a call asked to wait longer gives up instead, as retrying sooner
+// would only be rejected again
+const maxRetryAfter = time.Minute
+
+// WithRetry makes calls failing with a transient error, like a network error or a 429, 502, 503
+// or 504 status, retried up to maxAttempts attempts in total. waits double from baseDelay, with
+// jitter, unless the response has a Retry-After header, which is honored. takes precedence over
+// Defaults.Retry
+func WithRetry(maxAttempts int, baseDelay time.Duration) Option {
+	return func(c *Client) {
+		c.declare(optRetry)
+		if maxAttempts < 1 || baseDelay < 0 {
+			c.rejectOption(optRetry, fmt.Errorf("needs at least 1 attempt and a non-negative delay, got %d and %s", maxAttempts, baseDelay))
+			return
+		}
+		c.retryPolicy = &RetryPolicy{MaxAttempts: maxAttempts, BaseDelay: baseDelay}
+	}
+}
+
+// wait asked by the Retry-After header of res, in seconds or as a date. ok is false if there is
+// none or it can't be parsed
+func retryAfter(res *http.Response, now time.Time) (time.Duration, bool) {
+	if res == nil {
+		return 0, false
+	}
+	value := res.Header.Get("Retry-After")
+	if value == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(value); err == nil {
+		if seconds < 0 {
+			return 0, false
+		}
+		return time.Duration(seconds) * time.Second, true
+	}
+	at, err := http.ParseTime(value)
+	if err != nil {
+		return 0, false
+	}
+	if d := at.Sub(now); d > 0 {
+		return d, true
+	}
+	return 0, true
+}
+
+// WithRandSource replaces the source of the jitter added to retry backoffs, to reproduce the
+// retry timing of a past send with SimulateRetrySchedule for example. the source doesn't need to
+// be safe for concurrent use. by default a source seeded from crypto/rand is used
+func WithRandSource(src rand.Source) Option {
+	return func(c *Client) {
+		c.jitter = &lockedRand{r: rand.New(src)}
+	}
+}
+
+// rand.Rand shared by concurrent calls
+type lockedRand struct {
+	mu sync.Mutex
+	r  *rand.Rand
+}
+
+func newLockedRand() *lockedRand {
+	var seed [8]byte
+	if _, err := crand.Read(seed[:]); err != nil {
+		binary.LittleEndian.PutUint64(seed[:], uint64(time.Now().UnixNano()))
+	}
+	return &lockedRand{r: rand.New(rand.NewSource(int64(binary.LittleEndian.Uint64(seed[:]))))}
+}
+
+func (l *lockedRand) backoff(policy RetryPolicy, retry int) time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return backoff(policy, retry, l.r)
+}
+
+// wait before the given retry, 1 being the first one. the delay doubles with every retry and is
+// jittered between half and all of it, so clients failing at the same time don't retry in sync
+func backoff(policy RetryPolicy, retry int, r *rand.Rand) time.Duration {
+	shift := retry - 1
+	if shift > 30 {
+		shift = 30
+	}
+	d := policy.BaseDelay << shift
+	if d <= 0 {
+		return 0
+	}
+	half := d / 2
+	return half + time.Duration(r.Int63n(int64(d-half)+1))
+}
+
+// SimulateRetrySchedule returns the waits before each retry of a call making the given number of
+// attempts under policy, with jitter drawn from a source seeded with seed. a client created with
+// WithRandSource(rand.NewSource(seed)) waits the same on its first retried call
+func SimulateRetrySchedule(policy RetryPolicy, seed int64, attempts int) []time.Duration {
+	r := rand.New(rand.NewSource(seed))
+	var schedule []time.Duration
+	for retry := 1; retry < attempts; retry++ {
+		schedule = append(schedule, backoff(policy, retry, r))
+	}
+	return schedule
+}
+
+// tells if a call should be retried. only failures which are likely to be transient are
+func retryable(res *http.Response, err error) bool {
+	if err != nil {
+		return !errors.Is(err, ErrUnsafeRedirect)
+	}
+	switch res.StatusCode {
+	case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+// wait for d, or until ctx is done
+func sleepContext(ctx context.Context, d time.Duration) error {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// attempts made by a call, collected for the archive record of a send
+type attemptLog struct {
+	mu       sync.Mutex
+	attempts []ArchiveAttempt
+}
+
+type attemptLogKey struct{}
+
+func withAttemptLog(ctx context.Context, log *attemptLog) context.Context {
+	return context.WithValue(ctx, attemptLogKey{}, log)
+}
+
+func recordAttempt(ctx context.Context, attempt ArchiveAttempt) {
+	if log, ok := ctx.Value(attemptLogKey{}).(*attemptLog); ok {
+		log.mu.Lock()
+		log.attempts = append(log.attempts, attempt)
+		log.mu.Unlock()
+	}
+}
+
+func (log *attemptLog) recorded() []ArchiveAttempt {
+	log.mu.Lock()
+	defer log.mu.Unlock()
+	return append([]ArchiveAttempt{}, log.attempts...)
+}
+
+// make the request, retrying it according to the retry policy of s
+func (c *Client) doWithRetry(e endpoint, req *http.Request, s *liveSettings, sampleId string) (*http.Response, error) {
+	ctx := req.Context()
+	var wait time.Duration
+	for attempt := 1; ; attempt++ {
+		if s.limiter != nil {
+			if wait := s.limiter.reserve(c.now()); wait > 0 {
+				if err := c.sleep(ctx, wait); err != nil {
+					return nil, &OperationError{Op: e.op, Endpoint: e.path, Key: requestKey(req), Err: err}
+				}
+			}
+		}
+
+		started := c.now()
+		res, err := c.do(e, req, s, sampleId)
+		if res != nil && s.limiter != nil {
+			c.observeRateLimit(s.limiter, res)
+		}
+		// retries come after waits which make their timing less reliable
+		if res != nil && attempt == 1 {
+			c.observeDate(res, started, c.now())
+		}
+
+		recorded := ArchiveAttempt{StartedAt: started, Duration: c.now().Sub(started), Backoff: wait}
+		if res != nil {
+			recorded.StatusCode = res.StatusCode
+		}
+		if err != nil {
+			recorded.Err = err.Error()
+		}
+		recordAttempt(ctx, recorded)
+		state := StateUnknown
+		if res != nil && e.op == endpointSendNotification.op {
+			state = readStatus(res).state(res.StatusCode)
+		}
+		c.observer().ObserveSend(recorded.StatusCode, state, recorded.Duration, err)
+
+		if attempt >= s.retry.MaxAttempts || !retryable(res, err) || ctx.Err() != nil ||
+			(req.Body != nil && req.GetBody == nil) {
+			c.recordError(ctx, e, attempt, res, err, false)
+			return res, err
+		}
+		asked, hasRetryAfter := retryAfter(res, c.now())
+		if hasRetryAfter && asked > maxRetryAfter {
+			c.recordError(ctx, e, attempt, res, err, false)
+			return res, err
+		}
+		c.recordError(ctx, e, attempt, res, err, true)
+
+		wait = c.jitter.backoff(s.retry, attempt)
+		if hasRetryAfter {
+			wait = asked
+		}
+		// cancelled while waiting, the failed response isn't the answer to the call
+		if sleepErr := c.sleep(ctx, wait); sleepErr != nil {
+			if res != nil {
+				discard(res)
+			}
+			return nil, &OperationError{Op: e.op, Endpoint: e.path, Key: requestKey(req), Err: sleepErr}
+		}
+		if res != nil {
+			discard(res)
+		}
+		c.observer().ObserveRetry(attempt + 1)
+		if req.GetBody != nil {
+			body, bodyErr := req.GetBody()
+			if bodyErr != nil {
+				return nil, bodyErr
+			}
+			req.Body = body
+		}
+	}
+}
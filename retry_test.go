@@ -0,0 +1,241 @@
+package engagespot
+
+import (
+	"context"
+	"errors"
+	"io"
+	"math/rand"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var testRetryPolicy = RetryPolicy{MaxAttempts: 4, BaseDelay: 100 * time.Millisecond}
+
+func TestSimulateRetrySchedule(t *testing.T) {
+	a := SimulateRetrySchedule(testRetryPolicy, 42, 4)
+	assert.Len(t, a, 3)
+	assert.Equal(t, a, SimulateRetrySchedule(testRetryPolicy, 42, 4))
+	assert.NotEqual(t, a, SimulateRetrySchedule(testRetryPolicy, 43, 4))
+
+	for i, d := range a {
+		full := testRetryPolicy.BaseDelay << i
+		assert.GreaterOrEqual(t, d, full/2)
+		assert.LessOrEqual(t, d, full)
+	}
+	assert.Empty(t, SimulateRetrySchedule(testRetryPolicy, 42, 1))
+}
+
+// client retrying under testRetryPolicy, which waits on clock and records every wait
+func retryingClient(clock *fakeClock, waits *[]time.Duration, opts ...Option) *Client {
+	d := DefaultConfig()
+	d.Retry = testRetryPolicy
+	c := NewEngagespotClient("A", "B", append(opts, WithDefaults(d))...)
+	c.now = clock.Now
+	c.sleep = func(ctx context.Context, d time.Duration) error {
+		*waits = append(*waits, d)
+		clock.Advance(d)
+		return nil
+	}
+	return c
+}
+
+// transport failing with status the first failures times
+func flaky(failures int, status int, bodies *[]string) http.RoundTripper {
+	calls := 0
+	return roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		body, _ := io.ReadAll(req.Body)
+		*bodies = append(*bodies, string(body))
+		calls++
+		if calls <= failures {
+			return respondWith(status, `{}`).RoundTrip(req)
+		}
+		return respondWith(200, `{}`).RoundTrip(req)
+	})
+}
+
+func TestRetryScheduleRecorded(t *testing.T) {
+	var waits []time.Duration
+	var bodies []string
+	a := &recordingArchiver{}
+	clock := newFakeClock()
+	c := retryingClient(clock, &waits, WithRandSource(rand.NewSource(42)), WithArchiver(a, ArchiveSync))
+	c.httpClient.Transport = flaky(3, 503, &bodies)
+
+	res, err := newMessage(c).Send()
+	assert.NoError(t, err)
+	assert.Equal(t, 200, res.StatusCode)
+
+	assert.Equal(t, SimulateRetrySchedule(testRetryPolicy, 42, 4), waits)
+
+	attempts := a.recorded()[0].Attempts
+	assert.Len(t, attempts, 4)
+	assert.Zero(t, attempts[0].Backoff)
+	for i := 1; i < len(attempts); i++ {
+		assert.Equal(t, waits[i-1], attempts[i].Backoff)
+		assert.Equal(t, waits[i-1], attempts[i].StartedAt.Sub(attempts[i-1].StartedAt))
+	}
+	assert.Equal(t, []int{503, 503, 503, 200}, []int{attempts[0].StatusCode, attempts[1].StatusCode, attempts[2].StatusCode, attempts[3].StatusCode})
+
+	// the body is sent again on every attempt
+	assert.Len(t, bodies, 4)
+	for _, b := range bodies {
+		assert.Equal(t, bodies[0], b)
+		assert.NotEmpty(t, b)
+	}
+}
+
+func TestRetryNotTransient(t *testing.T) {
+	var waits []time.Duration
+	var bodies []string
+	c := retryingClient(newFakeClock(), &waits)
+	c.httpClient.Transport = flaky(1, 400, &bodies)
+
+	_, err := newMessage(c).Send()
+	var apiErr *APIError
+	assert.ErrorAs(t, err, &apiErr)
+	assert.Equal(t, 400, apiErr.StatusCode)
+	assert.Empty(t, waits)
+}
+
+func TestRetryGivesUp(t *testing.T) {
+	var waits []time.Duration
+	var bodies []string
+	c := retryingClient(newFakeClock(), &waits)
+	c.httpClient.Transport = flaky(10, 502, &bodies)
+
+	res, err := newMessage(c).SendRaw()
+	assert.NoError(t, err)
+	assert.Equal(t, 502, res.StatusCode)
+	assert.Len(t, bodies, 4)
+	assert.Len(t, waits, 3)
+}
+
+func TestNoRetriesByDefault(t *testing.T) {
+	var bodies []string
+	c := NewEngagespotClient("A", "B")
+	c.httpClient.Transport = flaky(1, 503, &bodies)
+
+	res, err := newMessage(c).SendRaw()
+	assert.NoError(t, err)
+	assert.Equal(t, 503, res.StatusCode)
+	assert.Len(t, bodies, 1)
+}
+
+func TestWithRetryFlakyServer(t *testing.T) {
+	var bodies []string
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		bodies = append(bodies, string(body))
+		calls++
+		if calls <= 2 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer server.Close()
+
+	c := NewEngagespotClient("A", "B", WithBaseURL(server.URL), WithRetry(3, time.Millisecond))
+	res, err := newMessage(c).Send()
+	assert.NoError(t, err)
+	assert.Equal(t, 200, res.StatusCode)
+
+	// the body is sent whole on every attempt
+	assert.Len(t, bodies, 3)
+	assert.NotEmpty(t, bodies[0])
+	assert.Equal(t, []string{bodies[0], bodies[0], bodies[0]}, bodies)
+}
+
+func TestWithRetryWinsOverDefaults(t *testing.T) {
+	var bodies []string
+	c := NewEngagespotClient("A", "B", WithRetry(2, 0), WithDefaults(DefaultConfig()))
+	c.httpClient.Transport = flaky(10, 502, &bodies)
+	newMessage(c).SendRaw()
+	assert.Len(t, bodies, 2)
+
+	_, err := NewClient("A", "B", WithRetry(0, time.Second))
+	assert.EqualError(t, err, "engagespot: conflicting options: WithRetry: needs at least 1 attempt and a non-negative delay, got 0 and 1s")
+}
+
+// transport answering 429 with retryAfter the first time, then 200
+func rateLimitedOnce(retryAfter string) http.RoundTripper {
+	calls := 0
+	return roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		calls++
+		if calls > 1 {
+			return respondWith(200, `{}`).RoundTrip(req)
+		}
+		res, _ := respondWith(429, `{}`).RoundTrip(req)
+		res.Header.Set("Retry-After", retryAfter)
+		return res, nil
+	})
+}
+
+func TestRetryAfter(t *testing.T) {
+	// dates are relative to the clock every client starts at
+	clock := newFakeClock()
+	backoff := SimulateRetrySchedule(testRetryPolicy, 42, 2)[0]
+	for retryAfter, expected := range map[string]time.Duration{
+		"7":    7 * time.Second,
+		"0":    0,
+		"soon": backoff,
+		"-3":   backoff,
+		clock.Now().Add(20 * time.Second).Format(http.TimeFormat): 20 * time.Second,
+		clock.Now().Add(-time.Hour).Format(http.TimeFormat):       0,
+	} {
+		var waits []time.Duration
+		// headers which can't be parsed fall back to the jittered backoff
+		c := retryingClient(newFakeClock(), &waits, WithRandSource(rand.NewSource(42)))
+		c.httpClient.Transport = rateLimitedOnce(retryAfter)
+
+		res, err := newMessage(c).Send()
+		assert.NoError(t, err, retryAfter)
+		assert.Equal(t, 200, res.StatusCode, retryAfter)
+		assert.Equal(t, []time.Duration{expected}, waits, retryAfter)
+	}
+}
+
+func TestRetryAfterTooLong(t *testing.T) {
+	var waits []time.Duration
+	c := retryingClient(newFakeClock(), &waits)
+	c.httpClient.Transport = rateLimitedOnce("3600")
+
+	_, err := newMessage(c).Send()
+	assert.True(t, errors.Is(err, ErrRateLimited))
+	assert.Empty(t, waits)
+}
+
+func TestRetryBackoffCancelled(t *testing.T) {
+	var bodies []string
+	c := NewEngagespotClient("A", "B", WithRetry(3, time.Hour))
+	c.httpClient.Transport = flaky(10, 503, &bodies)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(50*time.Millisecond, cancel)
+	started := time.Now()
+	_, err := newMessage(c).SendCtx(ctx)
+	assert.ErrorIs(t, err, context.Canceled)
+	var apiErr *APIError
+	assert.False(t, errors.As(err, &apiErr), "the 503 before the cancellation isn't the answer")
+	assert.Less(t, time.Since(started), 5*time.Second)
+	assert.Len(t, bodies, 1)
+}
+
+func TestRetryBackoffCancelledClosesResponse(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	c := NewEngagespotClient("A", "B", WithRetry(3, time.Hour))
+	transport, bodies := trackedResponses(503, 503)
+	c.httpClient.Transport = roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		time.AfterFunc(10*time.Millisecond, cancel)
+		return transport.RoundTrip(req)
+	})
+
+	res, err := c.SendRawCtx(ctx, newMessage(c))
+	assert.Nil(t, res)
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Len(t, *bodies, 1)
+	assert.True(t, (*bodies)[0].closed)
+}
@@ -0,0 +1,54 @@
+package engagespot
+
+import (
+	"errors"
+	"fmt"
+)
+
+// MaxActions is the number of action buttons a notification can have, see AddAction
+const MaxActions = 3
+
+// Action is a button of an in-app notification, opening Url when clicked
+type Action struct {
+	Label string `json:"label"`
+	Url   string `json:"url"`
+}
+
+// AddAction can be used to add an action button to the in-app notification, after the ones already
+// added. url is validated like the url of SetUrl, and at most MaxActions can be added
+func (n *Notification) AddAction(label, url string) (*Notification, error) {
+	if label == "" {
+		return nil, invalidField("actions", label, errors.New("empty action label string"))
+	}
+	if err := validURL("action url", url, n.Client.relaxedValidation); err != nil {
+		return nil, invalidField("actions", url, err)
+	}
+	if len(n.Notification.Actions) >= MaxActions {
+		return nil, invalidField("actions", label, fmt.Errorf("at most %d actions are accepted", MaxActions))
+	}
+	n.Notification.Actions = append(n.Notification.Actions, Action{Label: label, Url: url})
+	return n, nil
+}
+
+// SetImage can be used to set an image shown in the body of the in-app notification, validated
+// like the url of SetUrl
+func (n *Notification) SetImage(imageUrl string) (*Notification, error) {
+	if imageUrl == "" {
+		return nil, invalidField("image", imageUrl, errors.New("empty image url string"))
+	}
+	if err := validURL("image url", imageUrl, n.Client.relaxedValidation); err != nil {
+		return nil, invalidField("image", imageUrl, err)
+	}
+	n.Notification.Image = imageUrl
+	return n, nil
+}
+
+// SetHTMLBody can be used to set an html body shown by the in-app channel instead of the message.
+// the message is still used by the other channels
+func (n *Notification) SetHTMLBody(html string) (*Notification, error) {
+	if html == "" {
+		return nil, invalidField("htmlBody", html, errors.New("empty html body string"))
+	}
+	n.Notification.HTMLBody = html
+	return n, nil
+}
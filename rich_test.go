@@ -0,0 +1,86 @@
+package engagespot
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var updateGolden = flag.Bool("update", false, "rewrite the golden files of testdata")
+
+// compare the indented json of v with testdata/name.json, rewritten with -update
+func assertGolden(t *testing.T, name string, v interface{}) {
+	t.Helper()
+	b, err := json.Marshal(v)
+	assert.NoError(t, err)
+	var indented bytes.Buffer
+	assert.NoError(t, json.Indent(&indented, b, "", "  "))
+	indented.WriteByte('\n')
+
+	file := filepath.Join("testdata", name+".json")
+	if *updateGolden {
+		assert.NoError(t, os.WriteFile(file, indented.Bytes(), 0o644))
+	}
+	want, err := os.ReadFile(file)
+	assert.NoError(t, err)
+	assert.Equal(t, string(want), indented.String())
+}
+
+func TestRichContentGolden(t *testing.T) {
+	n := newTestNotification(t)
+	n.Message("Your invoice is ready").Url("https://example.com/invoices/42").Icon("https://example.com/icon.png")
+	n.AddAction("Pay now", "https://example.com/invoices/42/pay")
+	n.AddAction("Download", "https://example.com/invoices/42.pdf?format=a4")
+	n.SetImage("https://example.com/invoices/42/preview.png")
+	n.SetHTMLBody("<p>Your invoice of <b>$42</b> is ready</p>")
+	n.AddRecipient("a")
+	assert.NoError(t, n.buildError())
+
+	assertGolden(t, "rich-content", n)
+}
+
+func TestRichContentOmittedWhenUnused(t *testing.T) {
+	n := newTestNotification(t)
+	n.AddRecipient("a")
+	b, err := json.Marshal(n)
+	assert.NoError(t, err)
+	for _, key := range []string{"actions", "image", "htmlBody"} {
+		assert.NotContains(t, string(b), key)
+	}
+}
+
+func TestAddActionValidation(t *testing.T) {
+	n := newTestNotification(t)
+	_, err := n.AddAction("", "https://example.com")
+	assert.EqualError(t, err, "empty action label string")
+	_, err = n.AddAction("Open", "myapp://orders")
+	assert.EqualError(t, err, `invalid action url "myapp://orders": scheme "myapp" isn't http or https, see WithRelaxedValidation`)
+
+	for i := 0; i < MaxActions; i++ {
+		_, err = n.AddAction("Open", "https://example.com")
+		assert.NoError(t, err)
+	}
+	_, err = n.AddAction("One more", "https://example.com")
+	assert.EqualError(t, err, "at most 3 actions are accepted")
+	assert.Len(t, n.Notification.Actions, MaxActions)
+
+	relaxed, _ := NewEngagespotClient("A", "B", WithRelaxedValidation()).NewNotification("Hello")
+	_, err = relaxed.AddAction("Open", "myapp://orders")
+	assert.NoError(t, err)
+}
+
+func TestSetImageAndHTMLBodyValidation(t *testing.T) {
+	n := newTestNotification(t)
+	_, err := n.SetImage("")
+	assert.EqualError(t, err, "empty image url string")
+	_, err = n.SetImage("preview.png")
+	assert.EqualError(t, err, `invalid image url "preview.png": not an absolute url`)
+	_, err = n.SetHTMLBody("")
+	assert.EqualError(t, err, "empty html body string")
+	assert.Empty(t, n.Notification.Image)
+}
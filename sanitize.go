@@ -0,0 +1,158 @@
+package engagespot
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Correction is a formatting mistake fixed by RecipientSanitizer
+type Correction string
+
+const (
+	// a mailto: prefix was stripped
+	CorrectionMailto Correction = "mailto"
+	// the address was extracted from a `Name <address>` form
+	CorrectionNameAddr Correction = "name-addr"
+	// the domain part of the address was lowercased
+	CorrectionDomainCase Correction = "domain-case"
+	// surrounding whitespace was trimmed or internal whitespace collapsed
+	CorrectionWhitespace Correction = "whitespace"
+)
+
+// SanitizedRecipient is an entry of the sanitization report of a notification, a recipient which
+// was changed by RecipientSanitizer
+type SanitizedRecipient struct {
+	Original    string
+	Sanitized   string
+	Corrections []Correction
+}
+
+// RecipientSanitizer fixes the formatting mistakes common in recipient lists exported from CRMs,
+// returning the sanitized recipient along with every correction applied, in order. `mailto:`
+// prefixes are stripped, the address is extracted from `Name <address>` forms, the domain part is
+// lowercased and whitespace is collapsed. the local part is left as is, since it can be case
+// sensitive
+func RecipientSanitizer(recipient string) (string, []Correction) {
+	var corrections []Correction
+
+	if collapsed := strings.Join(strings.Fields(recipient), " "); collapsed != recipient {
+		recipient = collapsed
+		corrections = append(corrections, CorrectionWhitespace)
+	}
+
+	// from RFC 5322, name-addr = [display-name] "<" addr-spec ">"
+	if strings.HasSuffix(recipient, ">") {
+		if start := strings.LastIndex(recipient, "<"); start >= 0 {
+			recipient = strings.TrimSpace(recipient[start+1 : len(recipient)-1])
+			corrections = append(corrections, CorrectionNameAddr)
+		}
+	}
+
+	if len(recipient) >= len("mailto:") && strings.EqualFold(recipient[:len("mailto:")], "mailto:") {
+		recipient = strings.TrimSpace(recipient[len("mailto:"):])
+		corrections = append(corrections, CorrectionMailto)
+	}
+
+	if at := strings.LastIndex(recipient, "@"); at >= 0 {
+		if domain := strings.ToLower(recipient[at+1:]); domain != recipient[at+1:] {
+			recipient = recipient[:at+1] + domain
+			corrections = append(corrections, CorrectionDomainCase)
+		}
+	}
+
+	return recipient, corrections
+}
+
+// WithRecipientSanitization can be used to run every recipient through RecipientSanitizer in
+// AddRecipient, before any normalizer set with WithUserIDNormalizer. recipients which still contain
+// whitespace after sanitization are rejected. corrections are reported by Sanitization and as
+// warnings on send
+func WithRecipientSanitization() Option {
+	return func(c *Client) {
+		c.sanitizeRecipients = true
+	}
+}
+
+// most recipients pulled from a source kept in the sanitization report, the others corrected are
+// only counted
+const maxSourceSanitization = 100
+
+// sanitize recipient if sanitization is enabled, recording the corrections on n
+func (n *Notification) sanitizeRecipient(recipient string) (string, error) {
+	sanitized, corrections, err := n.Client.sanitize(recipient)
+	if err == nil && len(corrections) > 0 {
+		n.sanitization = append(n.sanitization, SanitizedRecipient{
+			Original:    recipient,
+			Sanitized:   sanitized,
+			Corrections: corrections,
+		})
+	}
+	return sanitized, err
+}
+
+// sanitize a recipient pulled from the source of n. a source can yield more recipients than fit in
+// memory, so only the first maxSourceSanitization corrections are recorded, the others are counted
+func (n *Notification) sanitizeSourceRecipient(recipient string) (string, error) {
+	sanitized, corrections, err := n.Client.sanitize(recipient)
+	if err != nil || len(corrections) == 0 {
+		return sanitized, err
+	}
+	n.sourceCorrections++
+	if len(n.sanitization) < maxSourceSanitization {
+		n.sanitization = append(n.sanitization, SanitizedRecipient{
+			Original:    recipient,
+			Sanitized:   sanitized,
+			Corrections: corrections,
+		})
+	}
+	return sanitized, nil
+}
+
+// run recipient through RecipientSanitizer if sanitization is enabled
+func (c *Client) sanitize(recipient string) (string, []Correction, error) {
+	if !c.sanitizeRecipients {
+		return recipient, nil, nil
+	}
+
+	sanitized, corrections := RecipientSanitizer(recipient)
+	if sanitized == "" {
+		return "", nil, errors.New("empty recipient string")
+	}
+	if strings.ContainsAny(sanitized, " \t\r\n") {
+		return "", nil, errors.New("invalid recipient string")
+	}
+	return sanitized, corrections, nil
+}
+
+// warnings for the recipients corrected by sanitization
+func (n *Notification) sanitizationWarnings() []Warning {
+	// recipients of a source are pulled after validation, see sourceSanitizationWarning
+	if n.recipientSource != nil {
+		return nil
+	}
+	var warnings []Warning
+	for _, s := range n.sanitization {
+		warnings = append(warnings, Warning{
+			Field:   "recipients",
+			Message: fmt.Sprintf("recipient %q was corrected to %q", s.Original, s.Sanitized),
+		})
+	}
+	return warnings
+}
+
+// a single warning for the recipients of a source corrected by sanitization, as there can be too
+// many for one warning each
+func (n *Notification) sourceSanitizationWarning() Warning {
+	return Warning{
+		Field:   "recipients",
+		Message: fmt.Sprintf("%d recipients of the source were corrected by sanitization", n.sourceCorrections),
+	}
+}
+
+// Sanitization can be used to get every recipient corrected by sanitization so far, see
+// WithRecipientSanitization. for a notification sent from a recipient source, only the first 100
+// recipients corrected by the last send are kept
+func (n *Notification) Sanitization() []SanitizedRecipient {
+	return n.sanitization
+}
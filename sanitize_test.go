@@ -0,0 +1,110 @@
+package engagespot
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRecipientSanitizer(t *testing.T) {
+	cases := []struct {
+		in          string
+		want        string
+		corrections []Correction
+	}{
+		{"mailto:john@x.com", "john@x.com", []Correction{CorrectionMailto}},
+		{"MAILTO:john@x.com", "john@x.com", []Correction{CorrectionMailto}},
+		{"John <john@x.com>", "john@x.com", []Correction{CorrectionNameAddr}},
+		{"\"Doe, John\" <john@x.com>", "john@x.com", []Correction{CorrectionNameAddr}},
+		{"<john@x.com>", "john@x.com", []Correction{CorrectionNameAddr}},
+		{"john@X.COM", "john@x.com", []Correction{CorrectionDomainCase}},
+		{"John@x.com", "John@x.com", nil},
+		{"  john@x.com\t", "john@x.com", []Correction{CorrectionWhitespace}},
+		{"John   Doe  <mailto:John@X.com>", "John@x.com", []Correction{CorrectionWhitespace, CorrectionNameAddr, CorrectionMailto, CorrectionDomainCase}},
+		{"user-42", "user-42", nil},
+	}
+	for _, c := range cases {
+		got, corrections := RecipientSanitizer(c.in)
+		assert.Equal(t, c.want, got, c.in)
+		assert.Equal(t, c.corrections, corrections, c.in)
+	}
+}
+
+func TestRecipientSanitizationReport(t *testing.T) {
+	c := NewEngagespotClient("A", "B", WithRecipientSanitization())
+	n, _ := c.NewNotification("Hello")
+	for _, r := range []string{"mailto:a@x.com", "B <b@x.com>", "c@X.com", "d@x.com"} {
+		_, err := n.AddRecipient(r)
+		assert.NoError(t, err)
+	}
+
+	assert.Equal(t, []string{"a@x.com", "b@x.com", "c@x.com", "d@x.com"}, n.Recipients)
+	assert.Equal(t, []SanitizedRecipient{
+		{Original: "mailto:a@x.com", Sanitized: "a@x.com", Corrections: []Correction{CorrectionMailto}},
+		{Original: "B <b@x.com>", Sanitized: "b@x.com", Corrections: []Correction{CorrectionNameAddr}},
+		{Original: "c@X.com", Sanitized: "c@x.com", Corrections: []Correction{CorrectionDomainCase}},
+	}, n.Sanitization())
+
+	c.httpClient.Transport = respondWith(200, `{}`)
+	_, err := n.Send()
+	assert.NoError(t, err)
+	assert.Contains(t, n.Warnings(), Warning{Field: "recipients", Message: `recipient "c@X.com" was corrected to "c@x.com"`})
+}
+
+func TestRecipientSanitizationRejects(t *testing.T) {
+	c := NewEngagespotClient("A", "B", WithRecipientSanitization())
+	n, _ := c.NewNotification("Hello")
+	for _, r := range []string{"", "   ", "mailto:", "<>", "john doe@x.com"} {
+		_, err := n.AddRecipient(r)
+		assert.Error(t, err, r)
+	}
+	assert.Empty(t, n.Recipients)
+	assert.Empty(t, n.Sanitization())
+}
+
+func TestRecipientSanitizationDisabledByDefault(t *testing.T) {
+	n := newTestNotification(t)
+	_, err := n.AddRecipient("mailto:a@X.com")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"mailto:a@X.com"}, n.Recipients)
+	assert.Nil(t, n.Sanitization())
+}
+
+func TestRecipientSourceSanitizationBounded(t *testing.T) {
+	var bodies []map[string]interface{}
+	var handled []Warning
+	c := chunkedClient(100, WithRecipientSanitization(), WithWarningHandler(func(w []Warning) { handled = w }))
+	c.httpClient.Transport = recordBodies(&bodies)
+
+	n, _ := c.NewNotification("Hello")
+	sent := 0
+	n.SetRecipientSource(func(ctx context.Context) (string, bool, error) {
+		if sent == 1000 {
+			return "", false, nil
+		}
+		sent++
+		return fmt.Sprintf("mailto:user%d@x.com", sent), true, nil
+	})
+	_, err := n.Send()
+	assert.NoError(t, err)
+	assert.Len(t, bodies, 10)
+	assert.Equal(t, "user1000@x.com", bodies[9]["recipients"].([]interface{})[99])
+
+	assert.Len(t, n.Sanitization(), maxSourceSanitization)
+	assert.Equal(t, SanitizedRecipient{Original: "mailto:user1@x.com", Sanitized: "user1@x.com", Corrections: []Correction{CorrectionMailto}}, n.Sanitization()[0])
+	warning := Warning{Field: "recipients", Message: "1000 recipients of the source were corrected by sanitization"}
+	assert.Contains(t, n.Warnings(), warning)
+	assert.Contains(t, handled, warning)
+	for _, w := range n.Warnings() {
+		assert.NotContains(t, w.Message, "was corrected to")
+	}
+
+	// a send of a new source starts a new report
+	sent = 995
+	_, err = n.Send()
+	assert.NoError(t, err)
+	assert.Len(t, n.Sanitization(), 5)
+	assert.Contains(t, n.Warnings(), Warning{Field: "recipients", Message: "5 recipients of the source were corrected by sanitization"})
+}
@@ -0,0 +1,28 @@
+package engagespot
+
+import (
+	"context"
+	"errors"
+)
+
+// ErrScopeNotSupported is returned when a call is made with AsUser on an endpoint which only
+// accepts server level auth
+var ErrScopeNotSupported = errors.New("endpoint doesn't support user scope")
+
+// AsUser makes the call with the authority of userId only: the API key, the user id and its HMAC
+// signature are sent instead of the API secret. useful for backends acting on behalf of a browser,
+// which must never send the secret to the API. endpoints which require server level auth fail with
+// ErrScopeNotSupported
+func AsUser(userId string) CallOption {
+	return func(o *callOptions) {
+		o.asUser = &userId
+	}
+}
+
+type userScopeKey struct{}
+
+// user whose authority the call is made with, if AsUser has been used
+func userScope(ctx context.Context) (string, bool) {
+	userId, ok := ctx.Value(userScopeKey{}).(string)
+	return userId, ok
+}
@@ -0,0 +1,45 @@
+package engagespot
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestScopeHeaders(t *testing.T) {
+	var headers []http.Header
+	c := NewEngagespotClient("A", "B")
+	c.httpClient.Transport = roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		headers = append(headers, req.Header)
+		return respondWith(200, `{"devices":[]}`).RoundTrip(req)
+	})
+
+	_, err := c.ListDevices(context.Background(), "hello@example.com")
+	assert.NoError(t, err)
+	_, err = c.ListDevices(context.Background(), "hello@example.com", AsUser("hello@example.com"))
+	assert.NoError(t, err)
+
+	server := headers[0]
+	assert.Equal(t, "A", server.Get("X-ENGAGESPOT-API-KEY"))
+	assert.Equal(t, "B", server.Get("X-ENGAGESPOT-API-SECRET"))
+	assert.Empty(t, server.Get("X-ENGAGESPOT-USER-ID"))
+	assert.Empty(t, server.Get("X-ENGAGESPOT-USER-SIGNATURE"))
+
+	user := headers[1]
+	assert.Equal(t, "A", user.Get("X-ENGAGESPOT-API-KEY"))
+	assert.Empty(t, user.Values("X-ENGAGESPOT-API-SECRET"))
+	assert.Equal(t, "hello@example.com", user.Get("X-ENGAGESPOT-USER-ID"))
+	assert.Equal(t, c.GenHmac("hello@example.com"), user.Get("X-ENGAGESPOT-USER-SIGNATURE"))
+}
+
+func TestScopeNotSupported(t *testing.T) {
+	c := NewEngagespotClient("A", "B")
+	c.httpClient.Transport = noNetwork(t)
+
+	n, _ := c.NewNotification("Hello")
+	n.AddRecipient("hello@example.com")
+	_, err := n.Send(AsUser("hello@example.com"))
+	assert.ErrorIs(t, err, ErrScopeNotSupported)
+}
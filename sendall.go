@@ -0,0 +1,63 @@
+package engagespot
+
+import (
+	"context"
+	"fmt"
+	"sync"
+)
+
+// SendAllError is returned by SendAll when some of the notifications couldn't be sent. Errs is
+// aligned with the notifications given to SendAll, nil for those which were sent
+type SendAllError struct {
+	Errs []error
+}
+
+func (e *SendAllError) Error() string {
+	failed := e.Unwrap()
+	return fmt.Sprintf("engagespot: %d of %d notifications failed, first: %s", len(failed), len(e.Errs), failed[0])
+}
+
+// Unwrap returns the errors of the notifications which failed, in order
+func (e *SendAllError) Unwrap() []error {
+	var failed []error
+	for _, err := range e.Errs {
+		if err != nil {
+			failed = append(failed, err)
+		}
+	}
+	return failed
+}
+
+// SendAll sends every notification of ns, as SendCtx does, up to WithWorkers of them at the same
+// time. a failure doesn't stop the others. the results are aligned with ns, nil for the
+// notifications which failed, and the error is a *SendAllError if any did
+func (c *Client) SendAll(ctx context.Context, ns []*Notification, opts ...CallOption) ([]*SendResult, error) {
+	workers := c.asyncWorkers
+	if workers == 0 {
+		workers = defaultAsyncWorkers
+	}
+
+	results := make([]*SendResult, len(ns))
+	errs := make([]error, len(ns))
+	var wg sync.WaitGroup
+	slots := make(chan struct{}, workers)
+	for i, n := range ns {
+		slots <- struct{}{}
+		wg.Add(1)
+		go func(i int, n *Notification) {
+			defer func() {
+				<-slots
+				wg.Done()
+			}()
+			results[i], errs[i] = c.SendCtx(ctx, n, opts...)
+		}(i, n)
+	}
+	wg.Wait()
+
+	for _, err := range errs {
+		if err != nil {
+			return results, &SendAllError{Errs: errs}
+		}
+	}
+	return results, nil
+}
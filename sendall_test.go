@@ -0,0 +1,102 @@
+package engagespot
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSendAll(t *testing.T) {
+	c := NewEngagespotClient("A", "B")
+	c.httpClient.Transport = roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		var body struct {
+			Recipients []string `json:"recipients"`
+		}
+		json.NewDecoder(req.Body).Decode(&body)
+		switch body.Recipients[0] {
+		case "rejected":
+			return respondWith(500, `{"message":"boom"}`).RoundTrip(req)
+		case "unreachable":
+			return nil, errors.New("connection refused")
+		}
+		return respondWith(200, `{"id":"`+body.Recipients[0]+`"}`).RoundTrip(req)
+	})
+
+	invalid, _ := c.NewNotification("Hello")
+	ns := []*Notification{
+		newMessage(c, "a"),
+		newMessage(c, "rejected"),
+		newMessage(c, "b"),
+		invalid,
+		newMessage(c, "unreachable"),
+		newMessage(c, "c"),
+	}
+	results, err := c.SendAll(context.Background(), ns)
+	assert.Len(t, results, len(ns))
+	for i, id := range map[int]string{0: "a", 2: "b", 5: "c"} {
+		assert.Equal(t, id, results[i].Id)
+	}
+
+	var sendErr *SendAllError
+	assert.ErrorAs(t, err, &sendErr)
+	assert.Len(t, sendErr.Errs, len(ns))
+	for _, i := range []int{0, 2, 5} {
+		assert.NotNil(t, results[i], i)
+		assert.NoError(t, sendErr.Errs[i])
+	}
+	for _, i := range []int{1, 3, 4} {
+		assert.Nil(t, results[i])
+		assert.Error(t, sendErr.Errs[i])
+	}
+	assert.Len(t, sendErr.Unwrap(), 3)
+
+	var apiErr *APIError
+	assert.ErrorAs(t, err, &apiErr)
+	assert.Equal(t, 500, apiErr.StatusCode)
+	assert.Contains(t, err.Error(), "3 of 6 notifications failed")
+}
+
+func TestSendAllSucceeds(t *testing.T) {
+	c := NewEngagespotClient("A", "B")
+	c.httpClient.Transport = respondWith(200, `{"id":"n1"}`)
+
+	results, err := c.SendAll(context.Background(), []*Notification{newMessage(c), newMessage(c)})
+	assert.NoError(t, err)
+	assert.Len(t, results, 2)
+
+	results, err = c.SendAll(context.Background(), nil)
+	assert.NoError(t, err)
+	assert.Empty(t, results)
+}
+
+func TestSendAllBoundedParallelism(t *testing.T) {
+	var inFlight, peak int32
+	c := NewEngagespotClient("A", "B", WithWorkers(3))
+	c.httpClient.Transport = roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		now := atomic.AddInt32(&inFlight, 1)
+		for {
+			p := atomic.LoadInt32(&peak)
+			if now <= p || atomic.CompareAndSwapInt32(&peak, p, now) {
+				break
+			}
+		}
+		time.Sleep(5 * time.Millisecond)
+		atomic.AddInt32(&inFlight, -1)
+		return respondWith(200, `{}`).RoundTrip(req)
+	})
+
+	var ns []*Notification
+	for _, id := range recipientIds(20) {
+		ns = append(ns, newMessage(c, id))
+	}
+	_, err := c.SendAll(context.Background(), ns)
+	assert.NoError(t, err)
+	assert.LessOrEqual(t, peak, int32(3))
+	assert.Greater(t, peak, int32(1))
+}
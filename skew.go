@@ -0,0 +1,130 @@
+package engagespot
+
+import (
+	"net/http"
+	"sync"
+	"time"
+)
+
+// skew over which a warning is logged, unless another threshold is set with WithClockSkewWarning
+const defaultSkewThreshold = time.Minute
+
+// weight of each new sample in the smoothed skew
+const skewSmoothing = 0.2
+
+// responses taking longer than this to arrive are not measured, the time the server wrote the
+// Date header at is too uncertain
+const maxSkewSampleRoundTrip = 2 * time.Second
+
+// ClockSkewWarning is emitted when the clock skew measured by the client goes over the threshold
+// set with WithClockSkewWarning
+type ClockSkewWarning struct {
+	// server time minus local time, see ClockSkew
+	Skew      time.Duration
+	Threshold time.Duration
+}
+
+// smoothed offset between the clock of the API and the local one
+type skewTracker struct {
+	mu       sync.Mutex
+	skew     float64
+	measured bool
+	// the skew is over the threshold, so the warning isn't emitted again until it goes back under
+	over bool
+}
+
+// WithClockSkewWarning changes the clock skew over which a warning is emitted, one minute by
+// default. handler is called with the warning, which is logged if handler is nil. the warning is
+// emitted once each time the skew goes over threshold
+func WithClockSkewWarning(threshold time.Duration, handler func(ClockSkewWarning)) Option {
+	return func(c *Client) {
+		c.skewThreshold = threshold
+		c.skewHandler = handler
+	}
+}
+
+// WithSkewCorrection makes sends shift the time set with SetSendAt by the measured clock skew,
+// so a scheduled notification goes out when the local clock says it should, as seen from the API
+func WithSkewCorrection() Option {
+	return func(c *Client) {
+		c.declare(optSkewCorrection)
+		c.skewCorrection = true
+	}
+}
+
+// ClockSkew returns how far ahead the clock of the API is from the local clock, measured from the
+// Date header of responses and smoothed over time. a negative skew means the local clock is ahead.
+// 0 until a response has been measured
+func (c *Client) ClockSkew() time.Duration {
+	c.skew.mu.Lock()
+	defer c.skew.mu.Unlock()
+	return time.Duration(c.skew.skew)
+}
+
+// measure the skew from the Date header of res, answering a request sent at started. the server
+// wrote the header some time during the round trip, its midpoint is used as the local time
+func (c *Client) observeDate(res *http.Response, started, received time.Time) {
+	header := res.Header.Get("Date")
+	if header == "" {
+		return
+	}
+	date, err := http.ParseTime(header)
+	if err != nil {
+		return
+	}
+	roundTrip := received.Sub(started)
+	if roundTrip > maxSkewSampleRoundTrip {
+		return
+	}
+	local := started.Add(roundTrip / 2)
+	sample := float64(date.Sub(local))
+
+	c.skew.mu.Lock()
+	if c.skew.measured {
+		c.skew.skew += skewSmoothing * (sample - c.skew.skew)
+	} else {
+		c.skew.skew = sample
+		c.skew.measured = true
+	}
+	skew := time.Duration(c.skew.skew)
+
+	threshold := c.skewThreshold
+	if threshold <= 0 {
+		threshold = defaultSkewThreshold
+	}
+	over := skew > threshold || skew < -threshold
+	crossed := over && !c.skew.over
+	c.skew.over = over
+	c.skew.mu.Unlock()
+
+	if !crossed {
+		return
+	}
+	warning := ClockSkewWarning{Skew: skew, Threshold: threshold}
+	if c.skewHandler != nil {
+		c.skewHandler(warning)
+		return
+	}
+	c.log().Warn("engagespot: local clock is skewed from the API", "skew", skew, "threshold", threshold)
+}
+
+// n with its send and expiry times corrected by the measured skew, if enabled
+func (c *Client) correctSendAt(n *Notification) *Notification {
+	if !c.skewCorrection || (n.SendAt == nil && n.ExpiresAt == nil) {
+		return n
+	}
+	skew := c.ClockSkew()
+	if skew == 0 {
+		return n
+	}
+	corrected := *n
+	if n.SendAt != nil {
+		at := n.SendAt.Add(skew)
+		corrected.SendAt = &at
+	}
+	if n.ExpiresAt != nil {
+		at := n.ExpiresAt.Add(skew)
+		corrected.ExpiresAt = &at
+	}
+	return &corrected
+}
@@ -0,0 +1,199 @@
+package engagespot
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// fake API whose clock is skew ahead of clock, taking roundTrip to answer. responses have no Date
+// header when skew is nil. bodies of notifications sent are recorded
+type skewedServer struct {
+	clock     *fakeClock
+	skew      *time.Duration
+	roundTrip time.Duration
+	status    int
+	bodies    []map[string]interface{}
+}
+
+func (s *skewedServer) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Body != nil {
+		var body map[string]interface{}
+		json.NewDecoder(req.Body).Decode(&body)
+		s.bodies = append(s.bodies, body)
+	}
+	s.clock.Advance(s.roundTrip / 2)
+	header := http.Header{"Content-Type": {"application/json"}}
+	if s.skew != nil {
+		header.Set("Date", s.clock.Now().Add(*s.skew).UTC().Format(http.TimeFormat))
+	}
+	s.clock.Advance(s.roundTrip / 2)
+
+	status := s.status
+	if status == 0 {
+		status = 200
+	}
+	return &http.Response{StatusCode: status, Header: header, Body: io.NopCloser(strings.NewReader(`{}`)), Request: req}, nil
+}
+
+func skewed(skew time.Duration) *time.Duration {
+	return &skew
+}
+
+func skewClient(s *skewedServer, opts ...Option) *Client {
+	c := NewEngagespotClient("A", "B", opts...)
+	c.httpClient.Transport = s
+	c.now = s.clock.Now
+	return c
+}
+
+func TestClockSkewMeasured(t *testing.T) {
+	s := &skewedServer{clock: newFakeClock(), skew: skewed(-time.Hour)}
+	c := skewClient(s, WithClockSkewWarning(time.Hour, nil))
+	assert.Equal(t, time.Duration(0), c.ClockSkew())
+
+	_, err := newMessage(c).Send()
+	assert.NoError(t, err)
+	assert.Equal(t, -time.Hour, c.ClockSkew())
+
+	// a single sample only moves the smoothed skew part of the way
+	s.skew = skewed(-time.Hour + 10*time.Second)
+	newMessage(c).Send()
+	assert.Equal(t, -time.Hour+2*time.Second, c.ClockSkew())
+}
+
+func TestClockSkewIgnoredResponses(t *testing.T) {
+	s := &skewedServer{clock: newFakeClock(), skew: skewed(time.Minute)}
+	c := skewClient(s, WithClockSkewWarning(time.Hour, nil))
+	newMessage(c).Send()
+	assert.Equal(t, time.Minute, c.ClockSkew())
+
+	// without Date header
+	s.skew = nil
+	newMessage(c).Send()
+	assert.Equal(t, time.Minute, c.ClockSkew())
+
+	// too slow to tell when the header was written
+	s.skew, s.roundTrip = skewed(time.Hour), 5*time.Second
+	newMessage(c).Send()
+	assert.Equal(t, time.Minute, c.ClockSkew())
+}
+
+func TestClockSkewIgnoresRetries(t *testing.T) {
+	var waits []time.Duration
+	clock := newFakeClock()
+	s := &skewedServer{clock: clock, skew: skewed(time.Minute)}
+	c := retryingClient(clock, &waits, WithClockSkewWarning(time.Hour, nil))
+	c.httpClient.Transport = roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		res, err := s.RoundTrip(req)
+		if len(s.bodies) == 1 {
+			res.StatusCode = 503
+		}
+		// retried responses claim a wildly different time
+		*s.skew = time.Hour
+		return res, err
+	})
+
+	_, err := newMessage(c).Send()
+	assert.NoError(t, err)
+	assert.Len(t, s.bodies, 2)
+	assert.Equal(t, time.Minute, c.ClockSkew())
+}
+
+func TestClockSkewWarning(t *testing.T) {
+	var warnings []ClockSkewWarning
+	s := &skewedServer{clock: newFakeClock(), skew: skewed(2 * time.Minute)}
+	c := skewClient(s, WithClockSkewWarning(time.Minute, func(w ClockSkewWarning) {
+		warnings = append(warnings, w)
+	}))
+
+	newMessage(c).Send()
+	newMessage(c).Send()
+	assert.Equal(t, []ClockSkewWarning{{Skew: 2 * time.Minute, Threshold: time.Minute}}, warnings)
+
+	// back under the threshold, then over again
+	s.skew = skewed(0)
+	for i := 0; i < 10; i++ {
+		newMessage(c).Send()
+	}
+	assert.Len(t, warnings, 1)
+	s.skew = skewed(-10 * time.Minute)
+	newMessage(c).Send()
+	assert.Len(t, warnings, 2)
+	assert.Less(t, warnings[1].Skew, -time.Minute)
+}
+
+func scheduled(c *Client, at time.Time) *Notification {
+	n := newMessage(c)
+	_, err := n.SetSendAt(at)
+	if err != nil {
+		panic(err)
+	}
+	return n
+}
+
+func TestSkewCorrection(t *testing.T) {
+	clock := newFakeClock()
+	s := &skewedServer{clock: clock, skew: skewed(time.Hour)}
+	c := skewClient(s, WithSkewCorrection(), WithClockSkewWarning(2*time.Hour, nil))
+	at := clock.Now().Add(10 * time.Minute)
+
+	// nothing measured yet, sent as is
+	_, err := scheduled(c, at).Send()
+	assert.NoError(t, err)
+	assert.Equal(t, "2022-01-01T00:10:00Z", s.bodies[0]["sendAt"])
+
+	n := scheduled(c, at)
+	_, err = n.Send()
+	assert.NoError(t, err)
+	assert.Equal(t, "2022-01-01T01:10:00Z", s.bodies[1]["sendAt"])
+	assert.Equal(t, at, *n.SendAt)
+}
+
+func TestSkewCorrectionDisabled(t *testing.T) {
+	clock := newFakeClock()
+	s := &skewedServer{clock: clock, skew: skewed(time.Hour)}
+	c := skewClient(s, WithClockSkewWarning(2*time.Hour, nil))
+	at := clock.Now().Add(10 * time.Minute)
+
+	scheduled(c, at).Send()
+	scheduled(c, at).Send()
+	assert.Equal(t, "2022-01-01T00:10:00Z", s.bodies[1]["sendAt"])
+
+	_, err := newMessage(c).SetSendAt(time.Time{})
+	assert.EqualError(t, err, "zero send time")
+}
+
+func TestSendAtWorkflowWarning(t *testing.T) {
+	c := NewEngagespotClient("A", "B", WithWorkflowMapping(map[string]string{"orders": "order-shipped"}))
+	c.httpClient.Transport = respondWith(200, `{}`)
+	n := orderShipped(c, "orders")
+	n.SetSendAt(time.Now().Add(time.Hour))
+
+	_, err := n.Send()
+	assert.NoError(t, err)
+	assert.Contains(t, n.Warnings(), Warning{Field: "sendAt", Message: "workflow triggers can't be scheduled, the send time was dropped"})
+}
+
+func TestSendAtInThePast(t *testing.T) {
+	clock := newFakeClock()
+	c := NewEngagespotClient("A", "B")
+	c.now = clock.Now
+
+	n := newMessage(c)
+	for _, at := range []time.Time{clock.Now(), clock.Now().Add(-time.Second)} {
+		_, err := n.SetSendAt(at)
+		assert.EqualError(t, err, "send time "+at.Format(time.RFC3339)+" is not in the future")
+	}
+	assert.Nil(t, n.SendAt)
+
+	at := clock.Now().Add(time.Second).In(time.FixedZone("IST", 5*3600+1800))
+	_, err := n.SetSendAt(at)
+	assert.NoError(t, err)
+	assert.Equal(t, "2022-01-01T05:30:01+05:30", encodedPayload(t, n)["sendAt"])
+}
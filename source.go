@@ -0,0 +1,224 @@
+package engagespot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+var errMixedRecipients = errors.New("recipient source can't be mixed with added recipients")
+
+// RecipientSource is an iterator over recipients, like a database cursor. it returns false once
+// there are no more recipients
+type RecipientSource func(ctx context.Context) (string, bool, error)
+
+// PartialSendError is returned when a send with a recipient source stops midway, either because
+// the source failed or a chunk couldn't be sent. Submitted is the number of recipients already
+// sent to the API, which aren't sent again by a retry with a source resuming after them
+type PartialSendError struct {
+	Submitted int
+	Err       error
+}
+
+func (e *PartialSendError) Error() string {
+	return fmt.Sprintf("engagespot: send stopped after %d recipients: %s", e.Submitted, e.Err)
+}
+
+func (e *PartialSendError) Unwrap() error {
+	return e.Err
+}
+
+// SetRecipientSource can be used to pull recipients lazily from next at send instead of adding
+// them upfront, so they are never all held in memory. recipients are sent in chunks of
+// Defaults.ChunkSize, or as tuned by WithAdaptiveChunkSize, each going through normalization,
+// opt-out filtering and channel rules like added recipients do. a source can't be mixed with
+// AddRecipient
+func (n *Notification) SetRecipientSource(next RecipientSource) (*Notification, error) {
+	if next == nil {
+		return nil, invalidField("recipientSource", next, errors.New("nil recipient source"))
+	}
+	if len(n.Recipients) > 0 {
+		return nil, invalidField("recipientSource", next, errMixedRecipients)
+	}
+	n.recipientSource = next
+	return n, nil
+}
+
+// pull up to size recipients from the source of n. the bool is false once the source is exhausted
+func (n *Notification) nextRecipients(ctx context.Context, size int) ([]string, bool, error) {
+	var recipients []string
+	for len(recipients) < size {
+		recipient, ok, err := n.recipientSource(ctx)
+		if err != nil {
+			return nil, false, err
+		}
+		if !ok {
+			return recipients, false, nil
+		}
+		recipient, err = n.sanitizeSourceRecipient(recipient)
+		if err != nil {
+			return nil, false, err
+		}
+		recipient = n.Client.NormalizeUserID(recipient)
+		if recipient == "" {
+			return nil, false, errors.New("empty recipient string")
+		}
+		recipients = append(recipients, recipient)
+	}
+	return recipients, true, nil
+}
+
+// send n in chunks, pulled from its recipient source or cut from its recipients for a bulk send.
+// the response of the last chunk is returned, the opt-out report of every chunk is added to out
+func (c *Client) sendStreamed(ctx context.Context, n *Notification, out *sendOutcome) (*http.Response, error) {
+	base := c.defaults.ChunkSize
+	if base <= 0 {
+		base = DefaultConfig().ChunkSize
+	}
+	if batch, ok := ctx.Value(batchSizeKey{}).(int); ok {
+		if batch < 1 || batch > MaxRecipients {
+			return nil, fmt.Errorf("%w: batch size %d is not between 1 and %d", ErrRecipientLimit, batch, MaxRecipients)
+		}
+		base = batch
+	}
+	size := base
+	tuner := c.chunkTuner
+	key := ""
+	if tuner != nil {
+		key = tuner.key(n.Category)
+		size = tuner.size(key, base)
+	}
+
+	pull := n.nextRecipients
+	offset := 0
+	if n.recipientSource != nil {
+		n.sanitization, n.sourceCorrections = nil, 0
+	} else {
+		pull = func(ctx context.Context, size int) ([]string, bool, error) {
+			end := offset + size
+			if end > len(n.Recipients) {
+				end = len(n.Recipients)
+			}
+			recipients := n.Recipients[offset:end]
+			offset = end
+			return recipients, offset < len(n.Recipients), nil
+		}
+	}
+	// recipients of chunks rejected as too large, sent again before pulling more
+	var resend []string
+	if tuner != nil {
+		pullSource, sourceMore := pull, true
+		pull = func(ctx context.Context, size int) ([]string, bool, error) {
+			if len(resend) >= size {
+				recipients := resend[:size:size]
+				resend = resend[size:]
+				return recipients, true, nil
+			}
+			recipients := resend
+			resend = nil
+			if !sourceMore {
+				return recipients, false, nil
+			}
+			pulledMore, more, err := pullSource(ctx, size-len(recipients))
+			sourceMore = more
+			return append(recipients, pulledMore...), more, err
+		}
+	}
+	bulk := bulkControlFrom(ctx)
+
+	var res *http.Response
+	// first chunk failure of a bulk send, which goes on with the next chunks
+	var failed error
+	submitted, pulled := 0, 0
+	stop := func(err error) (*http.Response, error) {
+		if res != nil {
+			discard(res)
+		}
+		return nil, &PartialSendError{Submitted: submitted, Err: err}
+	}
+
+	for more := true; more; {
+		if bulk.cancelled() {
+			if n.recipientSource == nil {
+				bulk.unsent(append(append([]string{}, resend...), n.Recipients[offset:]...))
+			} else if len(resend) > 0 {
+				bulk.unsent(resend)
+			}
+			return stop(ErrSendCancelled)
+		}
+
+		recipients, next, err := pull(ctx, size)
+		if err != nil {
+			return stop(err)
+		}
+		more = next
+		if len(recipients) == 0 {
+			break
+		}
+		start := pulled
+		pulled += len(recipients)
+
+		chunk := *n
+		chunk.Recipients = recipients
+		chunk.chunked = true
+		chunk.chunkStart, chunk.chunkEnd = start, pulled
+		chunkStarted := c.now()
+		chunkRes, suppression, err := c.deliver(ctx, &chunk, submitted)
+		// a chunk over the payload limit is rejected locally, and split like one the API rejected
+		tooLarge := errors.Is(err, ErrPayloadTooLarge) || (err == nil && chunkRes.StatusCode == http.StatusRequestEntityTooLarge)
+		if tuner != nil && tooLarge && tuner.shrink(key, base, len(recipients)) {
+			if chunkRes != nil {
+				discard(chunkRes)
+			}
+			resend = append(append([]string{}, recipients...), resend...)
+			pulled -= len(recipients)
+			size = tuner.size(key, base)
+			more = true
+			continue
+		}
+
+		out.suppression.Suppressed = append(out.suppression.Suppressed, suppression.Suppressed...)
+		out.suppression.CacheHits += suppression.CacheHits
+		out.suppression.Lookups += suppression.Lookups
+		out.suppression.LookupErrors += suppression.LookupErrors
+
+		if errors.Is(err, ErrAllRecipientsSuppressed) {
+			continue
+		}
+		if err == nil && (chunkRes.StatusCode < 200 || chunkRes.StatusCode > 299) {
+			err = decodeInto(endpointSendNotification, chunkRes, nil)
+		}
+		bulk.record(recipients, len(recipients)-len(suppression.Suppressed), chunkRes, err)
+		if err != nil {
+			if bulk == nil || !chunkFailed(err) || ctx.Err() != nil {
+				return stop(err)
+			}
+			if failed == nil {
+				failed = err
+			}
+			continue
+		}
+
+		submitted += len(recipients) - len(suppression.Suppressed)
+		if tuner != nil {
+			tuner.success(key, base, c.now().Sub(chunkStarted))
+			size = tuner.size(key, base)
+		}
+		if res != nil {
+			discard(res)
+		}
+		res = chunkRes
+	}
+
+	if failed != nil {
+		return stop(failed)
+	}
+	if res == nil {
+		if pulled == 0 {
+			return nil, errors.New("not enough recipients")
+		}
+		return nil, ErrAllRecipientsSuppressed
+	}
+	return res, nil
+}
@@ -0,0 +1,111 @@
+package engagespot
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// source over ids, failing with err once failAt ids were returned. failAt < 0 never fails
+func sliceSource(ids []string, failAt int, err error) RecipientSource {
+	i := 0
+	return func(ctx context.Context) (string, bool, error) {
+		if i == failAt {
+			return "", false, err
+		}
+		if i == len(ids) {
+			return "", false, nil
+		}
+		i++
+		return ids[i-1], true, nil
+	}
+}
+
+func chunkedClient(size int, opts ...Option) *Client {
+	d := DefaultConfig()
+	d.ChunkSize = size
+	return NewEngagespotClient("A", "B", append(opts, WithDefaults(d))...)
+}
+
+func TestRecipientSourceChunks(t *testing.T) {
+	var bodies []map[string]interface{}
+	c := chunkedClient(2)
+	c.httpClient.Transport = recordBodies(&bodies)
+
+	n, _ := c.NewNotification("Hello")
+	_, err := n.SetRecipientSource(sliceSource([]string{"a", "b", "c", "d", "e"}, -1, nil))
+	assert.NoError(t, err)
+
+	res, err := n.Send()
+	assert.NoError(t, err)
+	assert.Equal(t, 200, res.StatusCode)
+	assert.Len(t, bodies, 3)
+	for i, want := range [][]interface{}{{"a", "b"}, {"c", "d"}, {"e"}} {
+		assert.Equal(t, want, bodies[i]["recipients"])
+	}
+	assert.Empty(t, n.Recipients)
+}
+
+func TestRecipientSourceError(t *testing.T) {
+	var bodies []map[string]interface{}
+	c := chunkedClient(2)
+	c.httpClient.Transport = recordBodies(&bodies)
+
+	cursorErr := errors.New("connection reset")
+	n, _ := c.NewNotification("Hello")
+	n.SetRecipientSource(sliceSource([]string{"a", "b", "c", "d", "e"}, 3, cursorErr))
+
+	res, err := n.Send()
+	assert.Nil(t, res)
+	assert.ErrorIs(t, err, cursorErr)
+
+	var partial *PartialSendError
+	assert.True(t, errors.As(err, &partial))
+	assert.Equal(t, 2, partial.Submitted)
+	assert.Len(t, bodies, 1)
+}
+
+func TestRecipientSourceChunkFailure(t *testing.T) {
+	calls := 0
+	c := chunkedClient(2)
+	c.httpClient.Transport = roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		calls++
+		if calls == 3 {
+			return respondWith(500, `{}`).RoundTrip(req)
+		}
+		return respondWith(200, `{}`).RoundTrip(req)
+	})
+
+	n, _ := c.NewNotification("Hello")
+	n.SetRecipientSource(sliceSource([]string{"a", "b", "c", "d", "e", "f"}, -1, nil))
+
+	_, err := n.Send()
+	var partial *PartialSendError
+	assert.True(t, errors.As(err, &partial))
+	assert.Equal(t, 4, partial.Submitted)
+}
+
+func TestRecipientSourceMixed(t *testing.T) {
+	n := newTestNotification(t)
+	n.AddRecipient("a")
+	_, err := n.SetRecipientSource(sliceSource(nil, -1, nil))
+	assert.Error(t, err)
+
+	n = newTestNotification(t)
+	n.SetRecipientSource(sliceSource(nil, -1, nil))
+	_, err = n.AddRecipient("a")
+	assert.Error(t, err)
+}
+
+func TestRecipientSourceEmpty(t *testing.T) {
+	c := chunkedClient(2)
+	c.httpClient.Transport = noNetwork(t)
+
+	n, _ := c.NewNotification("Hello")
+	n.SetRecipientSource(sliceSource(nil, -1, nil))
+	_, err := n.Send()
+	assert.EqualError(t, err, "not enough recipients")
+}
@@ -0,0 +1,122 @@
+package engagespot
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"time"
+)
+
+// interval between status checks while waiting for a queued notification to be processed
+const defaultPollInterval = time.Second
+
+// SendState tells how far a sent notification got on the API side
+type SendState int
+
+const (
+	// StateUnknown is the state of notifications whose response couldn't be interpreted
+	StateUnknown SendState = iota
+	// StateAccepted means the API queued the notification (202) but hasn't processed it yet
+	StateAccepted
+	// StateDelivered means the API processed the notification
+	StateDelivered
+)
+
+func (s SendState) String() string {
+	switch s {
+	case StateAccepted:
+		return "accepted"
+	case StateDelivered:
+		return "delivered"
+	default:
+		return "unknown"
+	}
+}
+
+// status of a notification as reported by the API, both in the response of a send and by
+// `GET notifications/{notificationId}`
+type notificationStatus struct {
+	Response
+	Id     string `json:"id"`
+	Status string `json:"status"`
+}
+
+// derive the state from the status code, refined by the status in the body when there is one
+func (s notificationStatus) state(statusCode int) SendState {
+	switch s.Status {
+	case "queued", "pending":
+		return StateAccepted
+	case "processed", "sent":
+		return StateDelivered
+	}
+	switch {
+	case statusCode == http.StatusAccepted:
+		return StateAccepted
+	case statusCode >= 200 && statusCode <= 299:
+		return StateDelivered
+	}
+	return StateUnknown
+}
+
+// read the status of a notification from the response of a send. the body is put back so the
+// caller can still read it
+func readStatus(res *http.Response) notificationStatus {
+	var s notificationStatus
+	body, err := io.ReadAll(res.Body)
+	res.Body.Close()
+	res.Body = io.NopCloser(bytes.NewReader(body))
+	if err == nil {
+		json.Unmarshal(body, &s)
+	}
+	return s
+}
+
+// WaitForProcessing makes Send wait, up to max, for the API to process the notification when it
+// only got queued (202). the state reached is available from State
+func (n *Notification) WaitForProcessing(max time.Duration) *Notification {
+	n.waitForProcessing = max
+	return n
+}
+
+// State can be used to get the state reached by the last send of the notification, see
+// SendResult for the state of a given send
+func (n *Notification) State() SendState {
+	return n.last.get().state
+}
+
+// poll the status of a queued notification until it leaves the accepted state or max elapses.
+// the last known state is returned along with an error if polling failed
+func (c *Client) pollState(ctx context.Context, id string, max time.Duration) (SendState, error) {
+	interval := c.pollInterval
+	if interval <= 0 {
+		interval = defaultPollInterval
+	}
+	deadline := c.now().Add(max)
+
+	for {
+		wait := interval
+		if remaining := deadline.Sub(c.now()); remaining < wait {
+			wait = remaining
+		}
+		if wait <= 0 {
+			return StateAccepted, nil
+		}
+		if err := c.sleep(ctx, wait); err != nil {
+			return StateAccepted, err
+		}
+
+		req, err := c.newRequestContext(ctx, endpointGetNotificationStatus, nil, id)
+		if err != nil {
+			return StateAccepted, err
+		}
+		var s notificationStatus
+		if err := c.callJSON(endpointGetNotificationStatus, req, &s); err != nil {
+			return StateAccepted, err
+		}
+		if state := s.state(http.StatusOK); state != StateAccepted {
+			return state, nil
+		}
+	}
+}
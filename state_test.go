@@ -0,0 +1,123 @@
+package engagespot
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// fake API queueing every notification, which becomes processed after `polls` status checks
+func queueingServer(status int, polls int32) (*httptest.Server, *int32) {
+	var checks int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPost {
+			w.WriteHeader(status)
+			if status == http.StatusAccepted {
+				io.WriteString(w, `{"id":"n1","status":"queued"}`)
+			} else {
+				io.WriteString(w, `{"id":"n1"}`)
+			}
+			return
+		}
+		if r.URL.Path != "/v3/notifications/n1" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		if atomic.AddInt32(&checks, 1) < polls {
+			io.WriteString(w, `{"id":"n1","status":"queued"}`)
+			return
+		}
+		io.WriteString(w, `{"id":"n1","status":"processed"}`)
+	}))
+	return server, &checks
+}
+
+func sendState(t *testing.T, server *httptest.Server, wait time.Duration) (*Notification, *http.Response) {
+	c := clientFor(server.URL+"/v3/", time.Second)
+	c.pollInterval = time.Millisecond
+
+	n, _ := c.NewNotification("Hello")
+	n.AddRecipient("hello@example.com")
+	if wait > 0 {
+		n.WaitForProcessing(wait)
+	}
+	res, err := n.SendRaw()
+	assert.NoError(t, err)
+	return n, res
+}
+
+func TestSendStateDelivered(t *testing.T) {
+	server, checks := queueingServer(http.StatusOK, 0)
+	defer server.Close()
+
+	n, res := sendState(t, server, time.Second)
+	assert.Equal(t, StateDelivered, n.State())
+	assert.Equal(t, int32(0), *checks)
+
+	// the body is still readable after the state has been derived from it
+	body, _ := io.ReadAll(res.Body)
+	assert.Equal(t, `{"id":"n1"}`, string(body))
+}
+
+func TestSendStateAcceptedWithoutWait(t *testing.T) {
+	server, checks := queueingServer(http.StatusAccepted, 1)
+	defer server.Close()
+
+	n, _ := sendState(t, server, 0)
+	assert.Equal(t, StateAccepted, n.State())
+	assert.Equal(t, "accepted", n.State().String())
+	assert.Equal(t, int32(0), *checks)
+}
+
+func TestSendStateAcceptedWithWait(t *testing.T) {
+	server, checks := queueingServer(http.StatusAccepted, 3)
+	defer server.Close()
+
+	n, _ := sendState(t, server, time.Second)
+	assert.Equal(t, StateDelivered, n.State())
+	assert.Equal(t, int32(3), *checks)
+}
+
+func TestSendStateWaitExpires(t *testing.T) {
+	server, _ := queueingServer(http.StatusAccepted, 1000000)
+	defer server.Close()
+
+	n, _ := sendState(t, server, 20*time.Millisecond)
+	assert.Equal(t, StateAccepted, n.State())
+}
+
+func TestSendStatePollsOnClientClock(t *testing.T) {
+	server, checks := queueingServer(http.StatusAccepted, 3)
+	defer server.Close()
+
+	var waits []time.Duration
+	clock := newFakeClock()
+	c := clientFor(server.URL+"/v3/", time.Second)
+	c.now = clock.Now
+	c.sleep = func(ctx context.Context, d time.Duration) error {
+		waits = append(waits, d)
+		clock.Advance(d)
+		return nil
+	}
+	c.pollInterval = time.Minute
+
+	n, _ := c.NewNotification("Hello")
+	n.AddRecipient("hello@example.com")
+	n.WaitForProcessing(time.Hour)
+	_, err := n.Send()
+	assert.NoError(t, err)
+	assert.Equal(t, StateDelivered, n.State())
+	assert.Equal(t, int32(3), *checks)
+	assert.Equal(t, []time.Duration{time.Minute, time.Minute, time.Minute}, waits)
+}
+
+func TestSendStateUnknown(t *testing.T) {
+	assert.Equal(t, StateUnknown, notificationStatus{}.state(http.StatusBadRequest))
+	assert.Equal(t, StateAccepted, notificationStatus{Status: "queued"}.state(http.StatusOK))
+}
@@ -0,0 +1,151 @@
+package engagespot
+
+import (
+	"math"
+	"sort"
+	"sync"
+	"time"
+)
+
+// OtherCategory is the Stats bucket of sends to categories not allowed by WithCategoryStats,
+// including sends without a category
+const OtherCategory = "other"
+
+// relative accuracy of latency percentiles. each bucket of the sketch covers latencies within 1%
+// of each other, so memory only grows with the log of the latency range, never with the number of
+// sends
+const sketchAccuracy = 0.01
+
+var sketchGamma = (1 + sketchAccuracy) / (1 - sketchAccuracy)
+
+// streaming quantile sketch of latencies, bucketed on a logarithmic scale
+type latencySketch struct {
+	buckets map[int]uint64
+	// latencies too small to be bucketed
+	zero  uint64
+	count uint64
+}
+
+func (s *latencySketch) add(d time.Duration) {
+	s.count++
+	if d < time.Microsecond {
+		s.zero++
+		return
+	}
+	if s.buckets == nil {
+		s.buckets = map[int]uint64{}
+	}
+	s.buckets[int(math.Ceil(math.Log(float64(d))/math.Log(sketchGamma)))]++
+}
+
+// estimate of the q quantile, 0 if nothing was added
+func (s *latencySketch) quantile(q float64) time.Duration {
+	if s.count == 0 {
+		return 0
+	}
+	rank := uint64(q * float64(s.count-1))
+	if rank < s.zero {
+		return 0
+	}
+	seen := s.zero
+
+	keys := make([]int, 0, len(s.buckets))
+	for k := range s.buckets {
+		keys = append(keys, k)
+	}
+	sort.Ints(keys)
+	for _, k := range keys {
+		seen += s.buckets[k]
+		if seen > rank {
+			return time.Duration(2 * math.Pow(sketchGamma, float64(k)) / (sketchGamma + 1))
+		}
+	}
+	return 0
+}
+
+// CategoryStats are the send counters of a single category. latencies are of successful sends
+// only, from the first request to the API accepting the notification
+type CategoryStats struct {
+	Sends    uint64
+	Failures uint64
+	P50      time.Duration
+	P95      time.Duration
+	P99      time.Duration
+}
+
+// Stats is a snapshot of send counters, keyed by category. see WithCategoryStats
+type Stats struct {
+	Categories map[string]CategoryStats
+	// chunk sizes learned by WithAdaptiveChunkSize, by category when tuned per category, under ""
+	// otherwise. nil if adaptive chunk size is disabled
+	ChunkSizes map[string]int
+}
+
+type categoryCounters struct {
+	sends    uint64
+	failures uint64
+	latency  latencySketch
+}
+
+type sendStats struct {
+	mu         sync.Mutex
+	categories map[string]*categoryCounters
+}
+
+// WithCategoryStats can be used to keep separate send counters, see Stats, for each of the given
+// categories. sends to other categories are counted under OtherCategory, which keeps the number of
+// counters bounded whatever categories are sent to
+func WithCategoryStats(categories ...string) Option {
+	return func(c *Client) {
+		c.statsCategories = map[string]bool{}
+		for _, category := range categories {
+			c.statsCategories[category] = true
+		}
+	}
+}
+
+// record the outcome of a single send
+func (c *Client) recordSend(category string, latency time.Duration, failed bool) {
+	if !c.statsCategories[category] {
+		category = OtherCategory
+	}
+
+	c.stats.mu.Lock()
+	defer c.stats.mu.Unlock()
+	if c.stats.categories == nil {
+		c.stats.categories = map[string]*categoryCounters{}
+	}
+	counters, ok := c.stats.categories[category]
+	if !ok {
+		counters = &categoryCounters{}
+		c.stats.categories[category] = counters
+	}
+
+	counters.sends++
+	if failed {
+		counters.failures++
+		return
+	}
+	counters.latency.add(latency)
+}
+
+// Stats returns a snapshot of send counters. categories which weren't sent to yet are left out
+func (c *Client) Stats() Stats {
+	c.stats.mu.Lock()
+	defer c.stats.mu.Unlock()
+
+	stats := Stats{Categories: map[string]CategoryStats{}}
+	if c.chunkTuner != nil {
+		stats.ChunkSizes = c.chunkTuner.snapshot()
+	}
+	for category, counters := range c.stats.categories {
+		stats.Categories[category] = CategoryStats{
+			Sends:    counters.sends,
+			Failures: counters.failures,
+			P50:      counters.latency.quantile(0.50),
+			P95:      counters.latency.quantile(0.95),
+			P99:      counters.latency.quantile(0.99),
+		}
+	}
+	return stats
+}
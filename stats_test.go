@@ -0,0 +1,91 @@
+package engagespot
+
+import (
+	"math/rand"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// client whose API takes delay, as measured by its fake clock, to answer each send
+func delayedClient(status int, delay func() time.Duration, opts ...Option) *Client {
+	clock := newFakeClock()
+	c := NewEngagespotClient("A", "B", opts...)
+	c.now = clock.Now
+	c.httpClient.Transport = roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		clock.Advance(delay())
+		return respondWith(status, `{}`).RoundTrip(req)
+	})
+	return c
+}
+
+func sendCategory(t *testing.T, c *Client, category string) {
+	n, _ := c.NewNotification("Order shipped")
+	n.AddRecipient("a")
+	if category != "" {
+		n.SetCategory(category)
+	}
+	n.Send()
+}
+
+func assertWithin(t *testing.T, want, got time.Duration) {
+	assert.InDelta(t, float64(want), float64(got), float64(want)*0.02, "want %s, got %s", want, got)
+}
+
+func TestCategoryLatencyPercentiles(t *testing.T) {
+	delays := rand.New(rand.NewSource(1)).Perm(1000)
+	i := 0
+	c := delayedClient(200, func() time.Duration {
+		d := time.Duration(delays[i]+1) * time.Millisecond
+		i++
+		return d
+	}, WithCategoryStats("order"))
+
+	for range delays {
+		sendCategory(t, c, "order")
+	}
+
+	stats := c.Stats().Categories["order"]
+	assert.Equal(t, uint64(1000), stats.Sends)
+	assert.Equal(t, uint64(0), stats.Failures)
+	assertWithin(t, 500*time.Millisecond, stats.P50)
+	assertWithin(t, 950*time.Millisecond, stats.P95)
+	assertWithin(t, 990*time.Millisecond, stats.P99)
+}
+
+func TestCategoryStatsOtherBucket(t *testing.T) {
+	c := delayedClient(200, func() time.Duration { return 2 * time.Second }, WithCategoryStats("order"))
+	sendCategory(t, c, "order")
+	sendCategory(t, c, "promo")
+	sendCategory(t, c, "digest")
+	sendCategory(t, c, "")
+
+	stats := c.Stats()
+	assert.Len(t, stats.Categories, 2)
+	assert.Equal(t, uint64(1), stats.Categories["order"].Sends)
+	assert.Equal(t, uint64(3), stats.Categories[OtherCategory].Sends)
+	assertWithin(t, 2*time.Second, stats.Categories[OtherCategory].P99)
+}
+
+func TestCategoryStatsFailures(t *testing.T) {
+	c := delayedClient(500, func() time.Duration { return time.Second }, WithCategoryStats("order"))
+	sendCategory(t, c, "order")
+	sendCategory(t, c, "order")
+
+	stats := c.Stats().Categories["order"]
+	assert.Equal(t, uint64(2), stats.Sends)
+	assert.Equal(t, uint64(2), stats.Failures)
+	assert.Zero(t, stats.P50)
+}
+
+func TestLatencySketchBounded(t *testing.T) {
+	var s latencySketch
+	for i := 0; i < 100000; i++ {
+		s.add(time.Duration(i) * time.Millisecond)
+	}
+	// 1ms to 100s at 1% accuracy
+	assert.Less(t, len(s.buckets), 700)
+	assertWithin(t, 50*time.Second, s.quantile(0.5))
+}
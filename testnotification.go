@@ -0,0 +1,100 @@
+package engagespot
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"runtime/debug"
+	"strings"
+)
+
+// TestNotificationCategory is the category of notifications sent by SendTestNotification, so they
+// can be filtered out of logs and analytics. don't use it for anything else
+const TestNotificationCategory = "engagespot-sdk-test"
+
+const modulePath = "github.com/ssiyad/engagespot-go"
+
+// version of the SDK, as recorded in the build info of the binary
+func sdkVersion() string {
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return "unknown"
+	}
+	if info.Main.Path == modulePath {
+		return info.Main.Version
+	}
+	for _, dep := range info.Deps {
+		if dep.Path == modulePath {
+			return dep.Version
+		}
+	}
+	return "unknown"
+}
+
+// SendTestNotification can be used to check the configuration of the app and the client, by
+// sending a diagnostic notification to recipient on every channel enabled for the app. enabled
+// channels come from the cache of WithChannelValidation when enabled, from GetAppInfo otherwise.
+// the notification describes the SDK version and the client configuration, never credentials
+func (c *Client) SendTestNotification(ctx context.Context, recipient string) (*SendResult, error) {
+	if c.config.readOnly {
+		return nil, ErrReadOnlyClient
+	}
+
+	var channels []string
+	if c.channelValidation != nil {
+		enabled, err := c.enabledChannels()
+		if err != nil {
+			return nil, fmt.Errorf("test notification: %w", err)
+		}
+		channels = enabled
+	} else {
+		info, err := c.GetAppInfo()
+		if err != nil {
+			return nil, fmt.Errorf("test notification: %w", err)
+		}
+		channels = info.EnabledChannels
+	}
+
+	n, err := c.newTestNotification(channels)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := n.AddRecipient(recipient); err != nil {
+		return nil, err
+	}
+	return c.SendCtx(ctx, n)
+}
+
+// diagnostic notification sent on channels
+func (c *Client) newTestNotification(channels []string) (*Notification, error) {
+	n, err := c.NewNotification("Engagespot test notification")
+	if err != nil {
+		return nil, err
+	}
+
+	hmac := "off"
+	if c.settings().hmac {
+		hmac = "on"
+	}
+	// the host only, the endpoint could carry credentials in its userinfo or query
+	endpoint := c.defaults.Endpoint
+	if u, err := url.Parse(endpoint); err == nil {
+		endpoint = u.Host
+	}
+	version := sdkVersion()
+	sentAt := c.now().UTC().Format("2006-01-02T15:04:05Z")
+
+	n.SetMessage(fmt.Sprintf(
+		"Sent by engagespot-go %s at %s. hmac: %s, endpoint: %s, channels: %s",
+		version, sentAt, hmac, endpoint, strings.Join(channels, ", "),
+	))
+	n.SetCategory(TestNotificationCategory)
+	for _, channel := range channels {
+		n.Override.AddChannelRaw(channel)
+	}
+	n.setDataKey("sdkVersion", version)
+	n.setDataKey("sentAt", sentAt)
+	n.setDataKey("hmac", hmac)
+	n.setDataKey("endpoint", endpoint)
+	return n, nil
+}
@@ -0,0 +1,80 @@
+package engagespot
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func testNotificationPayload(t *testing.T, opts ...Option) map[string]interface{} {
+	s := &appServer{channels: `["email","inApp"]`}
+	var sent []byte
+	c := NewEngagespotClient("api-key-1234", "api-secret-5678", opts...)
+	c.httpClient.Transport = roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		if req.Method == http.MethodPost {
+			sent, _ = io.ReadAll(req.Body)
+		}
+		return s.RoundTrip(req)
+	})
+	clock := newFakeClock()
+	c.now = clock.Now
+
+	res, err := c.SendTestNotification(context.Background(), "dev@example.com")
+	assert.NoError(t, err)
+	assert.Equal(t, 200, res.StatusCode)
+
+	var payload map[string]interface{}
+	assert.NoError(t, json.Unmarshal(sent, &payload))
+	assert.NotContains(t, string(sent), "api-secret-5678")
+	assert.NotContains(t, string(sent), "api-key-1234")
+	return payload
+}
+
+func TestSendTestNotification(t *testing.T) {
+	payload := testNotificationPayload(t)
+	version := sdkVersion()
+
+	assert.Equal(t, TestNotificationCategory, payload["category"])
+	assert.Equal(t, []interface{}{"dev@example.com"}, payload["recipients"])
+	assert.Equal(t, map[string]interface{}{"channels": []interface{}{"email", "inApp"}}, payload["override"])
+
+	n := payload["notification"].(map[string]interface{})
+	assert.Equal(t, "Engagespot test notification", n["title"])
+	assert.Equal(t, "Sent by engagespot-go "+version+" at 2022-01-01T00:00:00Z. hmac: off, endpoint: api.engagespot.co, channels: email, inApp", n["message"])
+	assert.Equal(t, map[string]interface{}{
+		"sdkVersion": version,
+		"sentAt":     "2022-01-01T00:00:00Z",
+		"hmac":       "off",
+		"endpoint":   "api.engagespot.co",
+	}, n["data"])
+}
+
+func TestSendTestNotificationHmac(t *testing.T) {
+	payload := testNotificationPayload(t, WithHmac(), WithChannelValidation(time.Minute))
+	data := payload["notification"].(map[string]interface{})["data"].(map[string]interface{})
+	assert.Equal(t, "on", data["hmac"])
+}
+
+func TestSendTestNotificationReadOnly(t *testing.T) {
+	c := NewEngagespotClient("A", "B", WithReadOnly())
+	c.httpClient.Transport = roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		t.Fatal("read-only client made a request")
+		return nil, nil
+	})
+
+	_, err := c.SendTestNotification(context.Background(), "dev@example.com")
+	assert.ErrorIs(t, err, ErrReadOnlyClient)
+}
+
+func TestSendTestNotificationAppInfoFailure(t *testing.T) {
+	c := NewEngagespotClient("A", "B")
+	c.httpClient.Transport = &appServer{}
+
+	_, err := c.SendTestNotification(context.Background(), "dev@example.com")
+	assert.EqualError(t, err, "test notification: engagespot: GetAppInfo app: unexpected status 503: Service Unavailable")
+}
@@ -0,0 +1,38 @@
+package engagespot
+
+import "errors"
+
+// AddTopic can be used to send the notification to every subscriber of a topic or segment, like
+// "all-beta-users", on top of or instead of explicit recipients. adding a topic twice sends to it
+// once
+func (n *Notification) AddTopic(topic string) (*Notification, error) {
+	if topic == "" {
+		return nil, invalidField("topics", topic, errors.New("empty topic string"))
+	}
+	for _, t := range n.Topics {
+		if t == topic {
+			return n, nil
+		}
+	}
+	n.Topics = append(n.Topics, topic)
+	return n, nil
+}
+
+// topics are sent in a single request with whatever recipients it has. features splitting a send
+// into several requests would send to the topics once per request, and workflow triggers only
+// take recipients, so they can't be combined with topics
+func (c *Client) validateTopics(n *Notification, streamed bool) error {
+	if len(n.Topics) == 0 {
+		return nil
+	}
+	switch {
+	case streamed:
+		return errors.New("topics can't be combined with a recipient source or SendBulk")
+	case n.hasActiveChannelRules():
+		return errors.New("topics can't be combined with channel rules, which apply to recipients")
+	}
+	if _, ok := c.workflowFor(n); ok {
+		return errors.New("topics can't be sent through workflow triggers")
+	}
+	return nil
+}
@@ -0,0 +1,69 @@
+package engagespot
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTopicEncoding(t *testing.T) {
+	n := newTestNotification(t)
+	_, err := n.AddTopic("all-beta-users")
+	assert.NoError(t, err)
+	n.AddTopic("all-beta-users")
+	n.AddTopic("eu")
+
+	payload := encodedPayload(t, n)
+	assert.Equal(t, []interface{}{"all-beta-users", "eu"}, payload["topics"])
+	assert.NotContains(t, payload, "recipients")
+
+	n.AddRecipient("a")
+	payload = encodedPayload(t, n)
+	assert.Equal(t, []interface{}{"a"}, payload["recipients"])
+	assert.Len(t, payload["topics"], 2)
+
+	assert.NotContains(t, encodedPayload(t, newMessage(NewEngagespotClient("A", "B"))), "topics")
+
+	_, err = n.AddTopic("")
+	assert.EqualError(t, err, "empty topic string")
+}
+
+func TestSendToTopic(t *testing.T) {
+	var bodies []map[string]interface{}
+	c := NewEngagespotClient("A", "B")
+	c.httpClient.Transport = recordBodies(&bodies)
+
+	n, _ := c.NewNotification("Hello")
+	_, err := n.SendCtx(context.Background())
+	assert.EqualError(t, err, "not enough recipients")
+
+	n.AddTopic("all-beta-users")
+	_, err = n.SendCtx(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, []interface{}{"all-beta-users"}, bodies[0]["topics"])
+	assert.NotContains(t, bodies[0], "recipients")
+}
+
+func TestTopicsRejectedWithSplitSends(t *testing.T) {
+	c := NewEngagespotClient("A", "B", WithWorkflowMapping(map[string]string{"orders": "order-shipped"}))
+	c.httpClient.Transport = noNetwork(t)
+	ctx := context.Background()
+
+	streamed, _ := c.NewNotification("Hello")
+	streamed.SetRecipientSource(sliceSource([]string{"a"}, -1, nil))
+	streamed.AddTopic("eu")
+	_, err := streamed.SendCtx(ctx)
+	assert.EqualError(t, err, "topics can't be combined with a recipient source or SendBulk")
+
+	ruled := newMessage(c)
+	ruled.AddChannelRule(OnlyIfNoDevice("email"))
+	ruled.AddTopic("eu")
+	_, err = ruled.SendCtx(ctx)
+	assert.EqualError(t, err, "topics can't be combined with channel rules, which apply to recipients")
+
+	workflow := orderShipped(c, "orders")
+	workflow.AddTopic("eu")
+	_, err = workflow.SendCtx(ctx)
+	assert.EqualError(t, err, "topics can't be sent through workflow triggers")
+}
@@ -0,0 +1,143 @@
+package engagespot
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptrace"
+	"sync/atomic"
+	"time"
+)
+
+// bodies at least this large are sent with `Expect: 100-continue` when WithExpectContinueTimeout
+// is used, so the API can reject them before they are uploaded. smaller bodies aren't worth the
+// extra round trip
+const expectContinueMinBytes = 64 << 10
+
+// PhaseExpectContinue is the Phase of an OperationError for calls which failed while waiting for
+// the server to answer `Expect: 100-continue`, before the body was sent. this usually means a
+// proxy mishandling the header rather than a slow API
+const PhaseExpectContinue = "expect-continue"
+
+// WithExpectContinueTimeout can be used to send large request bodies, like notifications with
+// inline attachments, with `Expect: 100-continue`. the body is sent once the server answers, or
+// after d if it doesn't. only applies to the transport built by the client, not to the one of a
+// client given with WithHTTPClient
+func WithExpectContinueTimeout(d time.Duration) Option {
+	return func(c *Client) {
+		c.declare(optExpectContinue)
+		c.expectContinue = d > 0
+		c.expectContinueTimeout = d
+	}
+}
+
+// WithoutExpectContinue can be used to never send `Expect: 100-continue`, for proxies which
+// stall on it
+func WithoutExpectContinue() Option {
+	return func(c *Client) {
+		c.declare(optWithoutExpectContinue)
+		c.expectContinue = false
+		c.expectContinueTimeout = 0
+	}
+}
+
+// WithHTTPClient can be used to send every call through hc, like a client with an instrumented
+// transport. a copy of hc is used, so hc itself isn't changed: its redirect policy runs after the
+// one of the SDK, which keeps credentials from leaving the endpoint host, and its timeout is kept
+// unless WithTimeout is used. the default transport is used if hc has none
+func WithHTTPClient(hc *http.Client) Option {
+	return func(c *Client) {
+		c.declare(optHTTPClient)
+		c.customHTTPClient = hc
+	}
+}
+
+// WithTimeout sets the time limit of a whole call to the API, including reading the response.
+// it defaults to Defaults.Timeout, 30s, and 0 means no limit
+func WithTimeout(d time.Duration) Option {
+	return func(c *Client) {
+		c.declare(optTimeout)
+		c.timeout = &d
+	}
+}
+
+// http client every call goes through, as configured by the options
+func (c *Client) newHTTPClient() *http.Client {
+	hc := &http.Client{Timeout: c.defaults.Timeout}
+	if c.customHTTPClient != nil {
+		copied := *c.customHTTPClient
+		hc = &copied
+	}
+	if c.timeout != nil {
+		hc.Timeout = *c.timeout
+	}
+	if hc.Transport == nil {
+		hc.Transport = c.newTransport()
+	}
+
+	if custom := hc.CheckRedirect; custom != nil {
+		hc.CheckRedirect = func(req *http.Request, via []*http.Request) error {
+			if err := c.checkRedirect(req, via); err != nil {
+				return err
+			}
+			return custom(req, via)
+		}
+	} else {
+		hc.CheckRedirect = c.checkRedirect
+	}
+	return hc
+}
+
+// transport of the http client built by NewEngagespotClient
+func (c *Client) newTransport() http.RoundTripper {
+	t := http.DefaultTransport.(*http.Transport).Clone()
+	t.ExpectContinueTimeout = c.expectContinueTimeout
+	return t
+}
+
+// set `Expect: 100-continue` on req if enabled and the body is large enough
+func (c *Client) setExpectContinue(req *http.Request, size int) {
+	if c.expectContinue && size >= expectContinueMinBytes {
+		req.Header.Set("Expect", "100-continue")
+	}
+}
+
+// tracks whether a request with `Expect: 100-continue` is still waiting for the server to answer
+type continueTrace struct {
+	waiting int32
+}
+
+// body of a traced request. net/http gives no trace event telling a body sent after
+// ExpectContinueTimeout apart from a request given up while waiting, so the first read of the
+// body is what ends the wait
+type continueBody struct {
+	io.ReadCloser
+	ct *continueTrace
+}
+
+func (b continueBody) Read(p []byte) (int, error) {
+	atomic.StoreInt32(&b.ct.waiting, 0)
+	return b.ReadCloser.Read(p)
+}
+
+// trace req if it expects a 100-continue. the returned trace is nil otherwise
+func traceContinue(req *http.Request) (*http.Request, *continueTrace) {
+	if req.Header.Get("Expect") == "" || req.Body == nil {
+		return req, nil
+	}
+	ct := &continueTrace{}
+	trace := &httptrace.ClientTrace{
+		Wait100Continue: func() { atomic.StoreInt32(&ct.waiting, 1) },
+		Got100Continue:  func() { atomic.StoreInt32(&ct.waiting, 0) },
+	}
+	req = req.WithContext(httptrace.WithClientTrace(req.Context(), trace))
+	req.Body = continueBody{ReadCloser: req.Body, ct: ct}
+	return req, ct
+}
+
+// phase the traced request was in when it failed
+func (ct *continueTrace) phase() string {
+	if ct != nil && atomic.LoadInt32(&ct.waiting) == 1 {
+		return PhaseExpectContinue
+	}
+	return ""
+}
@@ -0,0 +1,198 @@
+package engagespot
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// raw HTTP/1.1 server which never answers `Expect: 100-continue`. handle gets each request once
+// its headers are read
+func silentContinueServer(t *testing.T, handle func(req *http.Request, conn net.Conn)) string {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	t.Cleanup(func() { ln.Close() })
+
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				req, err := http.ReadRequest(bufio.NewReader(conn))
+				if err != nil {
+					return
+				}
+				handle(req, conn)
+			}()
+		}
+	}()
+	return "http://" + ln.Addr().String() + "/"
+}
+
+func withAttachment(t *testing.T, c *Client) *Notification {
+	n := newMessage(c)
+	_, err := n.AddEmailAttachment(EmailAttachment{Filename: "big.bin", Content: make([]byte, expectContinueMinBytes)})
+	assert.NoError(t, err)
+	return n
+}
+
+func TestExpectContinueTimeout(t *testing.T) {
+	waited := make(chan time.Duration, 1)
+	endpoint := silentContinueServer(t, func(req *http.Request, conn net.Conn) {
+		headersAt := time.Now()
+		assert.Equal(t, "100-continue", req.Header.Get("Expect"))
+		_, err := io.ReadFull(req.Body, make([]byte, 1))
+		assert.NoError(t, err)
+		waited <- time.Since(headersAt)
+		io.Copy(io.Discard, req.Body)
+		io.WriteString(conn, "HTTP/1.1 200 OK\r\nContent-Length: 2\r\n\r\n{}")
+	})
+
+	c := clientFor(endpoint, 5*time.Second, WithExpectContinueTimeout(100*time.Millisecond))
+	res, err := withAttachment(t, c).Send()
+	assert.NoError(t, err)
+	assert.Equal(t, 200, res.StatusCode)
+	assert.GreaterOrEqual(t, <-waited, 80*time.Millisecond)
+}
+
+func TestExpectContinueSmallBody(t *testing.T) {
+	endpoint := silentContinueServer(t, func(req *http.Request, conn net.Conn) {
+		assert.Empty(t, req.Header.Get("Expect"))
+		io.Copy(io.Discard, req.Body)
+		io.WriteString(conn, "HTTP/1.1 200 OK\r\nContent-Length: 2\r\n\r\n{}")
+	})
+
+	c := clientFor(endpoint, 5*time.Second, WithExpectContinueTimeout(time.Hour))
+	n, _ := c.NewNotification("Hello")
+	n.AddRecipient("a")
+	_, err := n.Send()
+	assert.NoError(t, err)
+}
+
+func TestExpectContinueStallClassified(t *testing.T) {
+	done := make(chan struct{})
+	defer close(done)
+	endpoint := silentContinueServer(t, func(req *http.Request, conn net.Conn) {
+		<-done
+	})
+
+	c := clientFor(endpoint, 200*time.Millisecond, WithExpectContinueTimeout(time.Hour))
+	_, err := withAttachment(t, c).Send()
+
+	var opErr *OperationError
+	assert.True(t, errors.As(err, &opErr))
+	assert.Equal(t, PhaseExpectContinue, opErr.Phase)
+	assert.Contains(t, err.Error(), "[expect-continue]")
+}
+
+func TestSlowServerNotClassifiedAsStall(t *testing.T) {
+	done := make(chan struct{})
+	defer close(done)
+	endpoint := silentContinueServer(t, func(req *http.Request, conn net.Conn) {
+		assert.Empty(t, req.Header.Get("Expect"))
+		io.Copy(io.Discard, req.Body)
+		<-done
+	})
+
+	c := clientFor(endpoint, 200*time.Millisecond, WithExpectContinueTimeout(time.Hour), WithoutExpectContinue())
+	_, err := withAttachment(t, c).Send()
+
+	var opErr *OperationError
+	assert.True(t, errors.As(err, &opErr))
+	assert.Empty(t, opErr.Phase)
+}
+
+func TestWithHTTPClient(t *testing.T) {
+	var paths []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		paths = append(paths, r.URL.Path)
+	}))
+	defer server.Close()
+
+	var wrapped int
+	hc := &http.Client{Transport: roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		wrapped++
+		return http.DefaultTransport.RoundTrip(req)
+	})}
+	d := DefaultConfig()
+	d.Endpoint = server.URL + "/v3/"
+	c := NewEngagespotClient("A", "B", WithDefaults(d), WithHTTPClient(hc))
+
+	_, err := newMessage(c).Send()
+	assert.NoError(t, err)
+	_, err = c.Connect("hello@example.com")
+	assert.NoError(t, err)
+	assert.Equal(t, 2, wrapped)
+	assert.Equal(t, []string{"/v3/notifications", "/v3/sdk/connect"}, paths)
+
+	// hc is used through a copy which keeps the timeout of hc
+	assert.NotSame(t, hc, c.httpClient)
+	assert.Nil(t, hc.CheckRedirect)
+	assert.Zero(t, c.httpClient.Timeout)
+}
+
+func TestWithHTTPClientRedirectPolicy(t *testing.T) {
+	other := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer other.Close()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, other.URL+r.URL.Path, http.StatusTemporaryRedirect)
+	}))
+	defer server.Close()
+
+	var custom int
+	hc := &http.Client{CheckRedirect: func(req *http.Request, via []*http.Request) error {
+		custom++
+		return nil
+	}}
+	c := NewEngagespotClient("A", "B", WithHTTPClient(hc))
+	c.defaults.Endpoint = server.URL + "/v3/"
+
+	// the SDK refuses to send credentials to another host before the policy of hc runs
+	_, err := newMessage(c).Send()
+	assert.True(t, errors.Is(err, ErrUnsafeRedirect))
+	assert.Zero(t, custom)
+
+	c = NewEngagespotClient("A", "B", WithHTTPClient(hc), WithRedirectAllowlist(strings.TrimPrefix(other.URL, "http://")))
+	c.defaults.Endpoint = server.URL + "/v3/"
+	_, err = newMessage(c).Send()
+	assert.NoError(t, err)
+	assert.Equal(t, 1, custom)
+}
+
+func TestWithTimeout(t *testing.T) {
+	done := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-done
+	}))
+	defer server.Close()
+	defer close(done)
+
+	d := DefaultConfig()
+	d.Endpoint = server.URL + "/"
+	// the timeout applies whatever the order of the options, and to a given client too
+	for _, opts := range [][]Option{
+		{WithTimeout(50 * time.Millisecond), WithDefaults(d)},
+		{WithDefaults(d), WithHTTPClient(&http.Client{Timeout: time.Hour}), WithTimeout(50 * time.Millisecond)},
+	} {
+		c := NewEngagespotClient("A", "B", opts...)
+		assert.Equal(t, 50*time.Millisecond, c.httpClient.Timeout)
+
+		started := time.Now()
+		_, err := newMessage(c).Send()
+		var netErr net.Error
+		assert.True(t, errors.As(err, &netErr))
+		assert.True(t, netErr.Timeout())
+		assert.Less(t, time.Since(started), 5*time.Second)
+	}
+}
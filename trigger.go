@@ -0,0 +1,129 @@
+package engagespot
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// WorkflowTrigger is a trigger of a workflow of the app, built with NewWorkflowTrigger. unlike a
+// notification mapped to a workflow with WithWorkflowMapping, its data is sent as is
+// https://documentation.engagespot.co/docs/rest-api#tag/Workflows
+type WorkflowTrigger struct {
+	client     *Client
+	key        string
+	recipients []string
+	data       map[string]interface{}
+	override   *Override
+}
+
+// WorkflowRun is the run of a workflow started by a trigger
+type WorkflowRun struct {
+	Response
+	// id of the run, see CancelWorkflowRun
+	Id string `json:"id"`
+}
+
+// NewWorkflowTrigger can be used to create a trigger of the workflow identified by key, which can
+// later be sent with Send. the key can't be empty
+func (c *Client) NewWorkflowTrigger(key string) (*WorkflowTrigger, error) {
+	if key == "" {
+		return nil, errors.New("empty workflow key string")
+	}
+	return &WorkflowTrigger{client: c, key: key}, nil
+}
+
+// TriggerWorkflow triggers the workflow identified by key for recipients, with data. it's a
+// shorthand of NewWorkflowTrigger
+func (c *Client) TriggerWorkflow(ctx context.Context, key string, recipients []string, data map[string]interface{}, opts ...CallOption) (*WorkflowRun, error) {
+	t, err := c.NewWorkflowTrigger(key)
+	if err != nil {
+		return nil, err
+	}
+	for _, r := range recipients {
+		if _, err := t.AddRecipient(r); err != nil {
+			return nil, err
+		}
+	}
+	if data != nil {
+		if _, err := t.SetData(data); err != nil {
+			return nil, err
+		}
+	}
+	return t.Send(ctx, opts...)
+}
+
+// AddRecipient can be used to add a recipient to the trigger, normalized like the recipients of a
+// notification
+func (t *WorkflowTrigger) AddRecipient(recipient string) (*WorkflowTrigger, error) {
+	if recipient == "" {
+		return nil, invalidField("recipients", recipient, errors.New("empty recipient string"))
+	}
+	t.recipients = append(t.recipients, t.client.NormalizeUserID(recipient))
+	return t, nil
+}
+
+// SetData can be used to set the data the workflow is triggered with, replacing the data already
+// set
+func (t *WorkflowTrigger) SetData(data map[string]interface{}) (*WorkflowTrigger, error) {
+	if data == nil {
+		return nil, invalidField("data", data, errors.New("nil data map"))
+	}
+	replaced := make(map[string]interface{}, len(data))
+	for key, value := range data {
+		if key == "" {
+			return nil, invalidField("data", key, errors.New("empty data key string"))
+		}
+		replaced[key] = value
+	}
+	t.data = replaced
+	return t, nil
+}
+
+// SetOverride can be used to override the channels and provider configurations of the steps of the
+// workflow, see Override. nil removes the override
+func (t *WorkflowTrigger) SetOverride(o *Override) *WorkflowTrigger {
+	t.override = o
+	return t
+}
+
+// body of the trigger
+func (t *WorkflowTrigger) payload() workflowTrigger {
+	recipients := make([]Recipient, len(t.recipients))
+	for i, r := range t.recipients {
+		recipients[i] = Recipient{Identifier: r}
+	}
+	p := workflowTrigger{
+		Workflow: workflowRef{Identifier: t.key},
+		SendTo:   workflowSendTo{Recipients: recipients},
+		Data:     t.data,
+	}
+	if !t.override.empty() {
+		p.Override = t.override
+	}
+	return p
+}
+
+// Send triggers the workflow, using `POST workflows/trigger` under the hood. at least one recipient
+// is required. non-2xx responses are returned as an *APIError
+func (t *WorkflowTrigger) Send(ctx context.Context, opts ...CallOption) (*WorkflowRun, error) {
+	if len(t.recipients) == 0 {
+		return nil, errors.New("workflow trigger has no recipients")
+	}
+	payload, err := json.Marshal(t.payload())
+	if err != nil {
+		return nil, fmt.Errorf("encoding workflow trigger: %w", err)
+	}
+
+	c := t.client
+	req, err := c.newRequestContext(applyCallOptions(ctx, opts), endpointTriggerWorkflow, payload)
+	if err != nil {
+		return nil, err
+	}
+	run := &WorkflowRun{}
+	if err := c.callJSON(endpointTriggerWorkflow, req, run); err != nil {
+		return nil, err
+	}
+	return run, nil
+}
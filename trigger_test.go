@@ -0,0 +1,105 @@
+package engagespot
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// server answering workflow triggers with a run id, recording their bodies
+func triggerServer(t *testing.T, bodies *[]map[string]interface{}) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodPost, r.Method)
+		assert.Equal(t, "/v3/workflows/trigger", r.URL.Path)
+		b, _ := io.ReadAll(r.Body)
+		var body map[string]interface{}
+		assert.NoError(t, json.Unmarshal(b, &body))
+		*bodies = append(*bodies, body)
+		w.Write([]byte(`{"id":"run-1"}`))
+	}))
+}
+
+func TestWorkflowTrigger(t *testing.T) {
+	var bodies []map[string]interface{}
+	server := triggerServer(t, &bodies)
+	defer server.Close()
+	c := clientFor(server.URL+"/v3/", time.Second, WithUserIDNormalizer(strings.ToLower))
+
+	trigger, err := c.NewWorkflowTrigger("order-shipped")
+	assert.NoError(t, err)
+	trigger.AddRecipient("Ada@example.com")
+	trigger.AddRecipient("grace@example.com")
+	trigger.SetData(map[string]interface{}{"orderId": "o-1", "items": 2})
+	trigger.SetOverride(&Override{Channels: []string{ChannelEmail}})
+
+	run, err := trigger.Send(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, "run-1", run.Id)
+	assert.Equal(t, map[string]interface{}{
+		"workflow": map[string]interface{}{"identifier": "order-shipped"},
+		"sendTo": map[string]interface{}{"recipients": []interface{}{
+			map[string]interface{}{"identifier": "ada@example.com"},
+			map[string]interface{}{"identifier": "grace@example.com"},
+		}},
+		"data":     map[string]interface{}{"orderId": "o-1", "items": 2.0},
+		"override": map[string]interface{}{"channels": []interface{}{"email"}},
+	}, bodies[0])
+}
+
+func TestTriggerWorkflow(t *testing.T) {
+	var bodies []map[string]interface{}
+	server := triggerServer(t, &bodies)
+	defer server.Close()
+	c := clientFor(server.URL+"/v3/", time.Second)
+
+	run, err := c.TriggerWorkflow(context.Background(), "welcome", []string{"a"}, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, "run-1", run.Id)
+	assert.Equal(t, map[string]interface{}{
+		"workflow": map[string]interface{}{"identifier": "welcome"},
+		"sendTo":   map[string]interface{}{"recipients": []interface{}{map[string]interface{}{"identifier": "a"}}},
+	}, bodies[0])
+}
+
+func TestWorkflowTriggerValidation(t *testing.T) {
+	c := NewEngagespotClient("A", "B")
+	c.httpClient.Transport = noNetwork(t)
+	ctx := context.Background()
+
+	_, err := c.NewWorkflowTrigger("")
+	assert.EqualError(t, err, "empty workflow key string")
+	_, err = c.TriggerWorkflow(ctx, "", []string{"a"}, nil)
+	assert.EqualError(t, err, "empty workflow key string")
+
+	_, err = c.TriggerWorkflow(ctx, "welcome", nil, nil)
+	assert.EqualError(t, err, "workflow trigger has no recipients")
+	_, err = c.TriggerWorkflow(ctx, "welcome", []string{""}, nil)
+	assert.EqualError(t, err, "empty recipient string")
+
+	trigger, _ := c.NewWorkflowTrigger("welcome")
+	_, err = trigger.SetData(map[string]interface{}{"": 1})
+	assert.EqualError(t, err, "empty data key string")
+	_, err = trigger.SetData(nil)
+	assert.EqualError(t, err, "nil data map")
+
+	trigger.AddRecipient("a")
+	trigger.SetData(map[string]interface{}{"score": math.NaN()})
+	_, err = trigger.Send(ctx)
+	assert.Contains(t, err.Error(), "encoding workflow trigger")
+}
+
+func TestWorkflowTriggerAPIError(t *testing.T) {
+	c := NewEngagespotClient("A", "B")
+	c.httpClient.Transport = respondWith(404, `{"message":"workflow not found"}`)
+
+	_, err := c.TriggerWorkflow(context.Background(), "missing", []string{"a"}, nil)
+	assert.ErrorIs(t, err, ErrNotFound)
+}
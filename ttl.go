@@ -0,0 +1,119 @@
+package engagespot
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// MaxTTL is the longest a notification can live, the limit of FCM. push providers drop longer
+// expiries, or silently cap them
+const MaxTTL = 28 * 24 * time.Hour
+
+// SetTTL makes the notification expire d after it's delivered, at its send time if it's
+// scheduled, see SetSendAt, and else when it's sent. the expiry time is only worked out at send, so
+// a send time set afterwards moves it along. an expired notification is no longer delivered nor
+// shown in the in-app feed. d must be positive and at most MaxTTL. it replaces an expiry time set
+// with SetExpiresAt
+func (n *Notification) SetTTL(d time.Duration) (*Notification, error) {
+	if d <= 0 {
+		return nil, invalidField("ttl", d, fmt.Errorf("ttl %s is not positive", d))
+	}
+	if d > MaxTTL {
+		return nil, invalidField("ttl", d, fmt.Errorf("ttl %s is over the maximum of %s", d, MaxTTL))
+	}
+	n.ttl = d
+	n.ExpiresAt = nil
+	return n, nil
+}
+
+// SetExpiresAt is SetTTL with the time the notification expires at, which stays the same whatever
+// the send time. it must be after the send time, and at most MaxTTL after it. it replaces a ttl set
+// with SetTTL
+func (n *Notification) SetExpiresAt(at time.Time) (*Notification, error) {
+	if at.IsZero() {
+		return nil, invalidField("expiresAt", at, errors.New("zero expiry time"))
+	}
+	start := n.deliveryTime()
+	if !at.After(start) {
+		return nil, invalidField("expiresAt", at, fmt.Errorf("expiry time %s is not after the send time", at.Format(time.RFC3339)))
+	}
+	if at.Sub(start) > MaxTTL {
+		return nil, invalidField("expiresAt", at, fmt.Errorf("expiry time %s is over %s after the send time", at.Format(time.RFC3339), MaxTTL))
+	}
+	n.ExpiresAt = &at
+	n.ttl = 0
+	return n, nil
+}
+
+// n with the expiry time of its ttl, from its delivery time, see SetTTL. n is returned as is if
+// it has no ttl, and is left as is otherwise
+func (n *Notification) withTTL() *Notification {
+	if n.ttl <= 0 {
+		return n
+	}
+	at := n.deliveryTime().Add(n.ttl)
+	withExpiry := *n
+	withExpiry.ExpiresAt = &at
+	return &withExpiry
+}
+
+// when n is delivered, by the local clock
+func (n *Notification) deliveryTime() time.Time {
+	if n.SendAt != nil {
+		return *n.SendAt
+	}
+	return n.Client.now()
+}
+
+// sent with its expiry added to its override for the push providers if the override targets push
+// channels. sent is n with its times corrected for the clock skew, see correctSendAt: the expiry
+// time is taken from sent, as the API reads it, and the ttl from n, the one set with SetTTL or
+// else worked out on the local clock. n and sent are left as is
+func withPushExpiry(sent, n *Notification) *Notification {
+	if sent.ExpiresAt == nil || sent.Override == nil {
+		return sent
+	}
+	mobile, web := sent.Override.hasChannel(ChannelMobilePush), sent.Override.hasChannel(ChannelWebPush)
+	if !mobile && !web {
+		return sent
+	}
+	ttl := n.ttl
+	if ttl <= 0 {
+		ttl = n.ExpiresAt.Sub(n.deliveryTime())
+	}
+	o := *sent.Override
+	o.expiry = &pushExpiry{
+		at:     *sent.ExpiresAt,
+		ttl:    ttl,
+		mobile: mobile,
+		web:    web,
+	}
+	withExpiry := *sent
+	withExpiry.Override = &o
+	return &withExpiry
+}
+
+func (o *Override) hasChannel(channel string) bool {
+	for _, c := range o.Channels {
+		if c == channel {
+			return true
+		}
+	}
+	return false
+}
+
+// expiry of a notification, as the push providers take it
+type pushExpiry struct {
+	at     time.Time
+	ttl    time.Duration
+	mobile bool
+	web    bool
+}
+
+// ttl in whole seconds, as the push providers take it. a partial second is rounded up rather than
+// expiring early
+func (e *pushExpiry) seconds() string {
+	return strconv.FormatInt(int64((e.ttl+time.Second-1)/time.Second), 10)
+}
@@ -0,0 +1,197 @@
+package engagespot
+
+import (
+	"encoding/json"
+	"errors"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func ttlClient() (*Client, *fakeClock) {
+	clock := newFakeClock()
+	c := NewEngagespotClient("A", "B")
+	c.now = clock.Now
+	return c, clock
+}
+
+// payload of n as its client sends it
+func sentPayload(t *testing.T, n *Notification) map[string]interface{} {
+	b, _, err := n.Client.sendPayload(n)
+	assert.NoError(t, err)
+	var payload map[string]interface{}
+	assert.NoError(t, json.Unmarshal(b, &payload))
+	return payload
+}
+
+func TestSetTTL(t *testing.T) {
+	c, clock := ttlClient()
+	n := newMessage(c)
+	_, err := n.SetTTL(10 * time.Minute)
+	assert.NoError(t, err)
+	assert.Nil(t, n.ExpiresAt)
+	assert.Equal(t, "2022-01-01T00:10:00Z", sentPayload(t, n)["expiresAt"])
+
+	// the expiry time is worked out when sending
+	clock.Advance(5 * time.Minute)
+	assert.Equal(t, "2022-01-01T00:15:00Z", sentPayload(t, n)["expiresAt"])
+
+	// a notification scheduled after its ttl was set expires from its send time
+	_, err = n.SetSendAt(clock.Now().Add(27 * 24 * time.Hour))
+	assert.NoError(t, err)
+	_, err = c.validate(n)
+	assert.NoError(t, err)
+	assert.Equal(t, "2022-01-28T00:15:00Z", sentPayload(t, n)["expiresAt"])
+	assert.Nil(t, n.ExpiresAt)
+}
+
+func TestSetExpiresAtReplacesTTL(t *testing.T) {
+	c, clock := ttlClient()
+	n := newMessage(c)
+	n.SetTTL(10 * time.Minute)
+	_, err := n.SetExpiresAt(clock.Now().Add(time.Hour))
+	assert.NoError(t, err)
+
+	// an expiry time stays the same whatever the send time
+	n.SetSendAt(clock.Now().Add(30 * time.Minute))
+	assert.Equal(t, "2022-01-01T01:00:00Z", sentPayload(t, n)["expiresAt"])
+
+	n.SetTTL(time.Minute)
+	assert.Nil(t, n.ExpiresAt)
+	assert.Equal(t, "2022-01-01T00:31:00Z", sentPayload(t, n)["expiresAt"])
+}
+
+func TestSetTTLValidation(t *testing.T) {
+	c, clock := ttlClient()
+	for _, tc := range []struct {
+		ttl    time.Duration
+		reason string
+	}{
+		{0, "ttl 0s is not positive"},
+		{-time.Minute, "ttl -1m0s is not positive"},
+		{MaxTTL + time.Second, "ttl 672h0m1s is over the maximum of 672h0m0s"},
+		{100 * 365 * 24 * time.Hour, "ttl 876000h0m0s is over the maximum of 672h0m0s"},
+	} {
+		n := newMessage(c)
+		_, err := n.SetTTL(tc.ttl)
+		var v *ValidationError
+		if assert.True(t, errors.As(err, &v), tc.reason) {
+			assert.Equal(t, "ttl", v.Field)
+			assert.Equal(t, tc.ttl, v.Value)
+			assert.Equal(t, tc.reason, v.Reason)
+		}
+		assert.Nil(t, n.ExpiresAt)
+	}
+
+	n := newMessage(c)
+	_, err := n.SetTTL(MaxTTL)
+	assert.NoError(t, err)
+	assert.Equal(t, clock.Now().Add(MaxTTL).Format(time.RFC3339), sentPayload(t, n)["expiresAt"])
+}
+
+func TestSetExpiresAtValidation(t *testing.T) {
+	c, clock := ttlClient()
+	now := clock.Now()
+	for _, tc := range []struct {
+		at     time.Time
+		reason string
+	}{
+		{time.Time{}, "zero expiry time"},
+		{now, "expiry time 2022-01-01T00:00:00Z is not after the send time"},
+		{now.Add(-time.Hour), "expiry time 2021-12-31T23:00:00Z is not after the send time"},
+		{now.Add(MaxTTL + time.Hour), "expiry time 2022-01-29T01:00:00Z is over 672h0m0s after the send time"},
+	} {
+		n := newMessage(c)
+		_, err := n.SetExpiresAt(tc.at)
+		var v *ValidationError
+		if assert.True(t, errors.As(err, &v), tc.reason) {
+			assert.Equal(t, "expiresAt", v.Field)
+			assert.Equal(t, tc.reason, v.Reason)
+		}
+		assert.Nil(t, n.ExpiresAt)
+	}
+
+	n := newMessage(c)
+	_, err := n.SetExpiresAt(now.Add(time.Hour))
+	assert.NoError(t, err)
+	assert.Equal(t, now.Add(time.Hour), *n.ExpiresAt)
+}
+
+func TestExpiredBeforeSend(t *testing.T) {
+	c, clock := ttlClient()
+	c.httpClient.Transport = noNetwork(t)
+	n := newMessage(c)
+	n.SetExpiresAt(clock.Now().Add(time.Minute))
+	clock.Advance(2 * time.Minute)
+
+	_, err := n.Send()
+	var v *ValidationError
+	assert.True(t, errors.As(err, &v))
+	assert.Equal(t, "expiresAt", v.Field)
+	assert.EqualError(t, err, "notification expired before it was sent")
+}
+
+func TestPushExpiryInOverride(t *testing.T) {
+	c, _ := ttlClient()
+	n := newMessage(c)
+	n.SetTTL(90*time.Second + time.Millisecond)
+	n.Override = &Override{}
+	n.Override.AddChannel(ChannelMobilePush)
+	n.Override.AddChannel(ChannelWebPush)
+
+	payload := sentPayload(t, n)
+	assert.Equal(t, map[string]interface{}{
+		"channels": []interface{}{"mobilePush", "webPush"},
+		"fcm":      map[string]interface{}{"android": map[string]interface{}{"ttl": "91s"}},
+		"apns":     map[string]interface{}{"headers": map[string]interface{}{"apns-expiration": "1640995290"}},
+		"webpush":  map[string]interface{}{"headers": map[string]interface{}{"TTL": "91"}},
+	}, payload["override"])
+
+	// the notification itself is left as is
+	assert.Nil(t, n.Override.expiry)
+}
+
+func TestPushExpiryOnlyForPushChannels(t *testing.T) {
+	c, _ := ttlClient()
+	n := newMessage(c)
+	n.SetTTL(time.Minute)
+	n.Override = &Override{}
+	n.Override.AddChannel(ChannelWebPush)
+
+	override := sentPayload(t, n)["override"].(map[string]interface{})
+	assert.Contains(t, override, "webpush")
+	assert.NotContains(t, override, "fcm")
+	assert.NotContains(t, override, "apns")
+
+	n.Override = &Override{}
+	n.Override.AddChannel(ChannelEmail)
+	override = sentPayload(t, n)["override"].(map[string]interface{})
+	assert.Equal(t, map[string]interface{}{"channels": []interface{}{"email"}}, override)
+}
+
+func TestPushExpiryWithSkewCorrection(t *testing.T) {
+	for _, skew := range []time.Duration{5 * time.Minute, -5 * time.Minute} {
+		clock := newFakeClock()
+		s := &skewedServer{clock: clock, skew: skewed(skew)}
+		c := skewClient(s, WithSkewCorrection(), WithClockSkewWarning(time.Hour, nil))
+		_, err := newMessage(c).Send()
+		assert.NoError(t, err)
+
+		n := newMessage(c)
+		n.SetChannels(ChannelMobilePush, ChannelWebPush)
+		n.SetTTL(2 * time.Minute)
+		_, err = n.Send()
+		assert.NoError(t, err)
+
+		// the expiry time is on the clock of the API, the ttl is the one set whatever the skew
+		body := s.bodies[1]
+		assert.Equal(t, clock.Now().Add(skew+2*time.Minute).UTC().Format(time.RFC3339), body["expiresAt"], skew)
+		override := body["override"].(map[string]interface{})
+		assert.Equal(t, map[string]interface{}{"android": map[string]interface{}{"ttl": "120s"}}, override["fcm"], skew)
+		assert.Equal(t, map[string]interface{}{"headers": map[string]interface{}{"TTL": "120"}}, override["webpush"], skew)
+		apns := override["apns"].(map[string]interface{})["headers"].(map[string]interface{})
+		assert.Equal(t, strconv.FormatInt(clock.Now().Add(skew+2*time.Minute).Unix(), 10), apns["apns-expiration"], skew)
+	}
+}
@@ -0,0 +1,79 @@
+package engagespot
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+)
+
+// WebhookEvent is an event posted by Engagespot to a webhook, with the data payload of the
+// notification it is about
+type WebhookEvent struct {
+	Type string          `json:"type"`
+	Data json.RawMessage `json:"data,omitempty"`
+}
+
+// ParseWebhookEvent decodes the body of a webhook request
+func ParseWebhookEvent(body []byte) (WebhookEvent, error) {
+	var event WebhookEvent
+	if err := json.Unmarshal(body, &event); err != nil {
+		return WebhookEvent{}, err
+	}
+	return event, nil
+}
+
+// typed data must be a struct, so its fields map to keys of the data object
+func checkDataType(t reflect.Type) error {
+	switch {
+	case t == nil:
+		return errors.New("typed data must be a struct, got nil")
+	case t.Kind() == reflect.Ptr:
+		return fmt.Errorf("typed data must be a struct, got pointer %s", t)
+	case t.Kind() != reflect.Struct:
+		return fmt.Errorf("typed data must be a struct, got %s", t)
+	}
+	return nil
+}
+
+// SetDataTyped can be used to set the data payload of n from a struct, honoring its json tags.
+// the fields are merged into the data already set, so keys set by helpers like SetAvatar are kept
+// unless the struct has a field with the same key
+func SetDataTyped[T any](n *Notification, v T) error {
+	if err := checkDataType(reflect.TypeOf(v)); err != nil {
+		return err
+	}
+
+	b, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	var data map[string]interface{}
+	d := json.NewDecoder(bytes.NewReader(b))
+	// keep numbers as sent, large integers would lose precision as float64
+	d.UseNumber()
+	if err := d.Decode(&data); err != nil {
+		return err
+	}
+
+	for key, value := range data {
+		n.setDataKey(key, value)
+	}
+	return nil
+}
+
+// DataAs decodes the data payload of event into T, the struct given to SetDataTyped when sending
+func DataAs[T any](event WebhookEvent) (T, error) {
+	var v T
+	if err := checkDataType(reflect.TypeOf((*T)(nil)).Elem()); err != nil {
+		return v, err
+	}
+	if len(event.Data) == 0 || string(event.Data) == "null" {
+		return v, fmt.Errorf("%s event has no data", event.Type)
+	}
+	if err := json.Unmarshal(event.Data, &v); err != nil {
+		return v, err
+	}
+	return v, nil
+}
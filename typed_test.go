@@ -0,0 +1,68 @@
+package engagespot
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type orderData struct {
+	OrderId  int64             `json:"orderId"`
+	Total    float64           `json:"total"`
+	Items    []string          `json:"items"`
+	Shipping map[string]string `json:"shipping,omitempty"`
+	internal string
+}
+
+func TestTypedDataRoundTrip(t *testing.T) {
+	var requests []capturedRequest
+	c := NewEngagespotClient("A", "B")
+	c.httpClient.Transport = captureRequests(&requests)
+
+	sent := orderData{
+		OrderId:  9007199254740993,
+		Total:    19.99,
+		Items:    []string{"book", "pen"},
+		Shipping: map[string]string{"city": "Kochi"},
+	}
+	n, _ := c.NewNotification("Order shipped")
+	n.AddRecipient("a")
+	n.SetAvatar("https://example.com/a.png")
+	assert.NoError(t, SetDataTyped(n, sent))
+	_, err := n.Send()
+	assert.NoError(t, err)
+
+	var payload struct {
+		Notification struct {
+			Data json.RawMessage `json:"data"`
+		} `json:"notification"`
+	}
+	assert.NoError(t, json.Unmarshal(requests[0].body, &payload))
+	webhook, _ := json.Marshal(map[string]interface{}{"type": "delivered", "data": payload.Notification.Data})
+
+	event, err := ParseWebhookEvent(webhook)
+	assert.NoError(t, err)
+	received, err := DataAs[orderData](event)
+	assert.NoError(t, err)
+	assert.Equal(t, sent, received)
+
+	// helper keys are kept
+	assert.Equal(t, "https://example.com/a.png", n.Notification.Data[DataKeyAvatar])
+}
+
+func TestTypedDataInvalidTypes(t *testing.T) {
+	n := newTestNotification(t)
+	assert.EqualError(t, SetDataTyped(n, &orderData{}), "typed data must be a struct, got pointer *engagespot.orderData")
+	assert.EqualError(t, SetDataTyped(n, 42), "typed data must be a struct, got int")
+	assert.EqualError(t, SetDataTyped[interface{}](n, nil), "typed data must be a struct, got nil")
+	assert.Nil(t, n.Notification.Data)
+
+	event := WebhookEvent{Type: "delivered", Data: json.RawMessage(`{"orderId":1}`)}
+	_, err := DataAs[*orderData](event)
+	assert.EqualError(t, err, "typed data must be a struct, got pointer *engagespot.orderData")
+	_, err = DataAs[map[string]interface{}](event)
+	assert.Error(t, err)
+	_, err = DataAs[orderData](WebhookEvent{Type: "delivered"})
+	assert.EqualError(t, err, "delivered event has no data")
+}
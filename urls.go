@@ -0,0 +1,36 @@
+package engagespot
+
+import (
+	"fmt"
+	"net/url"
+)
+
+// WithRelaxedValidation makes SetUrl and SetIcon accept absolute urls of any scheme, like the app
+// deep link myapp://orders/42, instead of only http and https ones
+func WithRelaxedValidation() Option {
+	return func(c *Client) {
+		c.relaxedValidation = true
+	}
+}
+
+// check that raw, the value of kind, is absolute. only http and https urls are accepted, with a
+// host, unless relaxed
+func validURL(kind, raw string, relaxed bool) error {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return fmt.Errorf("invalid %s %q: %w", kind, raw, err)
+	}
+	if !u.IsAbs() {
+		return fmt.Errorf("invalid %s %q: not an absolute url", kind, raw)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		if relaxed {
+			return nil
+		}
+		return fmt.Errorf("invalid %s %q: scheme %q isn't http or https, see WithRelaxedValidation", kind, raw, u.Scheme)
+	}
+	if u.Host == "" {
+		return fmt.Errorf("invalid %s %q: no host", kind, raw)
+	}
+	return nil
+}
@@ -0,0 +1,63 @@
+package engagespot
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSetUrlValidation(t *testing.T) {
+	n := newTestNotification(t)
+	for _, tc := range []struct {
+		url string
+		err string
+	}{
+		{"https://example.com/orders/42?tab=items&page=2#summary", ""},
+		{"http://example.com", ""},
+		{"/orders/42", `invalid url "/orders/42": not an absolute url`},
+		{"example.com/orders", `invalid url "example.com/orders": not an absolute url`},
+		{"htps://example.com", `invalid url "htps://example.com": scheme "htps" isn't http or https, see WithRelaxedValidation`},
+		{"myapp://orders/42", `invalid url "myapp://orders/42": scheme "myapp" isn't http or https, see WithRelaxedValidation`},
+		{"https:///orders", `invalid url "https:///orders": no host`},
+		{"https://exa mple.com", `invalid url "https://exa mple.com": parse "https://exa mple.com": invalid character " " in host name`},
+	} {
+		_, err := n.SetUrl(tc.url)
+		if tc.err == "" {
+			assert.NoError(t, err, tc.url)
+			assert.Equal(t, tc.url, n.Notification.Url)
+		} else {
+			assert.EqualError(t, err, tc.err, tc.url)
+		}
+	}
+}
+
+func TestSetIconValidation(t *testing.T) {
+	n := newTestNotification(t)
+	_, err := n.SetIcon("https://example.com/icon.png?v=2")
+	assert.NoError(t, err)
+
+	_, err = n.SetIcon("icon.png")
+	assert.EqualError(t, err, `invalid icon url "icon.png": not an absolute url`)
+	assert.Equal(t, "https://example.com/icon.png?v=2", n.Notification.Icon)
+}
+
+func TestRelaxedValidation(t *testing.T) {
+	c := NewEngagespotClient("A", "B", WithRelaxedValidation())
+	n, _ := c.NewNotification("Hello")
+
+	_, err := n.SetUrl("myapp://orders/42?tab=items#top")
+	assert.NoError(t, err)
+	assert.Equal(t, "myapp://orders/42?tab=items#top", n.Notification.Url)
+	_, err = n.SetIcon("app-asset://icons/bell")
+	assert.NoError(t, err)
+
+	// relative urls are still rejected
+	_, err = n.SetUrl("orders/42")
+	assert.EqualError(t, err, `invalid url "orders/42": not an absolute url`)
+}
+
+func TestFluentUrlValidation(t *testing.T) {
+	n := newTestNotification(t)
+	n.Url("htps://example.com").Recipient("a")
+	assert.Contains(t, n.buildError().Error(), `invalid url "htps://example.com"`)
+}
@@ -0,0 +1,243 @@
+package engagespot
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// default size of ImportUsers batches, and number of users upserted concurrently in a batch
+const (
+	defaultImportBatchSize   = 500
+	defaultImportConcurrency = 8
+)
+
+// UserUpsert is a user created or updated by ImportUsers, identified by Id. Profile holds the
+// attributes set on the user, like email or name
+type UserUpsert struct {
+	Id      string
+	Profile map[string]interface{}
+}
+
+// UserSource is an iterator over users to import. it returns false once there are no more users
+type UserSource func(ctx context.Context) (UserUpsert, bool, error)
+
+// ImportOptions controls how ImportUsers splits and resumes an import
+type ImportOptions struct {
+	// users per batch, 500 if not set
+	BatchSize int
+	// users upserted at the same time within a batch, 8 if not set
+	Concurrency int
+	// Progress of the report of a previous import of the same source, to resume it after its
+	// last successful batch
+	Resume string
+}
+
+// ImportBatch is the outcome of a single batch of ImportUsers. Failed maps user ids to the error
+// their upsert failed with
+type ImportBatch struct {
+	Users  int
+	Failed map[string]error
+}
+
+// ImportReport summarizes an import. Progress is a token to pass as ImportOptions.Resume to
+// resume an import which stopped, without sending again the batches which succeeded
+type ImportReport struct {
+	Batches  []ImportBatch
+	Imported int
+	Failed   int
+	// users skipped because a previous import already sent them, see ImportOptions.Resume
+	Skipped  int
+	Progress string
+}
+
+// CreateOrUpdateUser can be used to create a user, or update the profile of an existing one
+func (c *Client) CreateOrUpdateUser(ctx context.Context, userId string, profile map[string]interface{}, opts ...CallOption) error {
+	if err := validUserId(userId); err != nil {
+		return err
+	}
+	if profile == nil {
+		profile = map[string]interface{}{}
+	}
+	body, err := json.Marshal(profile)
+	if err != nil {
+		return err
+	}
+	req, err := c.newRequestContext(applyCallOptions(ctx, opts), endpointUpsertUser, body, c.NormalizeUserID(userId))
+	if err != nil {
+		return err
+	}
+	return c.callJSON(endpointUpsertUser, req, nil)
+}
+
+// reject an empty user id before making a request for it, which would go to the users collection
+func validUserId(userId string) error {
+	if userId == "" {
+		return invalidField("userId", userId, errors.New("empty user id string"))
+	}
+	return nil
+}
+
+// ErrUserNotFound is returned by the user calls for users which don't exist. the *APIError of the
+// 404 is still reachable with errors.As
+var ErrUserNotFound = errors.New("user not found")
+
+// tell a 404 of a user call apart from other errors
+func userNotFound(err error) error {
+	if errors.Is(err, ErrNotFound) {
+		return fmt.Errorf("%w: %w", ErrUserNotFound, err)
+	}
+	return err
+}
+
+// User is the profile of a user, as returned by `GET users/{userId}`. custom attributes set with
+// CreateOrUpdateUser can be read with Extra
+type User struct {
+	Response
+	Identifier string    `json:"identifier"`
+	Email      string    `json:"email,omitempty"`
+	Name       string    `json:"name,omitempty"`
+	Phone      string    `json:"phoneNumber,omitempty"`
+	CreatedAt  time.Time `json:"createdAt"`
+}
+
+// GetUser can be used to fetch the profile of a user, like to check that an email address is set
+// before sending through the email channel. ErrUserNotFound is returned if there is no such user
+func (c *Client) GetUser(ctx context.Context, userId string, opts ...CallOption) (*User, error) {
+	if err := validUserId(userId); err != nil {
+		return nil, err
+	}
+	req, err := c.newRequestContext(applyCallOptions(ctx, opts), endpointGetUser, nil, c.NormalizeUserID(userId))
+	if err != nil {
+		return nil, err
+	}
+
+	user := &User{}
+	if err := c.callJSON(endpointGetUser, req, user); err != nil {
+		return nil, userNotFound(err)
+	}
+	return user, nil
+}
+
+// DeleteUser can be used to delete a user along with their notifications, like for an erasure
+// request. ErrUserNotFound is returned if there is no such user
+func (c *Client) DeleteUser(ctx context.Context, userId string, opts ...CallOption) error {
+	if err := validUserId(userId); err != nil {
+		return err
+	}
+	req, err := c.newRequestContext(applyCallOptions(ctx, opts), endpointDeleteUser, nil, c.NormalizeUserID(userId))
+	if err != nil {
+		return err
+	}
+	return userNotFound(c.callJSON(endpointDeleteUser, req, nil))
+}
+
+// progress tokens hold the number of users of the source already imported
+func encodeProgress(offset int) string {
+	return base64.RawURLEncoding.EncodeToString([]byte("offset:" + strconv.Itoa(offset)))
+}
+
+func decodeProgress(token string) (int, error) {
+	if token == "" {
+		return 0, nil
+	}
+	b, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil || !strings.HasPrefix(string(b), "offset:") {
+		return 0, errors.New("invalid import progress token")
+	}
+	offset, err := strconv.Atoi(strings.TrimPrefix(string(b), "offset:"))
+	if err != nil || offset < 0 {
+		return 0, errors.New("invalid import progress token")
+	}
+	return offset, nil
+}
+
+// ImportUsers can be used to create or update users in bulk, pulling them lazily from users. the
+// API has no bulk endpoint, so users are upserted concurrently in batches of
+// ImportOptions.BatchSize. batches run one after the other and the import stops at the first batch
+// with a failure, returning the report along with an error. the report Progress then points right
+// after the last successful batch, so resuming sends the failed batch again and nothing before it
+func (c *Client) ImportUsers(ctx context.Context, users UserSource, opts ImportOptions) (*ImportReport, error) {
+	if c.config.readOnly {
+		return nil, ErrReadOnlyClient
+	}
+	size := opts.BatchSize
+	if size <= 0 {
+		size = defaultImportBatchSize
+	}
+	concurrency := opts.Concurrency
+	if concurrency <= 0 {
+		concurrency = defaultImportConcurrency
+	}
+	offset, err := decodeProgress(opts.Resume)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &ImportReport{Progress: encodeProgress(offset)}
+	for ; report.Skipped < offset; report.Skipped++ {
+		if _, ok, err := users(ctx); err != nil || !ok {
+			return report, err
+		}
+	}
+
+	for more := true; more; {
+		var batch []UserUpsert
+		for len(batch) < size {
+			u, ok, err := users(ctx)
+			if err != nil {
+				return report, err
+			}
+			if !ok {
+				more = false
+				break
+			}
+			batch = append(batch, u)
+		}
+		if len(batch) == 0 {
+			break
+		}
+
+		result := c.importBatch(ctx, batch, concurrency)
+		report.Batches = append(report.Batches, result)
+		report.Imported += len(batch) - len(result.Failed)
+		report.Failed += len(result.Failed)
+		if len(result.Failed) > 0 {
+			return report, fmt.Errorf("import batch %d: %d of %d users failed", len(report.Batches), len(result.Failed), len(batch))
+		}
+
+		offset += len(batch)
+		report.Progress = encodeProgress(offset)
+	}
+	return report, nil
+}
+
+// upsert the users of a batch, concurrency at a time
+func (c *Client) importBatch(ctx context.Context, batch []UserUpsert, concurrency int) ImportBatch {
+	result := ImportBatch{Users: len(batch), Failed: map[string]error{}}
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, concurrency)
+	for _, u := range batch {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(u UserUpsert) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			if err := c.CreateOrUpdateUser(ctx, u.Id, u.Profile); err != nil {
+				mu.Lock()
+				result.Failed[u.Id] = err
+				mu.Unlock()
+			}
+		}(u)
+	}
+	wg.Wait()
+	return result
+}
@@ -0,0 +1,207 @@
+package engagespot
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func userSource(count int) UserSource {
+	i := 0
+	return func(ctx context.Context) (UserUpsert, bool, error) {
+		if i == count {
+			return UserUpsert{}, false, nil
+		}
+		i++
+		return UserUpsert{Id: fmt.Sprintf("user-%d", i), Profile: map[string]interface{}{"name": fmt.Sprintf("User %d", i)}}, true, nil
+	}
+}
+
+// fake API recording upserted users, failing for users in failing
+type userServer struct {
+	mu       sync.Mutex
+	upserted map[string]int
+	profiles map[string]map[string]interface{}
+	failing  map[string]bool
+}
+
+func (s *userServer) RoundTrip(req *http.Request) (*http.Response, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	id := strings.TrimPrefix(req.URL.Path, "/v3/users/")
+	if profile, ok := s.profiles[id]; ok && req.Method == http.MethodGet {
+		user := map[string]interface{}{"identifier": id, "createdAt": "2022-01-02T03:04:05Z"}
+		for key, value := range profile {
+			user[key] = value
+		}
+		b, _ := json.Marshal(user)
+		return respondWith(200, string(b)).RoundTrip(req)
+	}
+	if req.Method == http.MethodGet {
+		return respondWith(404, `{}`).RoundTrip(req)
+	}
+	if req.Method != http.MethodPut || s.failing[id] {
+		return respondWith(500, `{}`).RoundTrip(req)
+	}
+	var profile map[string]interface{}
+	json.NewDecoder(req.Body).Decode(&profile)
+	if s.upserted == nil {
+		s.upserted, s.profiles = map[string]int{}, map[string]map[string]interface{}{}
+	}
+	s.upserted[id]++
+	s.profiles[id] = profile
+	return respondWith(200, `{}`).RoundTrip(req)
+}
+
+func TestImportUsersBatches(t *testing.T) {
+	s := &userServer{}
+	c := NewEngagespotClient("A", "B")
+	c.httpClient.Transport = s
+
+	report, err := c.ImportUsers(context.Background(), userSource(25), ImportOptions{BatchSize: 10, Concurrency: 3})
+	assert.NoError(t, err)
+	assert.Equal(t, []int{10, 10, 5}, []int{report.Batches[0].Users, report.Batches[1].Users, report.Batches[2].Users})
+	assert.Equal(t, 25, report.Imported)
+	assert.Zero(t, report.Failed)
+	assert.Len(t, s.upserted, 25)
+	assert.Equal(t, map[string]interface{}{"name": "User 7"}, s.profiles["user-7"])
+}
+
+func TestImportUsersResume(t *testing.T) {
+	s := &userServer{failing: map[string]bool{"user-17": true}}
+	c := NewEngagespotClient("A", "B")
+	c.httpClient.Transport = s
+
+	report, err := c.ImportUsers(context.Background(), userSource(25), ImportOptions{BatchSize: 10})
+	assert.Error(t, err)
+	assert.Len(t, report.Batches, 2)
+	assert.Equal(t, 19, report.Imported)
+	assert.Equal(t, 1, report.Failed)
+	assert.Contains(t, report.Batches[1].Failed, "user-17")
+	assert.Zero(t, s.upserted["user-21"])
+
+	s.failing = nil
+	resumed, err := c.ImportUsers(context.Background(), userSource(25), ImportOptions{BatchSize: 10, Resume: report.Progress})
+	assert.NoError(t, err)
+	assert.Equal(t, 10, resumed.Skipped)
+	assert.Equal(t, 15, resumed.Imported)
+
+	// the first batch is never sent again, the failed one is sent again as a whole
+	assert.Equal(t, 1, s.upserted["user-1"])
+	assert.Equal(t, 2, s.upserted["user-11"])
+	assert.Equal(t, 1, s.upserted["user-17"])
+	assert.Equal(t, 1, s.upserted["user-25"])
+}
+
+func TestImportUsersInvalidResume(t *testing.T) {
+	c := NewEngagespotClient("A", "B")
+	c.httpClient.Transport = noNetwork(t)
+	_, err := c.ImportUsers(context.Background(), userSource(1), ImportOptions{Resume: "nope"})
+	assert.EqualError(t, err, "invalid import progress token")
+}
+
+func TestCreateOrUpdateUserReadOnly(t *testing.T) {
+	c := NewEngagespotClient("A", "B", WithReadOnly())
+	c.httpClient.Transport = noNetwork(t)
+	assert.ErrorIs(t, c.CreateOrUpdateUser(context.Background(), "a", nil), ErrReadOnlyClient)
+}
+
+func TestGetUser(t *testing.T) {
+	s := &userServer{}
+	c := NewEngagespotClient("A", "B")
+	c.httpClient.Transport = s
+	ctx := context.Background()
+
+	assert.NoError(t, c.CreateOrUpdateUser(ctx, "a@example.com", map[string]interface{}{"email": "a@example.com", "name": "A", "plan": "pro"}))
+	user, err := c.GetUser(ctx, "a@example.com")
+	assert.NoError(t, err)
+	assert.Equal(t, "a@example.com", user.Identifier)
+	assert.Equal(t, "a@example.com", user.Email)
+	assert.Equal(t, "A", user.Name)
+	assert.Equal(t, time.Date(2022, 1, 2, 3, 4, 5, 0, time.UTC), user.CreatedAt)
+
+	var plan string
+	assert.NoError(t, user.Extra("plan", &plan))
+	assert.Equal(t, "pro", plan)
+
+	_, err = c.GetUser(ctx, "b@example.com")
+	assert.ErrorIs(t, err, ErrUserNotFound)
+	var apiErr *APIError
+	assert.ErrorAs(t, err, &apiErr)
+	assert.Equal(t, "GetUser", apiErr.Op)
+}
+
+func TestDeleteUser(t *testing.T) {
+	var mu sync.Mutex
+	users := map[string]bool{"a+b@example.com": true}
+	var paths []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		paths = append(paths, r.Method+" "+r.URL.Path)
+		id := strings.TrimPrefix(r.URL.Path, "/v3/users/")
+		if !users[id] {
+			w.WriteHeader(404)
+			w.Write([]byte(`{"message":"user not found"}`))
+			return
+		}
+		delete(users, id)
+		w.WriteHeader(204)
+	}))
+	defer server.Close()
+	c := clientFor(server.URL+"/v3/", time.Second)
+	ctx := context.Background()
+
+	assert.NoError(t, c.DeleteUser(ctx, "a+b@example.com"))
+	assert.Empty(t, users)
+
+	err := c.DeleteUser(ctx, "a+b@example.com")
+	assert.ErrorIs(t, err, ErrUserNotFound)
+	assert.ErrorIs(t, err, ErrNotFound)
+	var apiErr *APIError
+	assert.ErrorAs(t, err, &apiErr)
+	assert.Equal(t, "DeleteUser", apiErr.Op)
+	assert.Equal(t, []string{"DELETE /v3/users/a+b@example.com", "DELETE /v3/users/a+b@example.com"}, paths)
+
+	err = c.DeleteUser(ctx, "")
+	assert.EqualError(t, err, "empty user id string")
+}
+
+func TestUserCallsRejectEmptyId(t *testing.T) {
+	c := NewEngagespotClient("A", "B")
+	c.httpClient.Transport = noNetwork(t)
+	ctx := context.Background()
+
+	_, getErr := c.GetUser(ctx, "")
+	for _, err := range []error{
+		getErr,
+		c.CreateOrUpdateUser(ctx, "", map[string]interface{}{"name": "A"}),
+		c.DeleteUser(ctx, ""),
+	} {
+		var v *ValidationError
+		if assert.ErrorAs(t, err, &v) {
+			assert.Equal(t, "userId", v.Field)
+			assert.Equal(t, "empty user id string", v.Reason)
+		}
+	}
+}
+
+func TestDeleteUserEscapesId(t *testing.T) {
+	var paths []string
+	c := NewEngagespotClient("A", "B")
+	c.httpClient.Transport = roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		paths = append(paths, req.URL.EscapedPath())
+		return respondWith(204, ``).RoundTrip(req)
+	})
+	assert.NoError(t, c.DeleteUser(context.Background(), "a b/c?@example.com"))
+	assert.Equal(t, []string{"/v3/users/a%20b%2Fc%3F@example.com"}, paths)
+}
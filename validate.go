@@ -0,0 +1,161 @@
+package engagespot
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// MaxPushMessageLength is the message length, in characters, above which push providers are
+// likely to truncate the notification
+const MaxPushMessageLength = 178
+
+// ValidationError is returned by the Set and Add methods of a notification for a rejected value,
+// and by the validation run before a send. Field names the field at fault, like "url" or
+// "recipients", and Value is the rejected value. the message is Reason alone, as it was before
+// the error had fields, so code matching on it keeps working
+type ValidationError struct {
+	Field  string
+	Value  interface{}
+	Reason string
+	// cause of the error, which errors.Is and errors.As see through, like ErrDuplicateRecipient
+	// or a *RecipientError
+	Err error
+}
+
+func (e *ValidationError) Error() string {
+	return e.Reason
+}
+
+func (e *ValidationError) Unwrap() error {
+	return e.Err
+}
+
+// err as a *ValidationError of field, if it isn't one already
+func invalidField(field string, value interface{}, err error) error {
+	var v *ValidationError
+	if errors.As(err, &v) {
+		return err
+	}
+	return &ValidationError{Field: field, Value: value, Reason: err.Error(), Err: err}
+}
+
+// ValidationErrors gathers the validation errors of a notification built with the chainable
+// setters, see Message. its message is every reason on its own line, Summary adds the fields
+type ValidationErrors []*ValidationError
+
+func (e ValidationErrors) Error() string {
+	reasons := make([]string, len(e))
+	for i, err := range e {
+		reasons[i] = err.Reason
+	}
+	return strings.Join(reasons, "\n")
+}
+
+// Unwrap returns the errors, so errors.Is and errors.As see each of them
+func (e ValidationErrors) Unwrap() []error {
+	errs := make([]error, len(e))
+	for i, err := range e {
+		errs[i] = err
+	}
+	return errs
+}
+
+// Summary is a readable multi-line list of the errors, with their fields
+func (e ValidationErrors) Summary() string {
+	var b strings.Builder
+	if len(e) == 1 {
+		b.WriteString("1 validation error:")
+	} else {
+		fmt.Fprintf(&b, "%d validation errors:", len(e))
+	}
+	for _, err := range e {
+		fmt.Fprintf(&b, "\n  %s: %s", err.Field, err.Reason)
+	}
+	return b.String()
+}
+
+// Warning is a problem with a notification which is worth flagging but doesn't prevent it from
+// being sent, unlike a validation error
+type Warning struct {
+	Field   string
+	Message string
+}
+
+func (w Warning) String() string {
+	return w.Field + ": " + w.Message
+}
+
+// WithWarningHandler can be used to receive the warnings of every successful send in a single
+// place, for example to log them
+func WithWarningHandler(handler func([]Warning)) Option {
+	return func(c *Client) {
+		c.warningHandler = handler
+	}
+}
+
+// validation layer, run before every send. errors block the send, warnings only get reported
+func (c *Client) validate(n *Notification) ([]Warning, error) {
+	return c.check(n, true)
+}
+
+// validate, recording the data schema and category of n for the warnings of later sends when
+// record is set. a dry run doesn't record, so it leaves the warnings of the send after it as they are
+func (c *Client) check(n *Notification, record bool) ([]Warning, error) {
+	if err := n.buildError(); err != nil {
+		return nil, err
+	}
+	if err := validTitle(n.Notification.Title); err != nil {
+		return nil, invalidField("title", n.Notification.Title, err)
+	}
+	if !n.hasEnoughRecipients() {
+		return nil, invalidField("recipients", n.Recipients, errors.New("not enough recipients"))
+	}
+	if n.ExpiresAt != nil && !n.ExpiresAt.After(n.deliveryTime()) {
+		return nil, invalidField("expiresAt", *n.ExpiresAt, errors.New("notification expired before it was sent"))
+	}
+
+	warnings := n.sanitizationWarnings()
+	warnings = append(warnings, n.reservedDataWarnings()...)
+	warnings = append(warnings, c.workflowWarnings(n)...)
+	warnings = append(warnings, n.Override.channelWarnings()...)
+	warnings = append(warnings, c.fingerprintWarnings(n, record)...)
+	if c.channelValidation != nil {
+		w, err := c.validateChannels(n.Override.Channels)
+		if err != nil {
+			return nil, err
+		}
+		warnings = append(warnings, w...)
+	}
+	if n.Notification.Icon == "" {
+		warnings = append(warnings, Warning{Field: "icon", Message: "no icon set"})
+	}
+	if n.Category != "" {
+		var seen bool
+		if record {
+			_, seen = c.categories.LoadOrStore(n.Category, struct{}{})
+		} else {
+			_, seen = c.categories.Load(n.Category)
+		}
+		if !seen {
+			warnings = append(warnings, Warning{
+				Field:   "category",
+				Message: fmt.Sprintf("category %q not seen before, it will be created", n.Category),
+			})
+		}
+	}
+	if length := utf8.RuneCountInString(n.Notification.Message); length > MaxPushMessageLength {
+		warnings = append(warnings, Warning{
+			Field:   "message",
+			Message: fmt.Sprintf("message is %d characters, push channels may truncate it after %d", length, MaxPushMessageLength),
+		})
+	}
+
+	return warnings, nil
+}
+
+// Warnings can be used to get the warnings produced by the last send of the notification
+func (n *Notification) Warnings() []Warning {
+	return n.last.get().warnings
+}
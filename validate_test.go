@@ -0,0 +1,139 @@
+package engagespot
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSendWithWarnings(t *testing.T) {
+	var handled []Warning
+	c := NewEngagespotClient("A", "B", WithWarningHandler(func(w []Warning) {
+		handled = append(handled, w...)
+	}))
+	c.httpClient.Transport = respondWith(200, `{}`)
+
+	n, _ := c.NewNotification("Hello")
+	n.SetMessage(strings.Repeat("a", MaxPushMessageLength+1))
+	n.AddRecipient("hello@example.com")
+
+	res, err := n.Send()
+	assert.NoError(t, err)
+	assert.Equal(t, 200, res.StatusCode)
+
+	warnings := n.Warnings()
+	assert.Len(t, warnings, 2)
+	assert.Equal(t, "icon", warnings[0].Field)
+	assert.Equal(t, "message", warnings[1].Field)
+	assert.Equal(t, warnings, handled)
+}
+
+func TestUnseenCategoryWarnsOnce(t *testing.T) {
+	c := NewEngagespotClient("A", "B")
+	c.httpClient.Transport = respondWith(200, `{}`)
+
+	n, _ := c.NewNotification("Hello")
+	n.SetIcon("https://example.com/icon.svg")
+	n.SetCategory("greet")
+	n.AddRecipient("hello@example.com")
+
+	_, err := n.Send()
+	assert.NoError(t, err)
+	assert.Len(t, n.Warnings(), 1)
+	assert.Equal(t, "category", n.Warnings()[0].Field)
+
+	_, err = n.Send()
+	assert.NoError(t, err)
+	assert.Empty(t, n.Warnings())
+}
+
+func TestValidationErrorBlocksSend(t *testing.T) {
+	handlerCalled := false
+	c := NewEngagespotClient("A", "B", WithWarningHandler(func([]Warning) { handlerCalled = true }))
+	c.httpClient.Transport = noNetwork(t)
+
+	n, _ := c.NewNotification("Hello")
+	_, err := n.Send()
+	assert.EqualError(t, err, "not enough recipients")
+	assert.False(t, handlerCalled)
+}
+
+func TestSetterValidationErrors(t *testing.T) {
+	n := newTestNotification(t)
+	for _, tc := range []struct {
+		err    error
+		field  string
+		value  interface{}
+		reason string
+	}{
+		{second(n.SetUrl("")), "url", "", "empty url string"},
+		{second(n.SetIcon("example.com/icon.png")), "icon", "example.com/icon.png", `invalid icon url "example.com/icon.png": not an absolute url`},
+		{second(n.SetCategory("")), "category", "", "empty category string"},
+		{second(n.AddData("", 1)), "data", "", "empty data key string"},
+		{second(n.AddTopic("")), "topics", "", "empty topic string"},
+		{second(n.SetTitle(" ")), "title", " ", "empty title string"},
+		{second(n.AddAction("", "https://example.com")), "actions", "", "empty action label string"},
+		{n.AddDataRaw("key", []byte("{")), "data", json.RawMessage("{"), `invalid raw data for key "key"`},
+	} {
+		var v *ValidationError
+		if assert.True(t, errors.As(tc.err, &v), tc.reason) {
+			assert.Equal(t, tc.field, v.Field)
+			assert.Equal(t, tc.value, v.Value)
+			assert.Equal(t, tc.reason, v.Reason)
+			// the message is the one from before the errors had fields
+			assert.EqualError(t, tc.err, tc.reason)
+		}
+	}
+
+	// sentinels and detailed errors are still found through the validation error
+	n.AddRecipient("a")
+	_, err := n.AddRecipients("b", "a")
+	var recipientErr *RecipientError
+	assert.ErrorIs(t, err, ErrDuplicateRecipient)
+	assert.True(t, errors.As(err, &recipientErr))
+	assert.Equal(t, 1, recipientErr.Index)
+	var v *ValidationError
+	assert.True(t, errors.As(err, &v))
+	assert.Equal(t, "recipients", v.Field)
+}
+
+func TestPreSendValidationErrors(t *testing.T) {
+	c := NewEngagespotClient("A", "B")
+	c.httpClient.Transport = noNetwork(t)
+
+	n, _ := c.NewNotification("Hello")
+	_, err := n.SendCtx(context.Background())
+	var v *ValidationError
+	assert.True(t, errors.As(err, &v))
+	assert.Equal(t, "recipients", v.Field)
+
+	n.SetRecipients(recipientIds(MaxRecipients + 1))
+	_, err = n.SendCtx(context.Background())
+	assert.ErrorIs(t, err, ErrRecipientLimit)
+	assert.True(t, errors.As(err, &v))
+	assert.Equal(t, "recipients", v.Field)
+	assert.Equal(t, MaxRecipients+1, v.Value)
+}
+
+// error of a setter returning the notification and an error
+func second(_ *Notification, err error) error {
+	return err
+}
+
+func TestNoHandlerCallWithoutWarnings(t *testing.T) {
+	handlerCalled := false
+	c := NewEngagespotClient("A", "B", WithWarningHandler(func([]Warning) { handlerCalled = true }))
+	c.httpClient.Transport = respondWith(200, `{}`)
+
+	n, _ := c.NewNotification("Hello")
+	n.SetIcon("https://example.com/icon.svg")
+	n.AddRecipient("hello@example.com")
+
+	_, err := n.Send()
+	assert.NoError(t, err)
+	assert.False(t, handlerCalled)
+}
@@ -0,0 +1,112 @@
+package engagespot
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// versions of the API the client can talk to, see WithAPIVersion
+const (
+	APIVersion3 = "v3"
+	APIVersion4 = "v4"
+)
+
+const defaultAPIVersion = APIVersion3
+
+// an API version. the endpoint table holds the v3 paths, a version only lists the paths and
+// payloads which differ, so supporting a new version is an entry in apiVersions rather than a
+// change to every method
+type apiVersion struct {
+	name string
+	// paths of the endpoints which differ from v3, by their v3 path
+	paths map[string]string
+	// turns the v3 payload of a notification send into the one of this version, nil if it's the
+	// same
+	sendPayload func(payload []byte) ([]byte, error)
+}
+
+var apiVersions = map[string]apiVersion{
+	APIVersion3: {name: APIVersion3},
+	APIVersion4: {
+		name: APIVersion4,
+		paths: map[string]string{
+			// v4 moved the in-app feed of a user under inbox
+			endpointListNotifications.path: "users/{userId}/inbox",
+		},
+		sendPayload: v4SendPayload,
+	},
+}
+
+// WithAPIVersion selects the version of the API the client talks to, one of APIVersion3, the
+// default, or APIVersion4. the version segment ending the endpoint, like the v3 of the default
+// one, is replaced by the selected version. an endpoint without a version segment, set with
+// WithBaseURL or Defaults.Endpoint, is used as is and must point to the selected version. an
+// unknown version is logged, or rejected by NewClient, and the client stays on v3
+func WithAPIVersion(v string) Option {
+	return func(c *Client) {
+		c.declare(optAPIVersion)
+		version, ok := apiVersions[v]
+		if !ok {
+			c.rejectOption(optAPIVersion, fmt.Errorf("unsupported api version %q, expected one of %s", v, strings.Join(knownAPIVersions(), ", ")))
+			return
+		}
+		c.apiVersion = version
+	}
+}
+
+// APIVersion returns the version of the API the client talks to, see WithAPIVersion
+func (c *Client) APIVersion() string {
+	return c.apiVersion.name
+}
+
+func knownAPIVersions() []string {
+	names := make([]string, 0, len(apiVersions))
+	for name := range apiVersions {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// e with the path of this version
+func (v apiVersion) endpoint(e endpoint) endpoint {
+	if path, ok := v.paths[e.path]; ok {
+		e.path = path
+	}
+	return e
+}
+
+// base url of this version, the endpoint with its trailing version segment, if it has one,
+// replaced by the name of v
+func (v apiVersion) base(endpoint string) string {
+	trimmed := strings.TrimSuffix(endpoint, "/")
+	i := strings.LastIndex(trimmed, "/")
+	if _, ok := apiVersions[trimmed[i+1:]]; !ok {
+		return endpoint
+	}
+	return trimmed[:i+1] + v.name + "/"
+}
+
+// v4 groups who a notification is sent to under sendTo, instead of the top-level recipients and
+// topics of v3
+func v4SendPayload(payload []byte) ([]byte, error) {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(payload, &fields); err != nil {
+		return nil, err
+	}
+	sendTo := map[string]json.RawMessage{}
+	for _, key := range []string{"recipients", "topics"} {
+		if raw, ok := fields[key]; ok {
+			sendTo[key] = raw
+			delete(fields, key)
+		}
+	}
+	b, err := json.Marshal(sendTo)
+	if err != nil {
+		return nil, err
+	}
+	fields["sendTo"] = b
+	return json.Marshal(fields)
+}
@@ -0,0 +1,87 @@
+package engagespot
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAPIVersions(t *testing.T) {
+	for _, tc := range []struct {
+		version string
+		paths   []string
+		send    string
+		topics  string
+	}{
+		{
+			version: APIVersion3,
+			paths:   []string{"/v3/notifications", "/v3/notifications", "/v3/sdk/connect", "/v3/users/hello@example.com/notifications"},
+			send:    `{"notification":{"title":"Hello"},"recipients":["a"]}`,
+			topics:  `{"notification":{"title":"hello"},"topics":["news"]}`,
+		},
+		{
+			version: APIVersion4,
+			paths:   []string{"/v4/notifications", "/v4/notifications", "/v4/sdk/connect", "/v4/users/hello@example.com/inbox"},
+			send:    `{"notification":{"title":"Hello"},"sendTo":{"recipients":["a"]}}`,
+			topics:  `{"notification":{"title":"hello"},"sendTo":{"topics":["news"]}}`,
+		},
+	} {
+		var paths, bodies []string
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			body, _ := io.ReadAll(r.Body)
+			paths = append(paths, r.URL.Path)
+			bodies = append(bodies, string(body))
+			w.Write([]byte(`{}`))
+		}))
+
+		c := clientFor(server.URL+"/v3/", time.Second, WithAPIVersion(tc.version))
+		assert.Equal(t, tc.version, c.APIVersion())
+		ctx := context.Background()
+		_, err := newMessage(c).SendCtx(ctx)
+		assert.NoError(t, err, tc.version)
+		topics, _ := c.NewNotification("hello")
+		topics.AddTopic("news")
+		_, err = topics.SendCtx(ctx)
+		assert.NoError(t, err, tc.version)
+		_, err = c.ConnectUser(ctx, "hello@example.com", ConnectOptions{})
+		assert.NoError(t, err, tc.version)
+		_, err = c.ListNotifications(ctx, "hello@example.com", ListOptions{})
+		assert.NoError(t, err, tc.version)
+		server.Close()
+
+		assert.Equal(t, tc.paths, paths, tc.version)
+		assert.JSONEq(t, tc.send, bodies[0], tc.version)
+		assert.JSONEq(t, tc.topics, bodies[1], tc.version)
+	}
+}
+
+func TestAPIVersionDefault(t *testing.T) {
+	c := NewEngagespotClient("A", "B")
+	assert.Equal(t, APIVersion3, c.APIVersion())
+
+	payload, _, err := newMessage(NewEngagespotClient("A", "B", WithAPIVersion(APIVersion4))).DryRun()
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"notification":{"title":"Hello"},"sendTo":{"recipients":["a"]}}`, string(payload))
+}
+
+func TestAPIVersionBase(t *testing.T) {
+	v4 := apiVersions[APIVersion4]
+	assert.Equal(t, "https://api.engagespot.co/v4/", v4.base(defaultEndpoint))
+	assert.Equal(t, "https://eu.example.com/staging/v4/", v4.base("https://eu.example.com/staging/v3/"))
+	// an endpoint without a version is used as is
+	assert.Equal(t, "http://127.0.0.1:8080/", v4.base("http://127.0.0.1:8080/"))
+	assert.Equal(t, "https://proxy.example.com/engagespot/", v4.base("https://proxy.example.com/engagespot/"))
+}
+
+func TestWithAPIVersionInvalid(t *testing.T) {
+	_, err := NewClient("A", "B", WithAPIVersion("v2"))
+	assert.EqualError(t, err, `engagespot: conflicting options: WithAPIVersion: unsupported api version "v2", expected one of v3, v4`)
+
+	c := NewEngagespotClient("A", "B", WithAPIVersion("v5"))
+	assert.Equal(t, APIVersion3, c.APIVersion())
+}
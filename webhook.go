@@ -0,0 +1,131 @@
+package engagespot
+
+import (
+	"encoding/json"
+	"errors"
+	"time"
+)
+
+// types of the webhook events decoded by DecodeWebhookEvent into a struct of their own
+const (
+	EventDelivered    = "notification.delivered"
+	EventRead         = "notification.read"
+	EventClicked      = "notification.clicked"
+	EventUnsubscribed = "user.unsubscribed"
+)
+
+// Event is a webhook event decoded by DecodeWebhookEvent. it is one of DeliveryEvent, ReadEvent,
+// ClickEvent, UnsubscribeEvent or, for types this version of the SDK doesn't know, RawEvent:
+//
+//	switch e := event.(type) {
+//	case DeliveryEvent:
+//		markDelivered(e.NotificationId, e.Channel)
+//	case RawEvent:
+//		log.Printf("unhandled %s event", e.Type)
+//	}
+type Event interface {
+	// type of the event, like EventDelivered
+	Event() string
+}
+
+// EventMeta holds the fields common to the events about a notification. Data is the data payload
+// of the notification, as set with SetData or SetDataTyped
+type EventMeta struct {
+	NotificationId string          `json:"notificationId"`
+	Recipient      string          `json:"recipient"`
+	Timestamp      time.Time       `json:"timestamp"`
+	Data           json.RawMessage `json:"data,omitempty"`
+}
+
+// DeliveryEvent is sent once a notification is delivered to a recipient on a channel
+type DeliveryEvent struct {
+	EventMeta
+	Channel string `json:"channel"`
+}
+
+// ReadEvent is sent when a recipient reads a notification
+type ReadEvent struct {
+	EventMeta
+	Channel string `json:"channel"`
+}
+
+// ClickEvent is sent when a recipient follows the url of a notification
+type ClickEvent struct {
+	EventMeta
+	Channel string `json:"channel"`
+	Url     string `json:"url"`
+}
+
+// UnsubscribeEvent is sent when a recipient opts out of a category, on every channel if Channel
+// is empty
+type UnsubscribeEvent struct {
+	EventMeta
+	Category string `json:"category"`
+	Channel  string `json:"channel,omitempty"`
+}
+
+// RawEvent is an event of a type without a struct of its own. Raw is the body of the event as
+// received
+type RawEvent struct {
+	Type string
+	Raw  json.RawMessage
+}
+
+func (DeliveryEvent) Event() string    { return EventDelivered }
+func (ReadEvent) Event() string        { return EventRead }
+func (ClickEvent) Event() string       { return EventClicked }
+func (UnsubscribeEvent) Event() string { return EventUnsubscribed }
+func (e RawEvent) Event() string       { return e.Type }
+
+// DecodeWebhookEvent decodes the body of a webhook request into the struct of its type. events of
+// unknown types are returned as a RawEvent rather than an error, so that new types of events sent
+// by the API don't break existing handlers
+func DecodeWebhookEvent(body []byte) (Event, error) {
+	var head struct {
+		Type string `json:"type"`
+	}
+	if err := json.Unmarshal(body, &head); err != nil {
+		return nil, err
+	}
+	if head.Type == "" {
+		return nil, errors.New("webhook event has no type")
+	}
+
+	switch head.Type {
+	case EventDelivered:
+		return decodeEvent[DeliveryEvent](body)
+	case EventRead:
+		return decodeEvent[ReadEvent](body)
+	case EventClicked:
+		return decodeEvent[ClickEvent](body)
+	case EventUnsubscribed:
+		return decodeEvent[UnsubscribeEvent](body)
+	}
+	return RawEvent{Type: head.Type, Raw: append(json.RawMessage(nil), body...)}, nil
+}
+
+func decodeEvent[T Event](body []byte) (Event, error) {
+	var e T
+	if err := json.Unmarshal(body, &e); err != nil {
+		return nil, err
+	}
+	return e, nil
+}
+
+// EncodeWebhookEvent encodes e the way DecodeWebhookEvent expects it, with its type, like to
+// forward it or to build events in tests. a RawEvent is encoded as it was received
+func EncodeWebhookEvent(e Event) ([]byte, error) {
+	if raw, ok := e.(RawEvent); ok {
+		return raw.Raw, nil
+	}
+	b, err := json.Marshal(e)
+	if err != nil {
+		return nil, err
+	}
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(b, &fields); err != nil {
+		return nil, err
+	}
+	fields["type"], _ = json.Marshal(e.Event())
+	return json.Marshal(fields)
+}
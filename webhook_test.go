@@ -0,0 +1,69 @@
+package engagespot
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var eventMeta = EventMeta{
+	NotificationId: "n-1",
+	Recipient:      "a@example.com",
+	Timestamp:      time.Date(2022, 1, 2, 3, 4, 5, 0, time.UTC),
+	Data:           json.RawMessage(`{"orderId":42}`),
+}
+
+func TestWebhookEventRoundTrip(t *testing.T) {
+	for body, expected := range map[string]Event{
+		`{"type":"notification.delivered","notificationId":"n-1","recipient":"a@example.com","timestamp":"2022-01-02T03:04:05Z","data":{"orderId":42},"channel":"email"}`:                             DeliveryEvent{EventMeta: eventMeta, Channel: "email"},
+		`{"type":"notification.read","notificationId":"n-1","recipient":"a@example.com","timestamp":"2022-01-02T03:04:05Z","data":{"orderId":42},"channel":"inApp"}`:                                  ReadEvent{EventMeta: eventMeta, Channel: "inApp"},
+		`{"type":"notification.clicked","notificationId":"n-1","recipient":"a@example.com","timestamp":"2022-01-02T03:04:05Z","data":{"orderId":42},"channel":"webPush","url":"https://example.com"}`: ClickEvent{EventMeta: eventMeta, Channel: "webPush", Url: "https://example.com"},
+		`{"type":"user.unsubscribed","notificationId":"n-1","recipient":"a@example.com","timestamp":"2022-01-02T03:04:05Z","data":{"orderId":42},"category":"promo"}`:                                 UnsubscribeEvent{EventMeta: eventMeta, Category: "promo"},
+	} {
+		event, err := DecodeWebhookEvent([]byte(body))
+		assert.NoError(t, err)
+		assert.Equal(t, expected, event)
+
+		encoded, err := EncodeWebhookEvent(event)
+		assert.NoError(t, err)
+		assert.JSONEq(t, body, string(encoded))
+	}
+}
+
+func TestWebhookEventSwitch(t *testing.T) {
+	event, err := DecodeWebhookEvent([]byte(`{"type":"notification.clicked","url":"https://example.com"}`))
+	assert.NoError(t, err)
+
+	switch e := event.(type) {
+	case ClickEvent:
+		assert.Equal(t, "https://example.com", e.Url)
+		assert.Equal(t, EventClicked, e.Event())
+	default:
+		t.Fatalf("unexpected event %T", event)
+	}
+}
+
+func TestUnknownWebhookEvent(t *testing.T) {
+	body := `{"type":"notification.bounced","notificationId":"n-1","reason":"mailbox full"}`
+	event, err := DecodeWebhookEvent([]byte(body))
+	assert.NoError(t, err)
+	assert.Equal(t, RawEvent{Type: "notification.bounced", Raw: json.RawMessage(body)}, event)
+	assert.Equal(t, "notification.bounced", event.Event())
+
+	encoded, err := EncodeWebhookEvent(event)
+	assert.NoError(t, err)
+	assert.Equal(t, body, string(encoded))
+}
+
+func TestInvalidWebhookEvent(t *testing.T) {
+	_, err := DecodeWebhookEvent([]byte(`{"notificationId":"n-1"}`))
+	assert.EqualError(t, err, "webhook event has no type")
+
+	_, err = DecodeWebhookEvent([]byte(`{"type":"notification.read","timestamp":"yesterday"}`))
+	assert.Error(t, err)
+
+	_, err = DecodeWebhookEvent([]byte(`[]`))
+	assert.Error(t, err)
+}
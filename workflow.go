@@ -0,0 +1,155 @@
+package engagespot
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// SendPath is the API a notification was sent through
+type SendPath int
+
+const (
+	// the notification was posted as is to `POST notifications`
+	PathLegacy SendPath = iota
+	// the notification was converted to a workflow trigger, see WithWorkflowMapping
+	PathWorkflow
+)
+
+func (p SendPath) String() string {
+	if p == PathWorkflow {
+		return "workflow"
+	}
+	return "legacy"
+}
+
+// endpoint of the API notifications taking p are sent to
+func (p SendPath) endpoint() endpoint {
+	if p == PathWorkflow {
+		return endpointTriggerWorkflow
+	}
+	return endpointSendNotification
+}
+
+// keys of the trigger data a notification is converted to. custom data of the notification is
+// nested under WorkflowKeyData so it can't clash with the other keys
+const (
+	WorkflowKeyTitle   = "title"
+	WorkflowKeyMessage = "message"
+	WorkflowKeyUrl     = "url"
+	WorkflowKeyIcon    = "icon"
+	WorkflowKeyData    = "data"
+)
+
+type workflowRef struct {
+	Identifier string `json:"identifier"`
+}
+
+type workflowSendTo struct {
+	Recipients []Recipient `json:"recipients"`
+}
+
+// body of the workflow trigger endpoint
+type workflowTrigger struct {
+	Workflow workflowRef            `json:"workflow"`
+	SendTo   workflowSendTo         `json:"sendTo"`
+	Data     map[string]interface{} `json:"data,omitempty"`
+	// only set by WorkflowTrigger, overrides of mapped notifications are dropped
+	Override *Override `json:"override,omitempty"`
+}
+
+// WithWorkflowMapping can be used to move to workflows without changing how notifications are
+// built. notifications with a category in categoryToWorkflow are converted to a trigger of the
+// mapped workflow on send. other notifications are sent as usual. SendPath tells which path a
+// notification took. overrides don't apply to workflows and are dropped with a warning
+func WithWorkflowMapping(categoryToWorkflow map[string]string) Option {
+	return func(c *Client) {
+		c.workflows = categoryToWorkflow
+	}
+}
+
+// workflow n is mapped to, if any
+func (c *Client) workflowFor(n *Notification) (string, bool) {
+	if n.Category == "" {
+		return "", false
+	}
+	workflow, ok := c.workflows[n.Category]
+	return workflow, ok
+}
+
+// path n is sent through
+func (c *Client) sendPath(n *Notification) SendPath {
+	if _, ok := c.workflowFor(n); ok {
+		return PathWorkflow
+	}
+	return PathLegacy
+}
+
+// SendPath can be used to get the path taken by the last send of the notification, see
+// SendResult for the path of a given send
+func (n *Notification) SendPath() SendPath {
+	return n.last.get().path
+}
+
+// convert n to a trigger of workflow
+func (n *Notification) workflowTrigger(workflow string) workflowTrigger {
+	recipients := make([]Recipient, len(n.Recipients))
+	for i, r := range n.Recipients {
+		recipients[i] = n.recipient(r)
+	}
+
+	data := map[string]interface{}{WorkflowKeyTitle: n.Notification.Title}
+	for key, value := range map[string]string{
+		WorkflowKeyMessage: n.Notification.Message,
+		WorkflowKeyUrl:     n.Notification.Url,
+		WorkflowKeyIcon:    n.Notification.Icon,
+	} {
+		if value != "" {
+			data[key] = value
+		}
+	}
+	if len(n.Notification.Data) > 0 {
+		data[WorkflowKeyData] = n.Notification.Data
+	}
+
+	return workflowTrigger{
+		Workflow: workflowRef{Identifier: workflow},
+		SendTo:   workflowSendTo{Recipients: recipients},
+		Data:     data,
+	}
+}
+
+// payload and endpoint to send n through. a payload which can't be encoded, like data holding a
+// channel or a NaN, is an error here rather than an empty body the API would reject
+func (c *Client) sendPayload(n *Notification) ([]byte, endpoint, error) {
+	sent := n.withTTL()
+	e, v := endpointSendNotification, interface{}(withPushExpiry(c.correctSendAt(sent), sent))
+	if workflow, ok := c.workflowFor(n); ok {
+		e, v = endpointTriggerWorkflow, n.workflowTrigger(workflow)
+	}
+	payload, err := json.Marshal(v)
+	if err == nil && e == endpointSendNotification && c.apiVersion.sendPayload != nil {
+		payload, err = c.apiVersion.sendPayload(payload)
+	}
+	if err != nil {
+		return nil, e, fmt.Errorf("encoding notification: %w", err)
+	}
+	return payload, e, nil
+}
+
+// warning for overrides dropped by the workflow path
+func (c *Client) workflowWarnings(n *Notification) []Warning {
+	if _, ok := c.workflowFor(n); !ok {
+		return nil
+	}
+	var warnings []Warning
+	if !n.Override.empty() {
+		warnings = append(warnings, Warning{Field: "override", Message: "overrides don't apply to workflow triggers and were dropped"})
+	}
+	if n.SendAt != nil {
+		warnings = append(warnings, Warning{Field: "sendAt", Message: "workflow triggers can't be scheduled, the send time was dropped"})
+	}
+	if n.ExpiresAt != nil || n.ttl > 0 {
+		warnings = append(warnings, Warning{Field: "expiresAt", Message: "workflow triggers can't expire, the expiry time was dropped"})
+	}
+	return warnings
+}
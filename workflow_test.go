@@ -0,0 +1,99 @@
+package engagespot
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type capturedRequest struct {
+	path string
+	body []byte
+}
+
+func captureRequests(requests *[]capturedRequest) http.RoundTripper {
+	return roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		body, _ := io.ReadAll(req.Body)
+		*requests = append(*requests, capturedRequest{path: req.URL.Path, body: body})
+		return respondWith(200, `{}`).RoundTrip(req)
+	})
+}
+
+func orderShipped(c *Client, category string) *Notification {
+	n, _ := c.NewNotification("Order shipped")
+	n.SetMessage("Your order is on its way")
+	n.SetUrl("https://example.com/orders/42")
+	n.SetCategory(category)
+	n.AddRecipient("a")
+	n.AddRecipient("b")
+	n.Notification.Data = map[string]interface{}{"orderId": 42.0, "title": "data title"}
+	return n
+}
+
+func TestWorkflowMapping(t *testing.T) {
+	var requests []capturedRequest
+	c := NewEngagespotClient("A", "B", WithWorkflowMapping(map[string]string{"orders": "order-shipped"}))
+	c.httpClient.Transport = captureRequests(&requests)
+
+	n := orderShipped(c, "orders")
+	_, err := n.Send()
+	assert.NoError(t, err)
+	assert.Equal(t, PathWorkflow, n.SendPath())
+
+	assert.Len(t, requests, 1)
+	assert.Equal(t, "/v3/workflows/trigger", requests[0].path)
+
+	var body map[string]interface{}
+	assert.NoError(t, json.Unmarshal(requests[0].body, &body))
+	assert.Equal(t, map[string]interface{}{
+		"workflow": map[string]interface{}{"identifier": "order-shipped"},
+		"sendTo": map[string]interface{}{"recipients": []interface{}{
+			map[string]interface{}{"identifier": "a"},
+			map[string]interface{}{"identifier": "b"},
+		}},
+		"data": map[string]interface{}{
+			"title":   "Order shipped",
+			"message": "Your order is on its way",
+			"url":     "https://example.com/orders/42",
+			"data":    map[string]interface{}{"orderId": 42.0, "title": "data title"},
+		},
+	}, body)
+}
+
+func TestWorkflowMappingUnmappedUnchanged(t *testing.T) {
+	var mapped, legacy []capturedRequest
+	c := NewEngagespotClient("A", "B", WithWorkflowMapping(map[string]string{"orders": "order-shipped"}))
+	c.httpClient.Transport = captureRequests(&mapped)
+	plain := NewEngagespotClient("A", "B")
+	plain.httpClient.Transport = captureRequests(&legacy)
+
+	n := orderShipped(c, "promo")
+	n.Override.AddChannel("email")
+	_, err := n.Send()
+	assert.NoError(t, err)
+	assert.Equal(t, PathLegacy, n.SendPath())
+	assert.NotContains(t, n.Warnings(), Warning{Field: "override", Message: "overrides don't apply to workflow triggers and were dropped"})
+
+	n = orderShipped(plain, "promo")
+	n.Override.AddChannel("email")
+	_, err = n.Send()
+	assert.NoError(t, err)
+
+	assert.Equal(t, legacy, mapped)
+	assert.Equal(t, "/v3/notifications", mapped[0].path)
+}
+
+func TestWorkflowMappingDropsOverrides(t *testing.T) {
+	c := NewEngagespotClient("A", "B", WithWorkflowMapping(map[string]string{"orders": "order-shipped"}))
+	c.httpClient.Transport = respondWith(200, `{}`)
+
+	n := orderShipped(c, "orders")
+	n.SetIcon("https://example.com/icon.png")
+	n.Override.AddChannel("email")
+	_, err := n.Send()
+	assert.NoError(t, err)
+	assert.Contains(t, n.Warnings(), Warning{Field: "override", Message: "overrides don't apply to workflow triggers and were dropped"})
+}